@@ -26,7 +26,9 @@ import (
 	"github.com/kubescape/node-agent/pkg/containerwatcher/v1"
 	"github.com/kubescape/node-agent/pkg/dnsmanager"
 	"github.com/kubescape/node-agent/pkg/exporters"
+	"github.com/kubescape/node-agent/pkg/forensics"
 	"github.com/kubescape/node-agent/pkg/healthmanager"
+	"github.com/kubescape/node-agent/pkg/imageverifier"
 	"github.com/kubescape/node-agent/pkg/malwaremanager"
 	malwaremanagerv1 "github.com/kubescape/node-agent/pkg/malwaremanager/v1"
 	"github.com/kubescape/node-agent/pkg/metricsmanager"
@@ -40,11 +42,13 @@ import (
 	"github.com/kubescape/node-agent/pkg/objectcache/dnscache"
 	"github.com/kubescape/node-agent/pkg/objectcache/k8scache"
 	"github.com/kubescape/node-agent/pkg/objectcache/networkneighborhoodcache"
+	"github.com/kubescape/node-agent/pkg/objectcache/threatintelcache"
 	objectcachev1 "github.com/kubescape/node-agent/pkg/objectcache/v1"
 	"github.com/kubescape/node-agent/pkg/processmanager"
 	processmanagerv1 "github.com/kubescape/node-agent/pkg/processmanager/v1"
 	rulebinding "github.com/kubescape/node-agent/pkg/rulebindingmanager"
 	rulebindingcachev1 "github.com/kubescape/node-agent/pkg/rulebindingmanager/cache"
+	ruleenginetypes "github.com/kubescape/node-agent/pkg/ruleengine/types"
 	"github.com/kubescape/node-agent/pkg/rulemanager"
 	rulemanagerv1 "github.com/kubescape/node-agent/pkg/rulemanager/v1"
 	"github.com/kubescape/node-agent/pkg/sbommanager"
@@ -54,6 +58,7 @@ import (
 	"github.com/kubescape/node-agent/pkg/storage/v1"
 	"github.com/kubescape/node-agent/pkg/utils"
 	"github.com/kubescape/node-agent/pkg/validator"
+	"github.com/kubescape/node-agent/pkg/vulnerabilities"
 	"github.com/kubescape/node-agent/pkg/watcher/dynamicwatcher"
 	"github.com/kubescape/node-agent/pkg/watcher/seccompprofilewatcher"
 )
@@ -133,7 +138,7 @@ func main() {
 	}
 
 	// Start the health manager
-	healthManager := healthmanager.NewHealthManager()
+	healthManager := healthmanager.NewHealthManager(cfg.LocalAPISocket)
 	healthManager.Start(ctx)
 
 	// Create clients
@@ -169,14 +174,26 @@ func main() {
 		}
 		seccompWatcher := seccompprofilewatcher.NewSeccompProfileWatcher(storageClient.StorageClient, seccompManager)
 		dWatcher.AddAdaptor(seccompWatcher)
+		healthManager.RegisterLocalHandler(seccompmanager.HandlerPath, seccompmanager.NewHandler(seccompManager))
 	} else {
 		seccompManager = seccompmanager.NewSeccompManagerMock()
 	}
 
+	// Create the process manager
+	var processManager processmanager.ProcessManagerClient
+	if cfg.EnableRuntimeDetection {
+		processManager = processmanagerv1.CreateProcessManager(ctx, cfg)
+	} else {
+		processManager = processmanager.CreateProcessManagerMock()
+	}
+	if cfg.EnableProcessAPI {
+		healthManager.RegisterLocalHandler(processmanager.HandlerPath, processmanager.NewHandler(processManager))
+	}
+
 	// Create the application profile manager
 	var applicationProfileManager applicationprofilemanager.ApplicationProfileManagerClient
 	if cfg.EnableApplicationProfile {
-		applicationProfileManager, err = applicationprofilemanagerv1.CreateApplicationProfileManager(ctx, cfg, clusterData.ClusterName, k8sClient, storageClient, k8sObjectCache, seccompManager)
+		applicationProfileManager, err = applicationprofilemanagerv1.CreateApplicationProfileManager(ctx, cfg, clusterData.ClusterName, k8sClient, storageClient, k8sObjectCache, seccompManager, processManager, prometheusExporter)
 		if err != nil {
 			logger.L().Ctx(ctx).Fatal("error creating the application profile manager", helpers.Error(err))
 		}
@@ -204,7 +221,6 @@ func main() {
 	}
 
 	var ruleManager rulemanager.RuleManagerClient
-	var processManager processmanager.ProcessManagerClient
 	var objCache objectcache.ObjectCache
 	var ruleBindingNotify chan rulebinding.RuleBindingNotify
 	var cloudMetadata *apitypes.CloudMetadata
@@ -216,10 +232,55 @@ func main() {
 		}
 	}
 
-	if cfg.EnableRuntimeDetection {
-		// create the process manager
-		processManager = processmanagerv1.CreateProcessManager(ctx)
+	// Create the vulnerability matcher, it also enriches the alerts with the top CVEs of the image
+	var vulnerabilityMatcher *vulnerabilities.Matcher
+	var enrichers ruleenginetypes.Enrichers
+	if cfg.VulnerabilityMatching.Enabled {
+		vulnerabilityMatcher, err = vulnerabilities.NewMatcher(cfg.VulnerabilityMatching, storageClient)
+		if err != nil {
+			logger.L().Ctx(ctx).Fatal("error creating the vulnerability matcher", helpers.Error(err))
+		}
+		defer vulnerabilityMatcher.Close()
+		enrichers = append(enrichers, vulnerabilityMatcher)
+	}
 
+	// Create the IG k8sClient
+	if err := igconfig.Config.ReadInConfig(); err != nil {
+		logger.L().Warning("reading IG config", helpers.Error(err))
+	}
+	igK8sClient, err := containercollection.NewK8sClient(cfg.NodeName)
+	if err != nil {
+		logger.L().Fatal("error creating IG Kubernetes client", helpers.Error(err))
+	}
+	defer igK8sClient.Close()
+	logger.L().Info("IG Kubernetes client created", helpers.Interface("client", igK8sClient))
+	logger.L().Info("detected container runtime", helpers.String("containerRuntime", igK8sClient.RuntimeConfig.Name.String()))
+
+	// Create the image verifier
+	var imageVerifier *imageverifier.Verifier
+	if cfg.ImageVerification.Enabled {
+		imageVerifier, err = imageverifier.NewVerifier(cfg, k8sObjectCache)
+		if err != nil {
+			logger.L().Ctx(ctx).Fatal("error creating the image verifier", helpers.Error(err))
+		}
+	}
+
+	// Create the SBOM manager
+	var sbomManager sbommanager.SbomManagerClient
+	if cfg.EnableSbomGeneration {
+		sbomManagerV1, err := sbommanagerv1.CreateSbomManager(ctx, cfg, igK8sClient.RuntimeConfig.SocketPath, storageClient, k8sObjectCache, vulnerabilityMatcher, imageVerifier, prometheusExporter)
+		if err != nil {
+			logger.L().Ctx(ctx).Fatal("error creating SbomManager", helpers.Error(err))
+		}
+		if documentStore := sbomManagerV1.DocumentStore(); documentStore != nil {
+			healthManager.RegisterLocalHandler(sbommanagerv1.FormatsHandlerPath, documentStore)
+		}
+		sbomManager = sbomManagerV1
+	} else {
+		sbomManager = sbommanager.CreateSbomManagerMock()
+	}
+
+	if cfg.EnableRuntimeDetection {
 		// create ruleBinding cache
 		ruleBindingCache := rulebindingcachev1.NewCache(cfg.NodeName, k8sClient)
 		dWatcher.AddAdaptor(ruleBindingCache)
@@ -235,14 +296,35 @@ func main() {
 
 		dc := dnscache.NewDnsCache(dnsResolver)
 
+		// create threat intel cache
+		var tc objectcache.ThreatIntelCache
+		if cfg.EnableThreatIntel {
+			threatIntelCache := threatintelcache.NewThreatIntelCache(cfg.ThreatIntelPaths, cfg.ThreatIntelConfigMaps, cfg.NamespaceName, k8sClient, cfg.ThreatIntelReloadInterval)
+			threatIntelCache.Start(ctx)
+			tc = threatIntelCache
+		} else {
+			tc = &objectcache.ThreatIntelCacheMock{}
+		}
+
 		// create object cache
-		objCache = objectcachev1.NewObjectCache(k8sObjectCache, apc, nnc, dc)
+		objCache = objectcachev1.NewObjectCache(k8sObjectCache, apc, nnc, dc, tc, processManager)
 
 		// create exporter
 		exporter := exporters.InitExporters(cfg.Exporters, clusterData.ClusterName, cfg.NodeName, cloudMetadata)
 
+		// create the forensic evidence collector
+		var evidenceCollector *forensics.Collector
+		if cfg.Forensics.Enabled {
+			evidenceCollector, err = forensics.NewCollector(cfg.Forensics)
+			if err != nil {
+				logger.L().Ctx(ctx).Fatal("error creating the evidence collector", helpers.Error(err))
+			}
+			// the bundles hold the process memory maps and environment, they are served to the node only
+			healthManager.RegisterLocalHandler(forensics.HandlerPath, evidenceCollector.Store())
+		}
+
 		// create runtimeDetection managers
-		ruleManager, err = rulemanagerv1.CreateRuleManager(ctx, cfg, k8sClient, ruleBindingCache, objCache, exporter, prometheusExporter, cfg.NodeName, clusterData.ClusterName, processManager, dnsResolver, nil)
+		ruleManager, err = rulemanagerv1.CreateRuleManager(ctx, cfg, k8sClient, ruleBindingCache, objCache, exporter, prometheusExporter, cfg.NodeName, clusterData.ClusterName, processManager, dnsResolver, sbomManager, enrichers, evidenceCollector)
 		if err != nil {
 			logger.L().Ctx(ctx).Fatal("error creating RuleManager", helpers.Error(err))
 		}
@@ -252,9 +334,9 @@ func main() {
 		apc := &objectcache.ApplicationProfileCacheMock{}
 		nnc := &objectcache.NetworkNeighborhoodCacheMock{}
 		dc := &objectcache.DnsCacheMock{}
-		objCache = objectcachev1.NewObjectCache(k8sObjectCache, apc, nnc, dc)
+		tc := &objectcache.ThreatIntelCacheMock{}
+		objCache = objectcachev1.NewObjectCache(k8sObjectCache, apc, nnc, dc, tc, processManager)
 		ruleBindingNotify = make(chan rulebinding.RuleBindingNotify, 1)
-		processManager = processmanager.CreateProcessManagerMock()
 	}
 
 	// Create the node profile manager
@@ -271,7 +353,7 @@ func main() {
 	if cfg.EnableMalwareDetection {
 		// create exporter
 		exporter := exporters.InitExporters(cfg.Exporters, clusterData.ClusterName, cfg.NodeName, cloudMetadata)
-		malwareManager, err = malwaremanagerv1.CreateMalwareManager(cfg, k8sClient, cfg.NodeName, clusterData.ClusterName, exporter, prometheusExporter, k8sObjectCache)
+		malwareManager, err = malwaremanagerv1.CreateMalwareManager(ctx, cfg, k8sClient, cfg.NodeName, clusterData.ClusterName, exporter, prometheusExporter, k8sObjectCache)
 		if err != nil {
 			logger.L().Ctx(ctx).Fatal("error creating MalwareManager", helpers.Error(err))
 		}
@@ -279,35 +361,16 @@ func main() {
 		malwareManager = malwaremanager.CreateMalwareManagerMock()
 	}
 
-	// Create the IG k8sClient
-	if err := igconfig.Config.ReadInConfig(); err != nil {
-		logger.L().Warning("reading IG config", helpers.Error(err))
-	}
-	igK8sClient, err := containercollection.NewK8sClient(cfg.NodeName)
-	if err != nil {
-		logger.L().Fatal("error creating IG Kubernetes client", helpers.Error(err))
-	}
-	defer igK8sClient.Close()
-	logger.L().Info("IG Kubernetes client created", helpers.Interface("client", igK8sClient))
-	logger.L().Info("detected container runtime", helpers.String("containerRuntime", igK8sClient.RuntimeConfig.Name.String()))
-
-	// Create the SBOM manager
-	var sbomManager sbommanager.SbomManagerClient
-	if cfg.EnableSbomGeneration {
-		sbomManager, err = sbommanagerv1.CreateSbomManager(ctx, cfg, igK8sClient.RuntimeConfig.SocketPath, storageClient, k8sObjectCache)
-		if err != nil {
-			logger.L().Ctx(ctx).Fatal("error creating SbomManager", helpers.Error(err))
-		}
-	} else {
-		sbomManager = sbommanager.CreateSbomManagerMock()
-	}
-
 	// Create the container handler
 	mainHandler, err := containerwatcher.CreateIGContainerWatcher(cfg, applicationProfileManager, k8sClient, igK8sClient, networkManagerClient, dnsManagerClient, prometheusExporter, ruleManager, malwareManager, sbomManager, &ruleBindingNotify, igK8sClient.RuntimeConfig, nil, nil, processManager, clusterData.ClusterName, objCache)
 	if err != nil {
 		logger.L().Ctx(ctx).Fatal("error creating the container watcher", helpers.Error(err))
 	}
 	healthManager.SetContainerWatcher(mainHandler)
+	if imageVerifier != nil {
+		imageVerifier.SetRuleManager(ruleManager)
+		mainHandler.RegisterContainerReceiver(imageVerifier)
+	}
 
 	// Start the profileManager
 	profileManager.Start(ctx)
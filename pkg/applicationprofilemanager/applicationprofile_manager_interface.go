@@ -2,7 +2,9 @@ package applicationprofilemanager
 
 import (
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	"github.com/kubescape/node-agent/pkg/ebpf/events"
 	tracerhttptype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/http/types"
+	tracerseccompaudittype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer/types"
 )
 
 type ApplicationProfileManagerClient interface {
@@ -10,9 +12,11 @@ type ApplicationProfileManagerClient interface {
 	RegisterPeekFunc(peek func(mntns uint64) ([]string, error))
 	ReportCapability(k8sContainerID, capability string)
 	ReportFileExec(k8sContainerID, path string, args []string)
+	ReportExecLineage(k8sContainerID string, event *events.ExecEvent)
 	ReportFileOpen(k8sContainerID, path string, flags []string)
 	ReportHTTPEvent(k8sContainerID string, event *tracerhttptype.Event)
 	ReportRulePolicy(k8sContainerID, ruleId, allowedProcess string, allowedContainer bool)
 	ReportDroppedEvent(k8sContainerID string)
+	ReportSeccompViolation(k8sContainerID string, event *tracerseccompaudittype.Event)
 	ContainerReachedMaxTime(containerID string)
 }
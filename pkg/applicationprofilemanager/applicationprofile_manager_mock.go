@@ -2,7 +2,9 @@ package applicationprofilemanager
 
 import (
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	"github.com/kubescape/node-agent/pkg/ebpf/events"
 	tracerhttptype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/http/types"
+	tracerseccompaudittype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer/types"
 )
 
 type ApplicationProfileManagerMock struct {
@@ -30,6 +32,10 @@ func (a ApplicationProfileManagerMock) ReportFileExec(_, _ string, _ []string) {
 	// noop
 }
 
+func (a ApplicationProfileManagerMock) ReportExecLineage(_ string, _ *events.ExecEvent) {
+	// noop
+}
+
 func (a ApplicationProfileManagerMock) ReportFileOpen(_, _ string, _ []string) {
 	// noop
 }
@@ -38,6 +44,10 @@ func (a ApplicationProfileManagerMock) ReportDroppedEvent(_ string) {
 	// noop
 }
 
+func (a ApplicationProfileManagerMock) ReportSeccompViolation(_ string, _ *tracerseccompaudittype.Event) {
+	// noop
+}
+
 func (a ApplicationProfileManagerMock) ReportHTTPEvent(_ string, _ *tracerhttptype.Event) {
 	// noop
 }
@@ -19,9 +19,13 @@ import (
 	helpersv1 "github.com/kubescape/k8s-interface/instanceidhandler/v1/helpers"
 	"github.com/kubescape/node-agent/pkg/applicationprofilemanager"
 	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/ebpf/events"
 	tracerhttptype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/http/types"
+	tracerseccompaudittype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer/types"
 	"github.com/kubescape/node-agent/pkg/k8sclient"
+	"github.com/kubescape/node-agent/pkg/metricsmanager"
 	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/processmanager"
 	"github.com/kubescape/node-agent/pkg/seccompmanager"
 	"github.com/kubescape/node-agent/pkg/storage"
 	"github.com/kubescape/node-agent/pkg/utils"
@@ -46,6 +50,7 @@ type ApplicationProfileManager struct {
 	trackedContainers        mapset.Set[string]                                                 // key is k8sContainerID
 	removedContainers        mapset.Set[string]                                                 // key is k8sContainerID
 	droppedEventsContainers  mapset.Set[string]                                                 // key is k8sContainerID
+	profileBudgets           maps.SafeMap[string, *ProfileBudget]                               // key is k8sContainerID
 	savedCapabilities        maps.SafeMap[string, cache.ExpiringCache]                          // key is k8sContainerID
 	savedEndpoints           maps.SafeMap[string, cache.ExpiringCache]                          // key is k8sContainerID
 	savedExecs               maps.SafeMap[string, cache.ExpiringCache]                          // key is k8sContainerID
@@ -63,11 +68,13 @@ type ApplicationProfileManager struct {
 	storageClient            storage.StorageClient
 	syscallPeekFunc          func(nsMountId uint64) ([]string, error)
 	seccompManager           seccompmanager.SeccompManagerClient
+	processManager           processmanager.ProcessManagerClient
+	metrics                  metricsmanager.MetricsManager
 }
 
 var _ applicationprofilemanager.ApplicationProfileManagerClient = (*ApplicationProfileManager)(nil)
 
-func CreateApplicationProfileManager(ctx context.Context, cfg config.Config, clusterName string, k8sClient k8sclient.K8sClientInterface, storageClient storage.StorageClient, k8sObjectCache objectcache.K8sObjectCache, seccompManager seccompmanager.SeccompManagerClient) (*ApplicationProfileManager, error) {
+func CreateApplicationProfileManager(ctx context.Context, cfg config.Config, clusterName string, k8sClient k8sclient.K8sClientInterface, storageClient storage.StorageClient, k8sObjectCache objectcache.K8sObjectCache, seccompManager seccompmanager.SeccompManagerClient, processManager processmanager.ProcessManagerClient, metrics metricsmanager.MetricsManager) (*ApplicationProfileManager, error) {
 	return &ApplicationProfileManager{
 		cfg:                     cfg,
 		clusterName:             clusterName,
@@ -80,6 +87,8 @@ func CreateApplicationProfileManager(ctx context.Context, cfg config.Config, clu
 		removedContainers:       mapset.NewSet[string](),
 		droppedEventsContainers: mapset.NewSet[string](),
 		seccompManager:          seccompManager,
+		processManager:          processManager,
+		metrics:                 metrics,
 	}, nil
 }
 
@@ -92,6 +101,7 @@ func (am *ApplicationProfileManager) deleteResources(watchedContainer *utils.Wat
 	watchedContainer.UpdateDataTicker.Stop()
 	am.trackedContainers.Remove(watchedContainer.K8sContainerID)
 	am.droppedEventsContainers.Remove(watchedContainer.K8sContainerID)
+	am.profileBudgets.Delete(watchedContainer.K8sContainerID)
 	am.savedCapabilities.Delete(watchedContainer.K8sContainerID)
 	am.savedEndpoints.Delete(watchedContainer.K8sContainerID)
 	am.savedExecs.Delete(watchedContainer.K8sContainerID)
@@ -138,13 +148,9 @@ func (am *ApplicationProfileManager) monitorContainer(ctx context.Context, conta
 				watchedContainer.UpdateDataTicker.Reset(utils.AddJitter(am.cfg.UpdateDataPeriod, am.cfg.MaxJitterPercentage))
 			}
 			watchedContainer.SetStatus(utils.WatchedContainerStatusReady)
-			am.saveProfile(ctx, watchedContainer, container.K8s.Namespace, nil)
-
-			// save profile after initialaztion
-			if initOps != nil {
-				am.saveProfile(ctx, watchedContainer, container.K8s.Namespace, initOps)
-				initOps = nil
-			}
+			// the initialization is saved with the first activity, so it doesn't reset the rule policies saved before it
+			am.saveProfile(ctx, watchedContainer, container.K8s.Namespace, initOps)
+			initOps = nil
 
 		case err := <-watchedContainer.SyncChannel:
 			switch {
@@ -154,13 +160,16 @@ func (am *ApplicationProfileManager) monitorContainer(ctx context.Context, conta
 					watchedContainer.SetStatus(utils.WatchedContainerStatusCompleted)
 				}
 				am.saveProfile(ctx, watchedContainer, container.K8s.Namespace, nil)
+				am.generateSeccompProfile(ctx, watchedContainer, container)
 				return err
 			case errors.Is(err, utils.ContainerReachedMaxTime):
 				watchedContainer.SetStatus(utils.WatchedContainerStatusCompleted)
 				am.saveProfile(ctx, watchedContainer, container.K8s.Namespace, nil)
+				am.generateSeccompProfile(ctx, watchedContainer, container)
 				return err
 			case errors.Is(err, utils.ObjectCompleted):
 				watchedContainer.SetStatus(utils.WatchedContainerStatusCompleted)
+				am.generateSeccompProfile(ctx, watchedContainer, container)
 				return err
 			case errors.Is(err, utils.TooLargeObjectError):
 				logger.L().Debug("ApplicationProfileManager - object is too large")
@@ -171,6 +180,71 @@ func (am *ApplicationProfileManager) monitorContainer(ctx context.Context, conta
 	}
 }
 
+// generateSeccompProfile turns the syscalls of a completed profile into seccomp profiles ready to be used by the pods
+func (am *ApplicationProfileManager) generateSeccompProfile(ctx context.Context, watchedContainer *utils.WatchedContainerData, container *containercollection.Container) {
+	if !am.cfg.EnableSeccompGeneration || watchedContainer.GetStatus() != utils.WatchedContainerStatusCompleted || watchedContainer.InstanceID == nil {
+		return
+	}
+	slug, err := watchedContainer.InstanceID.GetSlug(true)
+	if err != nil {
+		return
+	}
+	// the stored profile holds the syscalls of all the instances of the workload, not only the ones of this container
+	syscalls, err := am.completedSyscalls(container.K8s.Namespace, slug, container.K8s.ContainerName)
+	if err != nil {
+		logger.L().Debug("ApplicationProfileManager - no completed profile to generate the seccomp profile from", helpers.Error(err),
+			helpers.String("slug", slug),
+			helpers.String("container ID", watchedContainer.ContainerID),
+			helpers.String("k8s workload", watchedContainer.K8sContainerID))
+		return
+	}
+	// without learned syscalls the enforced profile would block the container
+	if len(syscalls) == 0 {
+		return
+	}
+	var podContainers []string
+	for _, containerType := range []utils.ContainerType{utils.Container, utils.InitContainer} {
+		for _, info := range watchedContainer.ContainerInfos[containerType] {
+			podContainers = append(podContainers, info.Name)
+		}
+	}
+	_, err = am.seccompManager.GenerateSeccompProfile(seccompmanager.GeneratedProfile{
+		Namespace:        container.K8s.Namespace,
+		Workload:         slug,
+		PodName:          container.K8s.PodName,
+		Container:        container.K8s.ContainerName,
+		PodContainers:    podContainers,
+		LocalhostProfile: watchedContainer.SeccompProfilePath,
+		Syscalls:         syscalls,
+	})
+	if err != nil {
+		logger.L().Ctx(ctx).Warning("ApplicationProfileManager - failed to generate seccomp profile", helpers.Error(err),
+			helpers.String("slug", slug),
+			helpers.Int("container index", watchedContainer.ContainerIndex),
+			helpers.String("container ID", watchedContainer.ContainerID),
+			helpers.String("k8s workload", watchedContainer.K8sContainerID))
+	}
+}
+
+// completedSyscalls returns the syscalls of the container in the stored profile, once the profile is completed
+func (am *ApplicationProfileManager) completedSyscalls(namespace, slug, containerName string) ([]string, error) {
+	profile, err := am.storageClient.GetApplicationProfile(namespace, slug)
+	if err != nil {
+		return nil, err
+	}
+	if status := profile.Annotations[helpersv1.StatusMetadataKey]; status != helpersv1.Completed {
+		return nil, fmt.Errorf("profile %s is %s", slug, status)
+	}
+	for _, containers := range [][]v1beta1.ApplicationProfileContainer{profile.Spec.Containers, profile.Spec.InitContainers, profile.Spec.EphemeralContainers} {
+		for _, c := range containers {
+			if c.Name == containerName {
+				return c.Syscalls, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("container %s not found in profile %s", containerName, slug)
+}
+
 func (am *ApplicationProfileManager) saveProfile(ctx context.Context, watchedContainer *utils.WatchedContainerData, namespace string, initalizeOperations []utils.PatchOperation) {
 	ctx, span := otel.Tracer("").Start(ctx, "ApplicationProfileManager.saveProfile")
 	defer span.End()
@@ -275,6 +349,20 @@ func (am *ApplicationProfileManager) saveProfile(ctx context.Context, watchedCon
 		return true
 	})
 
+	// compact the new entries to keep the profile within its budget
+	budget := NewProfileBudget(am.cfg.MaxProfileOpens, am.cfg.MaxProfileExecs, am.cfg.MaxProfileEndpoints)
+	if savedBudget, ok := am.profileBudgets.Load(watchedContainer.K8sContainerID); ok {
+		budget = savedBudget.Clone()
+	}
+	var openCollapsed, execCollapsed, endpointCollapsed int
+	opens, openCollapsed = compactOpens(opens, &budget.Opens)
+	execs, execCollapsed = compactExecs(execs, &budget.Execs)
+	endpoints, endpointCollapsed = compactEndpoints(endpoints, &budget.Endpoints)
+	recordEntries(&budget.Opens, opens, openCollapsed)
+	recordEntries(&budget.Execs, execs, execCollapsed)
+	recordEntries(&budget.Endpoints, endpoints, endpointCollapsed)
+	compactionStatsKey := getCompactionStatsMetadataKey(watchedContainer.ContainerType, watchedContainer.ContainerIndex)
+
 	// get rule policies
 	rulePolicies := make(map[string]v1beta1.RulePolicy)
 	toSaveRulePolicies := am.toSaveRulePolicies.Get(watchedContainer.K8sContainerID)
@@ -295,14 +383,12 @@ func (am *ApplicationProfileManager) saveProfile(ctx context.Context, watchedCon
 	// 3a. the object is missing its container slice - ADD one with the container profile at the right index
 	// 3b. the object is missing the container profile - ADD the container profile at the right index
 	// 3c. default - patch the container ourselves and REPLACE it at the right index
-	if len(capabilities) > 0 || len(endpoints) > 0 || len(execs) > 0 || len(opens) > 0 || len(toSaveSyscalls) > 0 || len(initalizeOperations) > 0 || watchedContainer.StatusUpdated() {
-		// 0. calculate patch
-		operations := utils.CreateCapabilitiesPatchOperations(capabilities, observedSyscalls, execs, opens, endpoints, rulePolicies, watchedContainer.ContainerType.String(), watchedContainer.ContainerIndex)
-		if len(initalizeOperations) > 0 {
-			operations = append(operations, initalizeOperations...)
-		}
+	if len(capabilities) > 0 || len(endpoints) > 0 || len(execs) > 0 || len(opens) > 0 || len(toSaveSyscalls) > 0 || len(rulePolicies) > 0 || len(initalizeOperations) > 0 || watchedContainer.StatusUpdated() {
+		// 0. calculate patch, the initialization replaces the rule policies so it goes before the policies to add
+		operations := append(initalizeOperations, utils.CreateCapabilitiesPatchOperations(capabilities, observedSyscalls, execs, opens, endpoints, rulePolicies, watchedContainer.ContainerType.String(), watchedContainer.ContainerIndex)...)
 
 		operations = utils.AppendStatusAnnotationPatchOperations(operations, watchedContainer)
+		operations = budget.AppendCompactionStatsPatchOperations(operations, compactionStatsKey)
 		operations = append(operations, utils.PatchOperation{
 			Op:    "add",
 			Path:  "/spec/architectures/-",
@@ -322,6 +408,7 @@ func (am *ApplicationProfileManager) saveProfile(ctx context.Context, watchedCon
 							helpersv1.WlidMetadataKey:       watchedContainer.Wlid,
 							helpersv1.CompletionMetadataKey: string(watchedContainer.GetCompletionStatus()),
 							helpersv1.StatusMetadataKey:     string(watchedContainer.GetStatus()),
+							compactionStatsKey:              budget.GetCompactionStatsAnnotation(),
 						},
 						Labels: utils.GetLabels(watchedContainer, true),
 					},
@@ -466,6 +553,7 @@ func (am *ApplicationProfileManager) saveProfile(ctx context.Context, watchedCon
 					})
 
 					replaceOperations = utils.AppendStatusAnnotationPatchOperations(replaceOperations, watchedContainer)
+					replaceOperations = budget.AppendCompactionStatsPatchOperations(replaceOperations, compactionStatsKey)
 					if len(existingObject.Spec.Architectures) == 0 {
 						replaceOperations = append(replaceOperations, utils.PatchOperation{
 							Op:    "add",
@@ -514,6 +602,12 @@ func (am *ApplicationProfileManager) saveProfile(ctx context.Context, watchedCon
 			// for status updates to be tracked, we reset the update flag
 			watchedContainer.ResetStatusUpdatedFlag()
 
+			// record budget usage
+			am.profileBudgets.Set(watchedContainer.K8sContainerID, budget)
+			am.metrics.ReportProfileCompaction(opensCategory, openCollapsed, budget.Opens.Usage())
+			am.metrics.ReportProfileCompaction(execsCategory, execCollapsed, budget.Execs.Usage())
+			am.metrics.ReportProfileCompaction(endpointsCategory, endpointCollapsed, budget.Endpoints.Usage())
+
 			// record saved syscalls
 			am.savedSyscalls.Get(watchedContainer.K8sContainerID).Append(toSaveSyscalls...)
 			// record saved capabilities
@@ -654,6 +748,7 @@ func (am *ApplicationProfileManager) ContainerCallback(notif containercollection
 		if am.watchedContainerChannels.Has(notif.Container.Runtime.ContainerID) {
 			return
 		}
+		am.profileBudgets.Set(k8sContainerID, NewProfileBudget(am.cfg.MaxProfileOpens, am.cfg.MaxProfileExecs, am.cfg.MaxProfileEndpoints))
 		am.savedCapabilities.Set(k8sContainerID, cache.NewTTL(5*am.cfg.UpdateDataPeriod, am.cfg.UpdateDataPeriod))
 		am.savedEndpoints.Set(k8sContainerID, cache.NewTTL(5*am.cfg.UpdateDataPeriod, am.cfg.UpdateDataPeriod))
 		am.savedExecs.Set(k8sContainerID, cache.NewTTL(5*am.cfg.UpdateDataPeriod, am.cfg.UpdateDataPeriod))
@@ -665,6 +760,8 @@ func (am *ApplicationProfileManager) ContainerCallback(notif containercollection
 		am.toSaveExecs.Set(k8sContainerID, new(maps.SafeMap[string, []string]))
 		am.toSaveOpens.Set(k8sContainerID, new(maps.SafeMap[string, mapset.Set[string]]))
 		am.toSaveRulePolicies.Set(k8sContainerID, new(maps.SafeMap[string, *v1beta1.RulePolicy]))
+		// mark the lineage as learned, so the lineage rule is evaluated even if no edge is ever recorded
+		am.toSaveRulePolicies.Get(k8sContainerID).Set(utils.ProcessLineageRuleID, &v1beta1.RulePolicy{AllowedProcesses: []string{utils.ProcessLineageLearned}})
 		am.removedContainers.Remove(k8sContainerID) // make sure container is not in the removed list
 		am.trackedContainers.Add(k8sContainerID)
 
@@ -708,6 +805,20 @@ func (am *ApplicationProfileManager) ReportFileExec(k8sContainerID, path string,
 	am.toSaveExecs.Get(k8sContainerID).Set(execIdentifier, append([]string{path}, args...))
 }
 
+// ReportExecLineage records the parent->child edge of an exec as a rule policy of the process lineage rule.
+// The parent executable is resolved from the process tree, as by the rule, falling back to the parent comm.
+func (am *ApplicationProfileManager) ReportExecLineage(k8sContainerID string, event *events.ExecEvent) {
+	path := utils.GetExecPathFromEvent(&event.Event)
+	if path == "" {
+		return
+	}
+	parentPath := objectcache.GetParentExecPath(am.processManager, event.Runtime.ContainerID, event.Ppid, event.Pcomm)
+	if parentPath == "" {
+		return
+	}
+	am.ReportRulePolicy(k8sContainerID, utils.ProcessLineageRuleID, utils.CreateProcessLineageEdge(parentPath, path), false)
+}
+
 func (am *ApplicationProfileManager) ReportFileOpen(k8sContainerID, path string, flags []string) {
 	if err := am.waitForContainer(k8sContainerID); err != nil {
 		return
@@ -734,6 +845,24 @@ func (am *ApplicationProfileManager) ReportDroppedEvent(k8sContainerID string) {
 	am.droppedEventsContainers.Add(k8sContainerID)
 }
 
+// ReportSeccompViolation feeds the syscalls logged or denied by the Localhost profile of the container back to the
+// seccomp manager as suggestions, the violations of the runtime default profiles are not actionable
+func (am *ApplicationProfileManager) ReportSeccompViolation(k8sContainerID string, event *tracerseccompaudittype.Event) {
+	if event.Syscall == "" {
+		return
+	}
+	sharedData := am.k8sObjectCache.GetSharedContainerData(event.Runtime.ContainerID)
+	if sharedData == nil || sharedData.SeccompProfilePath == nil {
+		return
+	}
+	logger.L().Debug("ApplicationProfileManager - seccomp violation",
+		helpers.String("k8s workload", k8sContainerID),
+		helpers.String("syscall", event.Syscall),
+		helpers.String("action", string(event.Action)),
+		helpers.String("path", *sharedData.SeccompProfilePath))
+	am.seccompManager.SuggestSyscall(*sharedData.SeccompProfilePath, event.Syscall)
+}
+
 func (am *ApplicationProfileManager) ReportHTTPEvent(k8sContainerID string, event *tracerhttptype.Event) {
 	if err := am.waitForContainer(k8sContainerID); err != nil {
 		return
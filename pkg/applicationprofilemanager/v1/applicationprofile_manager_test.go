@@ -18,11 +18,14 @@ import (
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 	"github.com/kubescape/k8s-interface/instanceidhandler/v1"
+	helpersv1 "github.com/kubescape/k8s-interface/instanceidhandler/v1/helpers"
 	"github.com/kubescape/k8s-interface/workloadinterface"
 	"github.com/kubescape/node-agent/pkg/config"
 	tracerhttptype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/http/types"
 	"github.com/kubescape/node-agent/pkg/k8sclient"
+	"github.com/kubescape/node-agent/pkg/metricsmanager"
 	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/processmanager"
 	"github.com/kubescape/node-agent/pkg/seccompmanager"
 	"github.com/kubescape/node-agent/pkg/storage"
 	"github.com/kubescape/node-agent/pkg/utils"
@@ -30,6 +33,7 @@ import (
 	"github.com/kubescape/storage/pkg/registry/file/dynamicpathdetector"
 	"github.com/stretchr/testify/assert"
 	istiocache "istio.io/pkg/cache"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func ensureInstanceID(container *containercollection.Container, watchedContainer *utils.WatchedContainerData, k8sclient *k8sclient.K8sClientMock, clusterName string) error {
@@ -92,7 +96,7 @@ func TestApplicationProfileManager(t *testing.T) {
 	storageClient := &storage.StorageHttpClientMock{}
 	k8sObjectCacheMock := &objectcache.K8sObjectCacheMock{}
 	seccompManagerMock := &seccompmanager.SeccompManagerMock{}
-	am, err := CreateApplicationProfileManager(ctx, cfg, "cluster", k8sClient, storageClient, k8sObjectCacheMock, seccompManagerMock)
+	am, err := CreateApplicationProfileManager(ctx, cfg, "cluster", k8sClient, storageClient, k8sObjectCacheMock, seccompManagerMock, processmanager.CreateProcessManagerMock(), metricsmanager.NewMetricsMock())
 	assert.NoError(t, err)
 	// prepare container
 	container := &containercollection.Container{
@@ -485,7 +489,7 @@ func TestReportRulePolicy(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			am, err := CreateApplicationProfileManager(ctx, cfg, "cluster", k8sClient, storageClient, k8sObjectCacheMock, seccompManagerMock)
+			am, err := CreateApplicationProfileManager(ctx, cfg, "cluster", k8sClient, storageClient, k8sObjectCacheMock, seccompManagerMock, processmanager.CreateProcessManagerMock(), metricsmanager.NewMetricsMock())
 			assert.NoError(t, err)
 
 			am.savedRulePolicies.Set(tt.k8sContainerID, istiocache.NewTTL(5*am.cfg.UpdateDataPeriod, am.cfg.UpdateDataPeriod))
@@ -518,3 +522,34 @@ func TestReportRulePolicy(t *testing.T) {
 		})
 	}
 }
+
+func TestCompletedSyscalls(t *testing.T) {
+	storageClient := &storage.StorageHttpClientMock{}
+	am, err := CreateApplicationProfileManager(context.TODO(), config.Config{}, "cluster", &k8sclient.K8sClientMock{}, storageClient, &objectcache.K8sObjectCacheMock{}, &seccompmanager.SeccompManagerMock{}, processmanager.CreateProcessManagerMock(), metricsmanager.NewMetricsMock())
+	assert.NoError(t, err)
+
+	profile := &v1beta1.ApplicationProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "replicaset-nginx-77b4fdf86c",
+			Annotations: map[string]string{helpersv1.StatusMetadataKey: helpersv1.Ready},
+		},
+		Spec: v1beta1.ApplicationProfileSpec{
+			InitContainers: []v1beta1.ApplicationProfileContainer{{Name: "init", Syscalls: []string{"mount"}}},
+			Containers:     []v1beta1.ApplicationProfileContainer{{Name: "nginx", Syscalls: []string{"accept4", "read", "write"}}},
+		},
+	}
+	storageClient.ApplicationProfiles = append(storageClient.ApplicationProfiles, profile)
+	// the profile is not completed yet
+	_, err = am.completedSyscalls("default", profile.Name, "nginx")
+	assert.Error(t, err)
+
+	profile.Annotations[helpersv1.StatusMetadataKey] = helpersv1.Completed
+	syscalls, err := am.completedSyscalls("default", profile.Name, "nginx")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"accept4", "read", "write"}, syscalls)
+	syscalls, err = am.completedSyscalls("default", profile.Name, "init")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mount"}, syscalls)
+	_, err = am.completedSyscalls("default", profile.Name, "sidecar")
+	assert.Error(t, err)
+}
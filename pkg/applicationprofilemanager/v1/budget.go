@@ -0,0 +1,328 @@
+package applicationprofilemanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/kubescape/storage/pkg/registry/file/dynamicpathdetector"
+)
+
+const (
+	CompactionStatsMetadataKey = "kubescape.io/compaction-stats"
+
+	opensCategory     = "opens"
+	execsCategory     = "execs"
+	endpointsCategory = "endpoints"
+)
+
+// collapseThresholds are the path analyzer thresholds tried in order, from the least to the most aggressive
+var collapseThresholds = []int{50, 20, 10, 5, 2}
+
+// CategoryBudget holds the compaction statistics of a single profile category (opens, execs or endpoints).
+type CategoryBudget struct {
+	Limit     int `json:"limit"`
+	Saved     int `json:"saved"`
+	Collapsed int `json:"collapsed"`
+	// savedKeys are the distinct entries saved, the entries reported again once expired from the saved caches
+	// are counted once
+	savedKeys mapset.Set[string]
+}
+
+// ProfileBudget keeps track of the entries saved in the profile of a container, per category.
+type ProfileBudget struct {
+	Opens     CategoryBudget `json:"opens"`
+	Execs     CategoryBudget `json:"execs"`
+	Endpoints CategoryBudget `json:"endpoints"`
+}
+
+func NewProfileBudget(maxOpens, maxExecs, maxEndpoints int) *ProfileBudget {
+	return &ProfileBudget{
+		Opens:     CategoryBudget{Limit: maxOpens},
+		Execs:     CategoryBudget{Limit: maxExecs},
+		Endpoints: CategoryBudget{Limit: maxEndpoints},
+	}
+}
+
+// available returns the number of entries that can still be saved, a zero limit means no limit
+func (c *CategoryBudget) available() int {
+	if c.Limit <= 0 {
+		return -1
+	}
+	return max(c.Limit-c.Saved, 0)
+}
+
+// Usage returns the ratio of the budget used so far
+func (c *CategoryBudget) Usage() float64 {
+	if c.Limit <= 0 {
+		return 0
+	}
+	return float64(c.Saved) / float64(c.Limit)
+}
+
+func (c *CategoryBudget) isSaved(key string) bool {
+	return c.savedKeys != nil && c.savedKeys.ContainsOne(key)
+}
+
+func (c *CategoryBudget) clone() CategoryBudget {
+	clone := *c
+	if c.savedKeys != nil {
+		clone.savedKeys = c.savedKeys.Clone()
+	}
+	return clone
+}
+
+// Clone returns a copy of the budget, updated while saving and kept once the profile is saved
+func (b *ProfileBudget) Clone() *ProfileBudget {
+	return &ProfileBudget{
+		Opens:     b.Opens.clone(),
+		Execs:     b.Execs.clone(),
+		Endpoints: b.Endpoints.clone(),
+	}
+}
+
+// recordEntries counts the saved entries not saved before
+func recordEntries[V any](c *CategoryBudget, entries map[string]V, collapsed int) {
+	if c.savedKeys == nil {
+		c.savedKeys = mapset.NewThreadUnsafeSet[string]()
+	}
+	for key := range entries {
+		if c.savedKeys.Add(key) {
+			c.Saved++
+		}
+	}
+	c.Collapsed += collapsed
+}
+
+// splitSaved separates the entries saved before, they are saved again without using the budget
+func splitSaved[V any](entries map[string]V, budget *CategoryBudget) (map[string]V, map[string]V) {
+	saved := make(map[string]V)
+	fresh := make(map[string]V, len(entries))
+	for key, entry := range entries {
+		if budget.isSaved(key) {
+			saved[key] = entry
+		} else {
+			fresh[key] = entry
+		}
+	}
+	return saved, fresh
+}
+
+// generalizePath replaces the last segments of the path with the dynamic identifier, the depth of the path is kept
+// so the wildcard still matches it. The first segment, the root or the port of an endpoint, is never replaced.
+func generalizePath(path string, segments int) string {
+	parts := strings.Split(path, "/")
+	for i := max(len(parts)-segments, 1); i < len(parts); i++ {
+		parts[i] = dynamicpathdetector.DynamicIdentifier
+	}
+	return strings.Join(parts, "/")
+}
+
+// generalizationLevel returns the number of segments to generalize for the paths to fit in the budget, the paths
+// generalized into saved entries don't use it. The coarsest wildcards, one per path depth, are kept over the budget
+// rather than dropping the entries.
+func generalizationLevel(paths []string, isSaved func(path string) bool, available int) int {
+	depth := 0
+	for _, path := range paths {
+		depth = max(depth, strings.Count(path, "/"))
+	}
+	for level := 1; level < depth; level++ {
+		fresh := mapset.NewThreadUnsafeSet[string]()
+		for _, path := range paths {
+			if generalized := generalizePath(path, level); !isSaved(generalized) {
+				fresh.Add(generalized)
+			}
+		}
+		if fresh.Cardinality() <= available {
+			return level
+		}
+	}
+	return depth
+}
+
+// compactOpens collapses the opens with the least aggressive threshold that fits in the budget,
+// entries that still don't fit are generalized to their parent directories.
+func compactOpens(opens map[string]mapset.Set[string], budget *CategoryBudget) (map[string]mapset.Set[string], int) {
+	available := budget.available()
+	if available < 0 {
+		return opens, 0
+	}
+	saved, opens := splitSaved(opens, budget)
+	if len(opens) <= available {
+		maps.Copy(opens, saved)
+		return opens, 0
+	}
+	compacted := opens
+	for _, threshold := range collapseThresholds {
+		analyzer := dynamicpathdetector.NewPathAnalyzer(threshold)
+		for path := range opens {
+			_, _ = dynamicpathdetector.AnalyzeOpen(path, analyzer)
+		}
+		compacted = make(map[string]mapset.Set[string])
+		for path, flags := range opens {
+			dynamicPath, err := dynamicpathdetector.AnalyzeOpen(path, analyzer)
+			if err != nil {
+				dynamicPath = path
+			}
+			if existing, ok := compacted[dynamicPath]; ok {
+				existing.Append(flags.ToSlice()...)
+			} else {
+				compacted[dynamicPath] = flags.Clone()
+			}
+		}
+		if len(compacted) <= available {
+			break
+		}
+	}
+	if len(compacted) > available {
+		level := generalizationLevel(slices.Collect(maps.Keys(compacted)), budget.isSaved, available)
+		generalized := make(map[string]mapset.Set[string])
+		for path, flags := range compacted {
+			path = generalizePath(path, level)
+			if existing, ok := generalized[path]; ok {
+				existing.Append(flags.ToSlice()...)
+			} else {
+				generalized[path] = flags
+			}
+		}
+		compacted = generalized
+	}
+	collapsed := len(opens) - len(compacted)
+	for path, flags := range saved {
+		if existing, ok := compacted[path]; ok {
+			existing.Append(flags.ToSlice()...)
+		} else {
+			compacted[path] = flags
+		}
+	}
+	return compacted, collapsed
+}
+
+// compactExecs removes the arguments of the execs when they don't fit in the budget,
+// entries that still don't fit are generalized to their parent directories.
+func compactExecs(execs map[string][]string, budget *CategoryBudget) (map[string][]string, int) {
+	available := budget.available()
+	if available < 0 {
+		return execs, 0
+	}
+	saved, execs := splitSaved(execs, budget)
+	if len(execs) <= available {
+		maps.Copy(execs, saved)
+		return execs, 0
+	}
+	paths := mapset.NewThreadUnsafeSet[string]()
+	for _, pathAndArgs := range execs {
+		paths.Add(pathAndArgs[0])
+	}
+	level := 0
+	if paths.Cardinality() > available {
+		level = generalizationLevel(paths.ToSlice(), func(path string) bool {
+			return budget.isSaved(utils.CalculateSHA256FileExecHash(path, nil))
+		}, available)
+	}
+	compacted := make(map[string][]string)
+	for path := range paths.Iter() {
+		if level > 0 {
+			path = generalizePath(path, level)
+		}
+		compacted[utils.CalculateSHA256FileExecHash(path, nil)] = []string{path}
+	}
+	collapsed := len(execs) - len(compacted)
+	maps.Copy(compacted, saved)
+	return compacted, collapsed
+}
+
+// mergeEndpoints merges the endpoints on their generalized URL and direction, the methods are accumulated
+func mergeEndpoints(endpoints map[string]*v1beta1.HTTPEndpoint, generalize func(url string) string) map[string]*v1beta1.HTTPEndpoint {
+	merged := make(map[string]*v1beta1.HTTPEndpoint)
+	for _, endpoint := range endpoints {
+		url := generalize(endpoint.Endpoint)
+		key := url + string(endpoint.Direction)
+		if existing, ok := merged[key]; ok {
+			existing.Methods = mapset.Sorted(mapset.NewThreadUnsafeSet(slices.Concat(existing.Methods, endpoint.Methods)...))
+		} else {
+			newEndpoint := *endpoint
+			newEndpoint.Endpoint = url
+			newEndpoint.Methods = slices.Clone(endpoint.Methods)
+			merged[key] = &newEndpoint
+		}
+	}
+	compacted := make(map[string]*v1beta1.HTTPEndpoint, len(merged))
+	for _, endpoint := range merged {
+		compacted[CalculateHTTPEndpointHash(endpoint)] = endpoint
+	}
+	return compacted
+}
+
+// compactEndpoints collapses the endpoint URLs with the least aggressive threshold that fits in the budget,
+// entries that still don't fit are generalized to their parent paths.
+func compactEndpoints(endpoints map[string]*v1beta1.HTTPEndpoint, budget *CategoryBudget) (map[string]*v1beta1.HTTPEndpoint, int) {
+	available := budget.available()
+	if available < 0 {
+		return endpoints, 0
+	}
+	saved, endpoints := splitSaved(endpoints, budget)
+	if len(endpoints) <= available {
+		maps.Copy(endpoints, saved)
+		return endpoints, 0
+	}
+	compacted := endpoints
+	for _, threshold := range collapseThresholds {
+		analyzer := dynamicpathdetector.NewPathAnalyzer(threshold)
+		for _, endpoint := range endpoints {
+			_, _ = dynamicpathdetector.AnalyzeURL(endpoint.Endpoint, analyzer)
+		}
+		compacted = mergeEndpoints(endpoints, func(url string) string {
+			if dynamicURL, err := dynamicpathdetector.AnalyzeURL(url, analyzer); err == nil {
+				return dynamicURL
+			}
+			return url
+		})
+		if len(compacted) <= available {
+			break
+		}
+	}
+	if len(compacted) > available {
+		urls := make([]string, 0, len(compacted))
+		for _, endpoint := range compacted {
+			urls = append(urls, endpoint.Endpoint)
+		}
+		// the saved endpoints are keyed by their hash, the generalized endpoints are counted as fresh
+		level := generalizationLevel(urls, func(string) bool { return false }, available)
+		compacted = mergeEndpoints(compacted, func(url string) string {
+			return generalizePath(url, level)
+		})
+	}
+	collapsed := len(endpoints) - len(compacted)
+	maps.Copy(compacted, saved)
+	return compacted, collapsed
+}
+
+// getCompactionStatsMetadataKey returns the annotation key holding the compaction statistics of a container,
+// several containers share the same profile so the key is qualified with the container type and index
+func getCompactionStatsMetadataKey(containerType utils.ContainerType, containerIndex int) string {
+	return fmt.Sprintf("%s.%s.%d", CompactionStatsMetadataKey, containerType, containerIndex)
+}
+
+// GetCompactionStatsAnnotation returns the serialized compaction statistics to be stored as a profile annotation
+func (b *ProfileBudget) GetCompactionStatsAnnotation() string {
+	stats, err := json.Marshal(b)
+	if err != nil {
+		return ""
+	}
+	return string(stats)
+}
+
+// AppendCompactionStatsPatchOperations adds the compaction statistics annotation to the patch
+func (b *ProfileBudget) AppendCompactionStatsPatchOperations(existingPatch []utils.PatchOperation, key string) []utils.PatchOperation {
+	return append(existingPatch, utils.PatchOperation{
+		Op:    "add",
+		Path:  "/metadata/annotations/" + utils.EscapeJSONPointerElement(key),
+		Value: b.GetCompactionStatsAnnotation(),
+	})
+}
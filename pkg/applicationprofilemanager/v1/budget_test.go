@@ -0,0 +1,168 @@
+package applicationprofilemanager
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactOpens(t *testing.T) {
+	opens := make(map[string]mapset.Set[string])
+	for i := 0; i < 100; i++ {
+		opens[fmt.Sprintf("/var/cache/app/%d", i)] = mapset.NewSet[string]("O_RDONLY")
+	}
+	opens["/etc/passwd"] = mapset.NewSet[string]("O_RDONLY")
+	opens["/var/cache/app/0"].Add("O_WRONLY")
+
+	// within budget
+	budget := NewProfileBudget(200, 0, 0)
+	compacted, collapsed := compactOpens(opens, &budget.Opens)
+	assert.Equal(t, opens, compacted)
+	assert.Equal(t, 0, collapsed)
+
+	// collapsed to fit in budget
+	budget = NewProfileBudget(10, 0, 0)
+	compacted, collapsed = compactOpens(opens, &budget.Opens)
+	assert.Len(t, compacted, 2)
+	assert.Equal(t, 99, collapsed)
+	assert.Contains(t, compacted, "/etc/passwd")
+	assert.ElementsMatch(t, []string{"O_RDONLY", "O_WRONLY"}, compacted["/var/cache/app/⋯"].ToSlice())
+
+	// generalized to the parent directories to fit in the budget
+	budget = NewProfileBudget(1, 0, 0)
+	compacted, collapsed = compactOpens(map[string]mapset.Set[string]{
+		"/etc/passwd": mapset.NewSet[string]("O_RDONLY"),
+		"/etc/hosts":  mapset.NewSet[string]("O_RDONLY"),
+	}, &budget.Opens)
+	assert.Equal(t, []string{"/etc/⋯"}, slices.Collect(maps.Keys(compacted)))
+	assert.Equal(t, 1, collapsed)
+
+	// budget exhausted, the coarsest wildcards of each depth are kept
+	budget = NewProfileBudget(10, 0, 0)
+	budget.Opens.Saved = 9
+	compacted, collapsed = compactOpens(opens, &budget.Opens)
+	assert.ElementsMatch(t, []string{"/⋯/⋯/⋯/⋯", "/⋯/⋯"}, slices.Collect(maps.Keys(compacted)))
+	assert.Equal(t, 99, collapsed)
+}
+
+func TestCompactOpensSavedEntries(t *testing.T) {
+	budget := NewProfileBudget(3, 0, 0)
+	opens := map[string]mapset.Set[string]{
+		"/etc/passwd": mapset.NewSet[string]("O_RDONLY"),
+		"/etc/hosts":  mapset.NewSet[string]("O_RDONLY"),
+	}
+	compacted, _ := compactOpens(opens, &budget.Opens)
+	recordEntries(&budget.Opens, compacted, 0)
+	assert.Equal(t, 2, budget.Opens.Saved)
+
+	// the entries reported again after expiring from the saved caches are counted once
+	opens = map[string]mapset.Set[string]{
+		"/etc/passwd":      mapset.NewSet[string]("O_WRONLY"),
+		"/etc/hosts":       mapset.NewSet[string]("O_RDONLY"),
+		"/etc/resolv.conf": mapset.NewSet[string]("O_RDONLY"),
+	}
+	compacted, collapsed := compactOpens(opens, &budget.Opens)
+	assert.Len(t, compacted, 3)
+	assert.Equal(t, 0, collapsed)
+	recordEntries(&budget.Opens, compacted, collapsed)
+	assert.Equal(t, 3, budget.Opens.Saved)
+
+	// the budget is exhausted for the new entries only, they are generalized
+	opens = map[string]mapset.Set[string]{
+		"/etc/hosts":    mapset.NewSet[string]("O_RDONLY"),
+		"/etc/hostname": mapset.NewSet[string]("O_RDONLY"),
+	}
+	compacted, _ = compactOpens(opens, &budget.Opens)
+	assert.ElementsMatch(t, []string{"/etc/hosts", "/⋯/⋯"}, slices.Collect(maps.Keys(compacted)))
+	recordEntries(&budget.Opens, compacted, 0)
+	assert.Equal(t, 4, budget.Opens.Saved)
+
+	// the entries generalized into a saved wildcard don't use the budget
+	opens = map[string]mapset.Set[string]{
+		"/etc/group": mapset.NewSet[string]("O_RDONLY"),
+	}
+	compacted, _ = compactOpens(opens, &budget.Opens)
+	assert.Equal(t, []string{"/⋯/⋯"}, slices.Collect(maps.Keys(compacted)))
+	recordEntries(&budget.Opens, compacted, 0)
+	assert.Equal(t, 4, budget.Opens.Saved)
+
+	// the budget copy does not share the saved entries
+	clone := budget.Clone()
+	recordEntries(&clone.Opens, map[string]mapset.Set[string]{"/etc/hostname": nil}, 0)
+	assert.Equal(t, 5, clone.Opens.Saved)
+	assert.Equal(t, 4, budget.Opens.Saved)
+	assert.False(t, budget.Opens.isSaved("/etc/hostname"))
+}
+
+func TestCompactExecs(t *testing.T) {
+	execs := map[string][]string{}
+	for i := 0; i < 5; i++ {
+		args := []string{"/bin/sh", "-c", fmt.Sprintf("echo %d", i)}
+		execs[utils.CalculateSHA256FileExecHash("/bin/sh", args)] = append([]string{"/bin/sh"}, args...)
+	}
+	execs[utils.CalculateSHA256FileExecHash("/bin/ls", nil)] = []string{"/bin/ls"}
+
+	budget := NewProfileBudget(0, 3, 0)
+	compacted, collapsed := compactExecs(execs, &budget.Execs)
+	assert.Equal(t, map[string][]string{
+		utils.CalculateSHA256FileExecHash("/bin/sh", nil): {"/bin/sh"},
+		utils.CalculateSHA256FileExecHash("/bin/ls", nil): {"/bin/ls"},
+	}, compacted)
+	assert.Equal(t, 4, collapsed)
+
+	// the paths are generalized when the arguments don't fit
+	budget = NewProfileBudget(0, 1, 0)
+	compacted, collapsed = compactExecs(execs, &budget.Execs)
+	assert.Equal(t, map[string][]string{
+		utils.CalculateSHA256FileExecHash("/bin/⋯", nil): {"/bin/⋯"},
+	}, compacted)
+	assert.Equal(t, 5, collapsed)
+}
+
+func TestCompactEndpoints(t *testing.T) {
+	endpoints := make(map[string]*v1beta1.HTTPEndpoint)
+	for i := 0; i < 60; i++ {
+		endpoint := &v1beta1.HTTPEndpoint{
+			Endpoint:  fmt.Sprintf(":80/users/%d", i),
+			Methods:   []string{"GET"},
+			Direction: "inbound",
+		}
+		if i == 0 {
+			endpoint.Methods = []string{"POST"}
+		}
+		endpoints[CalculateHTTPEndpointHash(endpoint)] = endpoint
+	}
+
+	budget := NewProfileBudget(0, 0, 10)
+	compacted, collapsed := compactEndpoints(endpoints, &budget.Endpoints)
+	assert.Len(t, compacted, 1)
+	assert.Equal(t, 59, collapsed)
+	for _, endpoint := range compacted {
+		assert.Equal(t, ":80/users/⋯", endpoint.Endpoint)
+		assert.Equal(t, []string{"GET", "POST"}, endpoint.Methods)
+	}
+}
+
+func TestCompactionStatsPatchOperations(t *testing.T) {
+	budget := NewProfileBudget(10, 5, 0)
+	opens := make(map[string]mapset.Set[string])
+	for i := 0; i < 10; i++ {
+		opens[fmt.Sprintf("/etc/%d", i)] = mapset.NewSet[string]("O_RDONLY")
+	}
+	recordEntries(&budget.Opens, opens, 3)
+	key := getCompactionStatsMetadataKey(utils.Container, 1)
+	assert.Equal(t, "kubescape.io/compaction-stats.containers.1", key)
+	operations := budget.AppendCompactionStatsPatchOperations(nil, key)
+	assert.Equal(t, []utils.PatchOperation{{
+		Op:    "add",
+		Path:  "/metadata/annotations/kubescape.io~1compaction-stats.containers.1",
+		Value: `{"opens":{"limit":10,"saved":10,"collapsed":3},"execs":{"limit":5,"saved":0,"collapsed":0},"endpoints":{"limit":0,"saved":0,"collapsed":0}}`,
+	}}, operations)
+	assert.Equal(t, 1.0, budget.Opens.Usage())
+}
@@ -117,6 +117,10 @@ func GetInitOperations(containerType string, containerIndex int) []utils.PatchOp
 			AllowedProcesses: []string{},
 		}
 	}
+	// the lineage is learned from the start of the recording
+	rulePoliciesMap[utils.ProcessLineageRuleID] = v1beta1.RulePolicy{
+		AllowedProcesses: []string{utils.ProcessLineageLearned},
+	}
 
 	createMap := utils.PatchOperation{
 		Op:    "replace",
@@ -14,30 +14,139 @@ const PodNameEnvVar = "POD_NAME"
 const NamespaceEnvVar = "NAMESPACE_NAME"
 
 type Config struct {
-	Exporters                exporters.ExportersConfig `mapstructure:"exporters"`
-	InitialDelay             time.Duration             `mapstructure:"initialDelay"`
-	MaxSniffingTime          time.Duration             `mapstructure:"maxSniffingTimePerContainer"`
-	UpdateDataPeriod         time.Duration             `mapstructure:"updateDataPeriod"`
-	MaxDelaySeconds          int                       `mapstructure:"maxDelaySeconds"`
-	MaxJitterPercentage      int                       `mapstructure:"maxJitterPercentage"`
-	MaxImageSize             int64                     `mapstructure:"maxImageSize"`
-	MaxSBOMSize              int                       `mapstructure:"maxSBOMSize"`
-	EnableFullPathTracing    bool                      `mapstructure:"fullPathTracingEnabled"`
-	EnableApplicationProfile bool                      `mapstructure:"applicationProfileServiceEnabled"`
-	EnableMalwareDetection   bool                      `mapstructure:"malwareDetectionEnabled"`
-	EnablePrometheusExporter bool                      `mapstructure:"prometheusExporterEnabled"`
-	EnableRuntimeDetection   bool                      `mapstructure:"runtimeDetectionEnabled"`
-	EnableHttpDetection      bool                      `mapstructure:"httpDetectionEnabled"`
-	EnableNetworkTracing     bool                      `mapstructure:"networkServiceEnabled"`
-	EnableNodeProfile        bool                      `mapstructure:"nodeProfileServiceEnabled"`
-	NodeProfileInterval      time.Duration             `mapstructure:"nodeProfileInterval"`
-	EnableSeccomp            bool                      `mapstructure:"seccompServiceEnabled"`
-	ExcludeNamespaces        []string                  `mapstructure:"excludeNamespaces"`
-	IncludeNamespaces        []string                  `mapstructure:"includeNamespaces"`
-	EnableSbomGeneration     bool                      `mapstructure:"sbomGenerationEnabled"`
-	NamespaceName            string                    `mapstructure:"namespaceName"`
-	NodeName                 string                    `mapstructure:"nodeName"`
-	PodName                  string                    `mapstructure:"podName"`
+	Exporters                 exporters.ExportersConfig `mapstructure:"exporters"`
+	InitialDelay              time.Duration             `mapstructure:"initialDelay"`
+	MaxSniffingTime           time.Duration             `mapstructure:"maxSniffingTimePerContainer"`
+	UpdateDataPeriod          time.Duration             `mapstructure:"updateDataPeriod"`
+	MaxDelaySeconds           int                       `mapstructure:"maxDelaySeconds"`
+	MaxJitterPercentage       int                       `mapstructure:"maxJitterPercentage"`
+	MaxImageSize              int64                     `mapstructure:"maxImageSize"`
+	MaxSBOMSize               int                       `mapstructure:"maxSBOMSize"`
+	MaxProfileOpens           int                       `mapstructure:"maxProfileOpens"`
+	MaxProfileExecs           int                       `mapstructure:"maxProfileExecs"`
+	MaxProfileEndpoints       int                       `mapstructure:"maxProfileEndpoints"`
+	EnableFullPathTracing     bool                      `mapstructure:"fullPathTracingEnabled"`
+	EnableApplicationProfile  bool                      `mapstructure:"applicationProfileServiceEnabled"`
+	EnableMalwareDetection    bool                      `mapstructure:"malwareDetectionEnabled"`
+	EnablePrometheusExporter  bool                      `mapstructure:"prometheusExporterEnabled"`
+	EnableRuntimeDetection    bool                      `mapstructure:"runtimeDetectionEnabled"`
+	EnableHttpDetection       bool                      `mapstructure:"httpDetectionEnabled"`
+	EnableNetworkTracing      bool                      `mapstructure:"networkServiceEnabled"`
+	EnableNodeProfile         bool                      `mapstructure:"nodeProfileServiceEnabled"`
+	NodeProfileInterval       time.Duration             `mapstructure:"nodeProfileInterval"`
+	EnableSeccomp             bool                      `mapstructure:"seccompServiceEnabled"`
+	EnableSeccompGeneration   bool                      `mapstructure:"seccompGenerationEnabled"`
+	ExcludeNamespaces         []string                  `mapstructure:"excludeNamespaces"`
+	IncludeNamespaces         []string                  `mapstructure:"includeNamespaces"`
+	EnableSbomGeneration      bool                      `mapstructure:"sbomGenerationEnabled"`
+	EnableThreatIntel         bool                      `mapstructure:"threatIntelEnabled"`
+	ThreatIntelPaths          []string                  `mapstructure:"threatIntelPaths"`
+	ThreatIntelConfigMaps     []string                  `mapstructure:"threatIntelConfigMaps"`
+	ThreatIntelReloadInterval time.Duration             `mapstructure:"threatIntelReloadInterval"`
+	YaraRulesPaths            []string                  `mapstructure:"yaraRulesPaths"`
+	YaraRulesConfigMaps       []string                  `mapstructure:"yaraRulesConfigMaps"`
+	YaraReloadInterval        time.Duration             `mapstructure:"yaraReloadInterval"`
+	MalwareHashDBPaths        []string                  `mapstructure:"malwareHashDatabasePaths"`
+	MalwareHashDBReloadPeriod time.Duration             `mapstructure:"malwareHashDatabaseReloadPeriod"`
+	MalwareScanCacheSize      int                       `mapstructure:"malwareScanCacheSize"`
+	MalwareScanCacheTTL       time.Duration             `mapstructure:"malwareScanCacheTTL"`
+	EnableMalwareSweep        bool                      `mapstructure:"malwareSweepEnabled"`
+	MalwareSweepInclude       []string                  `mapstructure:"malwareSweepInclude"`
+	MalwareSweepExclude       []string                  `mapstructure:"malwareSweepExclude"`
+	MalwareSweepConcurrency   int                       `mapstructure:"malwareSweepConcurrency"`
+	MalwareSweepBytesPerSec   int64                     `mapstructure:"malwareSweepBytesPerSecond"`
+	MalwareResponse           MalwareResponseConfig     `mapstructure:"malwareResponse"`
+	RuntimeResponse           RuntimeResponseConfig     `mapstructure:"runtimeResponse"`
+	Forensics                 ForensicsConfig           `mapstructure:"forensics"`
+	LocalAPISocket            string                    `mapstructure:"localApiSocket"`
+	ProcessRetention          time.Duration             `mapstructure:"processRetention"`
+	EnableProcessAPI          bool                      `mapstructure:"processApiEnabled"`
+	SBOMFormats               SBOMFormatsConfig         `mapstructure:"sbomFormats"`
+	EnableRelevantSBOM        bool                      `mapstructure:"relevantSbomEnabled"`
+	VulnerabilityMatching     VulnerabilityMatchConfig  `mapstructure:"vulnerabilityMatching"`
+	SBOMQueue                 SBOMQueueConfig           `mapstructure:"sbomQueue"`
+	ImageVerification         ImageVerificationConfig   `mapstructure:"imageVerification"`
+	NamespaceName             string                    `mapstructure:"namespaceName"`
+	NodeName                  string                    `mapstructure:"nodeName"`
+	PodName                   string                    `mapstructure:"podName"`
+}
+
+// MalwareResponseConfig configures the automated response to malware detections.
+// The actions run only in the opted-in namespaces.
+type MalwareResponseConfig struct {
+	Namespaces    []string              `mapstructure:"namespaces"`
+	QuarantineDir string                `mapstructure:"quarantineDir"`
+	Rules         []MalwareResponseRule `mapstructure:"rules"`
+}
+
+// MalwareResponseRule lists the actions ("kill", "quarantine" or "freeze") to run, in order, on the detections
+// whose alert name or matched rule is in AlertNames, "*" matches all detections.
+type MalwareResponseRule struct {
+	AlertNames []string `mapstructure:"alertNames"`
+	Actions    []string `mapstructure:"actions"`
+}
+
+// RuntimeResponseConfig configures the response actions declared on the rule bindings.
+// The actions run only in the allowed namespaces, they are only audit-logged in dry-run.
+type RuntimeResponseConfig struct {
+	Namespaces          []string      `mapstructure:"namespaces"`
+	DryRun              bool          `mapstructure:"dryRun"`
+	MaxActionsPerMinute int           `mapstructure:"maxActionsPerMinute"`
+	Cooldown            time.Duration `mapstructure:"cooldown"`
+	QuarantineLabel     string        `mapstructure:"quarantineLabel"`
+}
+
+// ForensicsConfig configures the evidence collected when an alert of at least MinSeverity fires.
+// EnvironRedaction is "none", "secrets" (values of secret-like names) or "all" (every value).
+type ForensicsConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	MinSeverity      int    `mapstructure:"minSeverity"`
+	Directory        string `mapstructure:"directory"`
+	MaxBundles       int    `mapstructure:"maxBundles"`
+	MaxStoreSize     int64  `mapstructure:"maxStoreSize"`
+	EnvironRedaction string `mapstructure:"environRedaction"`
+	CopyExecutable   bool   `mapstructure:"copyExecutable"`
+}
+
+// SBOMFormatsConfig selects the SBOM formats generated in addition to the Syft JSON format kept in the storage:
+// "spdx-json" (SPDX 2.3) and "cyclonedx-json" (CycloneDX 1.5). The documents are encoded from the SBOMs of the
+// storage, the ones larger than the MaxSizes of their format are not served.
+type SBOMFormatsConfig struct {
+	Formats  []string       `mapstructure:"formats"`
+	MaxSizes map[string]int `mapstructure:"maxSizes"`
+}
+
+// VulnerabilityMatchConfig configures the matching of the generated SBOMs against an offline Grype
+// vulnerability database (schema v5) at DatabasePath. The TopCVEs of an image are attached to its alerts.
+// The matching is only available in the agents built with the grype build tag.
+type VulnerabilityMatchConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	DatabasePath string `mapstructure:"databasePath"`
+	UseCPEs      bool   `mapstructure:"useCPEs"`
+	TopCVEs      int    `mapstructure:"topCVEs"`
+}
+
+// SBOMQueueConfig bounds the SBOM generation to Parallelism workers, further limited by CPUBudget (cores, 0
+// disables it). The images cataloged at once must fit in MemoryBudget (bytes of image size, 0 disables it), and no
+// SBOM is started while the node pressure, the PSI "some avg10" percentage of cpu or memory, exceeds
+// PressureThreshold (0 disables it).
+type SBOMQueueConfig struct {
+	Parallelism       int     `mapstructure:"parallelism"`
+	CPUBudget         float64 `mapstructure:"cpuBudget"`
+	MemoryBudget      int64   `mapstructure:"memoryBudget"`
+	PressureThreshold float64 `mapstructure:"pressureThreshold"`
+}
+
+// ImageVerificationConfig configures the offline verification of the images at container start. The cosign
+// signatures and in-toto attestations are mirrored in Directory as sha256-<hex>.sig and sha256-<hex>.att, the
+// output of "cosign download signature|attestation", and verified with the PEM PublicKeys. With RequireAttestation
+// a valid SLSA provenance is needed, otherwise a valid signature or provenance is enough. The images failing the
+// verification are alerted by the rule R1020.
+type ImageVerificationConfig struct {
+	Enabled            bool     `mapstructure:"enabled"`
+	Directory          string   `mapstructure:"directory"`
+	PublicKeys         []string `mapstructure:"publicKeys"`
+	RequireAttestation bool     `mapstructure:"requireAttestation"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -53,6 +162,36 @@ func LoadConfig(path string) (Config, error) {
 	viper.SetDefault("maxJitterPercentage", 5)
 	viper.SetDefault("maxImageSize", 5*1024*1024*1024)
 	viper.SetDefault("maxSBOMSize", 20*1024*1024)
+	viper.SetDefault("maxProfileOpens", 5000)
+	viper.SetDefault("maxProfileExecs", 1000)
+	viper.SetDefault("maxProfileEndpoints", 1000)
+	viper.SetDefault("threatIntelReloadInterval", 10*time.Minute)
+	viper.SetDefault("yaraReloadInterval", 5*time.Minute)
+	viper.SetDefault("malwareHashDatabaseReloadPeriod", time.Hour)
+	viper.SetDefault("malwareScanCacheSize", 100000)
+	viper.SetDefault("malwareScanCacheTTL", time.Hour)
+	viper.SetDefault("malwareSweepExclude", []string{"/proc/**", "/sys/**", "/dev/**"})
+	viper.SetDefault("malwareSweepConcurrency", 1)
+	viper.SetDefault("malwareSweepBytesPerSecond", 10*1024*1024)
+	viper.SetDefault("malwareResponse.quarantineDir", "/var/lib/kubescape/quarantine")
+	viper.SetDefault("runtimeResponse.maxActionsPerMinute", 10)
+	viper.SetDefault("runtimeResponse.cooldown", 5*time.Minute)
+	viper.SetDefault("runtimeResponse.quarantineLabel", "kubescape.io/quarantine")
+	viper.SetDefault("forensics.minSeverity", 10)
+	viper.SetDefault("forensics.directory", "/var/lib/kubescape/evidence")
+	viper.SetDefault("forensics.maxBundles", 100)
+	viper.SetDefault("forensics.maxStoreSize", 1024*1024*1024)
+	viper.SetDefault("forensics.environRedaction", "secrets")
+	viper.SetDefault("localApiSocket", "/run/kubescape/node-agent.sock")
+	viper.SetDefault("processRetention", 5*time.Minute)
+	viper.SetDefault("sbomFormats.maxSizes", map[string]int{"spdx-json": 20 * 1024 * 1024, "cyclonedx-json": 20 * 1024 * 1024})
+	viper.SetDefault("vulnerabilityMatching.databasePath", "/var/lib/kubescape/grype/vulnerability.db")
+	viper.SetDefault("vulnerabilityMatching.topCVEs", 5)
+	viper.SetDefault("sbomQueue.parallelism", 2)
+	viper.SetDefault("sbomQueue.cpuBudget", 0)
+	viper.SetDefault("sbomQueue.memoryBudget", 1024*1024*1024)
+	viper.SetDefault("sbomQueue.pressureThreshold", 40)
+	viper.SetDefault("imageVerification.directory", "/var/lib/kubescape/signatures")
 	viper.SetDefault("namespaceName", os.Getenv(NamespaceEnvVar))
 	viper.SetDefault("nodeName", os.Getenv(NodeNameEnvVar))
 	viper.SetDefault("podName", os.Getenv(PodNameEnvVar))
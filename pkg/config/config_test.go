@@ -20,23 +20,43 @@ func TestLoadConfig(t *testing.T) {
 			name: "TestLoadConfig",
 			path: "../../configuration",
 			want: Config{
-				EnableFullPathTracing:    true,
-				EnableApplicationProfile: true,
-				EnableMalwareDetection:   true,
-				EnableNetworkTracing:     true,
-				EnableNodeProfile:        true,
-				EnableHttpDetection:      true,
-				InitialDelay:             2 * time.Minute,
-				MaxSniffingTime:          6 * time.Hour,
-				UpdateDataPeriod:         1 * time.Minute,
-				NodeProfileInterval:      1 * time.Minute,
-				MaxDelaySeconds:          30,
-				MaxJitterPercentage:      5,
-				MaxImageSize:             5368709120,
-				MaxSBOMSize:              20971520,
-				EnablePrometheusExporter: true,
-				EnableRuntimeDetection:   true,
-				EnableSeccomp:            true,
+				EnableFullPathTracing:     true,
+				EnableApplicationProfile:  true,
+				EnableMalwareDetection:    true,
+				EnableNetworkTracing:      true,
+				EnableNodeProfile:         true,
+				EnableHttpDetection:       true,
+				InitialDelay:              2 * time.Minute,
+				MaxSniffingTime:           6 * time.Hour,
+				UpdateDataPeriod:          1 * time.Minute,
+				NodeProfileInterval:       1 * time.Minute,
+				MaxDelaySeconds:           30,
+				MaxJitterPercentage:       5,
+				MaxImageSize:              5368709120,
+				MaxSBOMSize:               20971520,
+				MaxProfileOpens:           5000,
+				MaxProfileExecs:           1000,
+				MaxProfileEndpoints:       1000,
+				ThreatIntelReloadInterval: 10 * time.Minute,
+				YaraReloadInterval:        5 * time.Minute,
+				MalwareHashDBReloadPeriod: time.Hour,
+				MalwareScanCacheSize:      100000,
+				MalwareScanCacheTTL:       time.Hour,
+				MalwareSweepExclude:       []string{"/proc/**", "/sys/**", "/dev/**"},
+				MalwareSweepConcurrency:   1,
+				MalwareSweepBytesPerSec:   10485760,
+				MalwareResponse:           MalwareResponseConfig{QuarantineDir: "/var/lib/kubescape/quarantine"},
+				RuntimeResponse:           RuntimeResponseConfig{MaxActionsPerMinute: 10, Cooldown: 5 * time.Minute, QuarantineLabel: "kubescape.io/quarantine"},
+				Forensics:                 ForensicsConfig{MinSeverity: 10, Directory: "/var/lib/kubescape/evidence", MaxBundles: 100, MaxStoreSize: 1073741824, EnvironRedaction: "secrets"},
+				LocalAPISocket:            "/run/kubescape/node-agent.sock",
+				ProcessRetention:          5 * time.Minute,
+				SBOMFormats:               SBOMFormatsConfig{MaxSizes: map[string]int{"spdx-json": 20971520, "cyclonedx-json": 20971520}},
+				VulnerabilityMatching:     VulnerabilityMatchConfig{DatabasePath: "/var/lib/kubescape/grype/vulnerability.db", TopCVEs: 5},
+				SBOMQueue:                 SBOMQueueConfig{Parallelism: 2, MemoryBudget: 1073741824, PressureThreshold: 40},
+				ImageVerification:         ImageVerificationConfig{Directory: "/var/lib/kubescape/signatures"},
+				EnablePrometheusExporter:  true,
+				EnableRuntimeDetection:    true,
+				EnableSeccomp:             true,
 				Exporters: exporters.ExportersConfig{
 					SyslogExporter: "http://syslog.kubescape.svc.cluster.local:514",
 					StdoutExporter: &b,
@@ -10,4 +10,15 @@ const (
 	SYS_OPEN      = 2
 	SYS_OPENAT    = 257
 	SYS_FORK      = 57
+	SYS_UNLINK    = 87
+	SYS_UNLINKAT  = 263
+	SYS_RENAME    = 82
+	SYS_RENAMEAT  = 264
+	SYS_CHMOD     = 90
+	SYS_FCHMODAT  = 268
+	SYS_CHOWN     = 92
+	SYS_FCHOWNAT  = 260
+	SYS_MKDIR     = 83
+	SYS_MKDIRAT   = 258
+	SYS_MMAP      = 9
 )
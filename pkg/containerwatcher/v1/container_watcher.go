@@ -30,14 +30,22 @@ import (
 	"github.com/kubescape/node-agent/pkg/containerwatcher"
 	"github.com/kubescape/node-agent/pkg/dnsmanager"
 	"github.com/kubescape/node-agent/pkg/ebpf/events"
+	tracerfileops "github.com/kubescape/node-agent/pkg/ebpf/gadgets/fileops/tracer"
+	tracerfileopstype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/fileops/types"
 	tracerhardlink "github.com/kubescape/node-agent/pkg/ebpf/gadgets/hardlink/tracer"
 	tracerhardlinktype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/hardlink/types"
 	tracerhttp "github.com/kubescape/node-agent/pkg/ebpf/gadgets/http/tracer"
 	tracerhttptype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/http/types"
+	tracerprocess "github.com/kubescape/node-agent/pkg/ebpf/gadgets/process/tracer"
+	tracerprocesstype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/process/tracer/types"
 	tracerptrace "github.com/kubescape/node-agent/pkg/ebpf/gadgets/ptrace/tracer"
 	tracerptracetype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/ptrace/tracer/types"
 	tracerandomx "github.com/kubescape/node-agent/pkg/ebpf/gadgets/randomx/tracer"
 	tracerandomxtype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/randomx/types"
+	tracerreverseshell "github.com/kubescape/node-agent/pkg/ebpf/gadgets/reverseshell/tracer"
+	tracerreverseshelltype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/reverseshell/types"
+	tracerseccompaudit "github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer"
+	tracerseccompaudittype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer/types"
 	tracerssh "github.com/kubescape/node-agent/pkg/ebpf/gadgets/ssh/tracer"
 	tracersshtype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/ssh/types"
 	tracersymlink "github.com/kubescape/node-agent/pkg/ebpf/gadgets/symlink/tracer"
@@ -65,17 +73,24 @@ const (
 	randomxTraceName           = "trace_randomx"
 	symlinkTraceName           = "trace_symlink"
 	hardlinkTraceName          = "trace_hardlink"
+	seccompAuditTraceName      = "trace_seccomp_audit"
+	fileOpsTraceName           = "trace_fileops"
+	reverseShellTraceName      = "trace_reverseshell"
 	sshTraceName               = "trace_ssh"
 	httpTraceName              = "trace_http"
 	capabilitiesWorkerPoolSize = 1
 	execWorkerPoolSize         = 2
 	openWorkerPoolSize         = 8
 	ptraceWorkerPoolSize       = 1
+	processWorkerPoolSize      = 1 // a single worker keeps the forks before the exits
+	seccompWorkerPoolSize      = 1
 	networkWorkerPoolSize      = 1
 	dnsWorkerPoolSize          = 5
 	randomxWorkerPoolSize      = 1
 	symlinkWorkerPoolSize      = 1
 	hardlinkWorkerPoolSize     = 1
+	fileOpsWorkerPoolSize      = 1
+	reverseShellWorkerPoolSize = 1
 	sshWorkerPoolSize          = 1
 	httpWorkerPoolSize         = 4
 )
@@ -109,12 +124,16 @@ type IGContainerWatcher struct {
 	execTracer         *tracerexec.Tracer
 	openTracer         *traceropen.Tracer
 	ptraceTracer       *tracerptrace.Tracer
+	processTracer      *tracerprocess.Tracer
+	seccompAuditTracer *tracerseccompaudit.Tracer
 	syscallTracer      *tracerseccomp.Tracer
 	networkTracer      *tracernetwork.Tracer
 	dnsTracer          *tracerdns.Tracer
 	randomxTracer      *tracerandomx.Tracer
 	symlinkTracer      *tracersymlink.Tracer
 	hardlinkTracer     *tracerhardlink.Tracer
+	fileOpsTracer      *tracerfileops.Tracer
+	reverseShellTracer *tracerreverseshell.Tracer
 	sshTracer          *tracerssh.Tracer
 	httpTracer         *tracerhttp.Tracer
 	kubeIPInstance     operators.OperatorInstance
@@ -131,11 +150,15 @@ type IGContainerWatcher struct {
 	execWorkerPool         *ants.PoolWithFunc
 	openWorkerPool         *ants.PoolWithFunc
 	ptraceWorkerPool       *ants.PoolWithFunc
+	processWorkerPool      *ants.PoolWithFunc
+	seccompWorkerPool      *ants.PoolWithFunc
 	networkWorkerPool      *ants.PoolWithFunc
 	dnsWorkerPool          *ants.PoolWithFunc
 	randomxWorkerPool      *ants.PoolWithFunc
 	symlinkWorkerPool      *ants.PoolWithFunc
 	hardlinkWorkerPool     *ants.PoolWithFunc
+	fileOpsWorkerPool      *ants.PoolWithFunc
+	reverseShellWorkerPool *ants.PoolWithFunc
 	sshdWorkerPool         *ants.PoolWithFunc
 	httpWorkerPool         *ants.PoolWithFunc
 
@@ -143,11 +166,15 @@ type IGContainerWatcher struct {
 	execWorkerChan         chan *events.ExecEvent
 	openWorkerChan         chan *events.OpenEvent
 	ptraceWorkerChan       chan *tracerptracetype.Event
+	processWorkerChan      chan *tracerprocesstype.Event
+	seccompWorkerChan      chan *tracerseccompaudittype.Event
 	networkWorkerChan      chan *tracernetworktype.Event
 	dnsWorkerChan          chan *tracerdnstype.Event
 	randomxWorkerChan      chan *tracerandomxtype.Event
 	symlinkWorkerChan      chan *tracersymlinktype.Event
 	hardlinkWorkerChan     chan *tracerhardlinktype.Event
+	fileOpsWorkerChan      chan *tracerfileopstype.Event
+	reverseShellWorkerChan chan *tracerreverseshelltype.Event
 	sshWorkerChan          chan *tracersshtype.Event
 	httpWorkerChan         chan *tracerhttptype.Event
 
@@ -223,6 +250,11 @@ func CreateIGContainerWatcher(cfg config.Config, applicationProfileManager appli
 		metrics.ReportEvent(utils.ExecveEventType)
 		processManager.ReportEvent(utils.ExecveEventType, &event)
 		applicationProfileManager.ReportFileExec(k8sContainerID, path, event.Args)
+		applicationProfileManager.ReportExecLineage(k8sContainerID, &event)
+		if event.ExePath != "" {
+			path = event.ExePath
+		}
+		sbomManager.ReportFileExec(event.Runtime.ContainerID, path)
 		rulePolicyReporter.ReportEvent(utils.ExecveEventType, &event, k8sContainerID, event.Comm)
 
 		// Report exec events to event receivers
@@ -252,6 +284,7 @@ func CreateIGContainerWatcher(cfg config.Config, applicationProfileManager appli
 
 		metrics.ReportEvent(utils.OpenEventType)
 		applicationProfileManager.ReportFileOpen(k8sContainerID, path, event.Flags)
+		sbomManager.ReportFileOpen(event.Runtime.ContainerID, path)
 		ruleManager.ReportEvent(utils.OpenEventType, &event)
 		malwareManager.ReportEvent(utils.OpenEventType, &event)
 
@@ -297,8 +330,9 @@ func CreateIGContainerWatcher(cfg config.Config, applicationProfileManager appli
 			return
 		}
 
-		// ignore DNS events that do not have address resolution
+		// the responses without address resolution (NXDOMAIN, TXT...) are only scored by the DNS tunneling rule
 		if event.NumAnswers == 0 {
+			ruleManager.ReportEvent(utils.DnsNoAnswerEventType, &event)
 			return
 		}
 
@@ -362,6 +396,44 @@ func CreateIGContainerWatcher(cfg config.Config, applicationProfileManager appli
 	if err != nil {
 		return nil, fmt.Errorf("creating hardlink worker pool: %w", err)
 	}
+	// Create a fileops worker pool
+	fileOpsWorkerPool, err := ants.NewPoolWithFunc(fileOpsWorkerPoolSize, func(i interface{}) {
+		event := i.(tracerfileopstype.Event)
+		if event.K8s.ContainerName == "" {
+			return
+		}
+
+		k8sContainerID := utils.CreateK8sContainerID(event.K8s.Namespace, event.K8s.PodName, event.K8s.ContainerName)
+		eventType := fileOpsEventTypes[event.Operation].eventType
+
+		// the application profile has no field for the file operations, like for the links they go to the rules only
+		metrics.ReportEvent(eventType)
+		ruleManager.ReportEvent(eventType, &event)
+		rulePolicyReporter.ReportEvent(eventType, &event, k8sContainerID, event.Comm)
+		// Report fileops events to event receivers
+		reportEventToThirdPartyTracers(eventType, &event, thirdPartyEventReceivers)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating fileops worker pool: %w", err)
+	}
+	// Create a reverseshell worker pool
+	reverseShellWorkerPool, err := ants.NewPoolWithFunc(reverseShellWorkerPoolSize, func(i interface{}) {
+		event := i.(tracerreverseshelltype.Event)
+		if event.K8s.ContainerName == "" {
+			return
+		}
+
+		k8sContainerID := utils.CreateK8sContainerID(event.K8s.Namespace, event.K8s.PodName, event.K8s.ContainerName)
+
+		metrics.ReportEvent(utils.ReverseShellEventType)
+		ruleManager.ReportEvent(utils.ReverseShellEventType, &event)
+		rulePolicyReporter.ReportEvent(utils.ReverseShellEventType, &event, k8sContainerID, event.Comm)
+		// Report reverseshell events to event receivers
+		reportEventToThirdPartyTracers(utils.ReverseShellEventType, &event, thirdPartyEventReceivers)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating reverseshell worker pool: %w", err)
+	}
 	// Create a ssh worker pool
 	sshWorkerPool, err := ants.NewPoolWithFunc(sshWorkerPoolSize, func(i interface{}) {
 		event := i.(tracersshtype.Event)
@@ -418,6 +490,39 @@ func CreateIGContainerWatcher(cfg config.Config, applicationProfileManager appli
 		return nil, fmt.Errorf("creating ptrace worker pool: %w", err)
 	}
 
+	// Create a process worker pool
+	processWorkerPool, err := ants.NewPoolWithFunc(processWorkerPoolSize, func(i interface{}) {
+		event := i.(tracerprocesstype.Event)
+		switch event.Operation {
+		case tracerprocesstype.OperationFork:
+			processManager.ReportEvent(utils.ForkEventType, &event)
+		case tracerprocesstype.OperationExit:
+			processManager.ReportEvent(utils.ExitEventType, &event)
+		}
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("creating process worker pool: %w", err)
+	}
+
+	// Create a seccomp worker pool
+	seccompWorkerPool, err := ants.NewPoolWithFunc(seccompWorkerPoolSize, func(i interface{}) {
+		event := i.(tracerseccompaudittype.Event)
+		if event.K8s.ContainerName == "" {
+			return
+		}
+		k8sContainerID := utils.CreateK8sContainerID(event.K8s.Namespace, event.K8s.PodName, event.K8s.ContainerName)
+		metrics.ReportEvent(utils.SeccompEventType)
+		applicationProfileManager.ReportSeccompViolation(k8sContainerID, &event)
+		ruleManager.ReportEvent(utils.SeccompEventType, &event)
+
+		reportEventToThirdPartyTracers(utils.SeccompEventType, &event, thirdPartyEventReceivers)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("creating seccomp worker pool: %w", err)
+	}
+
 	return &IGContainerWatcher{
 		// Configuration
 		cfg:               cfg,
@@ -446,9 +551,13 @@ func CreateIGContainerWatcher(cfg config.Config, applicationProfileManager appli
 		randomxWorkerPool:      randomxWorkerPool,
 		symlinkWorkerPool:      symlinkWorkerPool,
 		hardlinkWorkerPool:     hardlinkWorkerPool,
+		fileOpsWorkerPool:      fileOpsWorkerPool,
+		reverseShellWorkerPool: reverseShellWorkerPool,
 		sshdWorkerPool:         sshWorkerPool,
 		httpWorkerPool:         httpWorkerPool,
 		ptraceWorkerPool:       ptraceWorkerPool,
+		processWorkerPool:      processWorkerPool,
+		seccompWorkerPool:      seccompWorkerPool,
 		metrics:                metrics,
 
 		// Channels
@@ -456,11 +565,15 @@ func CreateIGContainerWatcher(cfg config.Config, applicationProfileManager appli
 		execWorkerChan:         make(chan *events.ExecEvent, 10000),
 		openWorkerChan:         make(chan *events.OpenEvent, 500000),
 		ptraceWorkerChan:       make(chan *tracerptracetype.Event, 1000),
+		processWorkerChan:      make(chan *tracerprocesstype.Event, 10000),
+		seccompWorkerChan:      make(chan *tracerseccompaudittype.Event, 1000),
 		networkWorkerChan:      make(chan *tracernetworktype.Event, 500000),
 		dnsWorkerChan:          make(chan *tracerdnstype.Event, 100000),
 		randomxWorkerChan:      make(chan *tracerandomxtype.Event, 5000),
 		symlinkWorkerChan:      make(chan *tracersymlinktype.Event, 1000),
 		hardlinkWorkerChan:     make(chan *tracerhardlinktype.Event, 1000),
+		fileOpsWorkerChan:      make(chan *tracerfileopstype.Event, 10000),
+		reverseShellWorkerChan: make(chan *tracerreverseshelltype.Event, 1000),
 		sshWorkerChan:          make(chan *tracersshtype.Event, 1000),
 		httpWorkerChan:         make(chan *tracerhttptype.Event, 500000),
 
@@ -271,6 +271,13 @@ func (ch *IGContainerWatcher) startTracers() error {
 			return err
 		}
 		logger.L().Info("started syscall tracing")
+
+		// The seccomp violations are not reported without the kernel audit, the tracer probes audit_seccomp
+		if err := ch.startSeccompAuditTracing(); err != nil {
+			logger.L().Warning("IGContainerWatcher - error starting seccomp audit tracing", helpers.Error(err))
+		} else {
+			logger.L().Info("started seccomp audit tracing")
+		}
 	}
 	if ch.cfg.EnableApplicationProfile || ch.cfg.EnableRuntimeDetection {
 		// Start exec tracer
@@ -285,6 +292,12 @@ func (ch *IGContainerWatcher) startTracers() error {
 			return err
 		}
 		logger.L().Info("started open tracing")
+		// The file operations are not traced when the kernel has no BTF to locate the mount namespaces
+		if err := ch.startFileOpsTracing(); err != nil {
+			logger.L().Warning("IGContainerWatcher - error starting fileops tracing", helpers.Error(err))
+		} else {
+			logger.L().Info("started fileops tracing")
+		}
 	}
 
 	if ch.cfg.EnableNetworkTracing || ch.cfg.EnableRuntimeDetection {
@@ -343,6 +356,12 @@ func (ch *IGContainerWatcher) startTracers() error {
 		}
 		logger.L().Info("started hardlink tracing")
 
+		if err := ch.startReverseShellTracing(); err != nil {
+			logger.L().Error("IGContainerWatcher - error starting reverseshell tracing", helpers.Error(err))
+			return err
+		}
+		logger.L().Info("started reverseshell tracing")
+
 		// NOTE: SSH tracing relies on the network tracer, so it must be started after the network tracer.
 		if err := ch.startSshTracing(); err != nil {
 			logger.L().Error("IGContainerWatcher - error starting ssh tracing", helpers.Error(err))
@@ -356,6 +375,13 @@ func (ch *IGContainerWatcher) startTracers() error {
 		}
 		logger.L().Info("started ptrace tracing")
 
+		// The process tree falls back to the exec events and the /proc scans without the process tracer
+		if err := ch.startProcessTracing(); err != nil {
+			logger.L().Warning("IGContainerWatcher - error starting process tracing", helpers.Error(err))
+		} else {
+			logger.L().Info("started process tracing")
+		}
+
 		// Start third party tracers
 		for tracer := range ch.thirdPartyTracers.Iter() {
 			if err := tracer.Start(); err != nil {
@@ -390,6 +416,13 @@ func (ch *IGContainerWatcher) stopTracers() error {
 			logger.L().Error("IGContainerWatcher - error stopping seccomp tracing", helpers.Error(err))
 			errs = errors.Join(errs, err)
 		}
+		// Stop seccomp audit tracer
+		if ch.seccompAuditTracer != nil {
+			if err := ch.stopSeccompAuditTracing(); err != nil {
+				logger.L().Error("IGContainerWatcher - error stopping seccomp audit tracing", helpers.Error(err))
+				errs = errors.Join(errs, err)
+			}
+		}
 	}
 	if ch.cfg.EnableApplicationProfile || ch.cfg.EnableRuntimeDetection {
 		// Stop exec tracer
@@ -402,6 +435,13 @@ func (ch *IGContainerWatcher) stopTracers() error {
 			logger.L().Error("IGContainerWatcher - error stopping open tracing", helpers.Error(err))
 			errs = errors.Join(errs, err)
 		}
+		// Stop fileops tracer
+		if ch.fileOpsTracer != nil {
+			if err := ch.stopFileOpsTracing(); err != nil {
+				logger.L().Error("IGContainerWatcher - error stopping fileops tracing", helpers.Error(err))
+				errs = errors.Join(errs, err)
+			}
+		}
 	}
 
 	if ch.cfg.EnableNetworkTracing || ch.cfg.EnableRuntimeDetection {
@@ -438,6 +478,12 @@ func (ch *IGContainerWatcher) stopTracers() error {
 			errs = errors.Join(errs, err)
 		}
 
+		// Stop reverseshell tracer
+		if err := ch.stopReverseShellTracing(); err != nil {
+			logger.L().Error("IGContainerWatcher - error stopping reverseshell tracing", helpers.Error(err))
+			errs = errors.Join(errs, err)
+		}
+
 		// Stop ssh tracer
 		if err := ch.stopSshTracing(); err != nil {
 			logger.L().Error("IGContainerWatcher - error starting ssh tracing", helpers.Error(err))
@@ -450,6 +496,11 @@ func (ch *IGContainerWatcher) stopTracers() error {
 			errs = errors.Join(errs, err)
 		}
 
+		// Stop process tracer
+		if ch.processTracer != nil {
+			ch.stopProcessTracing()
+		}
+
 		// Stop third party tracers
 		for tracer := range ch.thirdPartyTracers.Iter() {
 			if err := tracer.Stop(); err != nil {
@@ -0,0 +1,77 @@
+package containerwatcher
+
+import (
+	"fmt"
+
+	tracerfileops "github.com/kubescape/node-agent/pkg/ebpf/gadgets/fileops/tracer"
+	tracerfileopstype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/fileops/types"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+// fileOpsEventTypes are the event types of the file operations, with the syscalls used for the enrichment
+var fileOpsEventTypes = map[tracerfileopstype.Operation]struct {
+	eventType utils.EventType
+	syscalls  []uint64
+}{
+	tracerfileopstype.OperationUnlink: {utils.UnlinkEventType, []uint64{SYS_UNLINK, SYS_UNLINKAT}},
+	tracerfileopstype.OperationRename: {utils.RenameEventType, []uint64{SYS_RENAME, SYS_RENAMEAT}},
+	tracerfileopstype.OperationChmod:  {utils.ChmodEventType, []uint64{SYS_CHMOD, SYS_FCHMODAT}},
+	tracerfileopstype.OperationChown:  {utils.ChownEventType, []uint64{SYS_CHOWN, SYS_FCHOWNAT}},
+	tracerfileopstype.OperationMkdir:  {utils.MkdirEventType, []uint64{SYS_MKDIR, SYS_MKDIRAT}},
+	tracerfileopstype.OperationMmap:   {utils.MmapEventType, []uint64{SYS_MMAP}},
+}
+
+func (ch *IGContainerWatcher) fileOpsEventCallback(event *tracerfileopstype.Event) {
+	if event.Type == types.DEBUG {
+		return
+	}
+
+	if isDroppedEvent(event.Type, event.Message) {
+		logger.L().Ctx(ch.ctx).Warning("fileops tracer got drop events - we may miss some realtime data", helpers.Interface("event", event), helpers.String("error", event.Message))
+		return
+	}
+
+	ch.enrichEvent(event, fileOpsEventTypes[event.Operation].syscalls)
+
+	ch.fileOpsWorkerChan <- event
+}
+
+func (ch *IGContainerWatcher) startFileOpsTracing() error {
+	if err := ch.tracerCollection.AddTracer(fileOpsTraceName, ch.containerSelector); err != nil {
+		return fmt.Errorf("adding tracer: %w", err)
+	}
+
+	// Get mount namespace map to filter by containers
+	fileOpsMountnsmap, err := ch.tracerCollection.TracerMountNsMap(fileOpsTraceName)
+	if err != nil {
+		return fmt.Errorf("getting fileOpsMountnsmap: %w", err)
+	}
+
+	tracerFileOps, err := tracerfileops.NewTracer(&tracerfileops.Config{MountnsMap: fileOpsMountnsmap}, ch.containerCollection, ch.fileOpsEventCallback)
+	if err != nil {
+		_ = ch.tracerCollection.RemoveTracer(fileOpsTraceName)
+		return fmt.Errorf("creating tracer: %w", err)
+	}
+	go func() {
+		for event := range ch.fileOpsWorkerChan {
+			_ = ch.fileOpsWorkerPool.Invoke(*event)
+		}
+	}()
+
+	ch.fileOpsTracer = tracerFileOps
+
+	return nil
+}
+
+func (ch *IGContainerWatcher) stopFileOpsTracing() error {
+	// Stop fileops tracer
+	if err := ch.tracerCollection.RemoveTracer(fileOpsTraceName); err != nil {
+		return fmt.Errorf("removing tracer: %w", err)
+	}
+	ch.fileOpsTracer.Stop()
+	return nil
+}
@@ -0,0 +1,38 @@
+package containerwatcher
+
+import (
+	"fmt"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	tracerprocess "github.com/kubescape/node-agent/pkg/ebpf/gadgets/process/tracer"
+	tracerprocesstype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/process/tracer/types"
+)
+
+func (ch *IGContainerWatcher) processEventCallback(event *tracerprocesstype.Event) {
+	if event.Type != types.NORMAL {
+		return
+	}
+
+	ch.processWorkerChan <- event
+}
+
+// startProcessTracing traces the forks and exits of the node, to track the lifetime of the processes
+func (ch *IGContainerWatcher) startProcessTracing() error {
+	tracerProcess, err := tracerprocess.NewTracer(ch.processEventCallback)
+	if err != nil {
+		return fmt.Errorf("creating tracer: %w", err)
+	}
+	go func() {
+		for event := range ch.processWorkerChan {
+			_ = ch.processWorkerPool.Invoke(*event)
+		}
+	}()
+
+	ch.processTracer = tracerProcess
+
+	return nil
+}
+
+func (ch *IGContainerWatcher) stopProcessTracing() {
+	ch.processTracer.Close()
+}
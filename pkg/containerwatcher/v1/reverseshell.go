@@ -0,0 +1,63 @@
+package containerwatcher
+
+import (
+	"fmt"
+
+	tracerreverseshell "github.com/kubescape/node-agent/pkg/ebpf/gadgets/reverseshell/tracer"
+	tracerreverseshelltype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/reverseshell/types"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+func (ch *IGContainerWatcher) reverseShellEventCallback(event *tracerreverseshelltype.Event) {
+	if event.Type == types.DEBUG {
+		return
+	}
+
+	if isDroppedEvent(event.Type, event.Message) {
+		logger.L().Ctx(ch.ctx).Warning("reverseshell tracer got drop events - we may miss some realtime data", helpers.Interface("event", event), helpers.String("error", event.Message))
+		return
+	}
+
+	ch.enrichEvent(event, []uint64{SYS_FORK})
+
+	ch.reverseShellWorkerChan <- event
+}
+
+func (ch *IGContainerWatcher) startReverseShellTracing() error {
+	if err := ch.tracerCollection.AddTracer(reverseShellTraceName, ch.containerSelector); err != nil {
+		return fmt.Errorf("adding tracer: %w", err)
+	}
+
+	// Get mount namespace map to filter by containers
+	reverseShellMountnsmap, err := ch.tracerCollection.TracerMountNsMap(reverseShellTraceName)
+	if err != nil {
+		return fmt.Errorf("getting reverseShellMountnsmap: %w", err)
+	}
+
+	tracerReverseShell, err := tracerreverseshell.NewTracer(&tracerreverseshell.Config{MountnsMap: reverseShellMountnsmap}, ch.containerCollection, ch.reverseShellEventCallback)
+	if err != nil {
+		_ = ch.tracerCollection.RemoveTracer(reverseShellTraceName)
+		return fmt.Errorf("creating tracer: %w", err)
+	}
+	go func() {
+		for event := range ch.reverseShellWorkerChan {
+			_ = ch.reverseShellWorkerPool.Invoke(*event)
+		}
+	}()
+
+	ch.reverseShellTracer = tracerReverseShell
+
+	return nil
+}
+
+func (ch *IGContainerWatcher) stopReverseShellTracing() error {
+	// Stop reverseshell tracer
+	if err := ch.tracerCollection.RemoveTracer(reverseShellTraceName); err != nil {
+		return fmt.Errorf("removing tracer: %w", err)
+	}
+	ch.reverseShellTracer.Stop()
+	return nil
+}
@@ -0,0 +1,60 @@
+package containerwatcher
+
+import (
+	"fmt"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	tracerseccompaudit "github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer"
+	tracerseccompaudittype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer/types"
+)
+
+func (ch *IGContainerWatcher) seccompAuditEventCallback(event *tracerseccompaudittype.Event) {
+	if event.Type == types.DEBUG {
+		return
+	}
+
+	if isDroppedEvent(event.Type, event.Message) {
+		logger.L().Ctx(ch.ctx).Warning("seccomp audit tracer got drop events - we may miss some realtime data", helpers.Interface("event", event), helpers.String("error", event.Message))
+		return
+	}
+
+	ch.seccompWorkerChan <- event
+}
+
+// startSeccompAuditTracing traces the seccomp actions logged by the kernel, e.g. the syscalls denied by the profiles
+func (ch *IGContainerWatcher) startSeccompAuditTracing() error {
+	if err := ch.tracerCollection.AddTracer(seccompAuditTraceName, ch.containerSelector); err != nil {
+		return fmt.Errorf("adding tracer: %w", err)
+	}
+
+	// Get mount namespace map to filter by containers
+	seccompAuditMountnsmap, err := ch.tracerCollection.TracerMountNsMap(seccompAuditTraceName)
+	if err != nil {
+		return fmt.Errorf("getting seccompAuditMountnsmap: %w", err)
+	}
+
+	tracerSeccompAudit, err := tracerseccompaudit.NewTracer(&tracerseccompaudit.Config{MountnsMap: seccompAuditMountnsmap}, ch.containerCollection, ch.seccompAuditEventCallback)
+	if err != nil {
+		return fmt.Errorf("creating tracer: %w", err)
+	}
+	go func() {
+		for event := range ch.seccompWorkerChan {
+			_ = ch.seccompWorkerPool.Invoke(*event)
+		}
+	}()
+
+	ch.seccompAuditTracer = tracerSeccompAudit
+
+	return nil
+}
+
+func (ch *IGContainerWatcher) stopSeccompAuditTracing() error {
+	// Stop seccomp audit tracer
+	if err := ch.tracerCollection.RemoveTracer(seccompAuditTraceName); err != nil {
+		return fmt.Errorf("removing tracer: %w", err)
+	}
+	ch.seccompAuditTracer.Close()
+	return nil
+}
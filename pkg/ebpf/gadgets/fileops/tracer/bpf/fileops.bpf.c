@@ -0,0 +1,287 @@
+#include "../../../../include/amd64/vmlinux.h"
+
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+
+#include "../../../../include/mntns_filter.h"
+#include "../../../../include/filesystem.h"
+#include "../../../../include/macros.h"
+#include "../../../../include/buffer.h"
+
+#include "fileops.h"
+
+// The directories of the relative paths fill at most half of the paths, so the verifier can bound the copy of the
+// relative path after them.
+#define MAX_DIR_SIZE (PATH_MAX / 2)
+
+// Events map.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+// Empty event map.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, struct event);
+} empty_event SEC(".maps");
+
+// we need this to make sure the compiler doesn't remove our struct.
+const struct event *unusedevent __attribute__((unused));
+
+// dir_path returns the directory the relative paths of the syscall start from, the working directory or the
+// directory of the file descriptor.
+static __always_inline struct path *dir_path(int dfd)
+{
+    if (dfd == AT_FDCWD) {
+        struct task_struct *task = (struct task_struct *)bpf_get_current_task();
+        struct fs_struct *fs = BPF_CORE_READ(task, fs);
+        if (!fs) {
+            return NULL;
+        }
+        return &fs->pwd;
+    }
+
+    struct file *file = get_struct_file_for_fd(dfd);
+    if (!file) {
+        return NULL;
+    }
+    return &file->f_path;
+}
+
+// read_dir copies the path of the directory or the file of the descriptor in the mount namespace of the process, it
+// returns the length of the path without the null byte.
+static __always_inline long read_dir(__u8 *dst, int dfd)
+{
+    dst[0] = '\0';
+    struct path *dir = dir_path(dfd);
+    char *dir_str = dir ? get_path_str(dir) : NULL;
+    if (!dir_str) {
+        return 0;
+    }
+    long len = bpf_probe_read_kernel_str(dst, MAX_DIR_SIZE - 1, dir_str);
+    if (len <= 1) {
+        dst[0] = '\0';
+        return 0;
+    }
+    return len - 1;
+}
+
+// read_path copies the path argument of the syscall, the relative paths are joined to their directory in the mount
+// namespace of the process and an empty path is the file of the descriptor.
+static __always_inline void read_path(__u8 *dst, int dfd, const char *path)
+{
+    dst[0] = '\0';
+    if (!path) {
+        return;
+    }
+
+    char first = '\0';
+    bpf_probe_read_user(&first, sizeof(first), path);
+    if (first == '/') {
+        bpf_probe_read_user_str(dst, PATH_MAX, path);
+        return;
+    }
+
+    long off = read_dir(dst, dfd);
+    if (first == '\0') {
+        // AT_EMPTY_PATH, the syscall changes the file of the descriptor
+        return;
+    }
+    // join with a slash unless the directory is the root
+    if (off > 0 && dst[(off - 1) & (MAX_DIR_SIZE - 1)] != '/') {
+        dst[off & (MAX_DIR_SIZE - 1)] = '/';
+        off++;
+    }
+    bpf_probe_read_user_str(&dst[off & (MAX_DIR_SIZE - 1)], MAX_DIR_SIZE, path);
+}
+
+// new_event fills the common fields of the event, it returns NULL when the process is not traced.
+static __always_inline struct event *new_event(enum operation operation, __u32 mode, __u32 flags,
+                                               __u32 owner_uid, __u32 owner_gid)
+{
+    struct event *event;
+    u32 zero = 0;
+    event = bpf_map_lookup_elem(&empty_event, &zero);
+    if (!event) {
+        return NULL;
+    }
+
+    struct task_struct *current_task = (struct task_struct*)bpf_get_current_task();
+    if (!current_task) {
+        return NULL;
+    }
+
+    u64 mntns_id = BPF_CORE_READ(current_task, nsproxy, mnt_ns, ns.inum);
+    if (gadget_should_discard_mntns_id(mntns_id)) {
+        return NULL;
+    }
+
+    u64 uid_gid = bpf_get_current_uid_gid();
+
+    event->timestamp = bpf_ktime_get_boot_ns();
+    event->mntns_id = mntns_id;
+    event->pid = bpf_get_current_pid_tgid() >> 32;
+    event->tid = bpf_get_current_pid_tgid() & 0xFFFFFFFF;
+    event->uid = (u32)uid_gid;
+    event->gid = (u32)(uid_gid >> 32);
+    event->operation = operation;
+    event->mode = mode;
+    event->flags = flags;
+    event->owner_uid = owner_uid;
+    event->owner_gid = owner_gid;
+    bpf_get_current_comm(&event->comm, sizeof(event->comm));
+
+    return event;
+}
+
+static __always_inline int trace_fileop(struct syscall_trace_enter *ctx, enum operation operation,
+                                        int dfd, const char *path, int newdfd, const char *newpath,
+                                        __u32 mode, __u32 flags, __u32 owner_uid, __u32 owner_gid)
+{
+    struct event *event = new_event(operation, mode, flags, owner_uid, owner_gid);
+    if (!event) {
+        return 0;
+    }
+
+    read_path(event->path, dfd, path);
+    read_path(event->newpath, newdfd, newpath);
+
+    /* emit event */
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, event, sizeof(struct event));
+
+    return 0;
+}
+
+// unlink, unlinkat and rmdir remove the files and the directories.
+SEC("tracepoint/syscalls/sys_enter_unlink")
+int tracepoint__sys_unlink(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_UNLINK, AT_FDCWD, (const char *)ctx->args[0], AT_FDCWD, NULL,
+                        0, 0, UNCHANGED_ID, UNCHANGED_ID);
+}
+
+SEC("tracepoint/syscalls/sys_enter_unlinkat")
+int tracepoint__sys_unlinkat(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_UNLINK, (int)ctx->args[0], (const char *)ctx->args[1], AT_FDCWD, NULL,
+                        0, (__u32)ctx->args[2], UNCHANGED_ID, UNCHANGED_ID);
+}
+
+SEC("tracepoint/syscalls/sys_enter_rmdir")
+int tracepoint__sys_rmdir(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_UNLINK, AT_FDCWD, (const char *)ctx->args[0], AT_FDCWD, NULL,
+                        0, 0, UNCHANGED_ID, UNCHANGED_ID);
+}
+
+// rename, renameat and renameat2 move the files.
+SEC("tracepoint/syscalls/sys_enter_rename")
+int tracepoint__sys_rename(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_RENAME, AT_FDCWD, (const char *)ctx->args[0], AT_FDCWD, (const char *)ctx->args[1],
+                        0, 0, UNCHANGED_ID, UNCHANGED_ID);
+}
+
+SEC("tracepoint/syscalls/sys_enter_renameat")
+int tracepoint__sys_renameat(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_RENAME, (int)ctx->args[0], (const char *)ctx->args[1], (int)ctx->args[2], (const char *)ctx->args[3],
+                        0, 0, UNCHANGED_ID, UNCHANGED_ID);
+}
+
+SEC("tracepoint/syscalls/sys_enter_renameat2")
+int tracepoint__sys_renameat2(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_RENAME, (int)ctx->args[0], (const char *)ctx->args[1], (int)ctx->args[2], (const char *)ctx->args[3],
+                        0, (__u32)ctx->args[4], UNCHANGED_ID, UNCHANGED_ID);
+}
+
+// chmod, fchmodat and fchmodat2 change the mode of the files.
+SEC("tracepoint/syscalls/sys_enter_chmod")
+int tracepoint__sys_chmod(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_CHMOD, AT_FDCWD, (const char *)ctx->args[0], AT_FDCWD, NULL,
+                        (__u32)ctx->args[1], 0, UNCHANGED_ID, UNCHANGED_ID);
+}
+
+SEC("tracepoint/syscalls/sys_enter_fchmodat")
+int tracepoint__sys_fchmodat(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_CHMOD, (int)ctx->args[0], (const char *)ctx->args[1], AT_FDCWD, NULL,
+                        (__u32)ctx->args[2], 0, UNCHANGED_ID, UNCHANGED_ID);
+}
+
+SEC("tracepoint/syscalls/sys_enter_fchmodat2")
+int tracepoint__sys_fchmodat2(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_CHMOD, (int)ctx->args[0], (const char *)ctx->args[1], AT_FDCWD, NULL,
+                        (__u32)ctx->args[2], (__u32)ctx->args[3], UNCHANGED_ID, UNCHANGED_ID);
+}
+
+// chown, lchown and fchownat change the owner of the files.
+SEC("tracepoint/syscalls/sys_enter_chown")
+int tracepoint__sys_chown(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_CHOWN, AT_FDCWD, (const char *)ctx->args[0], AT_FDCWD, NULL,
+                        0, 0, (__u32)ctx->args[1], (__u32)ctx->args[2]);
+}
+
+SEC("tracepoint/syscalls/sys_enter_lchown")
+int tracepoint__sys_lchown(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_CHOWN, AT_FDCWD, (const char *)ctx->args[0], AT_FDCWD, NULL,
+                        0, 0, (__u32)ctx->args[1], (__u32)ctx->args[2]);
+}
+
+SEC("tracepoint/syscalls/sys_enter_fchownat")
+int tracepoint__sys_fchownat(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_CHOWN, (int)ctx->args[0], (const char *)ctx->args[1], AT_FDCWD, NULL,
+                        0, (__u32)ctx->args[4], (__u32)ctx->args[2], (__u32)ctx->args[3]);
+}
+
+// mkdir and mkdirat create the directories.
+SEC("tracepoint/syscalls/sys_enter_mkdir")
+int tracepoint__sys_mkdir(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_MKDIR, AT_FDCWD, (const char *)ctx->args[0], AT_FDCWD, NULL,
+                        (__u32)ctx->args[1], 0, UNCHANGED_ID, UNCHANGED_ID);
+}
+
+SEC("tracepoint/syscalls/sys_enter_mkdirat")
+int tracepoint__sys_mkdirat(struct syscall_trace_enter *ctx)
+{
+    return trace_fileop(ctx, OPERATION_MKDIR, (int)ctx->args[0], (const char *)ctx->args[1], AT_FDCWD, NULL,
+                        (__u32)ctx->args[2], 0, UNCHANGED_ID, UNCHANGED_ID);
+}
+
+// mmap maps the files executable, such as the shared libraries mapped by the loader. The anonymous and the
+// non-executable mappings are not reported.
+SEC("tracepoint/syscalls/sys_enter_mmap")
+int tracepoint__sys_mmap(struct syscall_trace_enter *ctx)
+{
+    __u32 prot = (__u32)ctx->args[2];
+    int fd = (int)ctx->args[4];
+    if (!(prot & PROT_EXEC) || fd < 0) {
+        return 0;
+    }
+
+    struct event *event = new_event(OPERATION_MMAP, prot, (__u32)ctx->args[3], UNCHANGED_ID, UNCHANGED_ID);
+    if (!event) {
+        return 0;
+    }
+
+    read_dir(event->path, fd);
+    event->newpath[0] = '\0';
+
+    /* emit event */
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, event, sizeof(struct event));
+
+    return 0;
+}
+
+char _license[] SEC("license") = "GPL";
@@ -0,0 +1,49 @@
+#pragma once
+
+#include "../../../../include/types.h"
+
+#ifndef TASK_COMM_LEN
+#define TASK_COMM_LEN 16
+#endif
+#ifndef PATH_MAX
+#define PATH_MAX 4096
+#endif
+// Defined in include/uapi/linux/fcntl.h
+#define AT_FDCWD -100
+// The owner left unchanged by chown
+#define UNCHANGED_ID ((__u32)-1)
+// Defined in include/uapi/asm-generic/mman-common.h
+#define PROT_EXEC 0x4
+
+// Keep in sync with the operations of the tracer.
+enum operation {
+    OPERATION_UNLINK,
+    OPERATION_RENAME,
+    OPERATION_CHMOD,
+    OPERATION_CHOWN,
+    OPERATION_MKDIR,
+    OPERATION_MMAP,
+};
+
+// Note: the path should always be in the bottom of the struct to avoid trimming of data.
+struct event {
+    gadget_timestamp timestamp;
+    gadget_mntns_id mntns_id;
+    __u32 pid;
+    __u32 tid;
+    __u32 uid;
+    __u32 gid;
+    __u32 operation;
+    // the mode set by chmod and mkdir, the protection of the executable mappings
+    __u32 mode;
+    // the flags of unlinkat, renameat2, fchmodat2, fchownat and mmap
+    __u32 flags;
+    // the owner set by chown, UNCHANGED_ID when left unchanged
+    __u32 owner_uid;
+    __u32 owner_gid;
+    __u8 comm[TASK_COMM_LEN];
+    // the relative paths are resolved from the working directory or the directory of the file descriptor
+    __u8 path[PATH_MAX];
+    // the destination of the renames
+    __u8 newpath[PATH_MAX];
+};
@@ -0,0 +1,209 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build 386 || amd64 || arm || arm64 || loong64 || mips64le || mipsle || ppc64le || riscv64
+
+package tracer
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"github.com/cilium/ebpf"
+)
+
+type fileopsEvent struct {
+	Timestamp uint64
+	MntnsId   uint64
+	Pid       uint32
+	Tid       uint32
+	Uid       uint32
+	Gid       uint32
+	Operation uint32
+	Mode      uint32
+	Flags     uint32
+	OwnerUid  uint32
+	OwnerGid  uint32
+	Comm      [16]uint8
+	Path      [4096]uint8
+	Newpath   [4096]uint8
+	_         [4]byte
+}
+
+// loadFileops returns the embedded CollectionSpec for fileops.
+func loadFileops() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_FileopsBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load fileops: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadFileopsObjects loads fileops and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*fileopsObjects
+//	*fileopsPrograms
+//	*fileopsMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadFileopsObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadFileops()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// fileopsSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type fileopsSpecs struct {
+	fileopsProgramSpecs
+	fileopsMapSpecs
+	fileopsVariableSpecs
+}
+
+// fileopsProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type fileopsProgramSpecs struct {
+	TracepointSysChmod     *ebpf.ProgramSpec `ebpf:"tracepoint__sys_chmod"`
+	TracepointSysChown     *ebpf.ProgramSpec `ebpf:"tracepoint__sys_chown"`
+	TracepointSysFchmodat  *ebpf.ProgramSpec `ebpf:"tracepoint__sys_fchmodat"`
+	TracepointSysFchmodat2 *ebpf.ProgramSpec `ebpf:"tracepoint__sys_fchmodat2"`
+	TracepointSysFchownat  *ebpf.ProgramSpec `ebpf:"tracepoint__sys_fchownat"`
+	TracepointSysLchown    *ebpf.ProgramSpec `ebpf:"tracepoint__sys_lchown"`
+	TracepointSysMkdir     *ebpf.ProgramSpec `ebpf:"tracepoint__sys_mkdir"`
+	TracepointSysMkdirat   *ebpf.ProgramSpec `ebpf:"tracepoint__sys_mkdirat"`
+	TracepointSysMmap      *ebpf.ProgramSpec `ebpf:"tracepoint__sys_mmap"`
+	TracepointSysRename    *ebpf.ProgramSpec `ebpf:"tracepoint__sys_rename"`
+	TracepointSysRenameat  *ebpf.ProgramSpec `ebpf:"tracepoint__sys_renameat"`
+	TracepointSysRenameat2 *ebpf.ProgramSpec `ebpf:"tracepoint__sys_renameat2"`
+	TracepointSysRmdir     *ebpf.ProgramSpec `ebpf:"tracepoint__sys_rmdir"`
+	TracepointSysUnlink    *ebpf.ProgramSpec `ebpf:"tracepoint__sys_unlink"`
+	TracepointSysUnlinkat  *ebpf.ProgramSpec `ebpf:"tracepoint__sys_unlinkat"`
+}
+
+// fileopsMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type fileopsMapSpecs struct {
+	Bufs                 *ebpf.MapSpec `ebpf:"bufs"`
+	EmptyEvent           *ebpf.MapSpec `ebpf:"empty_event"`
+	Events               *ebpf.MapSpec `ebpf:"events"`
+	GadgetHeap           *ebpf.MapSpec `ebpf:"gadget_heap"`
+	GadgetMntnsFilterMap *ebpf.MapSpec `ebpf:"gadget_mntns_filter_map"`
+}
+
+// fileopsVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type fileopsVariableSpecs struct {
+	GadgetFilterByMntns *ebpf.VariableSpec `ebpf:"gadget_filter_by_mntns"`
+	Unusedevent         *ebpf.VariableSpec `ebpf:"unusedevent"`
+}
+
+// fileopsObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadFileopsObjects or ebpf.CollectionSpec.LoadAndAssign.
+type fileopsObjects struct {
+	fileopsPrograms
+	fileopsMaps
+	fileopsVariables
+}
+
+func (o *fileopsObjects) Close() error {
+	return _FileopsClose(
+		&o.fileopsPrograms,
+		&o.fileopsMaps,
+	)
+}
+
+// fileopsMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadFileopsObjects or ebpf.CollectionSpec.LoadAndAssign.
+type fileopsMaps struct {
+	Bufs                 *ebpf.Map `ebpf:"bufs"`
+	EmptyEvent           *ebpf.Map `ebpf:"empty_event"`
+	Events               *ebpf.Map `ebpf:"events"`
+	GadgetHeap           *ebpf.Map `ebpf:"gadget_heap"`
+	GadgetMntnsFilterMap *ebpf.Map `ebpf:"gadget_mntns_filter_map"`
+}
+
+func (m *fileopsMaps) Close() error {
+	return _FileopsClose(
+		m.Bufs,
+		m.EmptyEvent,
+		m.Events,
+		m.GadgetHeap,
+		m.GadgetMntnsFilterMap,
+	)
+}
+
+// fileopsVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadFileopsObjects or ebpf.CollectionSpec.LoadAndAssign.
+type fileopsVariables struct {
+	GadgetFilterByMntns *ebpf.Variable `ebpf:"gadget_filter_by_mntns"`
+	Unusedevent         *ebpf.Variable `ebpf:"unusedevent"`
+}
+
+// fileopsPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadFileopsObjects or ebpf.CollectionSpec.LoadAndAssign.
+type fileopsPrograms struct {
+	TracepointSysChmod     *ebpf.Program `ebpf:"tracepoint__sys_chmod"`
+	TracepointSysChown     *ebpf.Program `ebpf:"tracepoint__sys_chown"`
+	TracepointSysFchmodat  *ebpf.Program `ebpf:"tracepoint__sys_fchmodat"`
+	TracepointSysFchmodat2 *ebpf.Program `ebpf:"tracepoint__sys_fchmodat2"`
+	TracepointSysFchownat  *ebpf.Program `ebpf:"tracepoint__sys_fchownat"`
+	TracepointSysLchown    *ebpf.Program `ebpf:"tracepoint__sys_lchown"`
+	TracepointSysMkdir     *ebpf.Program `ebpf:"tracepoint__sys_mkdir"`
+	TracepointSysMkdirat   *ebpf.Program `ebpf:"tracepoint__sys_mkdirat"`
+	TracepointSysMmap      *ebpf.Program `ebpf:"tracepoint__sys_mmap"`
+	TracepointSysRename    *ebpf.Program `ebpf:"tracepoint__sys_rename"`
+	TracepointSysRenameat  *ebpf.Program `ebpf:"tracepoint__sys_renameat"`
+	TracepointSysRenameat2 *ebpf.Program `ebpf:"tracepoint__sys_renameat2"`
+	TracepointSysRmdir     *ebpf.Program `ebpf:"tracepoint__sys_rmdir"`
+	TracepointSysUnlink    *ebpf.Program `ebpf:"tracepoint__sys_unlink"`
+	TracepointSysUnlinkat  *ebpf.Program `ebpf:"tracepoint__sys_unlinkat"`
+}
+
+func (p *fileopsPrograms) Close() error {
+	return _FileopsClose(
+		p.TracepointSysChmod,
+		p.TracepointSysChown,
+		p.TracepointSysFchmodat,
+		p.TracepointSysFchmodat2,
+		p.TracepointSysFchownat,
+		p.TracepointSysLchown,
+		p.TracepointSysMkdir,
+		p.TracepointSysMkdirat,
+		p.TracepointSysMmap,
+		p.TracepointSysRename,
+		p.TracepointSysRenameat,
+		p.TracepointSysRenameat2,
+		p.TracepointSysRmdir,
+		p.TracepointSysUnlink,
+		p.TracepointSysUnlinkat,
+	)
+}
+
+func _FileopsClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed fileops_bpfel.o
+var _FileopsBytes []byte
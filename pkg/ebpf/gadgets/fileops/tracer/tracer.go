@@ -0,0 +1,233 @@
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/ebpf/gadgets/fileops/types"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -no-global-types -target bpfel -strip /usr/bin/llvm-strip-18  -cc /usr/bin/clang -cflags "-g -O2 -Wall -D __TARGET_ARCH_x86" -type event fileops bpf/fileops.bpf.c -- -I./bpf/
+
+// operations are indexed by the operation of the BPF events, keep in sync with bpf/fileops.h
+var operations = []types.Operation{types.OperationUnlink, types.OperationRename, types.OperationChmod, types.OperationChown, types.OperationMkdir, types.OperationMmap}
+
+type Config struct {
+	MountnsMap *ebpf.Map
+}
+
+// Tracer traces the unlink, rename, chmod, chown and mkdir syscalls of the containers, and the executable mappings
+// of their files
+type Tracer struct {
+	config        *Config
+	enricher      gadgets.DataEnricherByMntNs
+	eventCallback func(*types.Event)
+
+	objs fileopsObjects
+
+	links  []link.Link
+	reader *perf.Reader
+}
+
+func NewTracer(config *Config, enricher gadgets.DataEnricherByMntNs,
+	eventCallback func(*types.Event),
+) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		enricher:      enricher,
+		eventCallback: eventCallback,
+	}
+
+	if err := t.install(); err != nil {
+		t.close()
+		return nil, err
+	}
+
+	go t.run()
+
+	return t, nil
+}
+
+// Stop stops the tracer
+// TODO: Remove after refactoring
+func (t *Tracer) Stop() {
+	t.close()
+}
+
+func (t *Tracer) close() {
+	for i := range t.links {
+		t.links[i] = gadgets.CloseLink(t.links[i])
+	}
+	t.links = nil
+
+	if t.reader != nil {
+		t.reader.Close()
+	}
+
+	t.objs.Close()
+}
+
+func (t *Tracer) install() error {
+	spec, err := loadFileops()
+	if err != nil {
+		return fmt.Errorf("loading ebpf program: %w", err)
+	}
+
+	if err := gadgets.LoadeBPFSpec(t.config.MountnsMap, spec, nil, &t.objs); err != nil {
+		return fmt.Errorf("loading ebpf spec: %w", err)
+	}
+
+	// only the *at variants exist on arm64, and fchmodat2 was added in Linux 6.6
+	tracepoints := map[string]*ebpf.Program{
+		"sys_enter_unlink":    t.objs.TracepointSysUnlink,
+		"sys_enter_unlinkat":  t.objs.TracepointSysUnlinkat,
+		"sys_enter_rmdir":     t.objs.TracepointSysRmdir,
+		"sys_enter_rename":    t.objs.TracepointSysRename,
+		"sys_enter_renameat":  t.objs.TracepointSysRenameat,
+		"sys_enter_renameat2": t.objs.TracepointSysRenameat2,
+		"sys_enter_chmod":     t.objs.TracepointSysChmod,
+		"sys_enter_fchmodat":  t.objs.TracepointSysFchmodat,
+		"sys_enter_fchmodat2": t.objs.TracepointSysFchmodat2,
+		"sys_enter_chown":     t.objs.TracepointSysChown,
+		"sys_enter_lchown":    t.objs.TracepointSysLchown,
+		"sys_enter_fchownat":  t.objs.TracepointSysFchownat,
+		"sys_enter_mkdir":     t.objs.TracepointSysMkdir,
+		"sys_enter_mkdirat":   t.objs.TracepointSysMkdirat,
+		"sys_enter_mmap":      t.objs.TracepointSysMmap,
+	}
+	for tracepoint, program := range tracepoints {
+		l, err := link.Tracepoint("syscalls", tracepoint, program, nil)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("attaching tracepoint %s: %w", tracepoint, err)
+		}
+		t.links = append(t.links, l)
+	}
+	if len(t.links) == 0 {
+		return errors.New("no syscall tracepoint found")
+	}
+
+	t.reader, err = perf.NewReader(t.objs.fileopsMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("creating perf ring buffer: %w", err)
+	}
+
+	return nil
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				// nothing to do, we're done
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg)))
+			continue
+		}
+
+		event, err := parseEvent((*fileopsEvent)(unsafe.Pointer(&record.RawSample[0])))
+		if err != nil {
+			logger.L().Debug("fileops Tracer - failed to parse event", helpers.Error(err))
+			continue
+		}
+
+		if t.enricher != nil {
+			t.enricher.EnrichByMntNs(&event.CommonData, event.MountNsID)
+		}
+
+		t.eventCallback(event)
+	}
+}
+
+// parseEvent converts the event of the BPF programs, the paths are resolved in the mount namespace of the process
+// and cleaned of their . and .. elements
+func parseEvent(bpfEvent *fileopsEvent) (*types.Event, error) {
+	if int(bpfEvent.Operation) >= len(operations) {
+		return nil, fmt.Errorf("unknown operation %d", bpfEvent.Operation)
+	}
+	event := &types.Event{
+		Event: eventtypes.Event{
+			Type:      eventtypes.NORMAL,
+			Timestamp: gadgets.WallTimeFromBootTime(bpfEvent.Timestamp),
+		},
+		WithMountNsID: eventtypes.WithMountNsID{MountNsID: bpfEvent.MntnsId},
+		Pid:           bpfEvent.Pid,
+		Tid:           bpfEvent.Tid,
+		Uid:           bpfEvent.Uid,
+		Gid:           bpfEvent.Gid,
+		Comm:          gadgets.FromCString(bpfEvent.Comm[:]),
+		Operation:     operations[bpfEvent.Operation],
+		Path:          cleanPath(gadgets.FromCString(bpfEvent.Path[:])),
+		NewPath:       cleanPath(gadgets.FromCString(bpfEvent.Newpath[:])),
+		Mode:          bpfEvent.Mode,
+		Flags:         bpfEvent.Flags,
+		OwnerUid:      bpfEvent.OwnerUid,
+		OwnerGid:      bpfEvent.OwnerGid,
+	}
+	return event, nil
+}
+
+func cleanPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return filepath.Clean(path)
+}
+
+// --- Registry changes
+
+func (t *Tracer) Run(gadgetCtx gadgets.GadgetContext) error {
+	defer t.close()
+	if err := t.install(); err != nil {
+		return fmt.Errorf("installing tracer: %w", err)
+	}
+
+	go t.run()
+	gadgetcontext.WaitForTimeoutOrDone(gadgetCtx)
+
+	return nil
+}
+
+func (t *Tracer) SetMountNsMap(mountnsMap *ebpf.Map) {
+	t.config.MountnsMap = mountnsMap
+}
+
+func (t *Tracer) SetEventHandler(handler any) {
+	nh, ok := handler.(func(ev *types.Event))
+	if !ok {
+		logger.L().Fatal("fileops Tracer.SetEventHandler - invalid event handler", helpers.Interface("handler", handler))
+	}
+	t.eventCallback = nh
+}
+
+func (g *GadgetDesc) NewInstance() (gadgets.Gadget, error) {
+	tracer := &Tracer{
+		config: &Config{},
+	}
+	return tracer, nil
+}
@@ -0,0 +1,111 @@
+package tracer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/ebpf/gadgets/fileops/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseEvent(t *testing.T) {
+	bpfEvent := &fileopsEvent{
+		MntnsId:   4026531840,
+		Pid:       4321,
+		Tid:       4322,
+		Uid:       1001,
+		Gid:       1000,
+		Operation: 1,
+		OwnerUid:  types.UnchangedID,
+		OwnerGid:  types.UnchangedID,
+	}
+	copy(bpfEvent.Comm[:], "mv")
+	copy(bpfEvent.Path[:], "/app/./payload")
+	copy(bpfEvent.Newpath[:], "/usr/bin/ls")
+
+	event, err := parseEvent(bpfEvent)
+	require.NoError(t, err)
+	assert.Equal(t, types.OperationRename, event.Operation)
+	assert.Equal(t, uint64(4026531840), event.MountNsID)
+	assert.Equal(t, uint32(4321), event.Pid)
+	assert.Equal(t, uint32(4322), event.Tid)
+	assert.Equal(t, uint32(1001), event.Uid)
+	assert.Equal(t, uint32(1000), event.Gid)
+	assert.Equal(t, "mv", event.Comm)
+	assert.Equal(t, "/app/payload", event.Path)
+	assert.Equal(t, "/usr/bin/ls", event.NewPath)
+
+	// the operations without a destination
+	bpfEvent.Operation = 0
+	bpfEvent.Newpath = [4096]uint8{}
+	event, err = parseEvent(bpfEvent)
+	require.NoError(t, err)
+	assert.Equal(t, types.OperationUnlink, event.Operation)
+	assert.Empty(t, event.NewPath)
+
+	bpfEvent.Operation = uint32(len(operations))
+	_, err = parseEvent(bpfEvent)
+	assert.Error(t, err)
+}
+
+func TestTracer(t *testing.T) {
+	dir := t.TempDir()
+	events := make(chan *types.Event, 100)
+	tracer, err := NewTracer(&Config{}, nil, func(event *types.Event) {
+		if event.Pid == uint32(os.Getpid()) && strings.HasPrefix(event.Path, dir) {
+			events <- event
+		}
+	})
+	if err != nil {
+		t.Skipf("cannot load the fileops tracer: %v", err)
+	}
+	defer tracer.Stop()
+
+	// the relative paths are resolved from the directory of the file descriptor
+	f, err := os.Open(dir)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, unix.Mkdirat(int(f.Fd()), "sub", 0700))
+	require.NoError(t, unix.Renameat(int(f.Fd()), "sub", int(f.Fd()), "moved"))
+	// and from the working directory
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(filepath.Join(dir, "moved")))
+	defer os.Chdir(cwd)
+	require.NoError(t, unix.Chmod("../moved", 0755))
+	// the executable mappings of the files, the mapping may be denied by a noexec mount
+	library := filepath.Join(dir, "moved", "libpayload.so")
+	require.NoError(t, os.WriteFile(library, make([]byte, os.Getpagesize()), 0755))
+	l, err := os.Open(library)
+	require.NoError(t, err)
+	defer l.Close()
+	if data, err := unix.Mmap(int(l.Fd()), 0, os.Getpagesize(), unix.PROT_READ|unix.PROT_EXEC, unix.MAP_PRIVATE); err == nil {
+		_ = unix.Munmap(data)
+	}
+
+	expected := []struct {
+		operation types.Operation
+		path      string
+		newPath   string
+	}{
+		{types.OperationMkdir, filepath.Join(dir, "sub"), ""},
+		{types.OperationRename, filepath.Join(dir, "sub"), filepath.Join(dir, "moved")},
+		{types.OperationChmod, filepath.Join(dir, "moved"), ""},
+		{types.OperationMmap, filepath.Join(dir, "moved", "libpayload.so"), ""},
+	}
+	for _, e := range expected {
+		select {
+		case event := <-events:
+			assert.Equal(t, e.operation, event.Operation)
+			assert.Equal(t, e.path, event.Path)
+			assert.Equal(t, e.newPath, event.NewPath)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s event", e.operation)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package types
+
+import (
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+// Operation is the change made to the file system by the traced syscall
+type Operation string
+
+const (
+	OperationUnlink Operation = "unlink"
+	OperationRename Operation = "rename"
+	OperationChmod  Operation = "chmod"
+	OperationChown  Operation = "chown"
+	OperationMkdir  Operation = "mkdir"
+	// OperationMmap maps a file executable, such as a shared library loaded by the loader
+	OperationMmap Operation = "mmap"
+)
+
+// UnchangedID is the owner id left unchanged by chown
+const UnchangedID = ^uint32(0)
+
+type Event struct {
+	eventtypes.Event
+	eventtypes.WithMountNsID
+
+	Pid       uint32    `json:"pid,omitempty" column:"pid,template:pid"`
+	Tid       uint32    `json:"tid,omitempty" column:"tid,hide"`
+	Uid       uint32    `json:"uid,omitempty" column:"uid,template:uid"`
+	Gid       uint32    `json:"gid,omitempty" column:"gid,template:gid"`
+	Comm      string    `json:"comm,omitempty" column:"comm,template:comm"`
+	Operation Operation `json:"operation,omitempty" column:"operation"`
+	// Path is the file changed by the operation, the source of the renames, the mapped file of mmap
+	Path string `json:"path,omitempty" column:"path"`
+	// NewPath is the destination of the renames
+	NewPath string `json:"newpath,omitempty" column:"newpath"`
+	// Mode is the mode set by chmod and mkdir, the protection of mmap
+	Mode uint32 `json:"mode,omitempty" column:"mode"`
+	// Flags are the flags of unlinkat, renameat2, fchmodat2, fchownat and mmap
+	Flags uint32 `json:"flags,omitempty" column:"flags"`
+	// OwnerUid and OwnerGid are the owner set by chown, UnchangedID when left unchanged
+	OwnerUid uint32 `json:"owner_uid,omitempty" column:"owner_uid"`
+	OwnerGid uint32 `json:"owner_gid,omitempty" column:"owner_gid"`
+	extra    interface{}
+}
+
+func (event *Event) SetExtra(extra interface{}) {
+	event.extra = extra
+}
+
+func (event *Event) GetExtra() interface{} {
+	return event.extra
+}
+
+func (event *Event) GetPID() uint64 {
+	return (uint64(event.Pid) << 32) | uint64(event.Tid)
+}
+
+func GetColumns() *columns.Columns[Event] {
+	fileOpsColumns := columns.MustCreateColumns[Event]()
+
+	return fileOpsColumns
+}
+
+func Base(ev eventtypes.Event) *Event {
+	return &Event{
+		Event: ev,
+	}
+}
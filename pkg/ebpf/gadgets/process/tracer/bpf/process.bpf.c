@@ -0,0 +1,75 @@
+#include "../../../../include/amd64/vmlinux.h"
+
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include "process.h"
+
+// Events map.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+// we need this to make sure the compiler doesn't remove our struct.
+const struct event *unusedevent __attribute__((unused));
+
+// task_struct before Linux 5.5, start_boottime was named real_start_time
+struct task_struct___pre55 {
+    u64 real_start_time;
+} __attribute__((preserve_access_index));
+
+static __always_inline u64 task_start_boottime(struct task_struct *task)
+{
+    if (bpf_core_field_exists(task->start_boottime)) {
+        return BPF_CORE_READ(task, start_boottime);
+    }
+    return BPF_CORE_READ((struct task_struct___pre55 *)task, real_start_time);
+}
+
+// The forks of the threads are dropped, the children of the other thread group are processes.
+SEC("raw_tracepoint/sched_process_fork")
+int trace_fork(struct bpf_raw_tracepoint_args *ctx)
+{
+    struct task_struct *parent = (struct task_struct *)ctx->args[0];
+    struct task_struct *child = (struct task_struct *)ctx->args[1];
+
+    u32 pid = BPF_CORE_READ(child, pid);
+    if (pid != BPF_CORE_READ(child, tgid)) {
+        return 0;
+    }
+
+    struct event event = {};
+    event.timestamp = bpf_ktime_get_boot_ns();
+    event.start_time = task_start_boottime(child);
+    event.operation = OPERATION_FORK;
+    event.pid = pid;
+    event.ppid = BPF_CORE_READ(parent, tgid);
+
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+    return 0;
+}
+
+// Only the exits of the thread group leaders are reported, with their start time to tell a reused PID apart.
+SEC("raw_tracepoint/sched_process_exit")
+int trace_exit(struct bpf_raw_tracepoint_args *ctx)
+{
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    u32 pid = pid_tgid >> 32;
+    if (pid != (u32)pid_tgid) {
+        return 0;
+    }
+
+    struct event event = {};
+    event.timestamp = bpf_ktime_get_boot_ns();
+    event.start_time = task_start_boottime((struct task_struct *)bpf_get_current_task());
+    event.operation = OPERATION_EXIT;
+    event.pid = pid;
+
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+    return 0;
+}
+
+char _license[] SEC("license") = "GPL";
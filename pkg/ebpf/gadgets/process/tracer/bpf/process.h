@@ -0,0 +1,15 @@
+#pragma once
+
+#include "../../../../include/types.h"
+
+#define OPERATION_FORK 0
+#define OPERATION_EXIT 1
+
+struct event {
+    gadget_timestamp timestamp;
+    // start_time is the boot time of the forked or exiting process in nanoseconds, as /proc/<pid>/stat reports it
+    __u64 start_time;
+    __u32 operation;
+    __u32 pid;
+    __u32 ppid;
+};
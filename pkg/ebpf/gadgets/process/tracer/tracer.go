@@ -0,0 +1,135 @@
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/node-agent/pkg/ebpf/gadgets/process/tracer/types"
+	tracepointlib "github.com/kubescape/node-agent/pkg/ebpf/lib"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -no-global-types -target bpfel -strip /usr/bin/llvm-strip-18 -cc /usr/bin/clang -cflags "-g -O2 -Wall -D __TARGET_ARCH_x86" -type event process bpf/process.bpf.c -- -I./bpf/
+
+const (
+	// the operations of bpf/process.h
+	operationFork = 0
+	operationExit = 1
+
+	userHZ          = 100 // clock ticks per second of /proc/<pid>/stat
+	nanosecondsTick = 1_000_000_000 / userHZ
+)
+
+// Tracer reports the process forks and exits of the node, the events are not filtered by container:
+// the consumers only keep the processes they already track.
+type Tracer struct {
+	eventCallback func(*types.Event)
+
+	objs   processObjects
+	links  []link.Link
+	reader *perf.Reader
+}
+
+func NewTracer(eventCallback func(*types.Event)) (*Tracer, error) {
+	t := &Tracer{
+		eventCallback: eventCallback,
+	}
+
+	if err := t.install(); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	go t.run()
+
+	return t, nil
+}
+
+func (t *Tracer) Close() {
+	for _, l := range t.links {
+		gadgets.CloseLink(l)
+	}
+
+	if t.reader != nil {
+		t.reader.Close()
+	}
+
+	_ = t.objs.Close()
+}
+
+func (t *Tracer) install() error {
+	if err := loadProcessObjects(&t.objs, nil); err != nil {
+		return fmt.Errorf("loading ebpf programs: %w", err)
+	}
+
+	for name, program := range map[string]*ebpf.Program{"sched_process_fork": t.objs.TraceFork, "sched_process_exit": t.objs.TraceExit} {
+		l, err := link.AttachRawTracepoint(link.RawTracepointOptions{Name: name, Program: program})
+		if err != nil {
+			return fmt.Errorf("attaching raw tracepoint %s: %w", name, err)
+		}
+		t.links = append(t.links, l)
+	}
+
+	var err error
+	t.reader, err = perf.NewReader(t.objs.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("creating perf ring buffer: %w", err)
+	}
+
+	return nil
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				// nothing to do, we're done
+				return
+			}
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg)))
+			continue
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg)))
+			continue
+		}
+
+		bpfEvent := tracepointlib.ConvertToEvent[processEvent](&record)
+		if event := parseEvent(bpfEvent); event != nil {
+			t.eventCallback(event)
+		}
+	}
+}
+
+// parseEvent converts the perf event, the start time is converted to the clock ticks of /proc/<pid>/stat
+func parseEvent(bpfEvent *processEvent) *types.Event {
+	event := types.Event{
+		Event: eventtypes.Event{
+			Type:      eventtypes.NORMAL,
+			Timestamp: gadgets.WallTimeFromBootTime(bpfEvent.Timestamp),
+		},
+		Pid:  bpfEvent.Pid,
+		PPid: bpfEvent.Ppid,
+	}
+
+	switch bpfEvent.Operation {
+	case operationFork:
+		event.Operation = types.OperationFork
+	case operationExit:
+		event.Operation = types.OperationExit
+	default:
+		return nil
+	}
+	event.StartTime = bpfEvent.StartTime / nanosecondsTick
+
+	return &event
+}
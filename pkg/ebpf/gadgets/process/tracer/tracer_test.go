@@ -0,0 +1,67 @@
+package tracer
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/ebpf/gadgets/process/tracer/types"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEvent(t *testing.T) {
+	fork := parseEvent(&processEvent{Operation: operationFork, Pid: 10, Ppid: 1, StartTime: 12_345_678_901})
+	require.NotNil(t, fork)
+	assert.Equal(t, types.OperationFork, fork.Operation)
+	assert.Equal(t, uint32(1), fork.PPid)
+	assert.Equal(t, uint64(1234), fork.StartTime)
+
+	exit := parseEvent(&processEvent{Operation: operationExit, Pid: 10, StartTime: 12_345_678_901})
+	require.NotNil(t, exit)
+	assert.Equal(t, types.OperationExit, exit.Operation)
+	assert.Equal(t, uint64(1234), exit.StartTime)
+
+	assert.Nil(t, parseEvent(&processEvent{Operation: 2}))
+}
+
+func TestTracer(t *testing.T) {
+	events := make(chan *types.Event, 1000)
+	tracer, err := NewTracer(func(event *types.Event) {
+		events <- event
+	})
+	if err != nil {
+		t.Skipf("cannot load the process tracer: %v", err)
+	}
+	defer tracer.Close()
+
+	cmd := exec.Command("sleep", "0.2")
+	require.NoError(t, cmd.Start())
+	pid := uint32(cmd.Process.Pid)
+	stat, err := utils.GetProcessStat(cmd.Process.Pid)
+	require.NoError(t, err)
+	require.NoError(t, cmd.Wait())
+
+	var forked, exited bool
+	timeout := time.After(5 * time.Second)
+	for !forked || !exited {
+		select {
+		case event := <-events:
+			if event.Pid != pid {
+				continue
+			}
+			switch event.Operation {
+			case types.OperationFork:
+				forked = true
+				assert.NotZero(t, event.PPid)
+				assert.Equal(t, stat.Starttime, event.StartTime)
+			case types.OperationExit:
+				exited = true
+				assert.Equal(t, stat.Starttime, event.StartTime)
+			}
+		case <-timeout:
+			t.Fatalf("missing events for pid %d, fork: %v, exit: %v", pid, forked, exited)
+		}
+	}
+}
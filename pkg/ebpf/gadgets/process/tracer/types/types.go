@@ -0,0 +1,25 @@
+package types
+
+import eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+
+type Operation string
+
+const (
+	OperationFork Operation = "fork"
+	OperationExit Operation = "exit"
+)
+
+type Event struct {
+	eventtypes.Event
+	Operation Operation `json:"operation,omitempty" column:"operation,template:operation"`
+	Pid       uint32    `json:"pid,omitempty" column:"pid,template:pid"`
+	PPid      uint32    `json:"ppid,omitempty" column:"ppid,template:ppid"`
+	// StartTime is the process start time in clock ticks after boot, as in /proc/<pid>/stat
+	StartTime uint64 `json:"start_time,omitempty" column:"start_time,template:start_time"`
+}
+
+func Base(ev eventtypes.Event) *Event {
+	return &Event{
+		Event: ev,
+	}
+}
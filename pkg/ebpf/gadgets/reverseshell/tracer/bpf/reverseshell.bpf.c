@@ -0,0 +1,191 @@
+#include "../../../../include/amd64/vmlinux.h"
+
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_endian.h>
+
+#include "../../../../include/mntns_filter.h"
+#include "../../../../include/filesystem.h"
+#include "../../../../include/macros.h"
+#include "../../../../include/buffer.h"
+
+#include "reverseshell.h"
+
+// Defined in include/uapi/linux/magic.h
+#define OVERLAYFS_SUPER_MAGIC 0x794c7630
+
+// Events map.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+// Empty event map.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, struct event);
+} empty_event SEC(".maps");
+
+// we need this to make sure the compiler doesn't remove our struct.
+const struct event *unusedevent __attribute__((unused));
+
+static __always_inline bool has_upper_layer()
+{
+    struct task_struct *task = (struct task_struct *)bpf_get_current_task();
+    struct inode *inode = BPF_CORE_READ(task, mm, exe_file, f_inode);
+    if (!inode) {
+        return false;
+    }
+    unsigned long sb_magic = BPF_CORE_READ(inode, i_sb, s_magic);
+
+    if (sb_magic != OVERLAYFS_SUPER_MAGIC) {
+        return false;
+    }
+
+    struct dentry *upperdentry;
+
+    // struct ovl_inode defined in fs/overlayfs/ovl_entry.h
+    // Unfortunately, not exported to vmlinux.h
+    // and not available in /sys/kernel/btf/vmlinux
+    // See https://github.com/cilium/ebpf/pull/1300
+    // We only rely on vfs_inode and __upperdentry relative positions
+    bpf_probe_read_kernel(&upperdentry, sizeof(upperdentry),
+                  ((void *)inode) +
+                      bpf_core_type_size(struct inode));
+    return upperdentry != NULL;
+}
+
+// connected_socket returns the socket of the file descriptor when it is a network socket connected to a remote
+// endpoint. The listening and the unconnected sockets have no remote endpoint, 0.0.0.0:0.
+static __always_inline struct sock *connected_socket(int fd)
+{
+    struct file *file = get_struct_file_for_fd(fd);
+    if (!file) {
+        return NULL;
+    }
+
+    umode_t mode = BPF_CORE_READ(file, f_inode, i_mode);
+    if ((mode & S_IFMT) != S_IFSOCK) {
+        return NULL;
+    }
+
+    struct socket *socket = BPF_CORE_READ(file, private_data);
+    struct sock *sk = BPF_CORE_READ(socket, sk);
+    if (!sk) {
+        return NULL;
+    }
+
+    u16 family = BPF_CORE_READ(sk, __sk_common.skc_family);
+    if (BPF_CORE_READ(sk, __sk_common.skc_dport) == 0) {
+        return NULL;
+    }
+    if (family == AF_INET) {
+        if (BPF_CORE_READ(sk, __sk_common.skc_daddr) == 0) {
+            return NULL;
+        }
+        return sk;
+    }
+    if (family == AF_INET6) {
+        u64 daddr[2] = {};
+        BPF_CORE_READ_INTO(&daddr, sk, __sk_common.skc_v6_daddr);
+        if (daddr[0] == 0 && daddr[1] == 0) {
+            return NULL;
+        }
+        return sk;
+    }
+    return NULL;
+}
+
+static __always_inline void read_remote(struct sock *sk, struct gadget_l4endpoint_t *remote)
+{
+    u16 family = BPF_CORE_READ(sk, __sk_common.skc_family);
+    if (family == AF_INET) {
+        remote->addr_raw.v4 = BPF_CORE_READ(sk, __sk_common.skc_daddr);
+        remote->version = 4;
+    } else {
+        BPF_CORE_READ_INTO(&remote->addr_raw.v6, sk, __sk_common.skc_v6_daddr);
+        remote->version = 6;
+    }
+    remote->port = bpf_ntohs(BPF_CORE_READ(sk, __sk_common.skc_dport));
+    remote->proto = BPF_CORE_READ_BITFIELD_PROBED(sk, sk_protocol);
+}
+
+// This gadget checks the standard streams of the executed programs, they are read while the exec is in progress so
+// the short-lived processes are not missed.
+SEC("tracepoint/sched/sched_process_exec")
+int tracepoint__sched_process_exec(struct trace_event_raw_sched_process_exec *ctx)
+{
+    struct task_struct *current_task = (struct task_struct*)bpf_get_current_task();
+    if (!current_task) {
+        return 0;
+    }
+
+    u64 mntns_id = BPF_CORE_READ(current_task, nsproxy, mnt_ns, ns.inum);
+    if (gadget_should_discard_mntns_id(mntns_id)) {
+        return 0;
+    }
+
+    struct sock *remote_sk = NULL;
+    __u8 streams = 0;
+#pragma unroll
+    for (int fd = 0; fd < STDIO_FDS; fd++) {
+        struct sock *sk = connected_socket(fd);
+        if (!sk) {
+            continue;
+        }
+        streams |= 1 << fd;
+        if (!remote_sk) {
+            remote_sk = sk;
+        }
+    }
+    if (!remote_sk) {
+        return 0;
+    }
+
+    struct event *event;
+    u32 zero = 0;
+    event = bpf_map_lookup_elem(&empty_event, &zero);
+    if (!event) {
+        return 0;
+    }
+
+    u64 uid_gid = bpf_get_current_uid_gid();
+
+    event->timestamp = bpf_ktime_get_boot_ns();
+    event->mntns_id = mntns_id;
+    event->pid = bpf_get_current_pid_tgid() >> 32;
+    event->ppid = BPF_CORE_READ(current_task, real_parent, tgid);
+    event->uid = (u32)uid_gid;
+    event->gid = (u32)(uid_gid >> 32);
+    event->streams = streams;
+    event->upper_layer = has_upper_layer();
+    __builtin_memset(&event->remote, 0, sizeof(event->remote));
+    read_remote(remote_sk, &event->remote);
+    bpf_get_current_comm(&event->comm, sizeof(event->comm));
+    BPF_CORE_READ_STR_INTO(&event->pcomm, current_task, real_parent, comm);
+
+    event->exepath[0] = '\0';
+    struct file *exe_file = BPF_CORE_READ(current_task, mm, exe_file);
+    if (exe_file) {
+        char *exepath = get_path_str(&exe_file->f_path);
+        bpf_probe_read_kernel_str(event->exepath, MAX_STRING_SIZE, exepath);
+    }
+
+    // the servers allowed to serve shells over their connections are matched on their executable
+    event->parent_exepath[0] = '\0';
+    struct file *parent_exe_file = BPF_CORE_READ(current_task, real_parent, mm, exe_file);
+    if (parent_exe_file) {
+        char *parent_exepath = get_path_str(&parent_exe_file->f_path);
+        bpf_probe_read_kernel_str(event->parent_exepath, MAX_STRING_SIZE, parent_exepath);
+    }
+
+    /* emit event */
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, event, sizeof(struct event));
+
+    return 0;
+}
+
+char _license[] SEC("license") = "GPL";
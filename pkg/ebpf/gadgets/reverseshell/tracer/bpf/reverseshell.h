@@ -0,0 +1,34 @@
+#pragma once
+
+#include "../../../../include/types.h"
+
+#ifndef TASK_COMM_LEN
+#define TASK_COMM_LEN 16
+#endif
+// Defined in include/uapi/linux/stat.h
+#define S_IFMT 00170000
+#define S_IFSOCK 0140000
+// Defined in include/linux/socket.h
+#define AF_INET 2
+#define AF_INET6 10
+// The standard input, output and error
+#define STDIO_FDS 3
+
+// Note: the path should always be in the bottom of the struct to avoid trimming of data.
+struct event {
+    gadget_timestamp timestamp;
+    gadget_mntns_id mntns_id;
+    __u32 pid;
+    __u32 ppid;
+    __u32 uid;
+    __u32 gid;
+    // the remote endpoint of the first standard stream connected to a network socket
+    struct gadget_l4endpoint_t remote;
+    // bit i is set when the file descriptor i is connected to a network socket
+    __u8 streams;
+    bool upper_layer;
+    __u8 comm[TASK_COMM_LEN];
+    __u8 pcomm[TASK_COMM_LEN];
+    __u8 exepath[MAX_STRING_SIZE];
+    __u8 parent_exepath[MAX_STRING_SIZE];
+};
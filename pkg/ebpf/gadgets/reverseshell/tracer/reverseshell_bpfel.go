@@ -0,0 +1,172 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build 386 || amd64 || arm || arm64 || loong64 || mips64le || mipsle || ppc64le || riscv64
+
+package tracer
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"github.com/cilium/ebpf"
+)
+
+type reverseshellEvent struct {
+	Timestamp uint64
+	MntnsId   uint64
+	Pid       uint32
+	Ppid      uint32
+	Uid       uint32
+	Gid       uint32
+	Remote    struct {
+		AddrRaw struct{ V6 [16]uint8 }
+		Port    uint16
+		Proto   uint16
+		Version uint8
+		_       [3]byte
+	}
+	Streams       uint8
+	UpperLayer    bool
+	Comm          [16]uint8
+	Pcomm         [16]uint8
+	Exepath       [4096]uint8
+	ParentExepath [4096]uint8
+	_             [6]byte
+}
+
+// loadReverseshell returns the embedded CollectionSpec for reverseshell.
+func loadReverseshell() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_ReverseshellBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load reverseshell: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadReverseshellObjects loads reverseshell and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*reverseshellObjects
+//	*reverseshellPrograms
+//	*reverseshellMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadReverseshellObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadReverseshell()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// reverseshellSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type reverseshellSpecs struct {
+	reverseshellProgramSpecs
+	reverseshellMapSpecs
+	reverseshellVariableSpecs
+}
+
+// reverseshellProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type reverseshellProgramSpecs struct {
+	TracepointSchedProcessExec *ebpf.ProgramSpec `ebpf:"tracepoint__sched_process_exec"`
+}
+
+// reverseshellMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type reverseshellMapSpecs struct {
+	Bufs                 *ebpf.MapSpec `ebpf:"bufs"`
+	EmptyEvent           *ebpf.MapSpec `ebpf:"empty_event"`
+	Events               *ebpf.MapSpec `ebpf:"events"`
+	GadgetHeap           *ebpf.MapSpec `ebpf:"gadget_heap"`
+	GadgetMntnsFilterMap *ebpf.MapSpec `ebpf:"gadget_mntns_filter_map"`
+}
+
+// reverseshellVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type reverseshellVariableSpecs struct {
+	GadgetFilterByMntns *ebpf.VariableSpec `ebpf:"gadget_filter_by_mntns"`
+	Unusedevent         *ebpf.VariableSpec `ebpf:"unusedevent"`
+}
+
+// reverseshellObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadReverseshellObjects or ebpf.CollectionSpec.LoadAndAssign.
+type reverseshellObjects struct {
+	reverseshellPrograms
+	reverseshellMaps
+	reverseshellVariables
+}
+
+func (o *reverseshellObjects) Close() error {
+	return _ReverseshellClose(
+		&o.reverseshellPrograms,
+		&o.reverseshellMaps,
+	)
+}
+
+// reverseshellMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadReverseshellObjects or ebpf.CollectionSpec.LoadAndAssign.
+type reverseshellMaps struct {
+	Bufs                 *ebpf.Map `ebpf:"bufs"`
+	EmptyEvent           *ebpf.Map `ebpf:"empty_event"`
+	Events               *ebpf.Map `ebpf:"events"`
+	GadgetHeap           *ebpf.Map `ebpf:"gadget_heap"`
+	GadgetMntnsFilterMap *ebpf.Map `ebpf:"gadget_mntns_filter_map"`
+}
+
+func (m *reverseshellMaps) Close() error {
+	return _ReverseshellClose(
+		m.Bufs,
+		m.EmptyEvent,
+		m.Events,
+		m.GadgetHeap,
+		m.GadgetMntnsFilterMap,
+	)
+}
+
+// reverseshellVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadReverseshellObjects or ebpf.CollectionSpec.LoadAndAssign.
+type reverseshellVariables struct {
+	GadgetFilterByMntns *ebpf.Variable `ebpf:"gadget_filter_by_mntns"`
+	Unusedevent         *ebpf.Variable `ebpf:"unusedevent"`
+}
+
+// reverseshellPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadReverseshellObjects or ebpf.CollectionSpec.LoadAndAssign.
+type reverseshellPrograms struct {
+	TracepointSchedProcessExec *ebpf.Program `ebpf:"tracepoint__sched_process_exec"`
+}
+
+func (p *reverseshellPrograms) Close() error {
+	return _ReverseshellClose(
+		p.TracepointSchedProcessExec,
+	)
+}
+
+func _ReverseshellClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed reverseshell_bpfel.o
+var _ReverseshellBytes []byte
@@ -0,0 +1,197 @@
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/ebpf/gadgets/reverseshell/types"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -no-global-types -target bpfel -strip /usr/bin/llvm-strip-18  -cc /usr/bin/clang -cflags "-g -O2 -Wall -D __TARGET_ARCH_x86" -type event reverseshell bpf/reverseshell.bpf.c -- -I./bpf/
+
+// streamNames are the standard streams, indexed by their file descriptor
+var streamNames = []string{"stdin", "stdout", "stderr"}
+
+type Config struct {
+	MountnsMap *ebpf.Map
+}
+
+// Tracer reports the programs executed with their standard streams connected to a network endpoint, the streams are
+// checked in the exec so the short-lived processes are reported
+type Tracer struct {
+	config        *Config
+	enricher      gadgets.DataEnricherByMntNs
+	eventCallback func(*types.Event)
+
+	objs reverseshellObjects
+
+	execLink link.Link
+	reader   *perf.Reader
+}
+
+func NewTracer(config *Config, enricher gadgets.DataEnricherByMntNs,
+	eventCallback func(*types.Event),
+) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		enricher:      enricher,
+		eventCallback: eventCallback,
+	}
+
+	if err := t.install(); err != nil {
+		t.close()
+		return nil, err
+	}
+
+	go t.run()
+
+	return t, nil
+}
+
+// Stop stops the tracer
+// TODO: Remove after refactoring
+func (t *Tracer) Stop() {
+	t.close()
+}
+
+func (t *Tracer) close() {
+	t.execLink = gadgets.CloseLink(t.execLink)
+
+	if t.reader != nil {
+		t.reader.Close()
+	}
+
+	t.objs.Close()
+}
+
+func (t *Tracer) install() error {
+	var err error
+	spec, err := loadReverseshell()
+	if err != nil {
+		return fmt.Errorf("loading ebpf program: %w", err)
+	}
+
+	if err := gadgets.LoadeBPFSpec(t.config.MountnsMap, spec, nil, &t.objs); err != nil {
+		return fmt.Errorf("loading ebpf spec: %w", err)
+	}
+
+	t.execLink, err = link.Tracepoint("sched", "sched_process_exec", t.objs.TracepointSchedProcessExec, nil)
+	if err != nil {
+		return fmt.Errorf("attaching tracepoint: %w", err)
+	}
+
+	t.reader, err = perf.NewReader(t.objs.reverseshellMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("creating perf ring buffer: %w", err)
+	}
+
+	return nil
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				// nothing to do, we're done
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg)))
+			continue
+		}
+
+		event := parseEvent((*reverseshellEvent)(unsafe.Pointer(&record.RawSample[0])))
+		if t.enricher != nil {
+			t.enricher.EnrichByMntNs(&event.CommonData, event.MountNsID)
+		}
+
+		t.eventCallback(event)
+	}
+}
+
+// parseEvent converts the event of the BPF program
+func parseEvent(bpfEvent *reverseshellEvent) *types.Event {
+	event := types.Event{
+		Event: eventtypes.Event{
+			Type:      eventtypes.NORMAL,
+			Timestamp: gadgets.WallTimeFromBootTime(bpfEvent.Timestamp),
+		},
+		WithMountNsID: eventtypes.WithMountNsID{MountNsID: bpfEvent.MntnsId},
+		Pid:           bpfEvent.Pid,
+		PPid:          bpfEvent.Ppid,
+		Uid:           bpfEvent.Uid,
+		Gid:           bpfEvent.Gid,
+		UpperLayer:    bpfEvent.UpperLayer,
+		Comm:          gadgets.FromCString(bpfEvent.Comm[:]),
+		Pcomm:         gadgets.FromCString(bpfEvent.Pcomm[:]),
+		ExePath:       gadgets.FromCString(bpfEvent.Exepath[:]),
+		ParentExePath: gadgets.FromCString(bpfEvent.ParentExepath[:]),
+		Remote: eventtypes.L4Endpoint{
+			L3Endpoint: eventtypes.L3Endpoint{
+				Addr:    gadgets.IPStringFromBytes(bpfEvent.Remote.AddrRaw.V6, int(bpfEvent.Remote.Version)),
+				Version: bpfEvent.Remote.Version,
+			},
+			Port:  bpfEvent.Remote.Port,
+			Proto: bpfEvent.Remote.Proto,
+		},
+	}
+	for fd, name := range streamNames {
+		if bpfEvent.Streams&(1<<fd) != 0 {
+			event.Streams = append(event.Streams, name)
+		}
+	}
+	return &event
+}
+
+// --- Registry changes
+
+func (t *Tracer) Run(gadgetCtx gadgets.GadgetContext) error {
+	defer t.close()
+	if err := t.install(); err != nil {
+		return fmt.Errorf("installing tracer: %w", err)
+	}
+
+	go t.run()
+	gadgetcontext.WaitForTimeoutOrDone(gadgetCtx)
+
+	return nil
+}
+
+func (t *Tracer) SetMountNsMap(mountnsMap *ebpf.Map) {
+	t.config.MountnsMap = mountnsMap
+}
+
+func (t *Tracer) SetEventHandler(handler any) {
+	nh, ok := handler.(func(ev *types.Event))
+	if !ok {
+		logger.L().Fatal("reverseshell Tracer.SetEventHandler - invalid event handler", helpers.Interface("handler", handler))
+	}
+	t.eventCallback = nh
+}
+
+func (g *GadgetDesc) NewInstance() (gadgets.Gadget, error) {
+	tracer := &Tracer{
+		config: &Config{},
+	}
+	return tracer, nil
+}
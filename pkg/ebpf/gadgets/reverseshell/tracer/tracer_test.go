@@ -0,0 +1,98 @@
+package tracer
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/ebpf/gadgets/reverseshell/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEvent(t *testing.T) {
+	bpfEvent := &reverseshellEvent{
+		MntnsId: 4026531840,
+		Pid:     4321,
+		Ppid:    4320,
+		Uid:     1001,
+		Gid:     1000,
+		Streams: 0b101,
+	}
+	bpfEvent.Remote.Version = 4
+	bpfEvent.Remote.Port = 4444
+	bpfEvent.Remote.Proto = 6
+	copy(bpfEvent.Remote.AddrRaw.V6[:], []byte{10, 0, 0, 7})
+	copy(bpfEvent.Comm[:], "sh")
+	copy(bpfEvent.Pcomm[:], "nc")
+	copy(bpfEvent.Exepath[:], "/bin/sh")
+	copy(bpfEvent.ParentExepath[:], "/usr/bin/nc")
+
+	event := parseEvent(bpfEvent)
+	assert.Equal(t, uint64(4026531840), event.MountNsID)
+	assert.Equal(t, uint32(4321), event.Pid)
+	assert.Equal(t, uint32(4320), event.PPid)
+	assert.Equal(t, uint32(1001), event.Uid)
+	assert.Equal(t, uint32(1000), event.Gid)
+	assert.Equal(t, "sh", event.Comm)
+	assert.Equal(t, "nc", event.Pcomm)
+	assert.Equal(t, "/bin/sh", event.ExePath)
+	assert.Equal(t, "/usr/bin/nc", event.ParentExePath)
+	assert.Equal(t, []string{"stdin", "stderr"}, event.Streams)
+	assert.Equal(t, "10.0.0.7", event.Remote.Addr)
+	assert.Equal(t, uint16(4444), event.Remote.Port)
+	assert.Equal(t, uint16(6), event.Remote.Proto)
+}
+
+func TestTracer(t *testing.T) {
+	events := make(chan *types.Event, 100)
+	tracer, err := NewTracer(&Config{}, nil, func(event *types.Event) {
+		if event.PPid == uint32(os.Getpid()) {
+			events <- event
+		}
+	})
+	if err != nil {
+		t.Skipf("cannot load the reverseshell tracer: %v", err)
+	}
+	defer tracer.Stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	socket, err := conn.(*net.TCPConn).File()
+	require.NoError(t, err)
+	defer socket.Close()
+
+	// a short-lived process reading from the connection
+	cmd := exec.Command("/bin/true")
+	cmd.Stdin = socket
+	require.NoError(t, cmd.Run())
+	// the unconnected streams are not reported
+	require.NoError(t, exec.Command("/bin/true").Run())
+
+	select {
+	case event := <-events:
+		assert.Equal(t, []string{"stdin"}, event.Streams)
+		assert.Equal(t, "127.0.0.1", event.Remote.Addr)
+		assert.Equal(t, uint16(listener.Addr().(*net.TCPAddr).Port), event.Remote.Port)
+		assert.Contains(t, event.ExePath, "true")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reverseshell event")
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %+v", event)
+	case <-time.After(time.Second):
+	}
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+// Event is an executed program whose standard streams are connected to a network endpoint
+type Event struct {
+	eventtypes.Event
+	eventtypes.WithMountNsID
+
+	Pid           uint32 `json:"pid,omitempty" column:"pid,template:pid"`
+	PPid          uint32 `json:"ppid,omitempty" column:"ppid,template:ppid"`
+	Uid           uint32 `json:"uid,omitempty" column:"uid,template:uid"`
+	Gid           uint32 `json:"gid,omitempty" column:"gid,template:gid"`
+	UpperLayer    bool   `json:"upper_layer,omitempty" column:"upper_layer,template:upper_layer"`
+	Comm          string `json:"comm,omitempty" column:"comm,template:comm"`
+	Pcomm         string `json:"pcomm,omitempty" column:"pcomm,template:comm"`
+	ExePath       string `json:"exe_path,omitempty" column:"exe_path,template:exe_path"`
+	ParentExePath string `json:"parent_exe_path,omitempty" column:"parent_exe_path,template:exe_path"`
+	// Streams are the standard streams connected to a network socket, such as stdin
+	Streams []string `json:"streams,omitempty" column:"streams"`
+	// Remote is the endpoint of the first connected stream
+	Remote eventtypes.L4Endpoint `json:"remote,omitempty" column:"remote"`
+	extra  interface{}
+}
+
+func (event *Event) SetExtra(extra interface{}) {
+	event.extra = extra
+}
+
+func (event *Event) GetExtra() interface{} {
+	return event.extra
+}
+
+// GetPID returns the pid in the upper and the tid in the lower bits, the executing thread is the leader after the exec
+func (event *Event) GetPID() uint64 {
+	return (uint64(event.Pid) << 32) | uint64(event.Pid)
+}
+
+func GetColumns() *columns.Columns[Event] {
+	reverseShellColumns := columns.MustCreateColumns[Event]()
+
+	return reverseShellColumns
+}
+
+func Base(ev eventtypes.Event) *Event {
+	return &Event{
+		Event: ev,
+	}
+}
@@ -0,0 +1,89 @@
+#include "../../../../include/amd64/vmlinux.h"
+
+
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include "../../../../include/mntns_filter.h"
+#include "../../../../include/filesystem.h"
+#include "../../../../include/macros.h"
+#include "../../../../include/buffer.h"
+
+#include "seccompaudit.h"
+
+// Events map.
+struct {
+    __uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+    __uint(key_size, sizeof(u32));
+    __uint(value_size, sizeof(u32));
+} events SEC(".maps");
+
+// Empty event map.
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, u32);
+	__type(value, struct event);
+} empty_event SEC(".maps");
+
+// we need this to make sure the compiler doesn't remove our struct.
+const struct event *unusedevent __attribute__((unused));
+
+static __always_inline bool in_compat_syscall(struct task_struct *task)
+{
+    if (!bpf_core_field_exists(task->thread_info.status)) {
+        return false;
+    }
+    return BPF_CORE_READ(task, thread_info.status) & TS_COMPAT;
+}
+
+// audit_seccomp is called by seccomp_log in the context of the filtered task, for the actions logged as allowed by
+// /proc/sys/kernel/seccomp/actions_logged: the kill and log actions, and the other ones of the filters loaded with
+// SECCOMP_FILTER_FLAG_LOG. The syscall is attributed from the task, even when the action kills it.
+SEC("kprobe/audit_seccomp")
+int BPF_KPROBE(trace_audit_seccomp, unsigned long syscall, long signr, int code)
+{
+    struct event *event;
+    u32 zero = 0;
+    event = bpf_map_lookup_elem(&empty_event, &zero);
+    if (!event) {
+        return 0;
+    }
+
+    struct task_struct *current_task = (struct task_struct*)bpf_get_current_task();
+    if (!current_task) {
+        return 0;
+    }
+
+    u64 mntns_id = BPF_CORE_READ(current_task, nsproxy, mnt_ns, ns.inum);
+    if (gadget_should_discard_mntns_id(mntns_id)) {
+        return 0;
+    }
+
+    u64 pid_tgid = bpf_get_current_pid_tgid();
+    u64 uid_gid = bpf_get_current_uid_gid();
+
+    event->timestamp = bpf_ktime_get_boot_ns();
+    event->mntns_id = mntns_id;
+    event->pid = pid_tgid >> 32;
+    event->tid = (u32)pid_tgid;
+    event->uid = (u32)uid_gid;
+    event->gid = (u32)(uid_gid >> 32);
+    event->syscall_nr = (s32)syscall;
+    event->code = (u32)code;
+    event->compat = in_compat_syscall(current_task);
+    bpf_get_current_comm(&event->comm, sizeof(event->comm));
+
+    struct file *exe_file = BPF_CORE_READ(current_task, mm, exe_file);
+    char *exepath;
+    exepath = get_path_str(&exe_file->f_path);
+    bpf_probe_read_kernel_str(event->exepath, MAX_STRING_SIZE, exepath);
+
+    /* emit event */
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, event, sizeof(struct event));
+
+    return 0;
+}
+
+char _license[] SEC("license") = "GPL";
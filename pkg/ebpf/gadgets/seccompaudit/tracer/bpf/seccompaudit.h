@@ -0,0 +1,26 @@
+#pragma once
+
+#include "../../../../include/types.h"
+
+#ifndef TASK_COMM_LEN
+#define TASK_COMM_LEN 16
+#endif
+// Defined in arch/x86/include/asm/thread_info.h
+#define TS_COMPAT 0x0002
+
+// Note: the path should always be in the bottom of the struct to avoid trimming of data.
+struct event {
+    gadget_timestamp timestamp;
+    gadget_mntns_id mntns_id;
+    __u32 pid;
+    __u32 tid;
+    __u32 uid;
+    __u32 gid;
+    __s32 syscall_nr;
+    // the SECCOMP_RET_* action with its data, e.g. the errno
+    __u32 code;
+    // the syscall uses the compatibility syscall table, e.g. a 32-bit syscall of a 64-bit process
+    bool compat;
+    __u8 comm[TASK_COMM_LEN];
+    __u8 exepath[MAX_STRING_SIZE];
+};
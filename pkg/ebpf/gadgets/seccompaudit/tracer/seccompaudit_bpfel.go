@@ -0,0 +1,164 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build 386 || amd64 || arm || arm64 || loong64 || mips64le || mipsle || ppc64le || riscv64
+
+package tracer
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"github.com/cilium/ebpf"
+)
+
+type seccompauditEvent struct {
+	Timestamp uint64
+	MntnsId   uint64
+	Pid       uint32
+	Tid       uint32
+	Uid       uint32
+	Gid       uint32
+	SyscallNr int32
+	Code      uint32
+	Compat    bool
+	Comm      [16]uint8
+	Exepath   [4096]uint8
+	_         [7]byte
+}
+
+// loadSeccompaudit returns the embedded CollectionSpec for seccompaudit.
+func loadSeccompaudit() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_SeccompauditBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load seccompaudit: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadSeccompauditObjects loads seccompaudit and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*seccompauditObjects
+//	*seccompauditPrograms
+//	*seccompauditMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadSeccompauditObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadSeccompaudit()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// seccompauditSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type seccompauditSpecs struct {
+	seccompauditProgramSpecs
+	seccompauditMapSpecs
+	seccompauditVariableSpecs
+}
+
+// seccompauditProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type seccompauditProgramSpecs struct {
+	TraceAuditSeccomp *ebpf.ProgramSpec `ebpf:"trace_audit_seccomp"`
+}
+
+// seccompauditMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type seccompauditMapSpecs struct {
+	Bufs                 *ebpf.MapSpec `ebpf:"bufs"`
+	EmptyEvent           *ebpf.MapSpec `ebpf:"empty_event"`
+	Events               *ebpf.MapSpec `ebpf:"events"`
+	GadgetHeap           *ebpf.MapSpec `ebpf:"gadget_heap"`
+	GadgetMntnsFilterMap *ebpf.MapSpec `ebpf:"gadget_mntns_filter_map"`
+}
+
+// seccompauditVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type seccompauditVariableSpecs struct {
+	GadgetFilterByMntns *ebpf.VariableSpec `ebpf:"gadget_filter_by_mntns"`
+	Unusedevent         *ebpf.VariableSpec `ebpf:"unusedevent"`
+}
+
+// seccompauditObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadSeccompauditObjects or ebpf.CollectionSpec.LoadAndAssign.
+type seccompauditObjects struct {
+	seccompauditPrograms
+	seccompauditMaps
+	seccompauditVariables
+}
+
+func (o *seccompauditObjects) Close() error {
+	return _SeccompauditClose(
+		&o.seccompauditPrograms,
+		&o.seccompauditMaps,
+	)
+}
+
+// seccompauditMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadSeccompauditObjects or ebpf.CollectionSpec.LoadAndAssign.
+type seccompauditMaps struct {
+	Bufs                 *ebpf.Map `ebpf:"bufs"`
+	EmptyEvent           *ebpf.Map `ebpf:"empty_event"`
+	Events               *ebpf.Map `ebpf:"events"`
+	GadgetHeap           *ebpf.Map `ebpf:"gadget_heap"`
+	GadgetMntnsFilterMap *ebpf.Map `ebpf:"gadget_mntns_filter_map"`
+}
+
+func (m *seccompauditMaps) Close() error {
+	return _SeccompauditClose(
+		m.Bufs,
+		m.EmptyEvent,
+		m.Events,
+		m.GadgetHeap,
+		m.GadgetMntnsFilterMap,
+	)
+}
+
+// seccompauditVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadSeccompauditObjects or ebpf.CollectionSpec.LoadAndAssign.
+type seccompauditVariables struct {
+	GadgetFilterByMntns *ebpf.Variable `ebpf:"gadget_filter_by_mntns"`
+	Unusedevent         *ebpf.Variable `ebpf:"unusedevent"`
+}
+
+// seccompauditPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadSeccompauditObjects or ebpf.CollectionSpec.LoadAndAssign.
+type seccompauditPrograms struct {
+	TraceAuditSeccomp *ebpf.Program `ebpf:"trace_audit_seccomp"`
+}
+
+func (p *seccompauditPrograms) Close() error {
+	return _SeccompauditClose(
+		p.TraceAuditSeccomp,
+	)
+}
+
+func _SeccompauditClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed seccompaudit_bpfel.o
+var _SeccompauditBytes []byte
@@ -0,0 +1,216 @@
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/perf"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/syscalls"
+	"github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer/types"
+)
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -no-global-types -target bpfel -strip /usr/bin/llvm-strip-18 -cc /usr/bin/clang -cflags "-g -O2 -Wall -D __TARGET_ARCH_x86" -type event seccompaudit bpf/seccompaudit.bpf.c -- -I./bpf/
+
+const (
+	// the SECCOMP_RET_* actions, with the SECCOMP_RET_ACTION_FULL mask
+	retActionFull  = 0xffff0000
+	retData        = 0x0000ffff
+	retKillProcess = 0x80000000
+	retKillThread  = 0x00000000
+	retTrap        = 0x00030000
+	retErrno       = 0x00050000
+	retUserNotif   = 0x7fc00000
+	retTrace       = 0x7ff00000
+	retLog         = 0x7ffc0000
+	retAllow       = 0x7fff0000
+
+	// the AUDIT_ARCH_* values of the syscall tables
+	auditArchX86_64  = "c000003e"
+	auditArchI386    = "40000003"
+	auditArchAARCH64 = "c00000b7"
+	auditArchARM     = "40000028"
+
+	// x32SyscallBit flags the syscalls of the x32 ABI, they use the x86_64 table with their own numbers
+	x32SyscallBit = 0x40000000
+)
+
+type Config struct {
+	MountnsMap *ebpf.Map
+}
+
+// Tracer reports the seccomp actions logged by the kernel, e.g. the syscalls denied by the profiles or logged by
+// the complain profiles. The kernel logs the kill and log actions, and the other actions of the profiles loaded
+// with SECCOMP_FILTER_FLAG_LOG, as allowed by /proc/sys/kernel/seccomp/actions_logged.
+type Tracer struct {
+	config        *Config
+	enricher      gadgets.DataEnricherByMntNs
+	eventCallback func(*types.Event)
+
+	objs seccompauditObjects
+
+	auditSeccompLink link.Link
+	reader           *perf.Reader
+}
+
+func NewTracer(config *Config, enricher gadgets.DataEnricherByMntNs, eventCallback func(*types.Event)) (*Tracer, error) {
+	t := &Tracer{
+		config:        config,
+		enricher:      enricher,
+		eventCallback: eventCallback,
+	}
+
+	if err := t.install(); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	go t.run()
+
+	return t, nil
+}
+
+func (t *Tracer) Close() {
+	t.auditSeccompLink = gadgets.CloseLink(t.auditSeccompLink)
+
+	if t.reader != nil {
+		t.reader.Close()
+	}
+
+	t.objs.Close()
+}
+
+func (t *Tracer) install() error {
+	spec, err := loadSeccompaudit()
+	if err != nil {
+		return fmt.Errorf("loading ebpf program: %w", err)
+	}
+
+	if err := gadgets.LoadeBPFSpec(t.config.MountnsMap, spec, nil, &t.objs); err != nil {
+		return fmt.Errorf("loading ebpf spec: %w", err)
+	}
+
+	// audit_seccomp is only built with CONFIG_AUDITSYSCALL
+	t.auditSeccompLink, err = link.Kprobe("audit_seccomp", t.objs.TraceAuditSeccomp, nil)
+	if err != nil {
+		return fmt.Errorf("attaching kprobe: %w", err)
+	}
+
+	t.reader, err = perf.NewReader(t.objs.seccompauditMaps.Events, gadgets.PerfBufferPages*os.Getpagesize())
+	if err != nil {
+		return fmt.Errorf("creating perf ring buffer: %w", err)
+	}
+
+	return nil
+}
+
+func (t *Tracer) run() {
+	for {
+		record, err := t.reader.Read()
+		if err != nil {
+			if errors.Is(err, perf.ErrClosed) {
+				// nothing to do, we're done
+				return
+			}
+
+			msg := fmt.Sprintf("Error reading perf ring buffer: %s", err)
+			t.eventCallback(types.Base(eventtypes.Err(msg)))
+			return
+		}
+
+		if record.LostSamples > 0 {
+			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
+			t.eventCallback(types.Base(eventtypes.Warn(msg)))
+			continue
+		}
+
+		event := parseEvent((*seccompauditEvent)(unsafe.Pointer(&record.RawSample[0])))
+		if t.enricher != nil {
+			t.enricher.EnrichByMntNs(&event.CommonData, event.MountNsID)
+		}
+
+		t.eventCallback(event)
+	}
+}
+
+// parseEvent converts the event of the BPF program, the syscall names are resolved for the native table only
+func parseEvent(bpfEvent *seccompauditEvent) *types.Event {
+	event := types.Event{
+		Event: eventtypes.Event{
+			Type:      eventtypes.NORMAL,
+			Timestamp: gadgets.WallTimeFromBootTime(bpfEvent.Timestamp),
+		},
+		WithMountNsID: eventtypes.WithMountNsID{MountNsID: bpfEvent.MntnsId},
+		Pid:           bpfEvent.Pid,
+		Uid:           bpfEvent.Uid,
+		Gid:           bpfEvent.Gid,
+		Comm:          gadgets.FromCString(bpfEvent.Comm[:]),
+		ExePath:       gadgets.FromCString(bpfEvent.Exepath[:]),
+		SyscallNr:     int(bpfEvent.SyscallNr),
+		Arch:          nativeArch(),
+		Action:        parseAction(bpfEvent.Code),
+	}
+	if event.Action == types.ActionErrno {
+		event.Errno = bpfEvent.Code & retData
+	}
+	switch {
+	case bpfEvent.Compat:
+		event.Arch = compatArch()
+	case bpfEvent.SyscallNr&x32SyscallBit == 0:
+		event.Syscall, _ = syscalls.GetSyscallNameByNumber(event.SyscallNr)
+	}
+	return &event
+}
+
+func parseAction(code uint32) types.Action {
+	switch code & retActionFull {
+	case retKillProcess:
+		return types.ActionKillProcess
+	case retKillThread:
+		return types.ActionKillThread
+	case retTrap:
+		return types.ActionTrap
+	case retErrno:
+		return types.ActionErrno
+	case retUserNotif:
+		return types.ActionUserNotif
+	case retTrace:
+		return types.ActionTrace
+	case retLog:
+		return types.ActionLog
+	case retAllow:
+		return types.ActionAllow
+	default:
+		return types.Action(fmt.Sprintf("0x%x", code&retActionFull))
+	}
+}
+
+// nativeArch returns the audit architecture of the syscall table of the agent
+func nativeArch() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return auditArchX86_64
+	case "arm64":
+		return auditArchAARCH64
+	default:
+		return ""
+	}
+}
+
+// compatArch returns the audit architecture of the compatibility syscall table of the node
+func compatArch() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		return auditArchI386
+	case "arm64":
+		return auditArchARM
+	default:
+		return ""
+	}
+}
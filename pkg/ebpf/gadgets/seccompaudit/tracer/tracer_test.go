@@ -0,0 +1,63 @@
+package tracer
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cString(s string) [16]uint8 {
+	var b [16]uint8
+	copy(b[:], s)
+	return b
+}
+
+func TestParseEvent(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("syscall numbers of x86_64")
+	}
+	bpfEvent := &seccompauditEvent{MntnsId: 4026531840, Pid: 4321, Uid: 1000, Gid: 1000, SyscallNr: 41, Code: 0x50001, Comm: cString("curl")}
+	copy(bpfEvent.Exepath[:], "/usr/bin/curl")
+	event := parseEvent(bpfEvent)
+	assert.Equal(t, uint64(4026531840), event.MountNsID)
+	assert.Equal(t, uint32(4321), event.Pid)
+	assert.Equal(t, "curl", event.Comm)
+	assert.Equal(t, "/usr/bin/curl", event.ExePath)
+	assert.Equal(t, "socket", event.Syscall)
+	assert.Equal(t, auditArchX86_64, event.Arch)
+	assert.Equal(t, types.ActionErrno, event.Action)
+	assert.Equal(t, uint32(1), event.Errno)
+	assert.True(t, event.Action.Denied())
+
+	// the 32-bit syscalls use another table
+	event = parseEvent(&seccompauditEvent{SyscallNr: 11, Code: 0x7ffc0000, Compat: true})
+	assert.Empty(t, event.Syscall)
+	assert.Equal(t, auditArchI386, event.Arch)
+	assert.Equal(t, types.ActionLog, event.Action)
+	assert.False(t, event.Action.Denied())
+	assert.Zero(t, event.Errno)
+
+	// the x32 syscalls carry their own numbers
+	event = parseEvent(&seccompauditEvent{SyscallNr: x32SyscallBit | 41, Code: 0x80000000})
+	assert.Empty(t, event.Syscall)
+	assert.Equal(t, types.ActionKillProcess, event.Action)
+}
+
+func TestTracer(t *testing.T) {
+	tracer, err := NewTracer(&Config{}, nil, func(*types.Event) {})
+	if err != nil {
+		t.Skipf("cannot load the seccomp audit tracer: %v", err)
+	}
+	tracer.Close()
+}
+
+func TestParseAction(t *testing.T) {
+	assert.Equal(t, types.ActionKillThread, parseAction(0))
+	assert.Equal(t, types.ActionTrap, parseAction(0x30000))
+	assert.Equal(t, types.ActionAllow, parseAction(0x7fff0000))
+	assert.Equal(t, types.Action("0x12340000"), parseAction(0x12340000))
+	require.True(t, types.ActionKillThread.Denied())
+}
@@ -0,0 +1,50 @@
+package types
+
+import eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+
+// Action is the seccomp action taken on a syscall, from the SECCOMP_RET_* value of the audit record
+type Action string
+
+const (
+	ActionKillProcess Action = "kill_process"
+	ActionKillThread  Action = "kill_thread"
+	ActionTrap        Action = "trap"
+	ActionErrno       Action = "errno"
+	ActionUserNotif   Action = "user_notif"
+	ActionTrace       Action = "trace"
+	ActionLog         Action = "log"
+	ActionAllow       Action = "allow"
+)
+
+// Denied tells whether the syscall was blocked, the other actions only log or delegate it
+func (a Action) Denied() bool {
+	switch a {
+	case ActionKillProcess, ActionKillThread, ActionTrap, ActionErrno:
+		return true
+	default:
+		return false
+	}
+}
+
+type Event struct {
+	eventtypes.Event
+	eventtypes.WithMountNsID
+	Pid     uint32 `json:"pid,omitempty" column:"pid,template:pid"`
+	Uid     uint32 `json:"uid,omitempty" column:"uid,template:uid"`
+	Gid     uint32 `json:"gid,omitempty" column:"gid,template:gid"`
+	Comm    string `json:"comm,omitempty" column:"comm,template:comm"`
+	ExePath string `json:"exe_path,omitempty" column:"exe_path,template:exe_path"`
+	// Syscall is the name of the syscall, empty for the syscalls of a foreign architecture
+	Syscall   string `json:"syscall,omitempty" column:"syscall,template:syscall"`
+	SyscallNr int    `json:"syscall_nr,omitempty" column:"syscall_nr"`
+	Arch      string `json:"arch,omitempty" column:"arch"`
+	Action    Action `json:"action,omitempty" column:"action"`
+	// Errno is the error returned by the denied syscall with the errno action
+	Errno uint32 `json:"errno,omitempty" column:"errno"`
+}
+
+func Base(ev eventtypes.Event) *Event {
+	return &Event{
+		Event: ev,
+	}
+}
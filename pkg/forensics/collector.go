@@ -0,0 +1,297 @@
+package forensics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/utils"
+)
+
+const (
+	// BundleIDArgument is the alert argument referencing the evidence bundle
+	BundleIDArgument = "evidenceBundleID"
+
+	// environment redaction policies
+	RedactNone    = "none"
+	RedactSecrets = "secrets"
+	RedactAll     = "all"
+
+	redactedValue     = "[REDACTED]"
+	maxExecutableSize = 50 * 1024 * 1024 // 50MB
+	// maxConcurrentCollections bounds the snapshots taken at the same time, the snapshots of alert bursts are dropped
+	maxConcurrentCollections = 4
+)
+
+var (
+	// ErrCollectorBusy is returned when the snapshot is dropped because of an alert burst
+	ErrCollectorBusy = errors.New("too many evidence collections in progress")
+
+	// secretEnvName matches the environment variable names whose value is redacted by the secrets policy
+	secretEnvName = regexp.MustCompile(`(?i)(pass|secret|token|key|credential|auth|private|cert|session|cookie)`)
+
+	// procFiles are copied as is into the bundle, missing files are recorded in the manifest errors
+	procFiles = []string{"maps", "status", "net/tcp", "net/tcp6", "net/udp", "net/udp6", "net/unix"}
+)
+
+// AlertDetails identifies the alert the evidence is collected for
+type AlertDetails struct {
+	RuleID      string `json:"ruleID,omitempty"`
+	AlertName   string `json:"alertName,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	PodName     string `json:"podName,omitempty"`
+	ContainerID string `json:"containerID,omitempty"`
+}
+
+// Manifest is the summary of the process snapshot, stored as manifest.json in the bundle
+type Manifest struct {
+	BundleID         string            `json:"bundleID"`
+	Alert            AlertDetails      `json:"alert"`
+	PID              uint32            `json:"pid"`
+	Timestamp        time.Time         `json:"timestamp"`
+	Executable       string            `json:"executable,omitempty"`
+	SHA256           string            `json:"sha256,omitempty"`
+	SHA1             string            `json:"sha1,omitempty"`
+	MD5              string            `json:"md5,omitempty"`
+	ExecutableCopied bool              `json:"executableCopied"`
+	Cwd              string            `json:"cwd,omitempty"`
+	Cmdline          []string          `json:"cmdline,omitempty"`
+	Environ          map[string]string `json:"environ,omitempty"`
+	EnvironPolicy    string            `json:"environPolicy"`
+	FileDescriptors  map[string]string `json:"fileDescriptors,omitempty"`
+	Errors           []string          `json:"errors,omitempty"`
+}
+
+// Collector snapshots the /proc data of the processes of high-severity alerts into the evidence store
+type Collector struct {
+	cfg      config.ForensicsConfig
+	store    *Store
+	procRoot string
+	slots    chan struct{} // one per snapshot in progress
+}
+
+// NewCollector creates the collector and its evidence store
+func NewCollector(cfg config.ForensicsConfig) (*Collector, error) {
+	store, err := NewStore(cfg.Directory, cfg.MaxBundles, cfg.MaxStoreSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Collector{
+		cfg:      cfg,
+		store:    store,
+		procRoot: "/proc",
+		slots:    make(chan struct{}, maxConcurrentCollections),
+	}, nil
+}
+
+// Store returns the evidence store, to retrieve the bundles
+func (c *Collector) Store() *Store {
+	return c.store
+}
+
+// ShouldCollect checks if the alert severity requires evidence
+func (c *Collector) ShouldCollect(severity int) bool {
+	return severity >= c.cfg.MinSeverity
+}
+
+// Collect snapshots the process and stores the bundle, it returns the ID of the stored bundle.
+// It runs before the response actions of the alert, so a killed process is captured first. Partial snapshots are
+// stored, the unreadable data is listed in the manifest errors. ErrCollectorBusy is returned during alert bursts.
+func (c *Collector) Collect(pid uint32, alert AlertDetails) (string, error) {
+	select {
+	case c.slots <- struct{}{}:
+		defer func() {
+			<-c.slots
+		}()
+	default:
+		return "", ErrCollectorBusy
+	}
+	bundleID := uuid.NewString()
+	if err := c.collect(bundleID, pid, alert); err != nil {
+		return "", err
+	}
+	return bundleID, nil
+}
+
+func (c *Collector) collect(bundleID string, pid uint32, alert AlertDetails) error {
+	if pid == 0 {
+		return fmt.Errorf("no process to collect evidence from")
+	}
+	procDir := filepath.Join(c.procRoot, fmt.Sprintf("%d", pid))
+	if _, err := os.Stat(procDir); err != nil {
+		return fmt.Errorf("process %d is gone: %w", pid, err)
+	}
+
+	manifest := Manifest{
+		BundleID:      bundleID,
+		Alert:         alert,
+		PID:           pid,
+		Timestamp:     time.Now().UTC(),
+		EnvironPolicy: c.cfg.EnvironRedaction,
+	}
+	addError := func(err error) {
+		manifest.Errors = append(manifest.Errors, err.Error())
+	}
+
+	// read everything first, the process may exit while the bundle is written
+	if cmdline, err := os.ReadFile(filepath.Join(procDir, "cmdline")); err == nil {
+		manifest.Cmdline = splitNull(cmdline)
+	} else {
+		addError(err)
+	}
+	if environ, err := os.ReadFile(filepath.Join(procDir, "environ")); err == nil {
+		manifest.Environ = redactEnviron(splitNull(environ), c.cfg.EnvironRedaction)
+	} else {
+		addError(err)
+	}
+	if cwd, err := os.Readlink(filepath.Join(procDir, "cwd")); err == nil {
+		manifest.Cwd = cwd
+	} else {
+		addError(err)
+	}
+	if fds, err := readFileDescriptors(filepath.Join(procDir, "fd")); err == nil {
+		manifest.FileDescriptors = fds
+	} else {
+		addError(err)
+	}
+	if exe, err := os.Readlink(filepath.Join(procDir, "exe")); err == nil {
+		manifest.Executable = exe
+	} else {
+		addError(err)
+	}
+	exePath := filepath.Join(procDir, "exe")
+	if hashes, err := utils.GetFileHashes(exePath); err == nil {
+		manifest.SHA256 = hashes.SHA256
+		manifest.SHA1 = hashes.SHA1
+		manifest.MD5 = hashes.MD5
+	} else {
+		addError(err)
+	}
+
+	files := make(map[string][]byte)
+	for _, name := range procFiles {
+		data, err := os.ReadFile(filepath.Join(procDir, name))
+		if err != nil {
+			addError(err)
+			continue
+		}
+		files[name] = data
+	}
+
+	var executable *os.File
+	if c.cfg.CopyExecutable {
+		if size, err := utils.GetFileSize(exePath); err != nil {
+			addError(err)
+		} else if size > maxExecutableSize {
+			addError(fmt.Errorf("executable of %d bytes is larger than %d bytes", size, maxExecutableSize))
+		} else if executable, err = os.Open(exePath); err != nil {
+			addError(err)
+		} else {
+			defer func(executable *os.File) {
+				_ = executable.Close()
+			}(executable)
+			manifest.ExecutableCopied = true
+		}
+	}
+
+	return c.store.Add(manifest.BundleID, func(w io.Writer) error {
+		return writeBundle(w, &manifest, files, executable)
+	})
+}
+
+// writeBundle writes the gzipped tar archive of the bundle
+func writeBundle(w io.Writer, manifest *Manifest, files map[string][]byte, executable *os.File) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addTarFile(tarWriter, "manifest.json", int64(len(manifestData)), bytes.NewReader(manifestData)); err != nil {
+		return err
+	}
+	for _, name := range procFiles {
+		if data, ok := files[name]; ok {
+			if err := addTarFile(tarWriter, filepath.Join("proc", name), int64(len(data)), bytes.NewReader(data)); err != nil {
+				return err
+			}
+		}
+	}
+	if executable != nil {
+		info, err := executable.Stat()
+		if err != nil {
+			return err
+		}
+		if err := addTarFile(tarWriter, "executable", info.Size(), executable); err != nil {
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func addTarFile(tarWriter *tar.Writer, name string, size int64, data io.Reader) error {
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := io.CopyN(tarWriter, data, size)
+	return err
+}
+
+func splitNull(data []byte) []string {
+	var fields []string
+	for _, field := range strings.Split(string(data), "\x00") {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// redactEnviron applies the redaction policy to the environment, the names are always kept
+func redactEnviron(environ []string, policy string) map[string]string {
+	redacted := make(map[string]string, len(environ))
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		switch {
+		case policy == RedactNone:
+		case policy == RedactAll, secretEnvName.MatchString(name):
+			value = redactedValue
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+func readFileDescriptors(fdDir string) (map[string]string, error) {
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil, err
+	}
+	fds := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if target, err := os.Readlink(filepath.Join(fdDir, entry.Name())); err == nil {
+			fds[entry.Name()] = target
+		}
+	}
+	return fds, nil
+}
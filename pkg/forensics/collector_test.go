@@ -0,0 +1,120 @@
+package forensics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readBundle returns the files of the bundle by name
+func readBundle(t *testing.T, store *Store, id string) map[string][]byte {
+	bundle, err := store.Open(id)
+	require.NoError(t, err)
+	defer func() {
+		_ = bundle.Close()
+	}()
+	gzipReader, err := gzip.NewReader(bundle)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	files := make(map[string][]byte)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tarReader)
+		require.NoError(t, err)
+		files[header.Name] = data
+	}
+	return files
+}
+
+func TestCollect(t *testing.T) {
+	cmd := exec.Command("sleep", "30")
+	cmd.Env = []string{"API_TOKEN=s3cr3t", "MODE=debug"}
+	require.NoError(t, cmd.Start())
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	collector, err := NewCollector(config.ForensicsConfig{
+		MinSeverity:      10,
+		Directory:        t.TempDir(),
+		MaxBundles:       10,
+		EnvironRedaction: RedactSecrets,
+		CopyExecutable:   true,
+	})
+	require.NoError(t, err)
+	assert.True(t, collector.ShouldCollect(10))
+	assert.False(t, collector.ShouldCollect(5))
+
+	alert := AlertDetails{RuleID: "R1000", AlertName: "Exec from malicious source", Namespace: "default", PodName: "nginx"}
+	id, err := collector.Collect(uint32(cmd.Process.Pid), alert)
+	require.NoError(t, err)
+
+	files := readBundle(t, collector.Store(), id)
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	assert.Equal(t, id, manifest.BundleID)
+	assert.Equal(t, alert, manifest.Alert)
+	assert.Equal(t, []string{"sleep", "30"}, manifest.Cmdline)
+	assert.Equal(t, map[string]string{"API_TOKEN": redactedValue, "MODE": "debug"}, manifest.Environ)
+	assert.NotEmpty(t, manifest.Cwd)
+	assert.NotEmpty(t, manifest.FileDescriptors)
+	assert.Len(t, manifest.SHA256, 64)
+	assert.True(t, manifest.ExecutableCopied)
+	assert.NotEmpty(t, files["proc/maps"])
+	assert.NotEmpty(t, files["proc/status"])
+
+	info, err := os.Stat(cmd.Path)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), int64(len(files["executable"])))
+
+	// a process that is gone has no evidence
+	_, err = collector.Collect(0, alert)
+	assert.Error(t, err)
+	_, err = collector.Collect(1<<22+1, alert)
+	assert.Error(t, err)
+}
+
+func TestCollectBusy(t *testing.T) {
+	collector, err := NewCollector(config.ForensicsConfig{
+		Directory:        t.TempDir(),
+		MaxBundles:       10,
+		EnvironRedaction: RedactAll,
+	})
+	require.NoError(t, err)
+
+	// the snapshots beyond the concurrent ones are dropped
+	for i := 0; i < maxConcurrentCollections; i++ {
+		collector.slots <- struct{}{}
+	}
+	alert := AlertDetails{RuleID: "R1000"}
+	_, err = collector.Collect(uint32(os.Getpid()), alert)
+	assert.ErrorIs(t, err, ErrCollectorBusy)
+	assert.Empty(t, collector.Store().List())
+
+	<-collector.slots
+	id, err := collector.Collect(uint32(os.Getpid()), alert)
+	require.NoError(t, err)
+	var manifest Manifest
+	require.NoError(t, json.Unmarshal(readBundle(t, collector.Store(), id)["manifest.json"], &manifest))
+	assert.Equal(t, id, manifest.BundleID)
+}
+
+func TestRedactEnviron(t *testing.T) {
+	environ := []string{"PATH=/usr/bin", "DB_PASSWORD=hunter2", "AWS_SECRET_ACCESS_KEY=abc", "EMPTY="}
+	assert.Equal(t, map[string]string{"PATH": "/usr/bin", "DB_PASSWORD": "hunter2", "AWS_SECRET_ACCESS_KEY": "abc", "EMPTY": ""}, redactEnviron(environ, RedactNone))
+	assert.Equal(t, map[string]string{"PATH": "/usr/bin", "DB_PASSWORD": redactedValue, "AWS_SECRET_ACCESS_KEY": redactedValue, "EMPTY": ""}, redactEnviron(environ, RedactSecrets))
+	assert.Equal(t, map[string]string{"PATH": redactedValue, "DB_PASSWORD": redactedValue, "AWS_SECRET_ACCESS_KEY": redactedValue, "EMPTY": redactedValue}, redactEnviron(environ, RedactAll))
+}
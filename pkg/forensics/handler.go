@@ -0,0 +1,49 @@
+package forensics
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+// HandlerPath is the path prefix of the read-only evidence API:
+// GET /evidence/ lists the bundles, GET /evidence/<bundle ID> downloads a bundle.
+const HandlerPath = "/evidence/"
+
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, HandlerPath)
+	if id == "" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s.List()); err != nil {
+			logger.L().Debug("Forensics - failed to write bundle list", helpers.Error(err))
+		}
+		return
+	}
+
+	bundle, err := s.Open(id)
+	if errors.Is(err, ErrBundleNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer func(bundle io.ReadCloser) {
+		_ = bundle.Close()
+	}(bundle)
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+id+bundleSuffix+"\"")
+	if _, err := io.Copy(w, bundle); err != nil {
+		logger.L().Debug("Forensics - failed to write bundle", helpers.Error(err), helpers.String("bundle ID", id))
+	}
+}
@@ -0,0 +1,152 @@
+package forensics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+const bundleSuffix = ".tar.gz"
+
+var ErrBundleNotFound = errors.New("evidence bundle not found")
+
+// BundleInfo describes a stored evidence bundle
+type BundleInfo struct {
+	ID      string    `json:"id"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// Store keeps the evidence bundles in a local directory, the oldest bundles are removed
+// when the number of bundles or their total size exceeds the limits.
+type Store struct {
+	mutex      sync.RWMutex
+	directory  string
+	maxBundles int
+	maxSize    int64
+	bundles    []BundleInfo // sorted by creation time
+}
+
+// NewStore opens the store directory and indexes the bundles already present
+func NewStore(directory string, maxBundles int, maxSize int64) (*Store, error) {
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, fmt.Errorf("creating evidence directory: %w", err)
+	}
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("reading evidence directory: %w", err)
+	}
+	s := &Store{
+		directory:  directory,
+		maxBundles: maxBundles,
+		maxSize:    maxSize,
+	}
+	for _, entry := range entries {
+		id, found := strings.CutSuffix(entry.Name(), bundleSuffix)
+		if !found || !validBundleID(id) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		s.bundles = append(s.bundles, BundleInfo{ID: id, Size: info.Size(), Created: info.ModTime()})
+	}
+	sort.Slice(s.bundles, func(i, j int) bool {
+		return s.bundles[i].Created.Before(s.bundles[j].Created)
+	})
+	s.mutex.Lock()
+	s.evict()
+	s.mutex.Unlock()
+	return s, nil
+}
+
+func validBundleID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.directory, id+bundleSuffix)
+}
+
+// Add writes a new bundle, it is visible only once completely written
+func (s *Store) Add(id string, write func(w io.Writer) error) error {
+	if !validBundleID(id) {
+		return fmt.Errorf("invalid bundle ID %q", id)
+	}
+	file, err := os.CreateTemp(s.directory, ".bundle-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(file.Name())
+	}()
+	if err := write(file); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	info, err := os.Stat(file.Name())
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), s.path(id)); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.bundles = append(s.bundles, BundleInfo{ID: id, Size: info.Size(), Created: time.Now()})
+	s.evict()
+	return nil
+}
+
+// evict removes the oldest bundles until the limits are respected, the caller must hold the lock
+func (s *Store) evict() {
+	var total int64
+	for _, bundle := range s.bundles {
+		total += bundle.Size
+	}
+	for len(s.bundles) > 0 && ((s.maxBundles > 0 && len(s.bundles) > s.maxBundles) || (s.maxSize > 0 && total > s.maxSize)) {
+		oldest := s.bundles[0]
+		if err := os.Remove(s.path(oldest.ID)); err != nil && !os.IsNotExist(err) {
+			logger.L().Warning("Forensics - failed to remove evidence bundle", helpers.Error(err), helpers.String("bundle ID", oldest.ID))
+		}
+		total -= oldest.Size
+		s.bundles = s.bundles[1:]
+	}
+}
+
+// List returns the stored bundles, oldest first
+func (s *Store) List() []BundleInfo {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]BundleInfo(nil), s.bundles...)
+}
+
+// Open returns the gzipped tar archive of the bundle
+func (s *Store) Open(id string) (io.ReadCloser, error) {
+	if !validBundleID(id) {
+		return nil, ErrBundleNotFound
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	file, err := os.Open(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, ErrBundleNotFound
+	}
+	return file, err
+}
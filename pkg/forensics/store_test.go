@@ -0,0 +1,94 @@
+package forensics
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func addBundle(t *testing.T, store *Store, content string) string {
+	id := uuid.NewString()
+	require.NoError(t, store.Add(id, func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	}))
+	return id
+}
+
+func TestStoreLimits(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, 2, 10)
+	require.NoError(t, err)
+
+	first := addBundle(t, store, "aaaa")
+	second := addBundle(t, store, "bbbb")
+	third := addBundle(t, store, "cccc")
+
+	// the oldest bundle is evicted by the count limit
+	ids := func() []string {
+		var ids []string
+		for _, bundle := range store.List() {
+			ids = append(ids, bundle.ID)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{second, third}, ids())
+	_, err = store.Open(first)
+	assert.ErrorIs(t, err, ErrBundleNotFound)
+
+	// a large bundle evicts the others by the size limit
+	fourth := addBundle(t, store, "dddddddd")
+	assert.Equal(t, []string{fourth}, ids())
+
+	bundle, err := store.Open(fourth)
+	require.NoError(t, err)
+	data, err := io.ReadAll(bundle)
+	require.NoError(t, err)
+	require.NoError(t, bundle.Close())
+	assert.Equal(t, "dddddddd", string(data))
+
+	// the bundles are indexed again on restart
+	reopened, err := NewStore(dir, 2, 10)
+	require.NoError(t, err)
+	assert.Len(t, reopened.List(), 1)
+
+	// IDs are validated, the store directory cannot be escaped
+	_, err = store.Open("../../etc/passwd")
+	assert.ErrorIs(t, err, ErrBundleNotFound)
+	assert.Error(t, store.Add("../escape", func(w io.Writer) error { return nil }))
+}
+
+func TestStoreHandler(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 10, 0)
+	require.NoError(t, err)
+	id := addBundle(t, store, "bundle")
+
+	recorder := httptest.NewRecorder()
+	store.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, HandlerPath, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var bundles []BundleInfo
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &bundles))
+	require.Len(t, bundles, 1)
+	assert.Equal(t, id, bundles[0].ID)
+
+	recorder = httptest.NewRecorder()
+	store.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, HandlerPath+id, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "bundle", recorder.Body.String())
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Disposition"), "attachment"))
+
+	recorder = httptest.NewRecorder()
+	store.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, HandlerPath+uuid.NewString(), nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	store.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, HandlerPath+id, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
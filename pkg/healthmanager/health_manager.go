@@ -3,7 +3,10 @@ package healthmanager
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/kubescape/node-agent/pkg/containerwatcher/v1"
@@ -15,11 +18,16 @@ import (
 type HealthManager struct {
 	containerWatcher *containerwatcher.IGContainerWatcher
 	port             int
+	localAPISocket   string
+	localMux         *http.ServeMux
 }
 
-func NewHealthManager() *HealthManager {
+// NewHealthManager creates the health manager, the APIs reserved to the node are served on the local API socket
+func NewHealthManager(localAPISocket string) *HealthManager {
 	return &HealthManager{
-		port: 7888,
+		port:           7888,
+		localAPISocket: localAPISocket,
+		localMux:       http.NewServeMux(),
 	}
 }
 
@@ -41,6 +49,54 @@ func (h *HealthManager) Start(ctx context.Context) {
 			logger.L().Ctx(ctx).Fatal("HealthManager - failed to start", helpers.Error(err), helpers.Int("port", h.port))
 		}
 	}()
+	h.startLocalAPI(ctx)
+}
+
+// startLocalAPI serves the local handlers on the unix socket, only reachable from the node by root
+func (h *HealthManager) startLocalAPI(ctx context.Context) {
+	if h.localAPISocket == "" {
+		return
+	}
+	go func() {
+		listener, err := listenUnix(h.localAPISocket)
+		if err != nil {
+			logger.L().Ctx(ctx).Error("HealthManager - failed to listen on the local API socket", helpers.Error(err), helpers.String("socket", h.localAPISocket))
+			return
+		}
+		srv := &http.Server{
+			Handler:      h.localMux,
+			WriteTimeout: time.Minute,
+			ReadTimeout:  15 * time.Second,
+		}
+		logger.L().Info("starting local API", helpers.String("socket", h.localAPISocket))
+		if err := srv.Serve(listener); err != nil {
+			logger.L().Ctx(ctx).Error("HealthManager - local API stopped", helpers.Error(err), helpers.String("socket", h.localAPISocket))
+		}
+	}()
+}
+
+func listenUnix(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	// remove the socket left by a previous run
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// RegisterLocalHandler serves an API reserved to the node on the local API socket
+func (h *HealthManager) RegisterLocalHandler(pattern string, handler http.Handler) {
+	h.localMux.Handle(pattern, handler)
 }
 
 func (h *HealthManager) livenessProbe(w http.ResponseWriter, _ *http.Request) {
@@ -0,0 +1,414 @@
+package imageverifier
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/containerwatcher"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	ruleenginetypes "github.com/kubescape/node-agent/pkg/ruleengine/types"
+	"github.com/kubescape/node-agent/pkg/rulemanager"
+	"github.com/kubescape/node-agent/pkg/utils"
+)
+
+const (
+	// SignatureMetadataKey records the verification status of the image on its SBOM
+	SignatureMetadataKey = "kubescape.io/image-signature"
+	// ProvenanceMetadataKey records the predicate type of the verified attestation of the image on its SBOM
+	ProvenanceMetadataKey = "kubescape.io/image-provenance"
+
+	// StatusVerified is the status of the images with a valid signature or attestation
+	StatusVerified = "verified"
+	// StatusUnsigned is the status of the images without any signature nor attestation
+	StatusUnsigned = "unsigned"
+	// StatusMismatch is the status of the images whose signatures or attestations are not valid for their digest
+	// and the configured keys
+	StatusMismatch = "mismatch"
+
+	// the results are cached by digest, they expire to pick the signatures mirrored later up
+	resultCacheSize = 1000
+	resultCacheTTL  = time.Hour
+
+	cosignSignatureType = "cosign container image signature"
+	inTotoPayloadType   = "application/vnd.in-toto+json"
+	slsaPredicatePrefix = "https://slsa.dev/provenance/"
+)
+
+// Result is the verification of an image digest
+type Result struct {
+	Status     string
+	Signed     bool   // a signature is valid
+	Provenance string // the predicate type of the valid SLSA attestation
+	Reason     string
+}
+
+// Annotate records the result on the annotations of an object, e.g. the SBOM of the image
+func (r Result) Annotate(annotations map[string]string) {
+	annotations[SignatureMetadataKey] = r.Status
+	if r.Provenance != "" {
+		annotations[ProvenanceMetadataKey] = r.Provenance
+	} else {
+		delete(annotations, ProvenanceMetadataKey)
+	}
+}
+
+// signedPayload is a signature as written by "cosign download signature"
+type signedPayload struct {
+	Base64Signature string
+	Payload         []byte
+}
+
+// simpleSigning is the payload of a cosign signature
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// envelope is a DSSE envelope as written by "cosign download attestation"
+type envelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		KeyID string `json:"keyid"`
+		Sig   string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// statement is an in-toto statement
+type statement struct {
+	PredicateType string `json:"predicateType"`
+	Subject       []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+// Verifier checks the images of the starting containers against the signatures and attestations mirrored on the node,
+// it never reaches the registries. The verifications are reported to the rule manager.
+type Verifier struct {
+	cfg            config.ImageVerificationConfig
+	k8sObjectCache objectcache.K8sObjectCache
+	keys           []crypto.PublicKey
+	results        *expirable.LRU[string, Result] // key is image digest
+	ruleManager    rulemanager.RuleManagerClient
+	skipNamespace  func(string) bool
+}
+
+var _ containerwatcher.ContainerReceiver = (*Verifier)(nil)
+
+// NewVerifier loads the PEM public keys of the configuration
+func NewVerifier(cfg config.Config, k8sObjectCache objectcache.K8sObjectCache) (*Verifier, error) {
+	keys := make([]crypto.PublicKey, 0, len(cfg.ImageVerification.PublicKeys))
+	for _, path := range cfg.ImageVerification.PublicKeys {
+		key, err := loadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return &Verifier{
+		cfg:            cfg.ImageVerification,
+		k8sObjectCache: k8sObjectCache,
+		keys:           keys,
+		results:        expirable.NewLRU[string, Result](resultCacheSize, nil, resultCacheTTL),
+		ruleManager:    rulemanager.CreateRuleManagerMock(),
+		skipNamespace:  cfg.SkipNamespace,
+	}, nil
+}
+
+// SetRuleManager sets the rule manager evaluating the verifications, the SBOM manager needs the verifier before
+// the rule manager is created
+func (v *Verifier) SetRuleManager(ruleManager rulemanager.RuleManagerClient) {
+	v.ruleManager = ruleManager
+}
+
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// Verify returns the verification of the image, the results are cached by digest
+func (v *Verifier) Verify(imageID string) Result {
+	_, digest, found := strings.Cut(imageID, "@")
+	if !found {
+		digest = imageID
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		return Result{Status: StatusUnsigned, Reason: "image without digest"}
+	}
+	if result, ok := v.results.Get(digest); ok {
+		return result
+	}
+	result := v.verify(digest)
+	v.results.Add(digest, result)
+	return result
+}
+
+func (v *Verifier) verify(digest string) Result {
+	prefix := filepath.Join(v.cfg.Directory, strings.Replace(digest, ":", "-", 1))
+	signatures, sigErr := readLines[signedPayload](prefix + ".sig")
+	envelopes, attErr := readLines[envelope](prefix + ".att")
+	if len(signatures) == 0 && len(envelopes) == 0 {
+		reason := "no mirrored signature nor attestation"
+		if err := errors.Join(ignoreNotExist(sigErr), ignoreNotExist(attErr)); err != nil {
+			reason = err.Error()
+		}
+		return Result{Status: StatusUnsigned, Reason: reason}
+	}
+	var result Result
+	var reasons []string
+	for _, signature := range signatures {
+		if err := v.verifySignature(signature, digest); err != nil {
+			reasons = append(reasons, "signature: "+err.Error())
+			continue
+		}
+		result.Signed = true
+		break
+	}
+	for _, env := range envelopes {
+		predicateType, err := v.verifyAttestation(env, digest)
+		if err != nil {
+			reasons = append(reasons, "attestation: "+err.Error())
+			continue
+		}
+		result.Provenance = predicateType
+		break
+	}
+	switch {
+	case v.cfg.RequireAttestation && result.Provenance == "":
+		result.Status = StatusMismatch
+		reasons = append(reasons, "no valid SLSA attestation")
+	case result.Signed || result.Provenance != "":
+		result.Status = StatusVerified
+		return result
+	default:
+		result.Status = StatusMismatch
+	}
+	result.Reason = strings.Join(reasons, "; ")
+	return result
+}
+
+func (v *Verifier) verifySignature(signature signedPayload, digest string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature.Base64Signature)
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+	if err := v.verifyWithKeys(signature.Payload, sig); err != nil {
+		return err
+	}
+	var payload simpleSigning
+	if err := json.Unmarshal(signature.Payload, &payload); err != nil {
+		return fmt.Errorf("decoding payload: %w", err)
+	}
+	if payload.Critical.Type != cosignSignatureType {
+		return fmt.Errorf("unexpected payload type %q", payload.Critical.Type)
+	}
+	if payload.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("signed digest %s does not match", payload.Critical.Image.DockerManifestDigest)
+	}
+	return nil
+}
+
+// verifyAttestation returns the predicate type of a valid SLSA provenance of the digest
+func (v *Verifier) verifyAttestation(env envelope, digest string) (string, error) {
+	if env.PayloadType != inTotoPayloadType {
+		return "", fmt.Errorf("unexpected payload type %q", env.PayloadType)
+	}
+	body, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return "", fmt.Errorf("decoding payload: %w", err)
+	}
+	pae := preAuthEncoding(env.PayloadType, body)
+	verified := false
+	for _, signature := range env.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err == nil && v.verifyWithKeys(pae, sig) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return "", errors.New("no signature verified with the configured keys")
+	}
+	var st statement
+	if err := json.Unmarshal(body, &st); err != nil {
+		return "", fmt.Errorf("decoding statement: %w", err)
+	}
+	if !strings.HasPrefix(st.PredicateType, slsaPredicatePrefix) {
+		return "", fmt.Errorf("unexpected predicate type %q", st.PredicateType)
+	}
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	for _, subject := range st.Subject {
+		if subject.Digest[algorithm] == hex {
+			return st.PredicateType, nil
+		}
+	}
+	return "", errors.New("image digest is not a subject of the attestation")
+}
+
+// verifyWithKeys checks the signature of the data with any of the configured keys
+func (v *Verifier) verifyWithKeys(data, sig []byte) error {
+	hash := sha256.Sum256(data)
+	for _, key := range v.keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, hash[:], sig) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig) == nil {
+				return nil
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, data, sig) {
+				return nil
+			}
+		}
+	}
+	return errors.New("no signature verified with the configured keys")
+}
+
+// preAuthEncoding is the DSSE v1 pre-authentication encoding signed in the envelopes
+func preAuthEncoding(payloadType string, payload []byte) []byte {
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "DSSEv1 %d %s %d ", len(payloadType), payloadType, len(payload))
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+// readLines decodes a file of JSON lines
+func readLines[T any](path string) ([]T, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	var items []T
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var item T
+		if err := json.Unmarshal(line, &item); err != nil {
+			return items, fmt.Errorf("decoding %s: %w", filepath.Base(path), err)
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+func ignoreNotExist(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (v *Verifier) ContainerCallback(notif containercollection.PubSubEvent) {
+	if notif.Type != containercollection.EventTypeAddContainer || v.skipNamespace(notif.Container.K8s.Namespace) {
+		return
+	}
+	go v.verifyContainer(notif.Container)
+}
+
+// verifyContainer verifies the image of the container once its image ID is resolved and reports the result
+func (v *Verifier) verifyContainer(container *containercollection.Container) {
+	containerID := container.Runtime.ContainerID
+	err := backoff.Retry(func() error {
+		if v.k8sObjectCache.GetSharedContainerData(containerID) != nil {
+			return nil
+		}
+		return fmt.Errorf("container %s not found in shared data", containerID)
+	}, backoff.NewExponentialBackOff())
+	if err != nil {
+		logger.L().Debug("Verifier - container not found in shared data", helpers.String("container ID", containerID))
+		return
+	}
+	imageID := v.k8sObjectCache.GetSharedContainerData(containerID).ImageID
+	result := v.Verify(imageID)
+	if result.Status != StatusVerified {
+		logger.L().Debug("Verifier - image failed verification",
+			helpers.String("namespace", container.K8s.Namespace),
+			helpers.String("pod", container.K8s.PodName),
+			helpers.String("container", container.K8s.ContainerName),
+			helpers.String("imageID", imageID),
+			helpers.String("status", result.Status),
+			helpers.String("reason", result.Reason))
+	}
+	v.ruleManager.ReportEvent(utils.ImageVerificationEventType, newEvent(container, imageID, result))
+}
+
+func newEvent(container *containercollection.Container, imageID string, result Result) *ruleenginetypes.ImageVerificationEvent {
+	return &ruleenginetypes.ImageVerificationEvent{
+		Event: eventtypes.Event{
+			CommonData: eventtypes.CommonData{
+				Runtime: eventtypes.BasicRuntimeMetadata{
+					ContainerID:          container.Runtime.ContainerID,
+					ContainerName:        container.Runtime.ContainerName,
+					RuntimeName:          container.Runtime.RuntimeName,
+					ContainerImageName:   container.Runtime.ContainerImageName,
+					ContainerImageDigest: container.Runtime.ContainerImageDigest,
+				},
+				K8s: eventtypes.K8sMetadata{
+					BasicK8sMetadata: eventtypes.BasicK8sMetadata{
+						Namespace:     container.K8s.Namespace,
+						PodName:       container.K8s.PodName,
+						PodLabels:     container.K8s.PodLabels,
+						ContainerName: container.K8s.ContainerName,
+					},
+					HostNetwork: container.HostNetwork,
+				},
+			},
+			Timestamp: eventtypes.Time(time.Now().UnixNano()),
+			Type:      eventtypes.NORMAL,
+		},
+		Pid:        container.ContainerPid(),
+		ImageID:    imageID,
+		Verified:   result.Status == StatusVerified,
+		Status:     result.Status,
+		Provenance: result.Provenance,
+		Reason:     result.Reason,
+	}
+}
@@ -0,0 +1,122 @@
+package imageverifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	signedDigest   = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	attestedDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	forgedDigest   = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+	unsignedDigest = "sha256:4444444444444444444444444444444444444444444444444444444444444444"
+)
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, data []byte) string {
+	hash := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func writeJSON(t *testing.T, path string, v any) {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, append(data, '\n'), 0644))
+}
+
+func mirrorPath(dir, digest, ext string) string {
+	return filepath.Join(dir, strings.Replace(digest, ":", "-", 1)+ext)
+}
+
+func writeSignature(t *testing.T, dir string, key *ecdsa.PrivateKey, digest, signedDigest string) {
+	payload := []byte(`{"critical":{"identity":{"docker-reference":"docker.io/library/nginx"},"image":{"docker-manifest-digest":"` + signedDigest + `"},"type":"cosign container image signature"},"optional":null}`)
+	writeJSON(t, mirrorPath(dir, digest, ".sig"), map[string]any{
+		"Base64Signature": sign(t, key, payload),
+		"Payload":         payload,
+	})
+}
+
+func writeAttestation(t *testing.T, dir string, key *ecdsa.PrivateKey, digest string) {
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	body, err := json.Marshal(map[string]any{
+		"_type":         "https://in-toto.io/Statement/v0.1",
+		"predicateType": "https://slsa.dev/provenance/v0.2",
+		"subject":       []any{map[string]any{"name": "docker.io/library/nginx", "digest": map[string]string{algorithm: hex}}},
+		"predicate":     map[string]any{"builder": map[string]string{"id": "https://github.com/actions"}},
+	})
+	require.NoError(t, err)
+	writeJSON(t, mirrorPath(dir, digest, ".att"), map[string]any{
+		"payloadType": inTotoPayloadType,
+		"payload":     base64.StdEncoding.EncodeToString(body),
+		"signatures":  []any{map[string]string{"keyid": "", "sig": sign(t, key, preAuthEncoding(inTotoPayloadType, body))}},
+	})
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "cosign.pub")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	writeSignature(t, dir, key, signedDigest, signedDigest)
+	writeAttestation(t, dir, key, attestedDigest)
+	// a signature of another image and an attestation signed with an unknown key
+	writeSignature(t, dir, key, forgedDigest, signedDigest)
+	writeAttestation(t, dir, otherKey, forgedDigest)
+
+	cfg := config.Config{ImageVerification: config.ImageVerificationConfig{Enabled: true, Directory: dir, PublicKeys: []string{keyPath}}}
+	v, err := NewVerifier(cfg, nil)
+	require.NoError(t, err)
+
+	result := v.Verify("docker.io/library/nginx@" + signedDigest)
+	assert.Equal(t, Result{Status: StatusVerified, Signed: true}, result)
+	result = v.Verify(attestedDigest)
+	assert.Equal(t, Result{Status: StatusVerified, Provenance: "https://slsa.dev/provenance/v0.2"}, result)
+	result = v.Verify(forgedDigest)
+	assert.Equal(t, StatusMismatch, result.Status)
+	assert.Contains(t, result.Reason, "does not match")
+	assert.Contains(t, result.Reason, "no signature verified")
+	result = v.Verify(unsignedDigest)
+	assert.Equal(t, StatusUnsigned, result.Status)
+	assert.Equal(t, StatusUnsigned, v.Verify("docker.io/library/nginx:latest").Status)
+
+	annotations := map[string]string{}
+	v.Verify(attestedDigest).Annotate(annotations)
+	assert.Equal(t, map[string]string{SignatureMetadataKey: StatusVerified, ProvenanceMetadataKey: "https://slsa.dev/provenance/v0.2"}, annotations)
+
+	// the signature alone is not enough when an attestation is required
+	cfg.ImageVerification.RequireAttestation = true
+	v, err = NewVerifier(cfg, nil)
+	require.NoError(t, err)
+	result = v.Verify(signedDigest)
+	assert.Equal(t, StatusMismatch, result.Status)
+	assert.True(t, result.Signed)
+	assert.Equal(t, StatusVerified, v.Verify(attestedDigest).Status)
+}
+
+func TestNewVerifierInvalidKey(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	require.NoError(t, os.WriteFile(keyPath, []byte("not a key"), 0644))
+	_, err := NewVerifier(config.Config{ImageVerification: config.ImageVerificationConfig{PublicKeys: []string{keyPath}}}, nil)
+	assert.Error(t, err)
+}
@@ -19,6 +19,11 @@ type K8sClientMock struct {
 
 var _ K8sClientInterface = (*K8sClientMock)(nil)
 
+// NewK8sClientMock returns a mock whose clients serve the given objects
+func NewK8sClientMock(objects ...runtime.Object) *K8sClientMock {
+	return &K8sClientMock{objects: objects}
+}
+
 func (k *K8sClientMock) GetWorkload(namespace, _, name string) (k8sinterface.IWorkload, error) {
 	return workloadinterface.NewWorkloadObj(map[string]interface{}{
 		"apiVersion": "v1",
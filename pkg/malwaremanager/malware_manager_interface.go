@@ -8,6 +8,12 @@ import (
 	igtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
 
+const (
+	FixSuggestions = "Please remove the file from the system. If the file is required, please contact your security team for further investigation."
+	// MaxFileSize bounds the size of the files scanned for malware
+	MaxFileSize = 50 * 1024 * 1024 // 50MB
+)
+
 type MalwareManagerClient interface {
 	ReportEvent(eventType utils.EventType, event utils.K8sEvent)
 	ContainerCallback(notif containercollection.PubSubEvent)
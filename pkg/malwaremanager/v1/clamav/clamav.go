@@ -18,11 +18,6 @@ type ClamAVClient struct {
 
 var _ malwaremanager.MalwareScanner = (*ClamAVClient)(nil)
 
-const (
-	FixSuggestions = "Please remove the file from the system. If the file is required, please contact your security team for further investigation."
-	maxFileSize    = 50 * 1024 * 1024 // 50MB
-)
-
 func CreateClamAVClient(clamavSocket string) (*ClamAVClient, error) {
 	c := clamd.NewClamd(clamavSocket)
 	clamavClient := ClamAVClient{
@@ -45,7 +40,7 @@ func (c *ClamAVClient) Scan(eventType nautils.EventType, event utils.K8sEvent, c
 	switch eventType {
 	case nautils.ExecveEventType:
 		return c.handleExecEvent(event.(*tracerexectype.Event), containerPid)
-	case nautils.OpenEventType:
+	case nautils.OpenEventType, nautils.FileSweepEventType:
 		return c.handleOpenEvent(event.(*traceropentype.Event), containerPid)
 	default:
 		return nil
@@ -50,7 +50,7 @@ func (c *ClamAVClient) handleOpenEvent(event *types.Event, containerPid uint32)
 
 			sha1hash := ""
 			md5hash := ""
-			if size != 0 && size < maxFileSize {
+			if size != 0 && size < malwaremanager.MaxFileSize {
 				sha1hash, md5hash, err = utils.CalculateFileHashes(result.Path)
 				if err != nil {
 					logger.L().Debug("ClamAVClient.handleOpenEvent - getting file hashes", helpers.Error(err))
@@ -62,7 +62,7 @@ func (c *ClamAVClient) handleOpenEvent(event *types.Event, containerPid uint32)
 				BasicRuntimeAlert: armotypes.BaseRuntimeAlert{
 					AlertName:      result.Description,
 					InfectedPID:    event.Pid,
-					FixSuggestions: FixSuggestions,
+					FixSuggestions: malwaremanager.FixSuggestions,
 					SHA1Hash:       sha1hash,
 					MD5Hash:        md5hash,
 					Severity:       10, // TODO: Get severity from api.
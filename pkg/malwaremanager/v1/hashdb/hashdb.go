@@ -0,0 +1,264 @@
+package malwaremanager
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
+	traceropentype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/open/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/malwaremanager"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// malwareBazaarSignatureColumn is the signature column of the MalwareBazaar CSV dumps
+	malwareBazaarSignatureColumn = 8
+	unknownSignature             = "Known malware hash"
+)
+
+// hashDatabase indexes the known-bad hashes by their binary value to keep large dumps small in memory
+type hashDatabase struct {
+	sha256     map[[32]byte]string
+	sha1       map[[20]byte]string
+	md5        map[[16]byte]string
+	signatures map[string]string // interned signature names
+}
+
+func newHashDatabase() *hashDatabase {
+	return &hashDatabase{
+		sha256:     make(map[[32]byte]string),
+		sha1:       make(map[[20]byte]string),
+		md5:        make(map[[16]byte]string),
+		signatures: make(map[string]string),
+	}
+}
+
+func (db *hashDatabase) len() int {
+	return len(db.sha256) + len(db.sha1) + len(db.md5)
+}
+
+// add indexes a hex encoded hash, values that are not SHA256, SHA1 or MD5 hashes are ignored
+func (db *hashDatabase) add(hash, signature string) bool {
+	decoded, err := hex.DecodeString(strings.TrimSpace(hash))
+	if err != nil {
+		return false
+	}
+	if signature == "" || signature == "n/a" {
+		signature = unknownSignature
+	}
+	if interned, ok := db.signatures[signature]; ok {
+		signature = interned
+	} else {
+		db.signatures[signature] = signature
+	}
+	switch len(decoded) {
+	case 32:
+		db.sha256[[32]byte(decoded)] = signature
+	case 20:
+		db.sha1[[20]byte(decoded)] = signature
+	case 16:
+		db.md5[[16]byte(decoded)] = signature
+	default:
+		return false
+	}
+	return true
+}
+
+// lookup returns the signature of the first known hash
+func (db *hashDatabase) lookup(hashes utils.FileHashes) (string, bool) {
+	if decoded, err := hex.DecodeString(hashes.SHA256); err == nil && len(decoded) == 32 {
+		if signature, ok := db.sha256[[32]byte(decoded)]; ok {
+			return signature, true
+		}
+	}
+	if decoded, err := hex.DecodeString(hashes.SHA1); err == nil && len(decoded) == 20 {
+		if signature, ok := db.sha1[[20]byte(decoded)]; ok {
+			return signature, true
+		}
+	}
+	if decoded, err := hex.DecodeString(hashes.MD5); err == nil && len(decoded) == 16 {
+		if signature, ok := db.md5[[16]byte(decoded)]; ok {
+			return signature, true
+		}
+	}
+	return "", false
+}
+
+// HashDatabaseScanner is a fast path scanner matching the file hashes against local known-bad hash databases.
+type HashDatabaseScanner struct {
+	mutex          sync.RWMutex
+	database       *hashDatabase
+	paths          []string
+	modTimes       map[string]time.Time
+	reloadInterval time.Duration
+}
+
+var _ malwaremanager.MalwareScanner = (*HashDatabaseScanner)(nil)
+
+// CreateHashDatabaseScanner loads the hash databases and reloads them when they change until the context is done.
+func CreateHashDatabaseScanner(ctx context.Context, paths []string, reloadInterval time.Duration) (*HashDatabaseScanner, error) {
+	scanner := &HashDatabaseScanner{
+		database:       newHashDatabase(),
+		paths:          paths,
+		reloadInterval: reloadInterval,
+	}
+	scanner.Reload()
+
+	if reloadInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(reloadInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					scanner.Reload()
+				}
+			}
+		}()
+	}
+	return scanner, nil
+}
+
+// Reload reloads the databases if one of the files was added, removed or modified.
+func (h *HashDatabaseScanner) Reload() {
+	modTimes := make(map[string]time.Time)
+	for _, path := range h.paths {
+		if err := listDatabaseFiles(path, modTimes); err != nil {
+			logger.L().Warning("HashDatabaseScanner - failed to list hash databases", helpers.Error(err), helpers.String("path", path))
+		}
+	}
+	if !h.changed(modTimes) {
+		return
+	}
+
+	database := newHashDatabase()
+	for path := range modTimes {
+		if err := loadDatabaseFile(path, database); err != nil {
+			logger.L().Warning("HashDatabaseScanner - failed to load hash database", helpers.Error(err), helpers.String("path", path))
+		}
+	}
+
+	h.mutex.Lock()
+	h.database = database
+	h.modTimes = modTimes
+	h.mutex.Unlock()
+	logger.L().Info("HashDatabaseScanner - loaded hash databases", helpers.Int("files", len(modTimes)), helpers.Int("hashes", database.len()))
+}
+
+func (h *HashDatabaseScanner) changed(modTimes map[string]time.Time) bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if h.modTimes == nil || len(modTimes) != len(h.modTimes) {
+		return true
+	}
+	for path, modTime := range modTimes {
+		if previous, ok := h.modTimes[path]; !ok || !previous.Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// listDatabaseFiles lists a database file or the files of a directory with their modification time
+func listDatabaseFiles(path string, modTimes map[string]time.Time) error {
+	return utils.WalkFiles(path, func(filePath string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		modTimes[filePath] = info.ModTime()
+		return nil
+	})
+}
+
+// loadDatabaseFile loads a MalwareBazaar CSV dump or a text file with one "<hash> [signature]" per line, comments start with #
+func loadDatabaseFile(path string, database *hashDatabase) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.Contains(line, ",") {
+			hash, signature, _ := strings.Cut(line, " ")
+			database.add(hash, strings.TrimSpace(signature))
+			continue
+		}
+		reader := csv.NewReader(strings.NewReader(line))
+		reader.LazyQuotes = true
+		reader.TrimLeadingSpace = true
+		record, err := reader.Read()
+		if err != nil {
+			continue
+		}
+		// MalwareBazaar dumps start with the first seen date followed by the SHA256, MD5 and SHA1 hashes
+		if len(record) > malwareBazaarSignatureColumn && database.add(record[1], record[malwareBazaarSignatureColumn]) {
+			continue
+		}
+		signature := ""
+		if len(record) > 1 {
+			signature = record[1]
+		}
+		database.add(record[0], signature)
+	}
+	return scanner.Err()
+}
+
+func (h *HashDatabaseScanner) Scan(eventType utils.EventType, event utils.K8sEvent, containerPid uint32) malwaremanager.MalwareResult {
+	switch eventType {
+	case utils.ExecveEventType:
+		return h.handleExecEvent(event.(*tracerexectype.Event), containerPid)
+	case utils.OpenEventType, utils.FileSweepEventType:
+		openEvent := event.(*traceropentype.Event)
+		// discard if it is an open for writing event
+		if openEvent.FlagsRaw&unix.O_WRONLY != 0 {
+			return nil
+		}
+		return h.handleOpenEvent(openEvent, containerPid)
+	default:
+		return nil
+	}
+}
+
+// lookupFile hashes the file through the host path and looks the hashes up, files larger than malwaremanager.MaxFileSize are skipped
+func (h *HashDatabaseScanner) lookupFile(hostFilePath string) (string, utils.FileHashes, int64, bool) {
+	h.mutex.RLock()
+	database := h.database
+	h.mutex.RUnlock()
+	if database.len() == 0 {
+		return "", utils.FileHashes{}, 0, false
+	}
+
+	size, err := utils.GetFileSize(hostFilePath)
+	if err != nil || size == 0 || size > malwaremanager.MaxFileSize {
+		return "", utils.FileHashes{}, 0, false
+	}
+	hashes, err := utils.GetFileHashes(hostFilePath)
+	if err != nil {
+		logger.L().Debug("HashDatabaseScanner - getting file hashes", helpers.String("path", hostFilePath), helpers.Error(err))
+		return "", utils.FileHashes{}, 0, false
+	}
+	signature, found := database.lookup(hashes)
+	return signature, hashes, size, found
+}
@@ -0,0 +1,97 @@
+package malwaremanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
+	traceropentype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/open/types"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// SHA256, SHA1 and MD5 of "malware"
+const (
+	malwareSHA256 = "2f293f67aa33f2ce247b28d6fb2fef2623cfde731f96b3d7f84ae74e9e192bdd"
+	malwareSHA1   = "316ca0099385ebe6d7fcb9d5e0785deafedfe791"
+	malwareMD5    = "f3f0c6e992b7562598d9865b6fe8b3a6"
+)
+
+const malwareBazaarDump = `################################################################
+# MalwareBazaar full data dump (CSV)                           #
+################################################################
+# "first_seen_utc","sha256_hash","md5_hash","sha1_hash","reporter","file_name","file_type_guess","mime_type","signature","clamav","vtpercent","imphash","ssdeep","tlsh"
+"2024-01-01 00:00:00", "` + malwareSHA256 + `", "` + malwareMD5 + `", "` + malwareSHA1 + `", "reporter", "xmrig", "elf", "application/x-executable", "CoinMiner", "n/a", "n/a", "n/a", "n/a", "n/a"
+"2024-01-01 00:00:01", "0000000000000000000000000000000000000000000000000000000000000001", "n/a", "n/a", "reporter", "unknown", "elf", "application/x-executable", "n/a", "n/a", "n/a", "n/a", "n/a", "n/a"
+`
+
+func TestLoadDatabaseFile(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "full.csv"), []byte(malwareBazaarDump), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "hashes.txt"), []byte("# sha1 and md5 list\n"+malwareSHA1+" Mirai\n0123456789abcdef0123456789abcdef\nnot-a-hash\n"), 0644))
+
+	database := newHashDatabase()
+	assert.NoError(t, loadDatabaseFile(filepath.Join(dir, "full.csv"), database))
+	assert.NoError(t, loadDatabaseFile(filepath.Join(dir, "hashes.txt"), database))
+	assert.Equal(t, 2, len(database.sha256))
+	assert.Equal(t, 1, len(database.sha1))
+	assert.Equal(t, 1, len(database.md5))
+
+	signature, found := database.lookup(utils.FileHashes{SHA256: malwareSHA256})
+	assert.True(t, found)
+	assert.Equal(t, "CoinMiner", signature)
+
+	signature, found = database.lookup(utils.FileHashes{SHA256: "0000000000000000000000000000000000000000000000000000000000000001"})
+	assert.True(t, found)
+	assert.Equal(t, unknownSignature, signature)
+
+	signature, found = database.lookup(utils.FileHashes{SHA1: malwareSHA1})
+	assert.True(t, found)
+	assert.Equal(t, "Mirai", signature)
+
+	_, found = database.lookup(utils.FileHashes{MD5: "0123456789ABCDEF0123456789ABCDEF"})
+	assert.True(t, found)
+
+	_, found = database.lookup(utils.FileHashes{SHA256: "ff", SHA1: "", MD5: "zz"})
+	assert.False(t, found)
+}
+
+func TestHashDatabaseScanner(t *testing.T) {
+	dbDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dbDir, "full.csv"), []byte(malwareBazaarDump), 0644))
+
+	scanner, err := CreateHashDatabaseScanner(context.Background(), []string{dbDir}, 0)
+	assert.NoError(t, err)
+
+	// the files are reached through /proc/<pid>/root of the test process
+	dir := t.TempDir()
+	malware := filepath.Join(dir, "xmrig")
+	assert.NoError(t, os.WriteFile(malware, []byte("malware"), 0755))
+	clean := filepath.Join(dir, "clean")
+	assert.NoError(t, os.WriteFile(clean, []byte("clean"), 0755))
+	containerPid := uint32(os.Getpid())
+
+	exec := &tracerexectype.Event{Comm: "xmrig", Args: []string{malware}, Pid: 42}
+	if result := scanner.Scan(utils.ExecveEventType, exec, containerPid); assert.NotNil(t, result) {
+		assert.Equal(t, "CoinMiner", result.GetBasicRuntimeAlert().AlertName)
+		assert.Equal(t, malwareSHA256, result.GetBasicRuntimeAlert().SHA256Hash)
+		assert.Equal(t, "Known malicious file hash: CoinMiner", result.GetMalwareRuntimeAlert().MalwareDescription)
+	}
+
+	exec.Args = []string{clean}
+	assert.Nil(t, scanner.Scan(utils.ExecveEventType, exec, containerPid))
+
+	open := &traceropentype.Event{FullPath: malware, Pid: 42}
+	assert.NotNil(t, scanner.Scan(utils.OpenEventType, open, containerPid))
+	open.FlagsRaw = unix.O_WRONLY
+	assert.Nil(t, scanner.Scan(utils.OpenEventType, open, containerPid))
+
+	// a removed database is dropped on reload
+	assert.NoError(t, os.Remove(filepath.Join(dbDir, "full.csv")))
+	scanner.Reload()
+	exec.Args = []string{malware}
+	assert.Nil(t, scanner.Scan(utils.ExecveEventType, exec, containerPid))
+}
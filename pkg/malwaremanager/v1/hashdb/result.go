@@ -0,0 +1,108 @@
+package malwaremanager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/malwaremanager"
+	malwaremanager2 "github.com/kubescape/node-agent/pkg/malwaremanager/v1/types"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/dustin/go-humanize"
+	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
+	traceropentype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/open/types"
+	igtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+func (h *HashDatabaseScanner) handleExecEvent(event *tracerexectype.Event, containerPid uint32) malwaremanager.MalwareResult {
+	if event == nil {
+		return nil
+	}
+
+	hostFilePath, err := utils.GetHostFilePathFromEvent(event, containerPid)
+	if err != nil {
+		logger.L().Warning("HashDatabaseScanner.handleExecEvent - getting host file path", helpers.Error(err))
+		return nil
+	}
+
+	signature, hashes, size, found := h.lookupFile(hostFilePath)
+	if !found {
+		return nil
+	}
+
+	return newMalwareResult(signature, hashes, size, event.Event, armotypes.Process{
+		Comm:       event.Comm,
+		Path:       utils.GetExecPathFromEvent(event),
+		Gid:        &event.Gid,
+		PID:        event.Pid,
+		Uid:        &event.Uid,
+		UpperLayer: &event.UpperLayer,
+		PPID:       event.Ppid,
+		Pcomm:      event.Pcomm,
+		Cwd:        event.Cwd,
+		Hardlink:   event.ExePath,
+		Cmdline:    fmt.Sprintf("%s %s", utils.GetExecPathFromEvent(event), utils.GetExecArgsFromEvent(event)),
+	})
+}
+
+func (h *HashDatabaseScanner) handleOpenEvent(event *traceropentype.Event, containerPid uint32) malwaremanager.MalwareResult {
+	if event == nil {
+		return nil
+	}
+
+	hostFilePath, err := utils.GetHostFilePathFromEvent(event, containerPid)
+	if err != nil {
+		logger.L().Warning("HashDatabaseScanner.handleOpenEvent - getting host file path", helpers.Error(err))
+		return nil
+	}
+
+	signature, hashes, size, found := h.lookupFile(hostFilePath)
+	if !found {
+		return nil
+	}
+
+	return newMalwareResult(signature, hashes, size, event.Event, armotypes.Process{
+		Comm: event.Comm,
+		Path: event.FullPath,
+		Gid:  &event.Gid,
+		PID:  event.Pid,
+		Uid:  &event.Uid,
+	})
+}
+
+func newMalwareResult(signature string, hashes utils.FileHashes, size int64, event igtypes.Event, process armotypes.Process) malwaremanager.MalwareResult {
+	return &malwaremanager2.GenericMalwareResult{
+		BasicRuntimeAlert: armotypes.BaseRuntimeAlert{
+			AlertName:      signature,
+			InfectedPID:    process.PID,
+			FixSuggestions: malwaremanager.FixSuggestions,
+			SHA1Hash:       hashes.SHA1,
+			MD5Hash:        hashes.MD5,
+			SHA256Hash:     hashes.SHA256,
+			Severity:       10, // TODO: Get severity from api.
+			Size:           humanize.IBytes(uint64(size)),
+			Timestamp:      time.Unix(0, int64(event.Timestamp)),
+		},
+		RuntimeProcessDetails: armotypes.ProcessTree{
+			ProcessTree: process,
+			ContainerID: event.Runtime.ContainerID,
+		},
+		TriggerEvent: event,
+		MalwareRuntimeAlert: armotypes.MalwareAlert{
+			MalwareDescription: fmt.Sprintf("Known malicious file hash: %s", signature),
+		},
+		RuntimeAlertK8sDetails: armotypes.RuntimeAlertK8sDetails{
+			ContainerID:   event.Runtime.ContainerID,
+			ContainerName: event.K8s.ContainerName,
+			Namespace:     event.GetNamespace(),
+			PodName:       event.GetPod(),
+			PodNamespace:  event.GetNamespace(),
+			HostNetwork:   &event.K8s.HostNetwork,
+			Image:         event.Runtime.ContainerImageName,
+			ImageDigest:   event.Runtime.ContainerImageDigest,
+		},
+	}
+}
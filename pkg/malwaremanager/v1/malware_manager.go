@@ -1,6 +1,7 @@
 package malwaremanager
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,6 +13,8 @@ import (
 	"github.com/kubescape/node-agent/pkg/k8sclient"
 	"github.com/kubescape/node-agent/pkg/malwaremanager"
 	clamavv1 "github.com/kubescape/node-agent/pkg/malwaremanager/v1/clamav"
+	hashdbv1 "github.com/kubescape/node-agent/pkg/malwaremanager/v1/hashdb"
+	yarav1 "github.com/kubescape/node-agent/pkg/malwaremanager/v1/yara"
 	"github.com/kubescape/node-agent/pkg/metricsmanager"
 	"github.com/kubescape/node-agent/pkg/objectcache"
 	"github.com/kubescape/node-agent/pkg/utils"
@@ -19,6 +22,7 @@ import (
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/dustin/go-humanize"
 	"github.com/goradd/maps"
+	"github.com/hashicorp/golang-lru/v2/expirable"
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
 	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
 	traceropentype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/open/types"
@@ -29,7 +33,8 @@ import (
 
 const (
 	ScannedFilesMaxBufferLength = 10000
-	maxFileSize                 = 50 * 1024 * 1024 // 50MB
+	// minScanCacheSize is the minimum size of the scan cache, the cache would not be bounded with a size of 0
+	minScanCacheSize = 1000
 )
 
 type MalwareManager struct {
@@ -42,6 +47,11 @@ type MalwareManager struct {
 	nodeName             string
 	clusterName          string
 	malwareScanners      []malwaremanager.MalwareScanner
+	hashScanner          malwaremanager.MalwareScanner // fast path, nil when no hash database is configured
+	scanCache            *expirable.LRU[string, bool]  // key is the file SHA256, value is true if malware was found
+	sweeper              *fileSweeper                  // nil when the filesystem sweep is disabled
+	sweepCancels         maps.SafeMap[string, context.CancelFunc]
+	ctx                  context.Context
 	cfg                  config.Config
 	containerIdToShimPid maps.SafeMap[string, uint32]
 	k8sObjectCache       objectcache.K8sObjectCache
@@ -49,7 +59,7 @@ type MalwareManager struct {
 
 var _ malwaremanager.MalwareManagerClient = (*MalwareManager)(nil)
 
-func CreateMalwareManager(cfg config.Config, k8sClient k8sclient.K8sClientInterface, nodeName string, clusterName string, exporter exporters.Exporter, prometheusExporter metricsmanager.MetricsManager, k8sObjectCache objectcache.K8sObjectCache) (*MalwareManager, error) {
+func CreateMalwareManager(ctx context.Context, cfg config.Config, k8sClient k8sclient.K8sClientInterface, nodeName string, clusterName string, exporter exporters.Exporter, prometheusExporter metricsmanager.MetricsManager, k8sObjectCache objectcache.K8sObjectCache) (*MalwareManager, error) {
 
 	// Create malware scanners
 	var malwareScanners []malwaremanager.MalwareScanner
@@ -63,9 +73,46 @@ func CreateMalwareManager(cfg config.Config, k8sClient k8sclient.K8sClientInterf
 		}
 		malwareScanners = append(malwareScanners, clamavScanner)
 	}
+
+	// Create the hash database scanner, it runs before the other scanners
+	var hashScanner malwaremanager.MalwareScanner
+	if len(cfg.MalwareHashDBPaths) > 0 {
+		hashDatabaseScanner, err := hashdbv1.CreateHashDatabaseScanner(ctx, cfg.MalwareHashDBPaths, cfg.MalwareHashDBReloadPeriod)
+		if err != nil {
+			return nil, err
+		}
+		hashScanner = hashDatabaseScanner
+	}
+
+	// Create YARA scanner
+	// Check if YARA is enabled (rule paths or ConfigMaps are configured)
+	if len(cfg.YaraRulesPaths) > 0 || len(cfg.YaraRulesConfigMaps) > 0 {
+		yaraScanner, err := yarav1.CreateYaraScanner(ctx, cfg.YaraRulesPaths, cfg.YaraRulesConfigMaps, cfg.NamespaceName, k8sClient, prometheusExporter, cfg.YaraReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		malwareScanners = append(malwareScanners, yaraScanner)
+	}
+
+	// Create the filesystem sweeper, the container files are then scanned once at container start
+	var sweeper *fileSweeper
+	if cfg.EnableMalwareSweep {
+		sweeper = newFileSweeper(cfg.MalwareSweepInclude, cfg.MalwareSweepExclude, cfg.MalwareSweepConcurrency, cfg.MalwareSweepBytesPerSec)
+	}
+
+	scanCacheSize := cfg.MalwareScanCacheSize
+	if scanCacheSize < minScanCacheSize {
+		logger.L().Warning("MalwareManager - scan cache size is too small, using the minimum", helpers.Int("size", scanCacheSize), helpers.Int("minimum", minScanCacheSize))
+		scanCacheSize = minScanCacheSize
+	}
+
 	return &MalwareManager{
+		ctx:             ctx,
 		cfg:             cfg,
 		malwareScanners: malwareScanners,
+		hashScanner:     hashScanner,
+		scanCache:       expirable.NewLRU[string, bool](scanCacheSize, nil, cfg.MalwareScanCacheTTL),
+		sweeper:         sweeper,
 		exporter:        exporter,
 		k8sClient:       k8sClient,
 		nodeName:        nodeName,
@@ -99,6 +146,10 @@ func (mm *MalwareManager) ContainerCallback(notif containercollection.PubSubEven
 		mm.scannedFiles.Delete(notif.Container.Runtime.ContainerID)
 		mm.podToWlid.Delete(utils.CreateK8sPodID(notif.Container.K8s.Namespace, notif.Container.K8s.PodName))
 		mm.containerIdToShimPid.Delete(notif.Container.Runtime.ContainerID)
+		if cancel, ok := mm.sweepCancels.Load(notif.Container.Runtime.ContainerID); ok {
+			cancel()
+			mm.sweepCancels.Delete(notif.Container.Runtime.ContainerID)
+		}
 	}
 
 	go func() {
@@ -127,6 +178,13 @@ func (mm *MalwareManager) startMalwareManager(container *containercollection.Con
 			logger.L().Debug("MalwareManager - failed to get workload identifier", helpers.String("k8s workload", container.K8s.PodName))
 		}
 	}
+	if mm.sweeper != nil {
+		ctx, cancel := context.WithCancel(mm.ctx)
+		mm.sweepCancels.Set(container.Runtime.ContainerID, cancel)
+		mm.sweepContainer(ctx, container)
+		cancel()
+		mm.sweepCancels.Delete(container.Runtime.ContainerID)
+	}
 }
 
 func (mm *MalwareManager) waitForSharedContainerData(containerID string) error {
@@ -158,20 +216,21 @@ func (mm *MalwareManager) ReportEvent(eventType utils.EventType, event utils.K8s
 }
 
 func (mm *MalwareManager) reportFileExec(event *tracerexectype.Event) {
-	for _, scanner := range mm.malwareScanners {
-		if result := scanner.Scan(utils.ExecveEventType, event, mm.containerIdToPid.Get(event.Runtime.ContainerID)); result != nil {
-			result = mm.enrichMalwareResult(result)
-			result.SetWorkloadDetails(mm.podToWlid.Get(utils.CreateK8sPodID(event.GetNamespace(), event.GetPod())))
-			mm.exporter.SendMalwareAlert(result)
-		}
+	// the scanners resolve the file on their own, the executed file is scanned even without its host path
+	hostFilePath, err := utils.GetHostFilePathFromEvent(event, mm.containerIdToPid.Get(event.Runtime.ContainerID))
+	if err != nil {
+		logger.L().Debug("MalwareManager - failed to get the host path of the executed file", helpers.Error(err), helpers.String("comm", event.Comm))
 	}
 
-	if mm.scannedFiles.Has(event.Runtime.ContainerID) && mm.scannedFiles.Get(event.Runtime.ContainerID).Cardinality() <= ScannedFilesMaxBufferLength {
-		hostFilePath, err := utils.GetHostFilePathFromEvent(event, mm.containerIdToPid.Get(event.Runtime.ContainerID))
-		if err != nil {
-			return
-		}
+	for _, result := range mm.scanFile(utils.ExecveEventType, event, hostFilePath, mm.containerIdToPid.Get(event.Runtime.ContainerID)) {
+		result = mm.enrichMalwareResult(result)
+		result.SetWorkloadDetails(mm.podToWlid.Get(utils.CreateK8sPodID(event.GetNamespace(), event.GetPod())))
+		// argv[0] is set by the caller, the quarantined file is the executable resolved by the kernel
+		mm.respond(result, event.ExePath, mm.containerIdToPid.Get(event.Runtime.ContainerID))
+		mm.exporter.SendMalwareAlert(result)
+	}
 
+	if hostFilePath != "" && mm.scannedFiles.Has(event.Runtime.ContainerID) && mm.scannedFiles.Get(event.Runtime.ContainerID).Cardinality() <= ScannedFilesMaxBufferLength {
 		mm.scannedFiles.Get(event.Runtime.ContainerID).Add(hostFilePath)
 	}
 }
@@ -202,14 +261,55 @@ func (mm *MalwareManager) reportFileOpen(event *traceropentype.Event) {
 		}
 	}
 
+	for _, result := range mm.scanFile(utils.OpenEventType, event, hostFilePath, mm.containerIdToPid.Get(event.Runtime.ContainerID)) {
+		result = mm.enrichMalwareResult(result)
+		result.SetWorkloadDetails(mm.podToWlid.Get(utils.CreateK8sPodID(event.GetNamespace(), event.GetPod())))
+		mm.respond(result, event.FullPath, mm.containerIdToPid.Get(event.Runtime.ContainerID))
+		mm.exporter.SendMalwareAlert(result)
+		mm.metrics.ReportRuleAlert(result.GetBasicRuntimeAlert().AlertName)
+	}
+}
+
+// scanFile checks the file against the hash database first and falls back to the deep scanners.
+// Clean verdicts are cached by content, so a file shared by several containers is deep scanned once per node.
+// Malicious files are always scanned again, the results carry the details of the triggering event.
+func (mm *MalwareManager) scanFile(eventType utils.EventType, event utils.K8sEvent, hostFilePath string, containerPid uint32) []malwaremanager.MalwareResult {
+	if mm.hashScanner != nil {
+		if result := mm.hashScanner.Scan(eventType, event, containerPid); result != nil {
+			return []malwaremanager.MalwareResult{result}
+		}
+	}
+	if len(mm.malwareScanners) == 0 {
+		return nil
+	}
+
+	sha256 := ""
+	if size, err := utils.GetFileSize(hostFilePath); err == nil && size < malwaremanager.MaxFileSize {
+		if hashes, err := utils.GetFileHashes(hostFilePath); err == nil {
+			sha256 = hashes.SHA256
+		}
+	}
+	if sha256 != "" {
+		if malicious, ok := mm.scanCache.Get(sha256); ok && !malicious {
+			return nil
+		}
+	}
+
+	var results []malwaremanager.MalwareResult
 	for _, scanner := range mm.malwareScanners {
-		if result := scanner.Scan(utils.OpenEventType, event, mm.containerIdToPid.Get(event.Runtime.ContainerID)); result != nil {
-			result = mm.enrichMalwareResult(result)
-			result.SetWorkloadDetails(mm.podToWlid.Get(utils.CreateK8sPodID(event.GetNamespace(), event.GetPod())))
-			mm.exporter.SendMalwareAlert(result)
-			mm.metrics.ReportRuleAlert(result.GetBasicRuntimeAlert().AlertName)
+		if result := scanner.Scan(eventType, event, containerPid); result != nil {
+			// the SHA256 of the scanned content guards the quarantine, some scanners only report MD5 and SHA1
+			if baseRuntimeAlert := result.GetBasicRuntimeAlert(); baseRuntimeAlert.SHA256Hash == "" && sha256 != "" {
+				baseRuntimeAlert.SHA256Hash = sha256
+				result.SetBasicRuntimeAlert(baseRuntimeAlert)
+			}
+			results = append(results, result)
 		}
 	}
+	if sha256 != "" {
+		mm.scanCache.Add(sha256, len(results) > 0)
+	}
+	return results
 }
 
 func (mm *MalwareManager) enrichMalwareResult(malwareResult malwaremanager.MalwareResult) malwaremanager.MalwareResult {
@@ -238,7 +338,7 @@ func (mm *MalwareManager) enrichMalwareResult(malwareResult malwaremanager.Malwa
 		baseRuntimeAlert.Size = humanize.Bytes(uint64(size))
 	}
 
-	if size != 0 && size < maxFileSize && hostPath != "" {
+	if size != 0 && size < malwaremanager.MaxFileSize && hostPath != "" {
 		if baseRuntimeAlert.MD5Hash == "" || baseRuntimeAlert.SHA1Hash == "" {
 			sha1hash, md5hash, err := utils.CalculateFileHashes(hostPath)
 			if err == nil {
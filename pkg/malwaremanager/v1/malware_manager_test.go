@@ -0,0 +1,75 @@
+package malwaremanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
+	"github.com/kubescape/node-agent/pkg/malwaremanager"
+	malwaremanager2 "github.com/kubescape/node-agent/pkg/malwaremanager/v1/types"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// scannerMock counts the scans and reports the files whose path is in malicious
+type scannerMock struct {
+	scans     int
+	malicious map[string]bool
+}
+
+func (s *scannerMock) Scan(_ utils.EventType, event utils.K8sEvent, _ uint32) malwaremanager.MalwareResult {
+	s.scans++
+	if s.malicious[utils.GetExecPathFromEvent(event.(*tracerexectype.Event))] {
+		return &malwaremanager2.GenericMalwareResult{}
+	}
+	return nil
+}
+
+func TestScanFile(t *testing.T) {
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean")
+	assert.NoError(t, os.WriteFile(clean, []byte("clean"), 0755))
+	cleanCopy := filepath.Join(dir, "clean-copy")
+	assert.NoError(t, os.WriteFile(cleanCopy, []byte("clean"), 0755))
+	malware := filepath.Join(dir, "malware")
+	assert.NoError(t, os.WriteFile(malware, []byte("malware"), 0755))
+
+	deepScanner := &scannerMock{malicious: map[string]bool{malware: true}}
+	mm := &MalwareManager{
+		malwareScanners: []malwaremanager.MalwareScanner{deepScanner},
+		scanCache:       expirable.NewLRU[string, bool](10, nil, time.Hour),
+	}
+
+	scan := func(path string) []malwaremanager.MalwareResult {
+		event := &tracerexectype.Event{Args: []string{path}}
+		return mm.scanFile(utils.ExecveEventType, event, path, 0)
+	}
+
+	// clean files are deep scanned once per content
+	assert.Empty(t, scan(clean))
+	assert.Empty(t, scan(clean))
+	assert.Empty(t, scan(cleanCopy))
+	assert.Equal(t, 1, deepScanner.scans)
+
+	// malicious files are scanned every time to report the triggering event, with the SHA256 of the scanned content
+	results := scan(malware)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "2f293f67aa33f2ce247b28d6fb2fef2623cfde731f96b3d7f84ae74e9e192bdd", results[0].GetBasicRuntimeAlert().SHA256Hash)
+	}
+	assert.Len(t, scan(malware), 1)
+	assert.Equal(t, 3, deepScanner.scans)
+
+	// the files without a host path are scanned without caching
+	assert.Len(t, mm.scanFile(utils.ExecveEventType, &tracerexectype.Event{Args: []string{malware}}, "", 0), 1)
+	assert.Equal(t, 4, deepScanner.scans)
+
+	// the fast path skips the deep scanners
+	hashScanner := &scannerMock{malicious: map[string]bool{malware: true}}
+	mm.hashScanner = hashScanner
+	assert.Len(t, scan(malware), 1)
+	assert.Equal(t, 4, deepScanner.scans)
+	assert.Equal(t, 1, hashScanner.scans)
+}
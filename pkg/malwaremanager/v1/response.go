@@ -0,0 +1,83 @@
+package malwaremanager
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/malwaremanager"
+	"github.com/kubescape/node-agent/pkg/responseactions"
+)
+
+// respond runs the response actions configured for the detection in the opted-in namespaces, filePath is the path
+// of the detected file in the container. The outcomes are audit-logged and attached to the alert arguments, so they
+// are reported by the exporters.
+func (mm *MalwareManager) respond(result malwaremanager.MalwareResult, filePath string, containerPid uint32) {
+	k8sDetails := result.GetRuntimeAlertK8sDetails()
+	if !slices.Contains(mm.cfg.MalwareResponse.Namespaces, k8sDetails.Namespace) {
+		return
+	}
+	baseRuntimeAlert := result.GetBasicRuntimeAlert()
+	actions := mm.responseActions(baseRuntimeAlert.AlertName, baseRuntimeAlert.Arguments["rules"])
+	if len(actions) == 0 {
+		return
+	}
+
+	containerID := result.GetRuntimeProcessDetails().ContainerID
+	outcomes := make([]responseactions.Outcome, 0, len(actions))
+	for _, action := range actions {
+		var outcome responseactions.Outcome
+		switch responseactions.Action(action) {
+		case responseactions.ActionKill:
+			pid := baseRuntimeAlert.InfectedPID
+			outcome = responseactions.NewOutcome(responseactions.ActionKill, fmt.Sprintf("%d", pid), responseactions.KillProcess(pid, containerPid))
+		case responseactions.ActionQuarantine:
+			// the file is resolved again inside the container root, and quarantined only with the detected content
+			if containerPid == 0 {
+				outcome = responseactions.NewOutcome(responseactions.ActionQuarantine, filePath, responseactions.ErrNoProcess)
+				break
+			}
+			rootPath := fmt.Sprintf("/proc/%d/root", containerPid)
+			record, err := responseactions.QuarantineFile(rootPath, filePath, containerID, baseRuntimeAlert.AlertName, baseRuntimeAlert.SHA256Hash, mm.cfg.MalwareResponse.QuarantineDir)
+			outcome = responseactions.NewOutcome(responseactions.ActionQuarantine, filePath, err)
+			outcome.Details = record.QuarantinePath
+		case responseactions.ActionFreeze:
+			cgroupPath, err := responseactions.FreezeCgroup(containerPid)
+			outcome = responseactions.NewOutcome(responseactions.ActionFreeze, containerID, err)
+			outcome.Details = cgroupPath
+		default:
+			outcome = responseactions.NewOutcome(responseactions.Action(action), containerID, fmt.Errorf("unknown response action %q", action))
+		}
+		responseactions.Audit(outcome,
+			helpers.String("alert", baseRuntimeAlert.AlertName),
+			helpers.String("namespace", k8sDetails.Namespace),
+			helpers.String("pod", k8sDetails.PodName),
+			helpers.String("container ID", containerID))
+		outcomes = append(outcomes, outcome)
+	}
+
+	if baseRuntimeAlert.Arguments == nil {
+		baseRuntimeAlert.Arguments = make(map[string]interface{})
+	}
+	baseRuntimeAlert.Arguments[responseactions.OutcomesArgument] = outcomes
+	result.SetBasicRuntimeAlert(baseRuntimeAlert)
+}
+
+// responseActions returns the actions of the first response rule matching the alert name or one of the matched scanner rules
+func (mm *MalwareManager) responseActions(alertName string, matchedRules interface{}) []string {
+	names := []string{alertName}
+	if rules, ok := matchedRules.([]string); ok {
+		names = append(names, rules...)
+	}
+	for _, rule := range mm.cfg.MalwareResponse.Rules {
+		if slices.Contains(rule.AlertNames, "*") {
+			return rule.Actions
+		}
+		for _, name := range names {
+			if slices.Contains(rule.AlertNames, name) {
+				return rule.Actions
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package malwaremanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/kubescape/node-agent/pkg/config"
+	malwaremanager2 "github.com/kubescape/node-agent/pkg/malwaremanager/v1/types"
+	"github.com/kubescape/node-agent/pkg/responseactions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespond(t *testing.T) {
+	quarantineDir := t.TempDir()
+	mm := &MalwareManager{
+		cfg: config.Config{
+			MalwareResponse: config.MalwareResponseConfig{
+				Namespaces:    []string{"default"},
+				QuarantineDir: quarantineDir,
+				Rules: []config.MalwareResponseRule{
+					{AlertNames: []string{"xmrig_miner"}, Actions: []string{"quarantine", "kill"}},
+					{AlertNames: []string{"*"}, Actions: []string{"unknown"}},
+				},
+			},
+		},
+	}
+
+	newResult := func(namespace, alertName string, rules []string) *malwaremanager2.GenericMalwareResult {
+		result := &malwaremanager2.GenericMalwareResult{
+			BasicRuntimeAlert:      armotypes.BaseRuntimeAlert{AlertName: alertName},
+			RuntimeAlertK8sDetails: armotypes.RuntimeAlertK8sDetails{Namespace: namespace},
+		}
+		if rules != nil {
+			result.BasicRuntimeAlert.Arguments = map[string]interface{}{"rules": rules}
+		}
+		return result
+	}
+
+	// namespaces are opt-in
+	result := newResult("kube-system", "xmrig_miner", nil)
+	mm.respond(result, "", 0)
+	assert.NotContains(t, result.GetBasicRuntimeAlert().Arguments, responseactions.OutcomesArgument)
+
+	// the rule matches one of the matched YARA rules, the actions run in order
+	// the file is reached through /proc/<pid>/root of the test process
+	file := filepath.Join(t.TempDir(), "miner")
+	require.NoError(t, os.WriteFile(file, []byte("malware"), 0755))
+	containerPid := uint32(os.Getpid())
+
+	// the file is not quarantined without the SHA256 of the detected content
+	result = newResult("default", "miner_family", []string{"generic_elf", "xmrig_miner"})
+	mm.respond(result, file, containerPid)
+	outcomes, ok := result.GetBasicRuntimeAlert().Arguments[responseactions.OutcomesArgument].([]responseactions.Outcome)
+	require.True(t, ok)
+	assert.Equal(t, responseactions.ErrNoHash.Error(), outcomes[0].Error)
+	assert.FileExists(t, file)
+
+	result = newResult("default", "miner_family", []string{"generic_elf", "xmrig_miner"})
+	result.BasicRuntimeAlert.SHA256Hash = "2f293f67aa33f2ce247b28d6fb2fef2623cfde731f96b3d7f84ae74e9e192bdd"
+	mm.respond(result, file, containerPid)
+	outcomes, ok = result.GetBasicRuntimeAlert().Arguments[responseactions.OutcomesArgument].([]responseactions.Outcome)
+	require.True(t, ok)
+	require.Len(t, outcomes, 2)
+	assert.Equal(t, responseactions.ActionQuarantine, outcomes[0].Action)
+	assert.True(t, outcomes[0].Success)
+	assert.FileExists(t, outcomes[0].Details)
+	assert.NoFileExists(t, file)
+	// there is no infected process to kill
+	assert.Equal(t, responseactions.ActionKill, outcomes[1].Action)
+	assert.False(t, outcomes[1].Success)
+	assert.Equal(t, responseactions.ErrNoProcess.Error(), outcomes[1].Error)
+
+	// the wildcard rule catches the other detections
+	result = newResult("default", "other", nil)
+	mm.respond(result, "", 0)
+	outcomes = result.GetBasicRuntimeAlert().Arguments[responseactions.OutcomesArgument].([]responseactions.Outcome)
+	require.Len(t, outcomes, 1)
+	assert.False(t, outcomes[0].Success)
+}
@@ -0,0 +1,195 @@
+package malwaremanager
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	traceropentype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/open/types"
+	igtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/malwaremanager"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"golang.org/x/time/rate"
+)
+
+// SweepTriggerArgument is the alert argument marking the results found by a filesystem sweep
+const SweepTriggerArgument = "trigger"
+
+// fileSweeper walks the container root filesystems and scans their files, the sweeps share a per-node
+// concurrency limit and a read rate limit.
+type fileSweeper struct {
+	include   []string
+	exclude   []string
+	semaphore chan struct{}
+	limiter   *rate.Limiter
+}
+
+func newFileSweeper(include, exclude []string, concurrency int, bytesPerSecond int64) *fileSweeper {
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if bytesPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(bytesPerSecond), int(min(bytesPerSecond, malwaremanager.MaxFileSize)))
+	}
+	return &fileSweeper{
+		include:   include,
+		exclude:   exclude,
+		semaphore: make(chan struct{}, max(concurrency, 1)),
+		limiter:   limiter,
+	}
+}
+
+// shouldSweep checks the path relative to the container root against the include and exclude globs
+func (s *fileSweeper) shouldSweep(path string) bool {
+	for _, pattern := range s.exclude {
+		if match, _ := doublestar.Match(pattern, path); match {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, pattern := range s.include {
+		if match, _ := doublestar.Match(pattern, path); match {
+			return true
+		}
+	}
+	return false
+}
+
+// isExcludedDir checks if the directory is excluded, e.g. by /proc/**, its content is then skipped
+func (s *fileSweeper) isExcludedDir(path string) bool {
+	for _, pattern := range s.exclude {
+		if match, _ := doublestar.Match(pattern, path); match {
+			return true
+		}
+	}
+	return false
+}
+
+// throttle waits until the size can be read without exceeding the read rate
+func (s *fileSweeper) throttle(ctx context.Context, size int64) error {
+	if s.limiter.Limit() == rate.Inf {
+		return nil
+	}
+	for size > 0 {
+		n := min(size, int64(s.limiter.Burst()))
+		if err := s.limiter.WaitN(ctx, int(n)); err != nil {
+			return err
+		}
+		size -= n
+	}
+	return nil
+}
+
+// sweepContainer scans the files of the container root filesystem, it waits for a free sweep slot first
+func (mm *MalwareManager) sweepContainer(ctx context.Context, container *containercollection.Container) {
+	select {
+	case mm.sweeper.semaphore <- struct{}{}:
+		defer func() { <-mm.sweeper.semaphore }()
+	case <-ctx.Done():
+		return
+	}
+
+	containerPid := container.ContainerPid()
+	root := fmt.Sprintf("/proc/%d/root", containerPid)
+	start := time.Now()
+	var scanned, found int
+	err := filepath.WalkDir(root, func(hostPath string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			// unreadable entries are skipped, the sweep continues
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		path := "/" + filepath.ToSlash(mustRel(root, hostPath))
+		if d.IsDir() {
+			if hostPath != root && mm.sweeper.isExcludedDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !mm.sweeper.shouldSweep(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 || info.Size() > malwaremanager.MaxFileSize {
+			return nil
+		}
+		if err := mm.sweeper.throttle(ctx, info.Size()); err != nil {
+			return err
+		}
+
+		scanned++
+		event := newSweepEvent(container, path)
+		for _, result := range mm.scanFile(utils.FileSweepEventType, event, hostPath, containerPid) {
+			found++
+			baseRuntimeAlert := result.GetBasicRuntimeAlert()
+			if baseRuntimeAlert.Arguments == nil {
+				baseRuntimeAlert.Arguments = make(map[string]interface{})
+			}
+			baseRuntimeAlert.Arguments[SweepTriggerArgument] = string(utils.FileSweepEventType)
+			result.SetBasicRuntimeAlert(baseRuntimeAlert)
+			result = mm.enrichMalwareResult(result)
+			result.SetWorkloadDetails(mm.podToWlid.Get(utils.CreateK8sPodID(container.K8s.Namespace, container.K8s.PodName)))
+			mm.respond(result, hostPath, containerPid)
+			mm.exporter.SendMalwareAlert(result)
+			mm.metrics.ReportRuleAlert(result.GetBasicRuntimeAlert().AlertName)
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		logger.L().Warning("MalwareManager - container filesystem sweep failed", helpers.Error(err), helpers.String("container ID", container.Runtime.ContainerID))
+		return
+	}
+	logger.L().Debug("MalwareManager - container filesystem sweep done",
+		helpers.String("container ID", container.Runtime.ContainerID),
+		helpers.Int("scanned", scanned),
+		helpers.Int("found", found),
+		helpers.String("duration", time.Since(start).String()))
+}
+
+// newSweepEvent creates an open event of the swept file, scanners handle it as a file read without a process
+func newSweepEvent(container *containercollection.Container, path string) *traceropentype.Event {
+	return &traceropentype.Event{
+		Event: igtypes.Event{
+			CommonData: igtypes.CommonData{
+				Runtime: igtypes.BasicRuntimeMetadata{
+					ContainerID:          container.Runtime.ContainerID,
+					ContainerName:        container.Runtime.ContainerName,
+					ContainerImageName:   container.Runtime.ContainerImageName,
+					ContainerImageDigest: container.Runtime.ContainerImageDigest,
+				},
+				K8s: igtypes.K8sMetadata{
+					BasicK8sMetadata: igtypes.BasicK8sMetadata{
+						Namespace:     container.K8s.Namespace,
+						PodName:       container.K8s.PodName,
+						PodLabels:     container.K8s.PodLabels,
+						ContainerName: container.K8s.ContainerName,
+					},
+					HostNetwork: container.HostNetwork,
+				},
+			},
+			Timestamp: igtypes.Time(time.Now().UnixNano()),
+			Type:      igtypes.NORMAL,
+		},
+		Path:     path,
+		FullPath: path,
+	}
+}
+
+func mustRel(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
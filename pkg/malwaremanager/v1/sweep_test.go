@@ -0,0 +1,84 @@
+package malwaremanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/malwaremanager"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSweeperShouldSweep(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		path    string
+		want    bool
+	}{
+		{
+			name: "no filters",
+			path: "/usr/bin/ls",
+			want: true,
+		},
+		{
+			name:    "excluded",
+			exclude: []string{"/proc/**", "/sys/**"},
+			path:    "/proc/1/environ",
+			want:    false,
+		},
+		{
+			name:    "included",
+			include: []string{"/tmp/**", "/usr/**/bin/*"},
+			path:    "/usr/local/bin/miner",
+			want:    true,
+		},
+		{
+			name:    "not included",
+			include: []string{"/tmp/**"},
+			path:    "/usr/bin/ls",
+			want:    false,
+		},
+		{
+			name:    "exclude wins",
+			include: []string{"/tmp/**"},
+			exclude: []string{"**/*.log"},
+			path:    "/tmp/app/out.log",
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newFileSweeper(tt.include, tt.exclude, 1, 0)
+			assert.Equal(t, tt.want, s.shouldSweep(tt.path))
+		})
+	}
+}
+
+func TestFileSweeperIsExcludedDir(t *testing.T) {
+	s := newFileSweeper(nil, []string{"/proc/**", "/var/cache"}, 1, 0)
+	assert.True(t, s.isExcludedDir("/proc"))
+	assert.True(t, s.isExcludedDir("/proc/1"))
+	assert.True(t, s.isExcludedDir("/var/cache"))
+	assert.False(t, s.isExcludedDir("/var"))
+	assert.False(t, s.isExcludedDir("/usr"))
+}
+
+func TestFileSweeperThrottle(t *testing.T) {
+	// unlimited
+	s := newFileSweeper(nil, nil, 1, 0)
+	assert.NoError(t, s.throttle(context.Background(), malwaremanager.MaxFileSize))
+
+	// the first burst is immediate, the next one waits for the rate
+	s = newFileSweeper(nil, nil, 1, 1000)
+	start := time.Now()
+	assert.NoError(t, s.throttle(context.Background(), 1000))
+	assert.NoError(t, s.throttle(context.Background(), 100))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// a cancelled sweep stops waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, s.throttle(ctx, 1000))
+}
@@ -0,0 +1,980 @@
+package malwaremanager
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RejectedRule is a rule using features outside of the supported YARA subset, it is left out of the compiled
+// rules while the other rules of its source are kept.
+type RejectedRule struct {
+	Name string
+	Err  error
+}
+
+// CompileRules compiles the rules of a YARA source into the namespace.
+// Only a restricted subset of YARA is supported: text, hex and regular expression strings and the module-free part
+// of the condition language. Modules, the xor and base64 string modifiers, the for, string offset (@a[i]) and
+// string length (!a[i]) expressions, the arithmetic and bitwise operators other than +, - and &, and external
+// variables are not supported. The rules using them, or referencing a rejected rule, are returned as rejected instead of being
+// partially evaluated. A source including files, with a rejected global rule or failing to parse outside of a rule
+// fails to compile as a whole.
+func CompileRules(source, namespace string) ([]*Rule, []RejectedRule, error) {
+	p := &parser{lexer: &lexer{src: source}, namespace: namespace, rules: make(map[string]*Rule)}
+	rules, rejected, err := p.parseFile()
+	if err != nil {
+		return nil, nil, fmt.Errorf("line %d: %w", p.lexer.line(), err)
+	}
+	return rules, rejected, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenStringID
+	tokenCountID
+	tokenNumber
+	tokenText
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  int64
+}
+
+type lexer struct {
+	src    string
+	pos    int
+	peeked *token
+}
+
+func (l *lexer) line() int {
+	return strings.Count(l.src[:min(l.pos, len(l.src))], "\n") + 1
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			if i := strings.IndexByte(l.src[l.pos:], '\n'); i >= 0 {
+				l.pos += i
+			} else {
+				l.pos = len(l.src)
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			if i := strings.Index(l.src[l.pos+2:], "*/"); i >= 0 {
+				l.pos += i + 4
+			} else {
+				l.pos = len(l.src)
+			}
+		case strings.ContainsRune(" \t\r\n", rune(l.src[l.pos])):
+			l.pos++
+		default:
+			return
+		}
+	}
+}
+
+// ruleStart matches the keywords starting a rule or a directive
+var ruleStart = regexp.MustCompile(`\b(rule|private|global|import|include)\s`)
+
+// skipRule moves the lexer after the rejected rule starting at the given offset, to the next keyword starting a
+// line or following a closing brace, so the rules after it are still compiled
+func (l *lexer) skipRule(start int) {
+	l.peeked = nil
+	for _, loc := range ruleStart.FindAllStringIndex(l.src[start+1:], -1) {
+		pos := start + 1 + loc[0]
+		before := strings.TrimRight(l.src[:pos], " \t")
+		if before == "" || strings.HasSuffix(before, "\n") || strings.HasSuffix(strings.TrimRight(before, "\r\n"), "}") {
+			l.pos = pos
+			return
+		}
+	}
+	l.pos = len(l.src)
+}
+
+func (l *lexer) peek() (token, error) {
+	if l.peeked == nil {
+		t, err := l.scan()
+		if err != nil {
+			return token{}, err
+		}
+		l.peeked = &t
+	}
+	return *l.peeked, nil
+}
+
+func (l *lexer) next() (token, error) {
+	t, err := l.peek()
+	l.peeked = nil
+	return t, err
+}
+
+func (l *lexer) scan() (token, error) {
+	l.skipSpace()
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF}, nil
+	}
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case c == '$' || c == '#':
+		l.pos++
+		for l.pos < len(l.src) && (isIdentChar(l.src[l.pos]) || l.src[l.pos] == '*') {
+			l.pos++
+		}
+		kind := tokenStringID
+		if c == '#' {
+			kind = tokenCountID
+		}
+		return token{kind: kind, text: "$" + l.src[start+1:l.pos]}, nil
+	case isIdentChar(c) && !isDigit(c):
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.src[start:l.pos]}, nil
+	case isDigit(c):
+		for l.pos < len(l.src) && (isIdentChar(l.src[l.pos])) {
+			l.pos++
+		}
+		text := l.src[start:l.pos]
+		multiplier := int64(1)
+		if strings.HasSuffix(text, "KB") {
+			text, multiplier = strings.TrimSuffix(text, "KB"), 1024
+		} else if strings.HasSuffix(text, "MB") {
+			text, multiplier = strings.TrimSuffix(text, "MB"), 1024*1024
+		}
+		base := 10
+		if strings.HasPrefix(text, "0x") {
+			text, base = text[2:], 16
+		}
+		num, err := strconv.ParseInt(text, base, 64)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid number %q", l.src[start:l.pos])
+		}
+		return token{kind: tokenNumber, text: l.src[start:l.pos], num: num * multiplier}, nil
+	case c == '"':
+		text, err := l.readText()
+		return token{kind: tokenText, text: text}, err
+	}
+	for _, punct := range []string{"..", "==", "!=", "<=", ">=", "<", ">", "(", ")", "{", "}", ":", "=", ",", "+", "-", "&", "."} {
+		if strings.HasPrefix(l.src[l.pos:], punct) {
+			l.pos += len(punct)
+			return token{kind: tokenPunct, text: punct}, nil
+		}
+	}
+	return token{}, fmt.Errorf("unexpected character %q", c)
+}
+
+// readText reads a double quoted string with its escape sequences
+func (l *lexer) readText() (string, error) {
+	var b strings.Builder
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return b.String(), nil
+		case '\n':
+			return "", fmt.Errorf("unterminated string")
+		case '\\':
+			l.pos++
+			if l.pos >= len(l.src) {
+				return "", fmt.Errorf("unterminated string")
+			}
+			switch l.src[l.pos] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'x':
+				if l.pos+2 >= len(l.src) {
+					return "", fmt.Errorf("invalid escape sequence")
+				}
+				v, err := strconv.ParseUint(l.src[l.pos+1:l.pos+3], 16, 8)
+				if err != nil {
+					return "", fmt.Errorf("invalid escape sequence \\x%s", l.src[l.pos+1:l.pos+3])
+				}
+				b.WriteByte(byte(v))
+				l.pos += 2
+			default:
+				b.WriteByte(l.src[l.pos])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// readRaw reads the raw content until the closing delimiter, escaped delimiters are unescaped
+func (l *lexer) readRaw(closing byte) (string, error) {
+	var b strings.Builder
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		c := l.src[l.pos]
+		if c == '\\' && closing == '/' && l.pos+1 < len(l.src) {
+			if l.src[l.pos+1] != '/' {
+				b.WriteByte(c)
+			}
+			l.pos++
+			b.WriteByte(l.src[l.pos])
+			continue
+		}
+		if c == closing {
+			l.pos++
+			return b.String(), nil
+		}
+		b.WriteByte(c)
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentChar(c byte) bool {
+	return isAlphanumeric(c) || c == '_'
+}
+
+type parser struct {
+	lexer     *lexer
+	namespace string
+	rules     map[string]*Rule
+	current   *Rule
+}
+
+func (p *parser) expect(text string) error {
+	t, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	if (t.kind != tokenPunct && t.kind != tokenIdent) || t.text != text {
+		return fmt.Errorf("expected %q, found %q", text, t.text)
+	}
+	return nil
+}
+
+// accept consumes the next token if it is the given punctuation or keyword
+func (p *parser) accept(text string) (bool, error) {
+	t, err := p.lexer.peek()
+	if err != nil {
+		return false, err
+	}
+	if (t.kind == tokenPunct || t.kind == tokenIdent) && t.text == text {
+		_, err = p.lexer.next()
+		return true, err
+	}
+	return false, nil
+}
+
+func (p *parser) parseFile() ([]*Rule, []RejectedRule, error) {
+	var rules []*Rule
+	var rejected []RejectedRule
+	for {
+		t, err := p.lexer.next()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case t.kind == tokenEOF:
+			return rules, rejected, nil
+		case t.kind == tokenIdent && t.text == "import":
+			// the rules using the module are rejected when their condition references it
+			if module, err := p.lexer.next(); err != nil {
+				return nil, nil, err
+			} else if module.kind != tokenText {
+				return nil, nil, fmt.Errorf("expected module name, found %q", module.text)
+			}
+		case t.kind == tokenIdent && t.text == "include":
+			return nil, nil, fmt.Errorf("include is not supported")
+		case t.kind == tokenIdent && (t.text == "rule" || t.text == "private" || t.text == "global"):
+			start := p.lexer.pos - len(t.text)
+			rule, err := p.parseRule(t.text)
+			if err == nil {
+				rules = append(rules, rule)
+				continue
+			}
+			// a rejected global rule would widen the matches of the other rules of the source
+			if p.current.Global {
+				return nil, nil, fmt.Errorf("global rule %q: %w", p.current.Name, err)
+			}
+			rejected = append(rejected, RejectedRule{Name: p.current.Name, Err: fmt.Errorf("line %d: %w", p.lexer.line(), err)})
+			p.lexer.skipRule(start)
+		default:
+			return nil, nil, fmt.Errorf("unexpected %q", t.text)
+		}
+	}
+}
+
+func (p *parser) parseRule(first string) (*Rule, error) {
+	rule := &Rule{Namespace: p.namespace, Meta: make(map[string]string)}
+	p.current = rule
+	for keyword := first; keyword != "rule"; {
+		switch keyword {
+		case "private":
+			rule.Private = true
+		case "global":
+			rule.Global = true
+		default:
+			return nil, fmt.Errorf("unexpected %q", keyword)
+		}
+		t, err := p.lexer.next()
+		if err != nil {
+			return nil, err
+		}
+		keyword = t.text
+	}
+
+	name, err := p.lexer.next()
+	if err != nil {
+		return nil, err
+	}
+	if name.kind != tokenIdent {
+		return nil, fmt.Errorf("expected rule name, found %q", name.text)
+	}
+	rule.Name = name.text
+	if _, exists := p.rules[name.text]; exists {
+		return nil, fmt.Errorf("duplicated rule %q", name.text)
+	}
+
+	if ok, err := p.accept(":"); err != nil {
+		return nil, err
+	} else if ok {
+		for {
+			t, err := p.lexer.peek()
+			if err != nil {
+				return nil, err
+			}
+			if t.kind != tokenIdent {
+				break
+			}
+			_, _ = p.lexer.next()
+			rule.Tags = append(rule.Tags, t.text)
+		}
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	for {
+		section, err := p.lexer.next()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		switch section.text {
+		case "meta":
+			if err := p.parseMeta(rule); err != nil {
+				return nil, err
+			}
+		case "strings":
+			if err := p.parseStrings(rule); err != nil {
+				return nil, err
+			}
+		case "condition":
+			condition, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("}"); err != nil {
+				return nil, err
+			}
+			rule.condition = condition
+			p.rules[rule.Name] = rule
+			return rule, nil
+		default:
+			return nil, fmt.Errorf("unexpected section %q", section.text)
+		}
+	}
+}
+
+func (p *parser) parseMeta(rule *Rule) error {
+	for {
+		t, err := p.lexer.peek()
+		if err != nil {
+			return err
+		}
+		if t.kind != tokenIdent || t.text == "strings" || t.text == "condition" {
+			return nil
+		}
+		_, _ = p.lexer.next()
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		negative, err := p.accept("-")
+		if err != nil {
+			return err
+		}
+		value, err := p.lexer.next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case value.kind == tokenNumber && negative:
+			rule.Meta[t.text] = strconv.FormatInt(-value.num, 10)
+		case value.kind == tokenNumber:
+			rule.Meta[t.text] = strconv.FormatInt(value.num, 10)
+		case value.kind == tokenText, value.kind == tokenIdent && (value.text == "true" || value.text == "false"):
+			rule.Meta[t.text] = value.text
+		default:
+			return fmt.Errorf("invalid meta value %q", value.text)
+		}
+	}
+}
+
+func (p *parser) parseStrings(rule *Rule) error {
+	for {
+		t, err := p.lexer.peek()
+		if err != nil {
+			return err
+		}
+		if t.kind != tokenStringID {
+			return nil
+		}
+		_, _ = p.lexer.next()
+		if strings.HasSuffix(t.text, "*") {
+			return fmt.Errorf("invalid string identifier %q", t.text)
+		}
+		if t.text != "$" {
+			for _, s := range rule.strings {
+				if s.id == t.text {
+					return fmt.Errorf("duplicated string identifier %q", t.text)
+				}
+			}
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		pattern, err := p.parseString(t.text)
+		if err != nil {
+			return err
+		}
+		rule.strings = append(rule.strings, pattern)
+	}
+}
+
+func (p *parser) parseString(id string) (*stringPattern, error) {
+	l := p.lexer
+	l.skipSpace()
+	if l.pos >= len(l.src) {
+		return nil, fmt.Errorf("unexpected end of rule")
+	}
+	pattern := &stringPattern{id: id}
+	switch l.src[l.pos] {
+	case '"':
+		text, err := l.readText()
+		if err != nil {
+			return nil, err
+		}
+		if text == "" {
+			return nil, fmt.Errorf("empty string %s", id)
+		}
+		pattern.kind = textString
+		pattern.needles = [][]byte{[]byte(text)}
+	case '{':
+		raw, err := l.readRaw('}')
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := parseHexString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("string %s: %w", id, err)
+		}
+		pattern.kind = hexString
+		pattern.hex = tokens
+	case '/':
+		raw, err := l.readRaw('/')
+		if err != nil {
+			return nil, err
+		}
+		var flags string
+		for l.pos < len(l.src) && (l.src[l.pos] == 'i' || l.src[l.pos] == 's') {
+			flags += string(l.src[l.pos])
+			l.pos++
+		}
+		if flags != "" {
+			raw = "(?" + flags + ")" + raw
+		}
+		re, err := regexp.Compile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("string %s: %w", id, err)
+		}
+		pattern.kind = regexString
+		pattern.regex = re
+	default:
+		return nil, fmt.Errorf("invalid string %s", id)
+	}
+	return pattern, p.parseStringModifiers(pattern)
+}
+
+func (p *parser) parseStringModifiers(pattern *stringPattern) error {
+	var ascii bool
+	for {
+		t, err := p.lexer.peek()
+		if err != nil {
+			return err
+		}
+		if t.kind != tokenIdent {
+			break
+		}
+		switch t.text {
+		case "nocase":
+			pattern.nocase = true
+		case "wide":
+			pattern.wide = true
+		case "ascii":
+			ascii = true
+		case "fullword":
+			pattern.fullword = true
+		case "private":
+		case "xor", "base64", "base64wide":
+			return fmt.Errorf("string modifier %q is not supported", t.text)
+		default:
+			// next section or string
+			if t.text == "condition" || t.text == "strings" || t.text == "meta" {
+				return p.finishTextPattern(pattern, ascii)
+			}
+			return fmt.Errorf("unexpected %q", t.text)
+		}
+		_, _ = p.lexer.next()
+	}
+	return p.finishTextPattern(pattern, ascii)
+}
+
+// finishTextPattern builds the needles of a text string according to its modifiers
+func (p *parser) finishTextPattern(pattern *stringPattern, ascii bool) error {
+	if pattern.kind != textString {
+		if pattern.wide || pattern.fullword || (pattern.nocase && pattern.kind == hexString) {
+			return fmt.Errorf("string %s: modifiers are only supported on text strings", pattern.id)
+		}
+		if pattern.nocase {
+			pattern.regex = regexp.MustCompile("(?i)" + pattern.regex.String())
+		}
+		return nil
+	}
+	text := pattern.needles[0]
+	if len(text) > maxMatchLength/2 {
+		return fmt.Errorf("string %s is longer than %d bytes", pattern.id, maxMatchLength/2)
+	}
+	if pattern.nocase {
+		text = asciiLower(make([]byte, len(text)), text)
+	}
+	pattern.needles = nil
+	if !pattern.wide || ascii {
+		pattern.needles = append(pattern.needles, text)
+	}
+	if pattern.wide {
+		wide := make([]byte, 0, 2*len(text))
+		for _, c := range text {
+			wide = append(wide, c, 0)
+		}
+		pattern.needles = append(pattern.needles, wide)
+	}
+	return nil
+}
+
+// parseHexString parses the content of a hex string, e.g. 4D 5A ?? [2-4] (01 | 02 03)
+func parseHexString(raw string) ([]hexToken, error) {
+	tokens, rest, err := parseHexTokens(raw, false)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("unexpected %q in hex string", rest)
+	}
+	if len(tokens) == 0 || tokens[0].kind == hexJump || tokens[len(tokens)-1].kind == hexJump {
+		return nil, fmt.Errorf("hex strings can not be empty or start or end with a jump")
+	}
+	return tokens, nil
+}
+
+func parseHexTokens(raw string, inGroup bool) ([]hexToken, string, error) {
+	var tokens []hexToken
+	for {
+		raw = strings.TrimLeft(raw, " \t\r\n")
+		if raw == "" {
+			if inGroup {
+				return nil, "", fmt.Errorf("unterminated alternative in hex string")
+			}
+			return tokens, raw, nil
+		}
+		switch raw[0] {
+		case '|', ')':
+			if !inGroup {
+				return nil, "", fmt.Errorf("unexpected %q in hex string", raw[0])
+			}
+			return tokens, raw, nil
+		case '(':
+			token := hexToken{kind: hexAlternatives}
+			raw = raw[1:]
+			for {
+				alternative, rest, err := parseHexTokens(raw, true)
+				if err != nil {
+					return nil, "", err
+				}
+				if len(alternative) == 0 {
+					return nil, "", fmt.Errorf("empty alternative in hex string")
+				}
+				token.alternatives = append(token.alternatives, alternative)
+				if rest[0] == ')' {
+					raw = rest[1:]
+					break
+				}
+				raw = rest[1:]
+			}
+			tokens = append(tokens, token)
+		case '[':
+			end := strings.IndexByte(raw, ']')
+			if end < 0 {
+				return nil, "", fmt.Errorf("unterminated jump in hex string")
+			}
+			token, err := parseHexJump(strings.TrimSpace(raw[1:end]))
+			if err != nil {
+				return nil, "", err
+			}
+			tokens = append(tokens, token)
+			raw = raw[end+1:]
+		default:
+			if len(raw) < 2 {
+				return nil, "", fmt.Errorf("invalid byte %q in hex string", raw)
+			}
+			token := hexToken{kind: hexByte}
+			for i := 0; i < 2; i++ {
+				shift := uint(4 * (1 - i))
+				c := raw[i]
+				if c == '?' {
+					continue
+				}
+				v, err := strconv.ParseUint(string(c), 16, 8)
+				if err != nil {
+					return nil, "", fmt.Errorf("invalid byte %q in hex string", raw[:2])
+				}
+				token.value |= byte(v) << shift
+				token.mask |= 0xf << shift
+			}
+			tokens = append(tokens, token)
+			raw = raw[2:]
+		}
+	}
+}
+
+// parseHexJump parses the content of a jump: n, n-m, n- or -
+func parseHexJump(raw string) (hexToken, error) {
+	token := hexToken{kind: hexJump, max: -1}
+	low, high, isRange := strings.Cut(raw, "-")
+	var err error
+	if low = strings.TrimSpace(low); low != "" {
+		if token.min, err = strconv.Atoi(low); err != nil {
+			return token, fmt.Errorf("invalid jump [%s] in hex string", raw)
+		}
+	}
+	if !isRange {
+		token.max = token.min
+		return token, nil
+	}
+	if high = strings.TrimSpace(high); high != "" {
+		if token.max, err = strconv.Atoi(high); err != nil || token.max < token.min {
+			return token, fmt.Errorf("invalid jump [%s] in hex string", raw)
+		}
+	}
+	return token, nil
+}
+
+func (p *parser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if ok, err := p.accept("or"); err != nil {
+			return nil, err
+		} else if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{operator: "or", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (expression, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if ok, err := p.accept("and"); err != nil {
+			return nil, err
+		} else if !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{operator: "and", left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (expression, error) {
+	if ok, err := p.accept("not"); err != nil {
+		return nil, err
+	} else if ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (expression, error) {
+	left, err := p.parseBitAnd()
+	if err != nil {
+		return nil, err
+	}
+	for _, operator := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if ok, err := p.accept(operator); err != nil {
+			return nil, err
+		} else if ok {
+			right, err := p.parseBitAnd()
+			if err != nil {
+				return nil, err
+			}
+			return binaryExpr{operator: operator, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseBitAnd() (expression, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if ok, err := p.accept("&"); err != nil {
+			return nil, err
+		} else if !ok {
+			return left, nil
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{operator: "&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAdditive() (expression, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, err := p.lexer.peek()
+		if err != nil {
+			return nil, err
+		}
+		if t.kind != tokenPunct || (t.text != "+" && t.text != "-") {
+			return left, nil
+		}
+		_, _ = p.lexer.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{operator: t.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parsePrimary() (expression, error) {
+	t, err := p.lexer.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t.kind {
+	case tokenPunct:
+		if t.text == "(" {
+			e, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(")")
+		}
+	case tokenNumber:
+		if ok, err := p.accept("of"); err != nil {
+			return nil, err
+		} else if ok {
+			return p.parseOf(intExpr(t.num))
+		}
+		return intExpr(t.num), nil
+	case tokenStringID:
+		return p.parseStringExpr(t.text)
+	case tokenCountID:
+		pattern, err := p.lookupString(t.text)
+		if err != nil {
+			return nil, err
+		}
+		return countExpr{pattern: pattern}, nil
+	case tokenIdent:
+		return p.parseIdentifier(t.text)
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	return nil, fmt.Errorf("unexpected %q in condition", t.text)
+}
+
+func (p *parser) parseIdentifier(name string) (expression, error) {
+	switch name {
+	case "true":
+		return boolExpr(true), nil
+	case "false":
+		return boolExpr(false), nil
+	case "filesize":
+		return filesizeExpr{}, nil
+	case "any":
+		if err := p.expect("of"); err != nil {
+			return nil, err
+		}
+		return p.parseOf(intExpr(1))
+	case "all":
+		if err := p.expect("of"); err != nil {
+			return nil, err
+		}
+		return p.parseOf(intExpr(quantifierAll))
+	case "none":
+		if err := p.expect("of"); err != nil {
+			return nil, err
+		}
+		return p.parseOf(intExpr(quantifierNone))
+	case "uint8", "uint16", "uint32", "uint8be", "uint16be", "uint32be":
+		size, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "uint"), "be"))
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		offset, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return readIntExpr{size: size / 8, bigEndian: strings.HasSuffix(name, "be"), offset: offset}, nil
+	}
+	if ok, err := p.accept("."); err != nil {
+		return nil, err
+	} else if ok {
+		return nil, fmt.Errorf("module %q is not supported", name)
+	}
+	rule, ok := p.rules[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined identifier %q", name)
+	}
+	return ruleRefExpr{rule: rule}, nil
+}
+
+func (p *parser) parseStringExpr(id string) (expression, error) {
+	pattern, err := p.lookupString(id)
+	if err != nil {
+		return nil, err
+	}
+	e := stringExpr{pattern: pattern}
+	if ok, err := p.accept("at"); err != nil {
+		return nil, err
+	} else if ok {
+		if e.at, err = p.parseAdditive(); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	if ok, err := p.accept("in"); err != nil {
+		return nil, err
+	} else if ok {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		if e.start, err = p.parseAdditive(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(".."); err != nil {
+			return nil, err
+		}
+		if e.end, err = p.parseAdditive(); err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	}
+	return e, nil
+}
+
+func (p *parser) lookupString(id string) (*stringPattern, error) {
+	if id != "$" && !strings.HasSuffix(id, "*") {
+		for _, s := range p.current.strings {
+			if s.id == id {
+				return s, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("undefined string identifier %q", id)
+}
+
+// parseOf parses the string set of an of expression: them or ($a, $b*, ...)
+func (p *parser) parseOf(quantifier expression) (expression, error) {
+	e := ofExpr{quantifier: quantifier}
+	if ok, err := p.accept("them"); err != nil {
+		return nil, err
+	} else if ok {
+		e.patterns = p.current.strings
+	} else {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		for {
+			t, err := p.lexer.next()
+			if err != nil {
+				return nil, err
+			}
+			if t.kind != tokenStringID {
+				return nil, fmt.Errorf("expected string identifier, found %q", t.text)
+			}
+			prefix, wildcard := strings.CutSuffix(t.text, "*")
+			found := false
+			for _, s := range p.current.strings {
+				if s.id == t.text || (wildcard && strings.HasPrefix(s.id, prefix)) {
+					e.patterns = append(e.patterns, s)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("undefined string identifier %q", t.text)
+			}
+			if ok, err := p.accept(","); err != nil {
+				return nil, err
+			} else if !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(e.patterns) == 0 {
+		return nil, fmt.Errorf("empty string set")
+	}
+	return e, nil
+}
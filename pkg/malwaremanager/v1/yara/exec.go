@@ -0,0 +1,93 @@
+package malwaremanager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/malwaremanager"
+	malwaremanager2 "github.com/kubescape/node-agent/pkg/malwaremanager/v1/types"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	"github.com/armosec/armoapi-go/armotypes"
+	"github.com/dustin/go-humanize"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+func (y *YaraScanner) handleExecEvent(event *types.Event, containerPid uint32) malwaremanager.MalwareResult {
+	if event == nil {
+		return nil
+	}
+
+	hostFilePath, err := utils.GetHostFilePathFromEvent(event, containerPid)
+	if err != nil {
+		logger.L().Warning("YaraScanner.handleExecEvent - getting host file path", helpers.Error(err))
+		return nil
+	}
+
+	matches, err := y.scanFile(hostFilePath)
+	if err != nil {
+		logger.L().Debug("YaraScanner.handleExecEvent - scanning file", helpers.String("path", hostFilePath), helpers.Error(err))
+		return nil
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	// A malware was found, send an alert.
+	size, _ := utils.GetFileSize(hostFilePath)
+	hashes, err := utils.GetFileHashes(hostFilePath)
+	if err != nil {
+		logger.L().Debug("YaraScanner.handleExecEvent - getting file hashes", helpers.Error(err))
+	}
+	names, tags, description := describeMatches(matches)
+
+	return &malwaremanager2.GenericMalwareResult{
+		BasicRuntimeAlert: armotypes.BaseRuntimeAlert{
+			AlertName:      names[0],
+			InfectedPID:    event.Pid,
+			FixSuggestions: malwaremanager.FixSuggestions,
+			Arguments: map[string]interface{}{
+				"rules": names,
+				"tags":  tags,
+			},
+			SHA1Hash:   hashes.SHA1,
+			MD5Hash:    hashes.MD5,
+			SHA256Hash: hashes.SHA256,
+			Severity:   10, // TODO: Get severity from api.
+			Size:       humanize.IBytes(uint64(size)),
+			Timestamp:  time.Unix(0, int64(event.Timestamp)),
+		},
+		RuntimeProcessDetails: armotypes.ProcessTree{
+			ProcessTree: armotypes.Process{
+				Comm:       event.Comm,
+				Path:       utils.GetExecPathFromEvent(event),
+				Gid:        &event.Gid,
+				PID:        event.Pid,
+				Uid:        &event.Uid,
+				UpperLayer: &event.UpperLayer,
+				PPID:       event.Ppid,
+				Pcomm:      event.Pcomm,
+				Cwd:        event.Cwd,
+				Hardlink:   event.ExePath,
+				Cmdline:    fmt.Sprintf("%s %s", utils.GetExecPathFromEvent(event), utils.GetExecArgsFromEvent(event)),
+			},
+			ContainerID: event.Runtime.ContainerID,
+		},
+		TriggerEvent: event.Event,
+		MalwareRuntimeAlert: armotypes.MalwareAlert{
+			MalwareDescription: description,
+		},
+		RuntimeAlertK8sDetails: armotypes.RuntimeAlertK8sDetails{
+			ContainerID:   event.Runtime.ContainerID,
+			ContainerName: event.K8s.ContainerName,
+			Namespace:     event.GetNamespace(),
+			PodName:       event.GetPod(),
+			PodNamespace:  event.GetNamespace(),
+			HostNetwork:   &event.K8s.HostNetwork,
+			Image:         event.Runtime.ContainerImageName,
+			ImageDigest:   event.Runtime.ContainerImageDigest,
+		},
+	}
+}
@@ -0,0 +1,506 @@
+package malwaremanager
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"regexp"
+)
+
+const (
+	// maxMatchesPerString bounds the number of matches recorded for a single string of a rule
+	maxMatchesPerString = 10000
+	// scanChunkSize is the size of the chunks read from the scanned data
+	scanChunkSize = 1024 * 1024
+	// maxMatchLength bounds the length of a match, the chunks overlap by this length so matches crossing
+	// a chunk boundary are found. Regular expressions and hex jumps matching more data are cut at this length.
+	maxMatchLength = 4096
+	// matchContext is the number of bytes kept before a chunk for the fullword checks of wide strings
+	matchContext = 2
+)
+
+// Rule is a compiled YARA rule.
+type Rule struct {
+	Name      string
+	Namespace string
+	Tags      []string
+	Meta      map[string]string
+	Private   bool
+	Global    bool
+	strings   []*stringPattern
+	condition expression
+}
+
+// Match is a rule that matched the scanned data.
+type Match struct {
+	Rule      string
+	Namespace string
+	Tags      []string
+	Meta      map[string]string
+}
+
+// RuleSet is a set of compiled rules, rules are evaluated per namespace in declaration order.
+type RuleSet struct {
+	rules    []*Rule
+	patterns []*stringPattern // strings of all the rules, searched in a single pass
+	nocase   bool             // some strings are case insensitive
+}
+
+func NewRuleSet(rules ...[]*Rule) *RuleSet {
+	rs := &RuleSet{}
+	for _, r := range rules {
+		rs.rules = append(rs.rules, r...)
+	}
+	for _, rule := range rs.rules {
+		for _, pattern := range rule.strings {
+			rs.patterns = append(rs.patterns, pattern)
+			rs.nocase = rs.nocase || (pattern.nocase && pattern.kind == textString)
+		}
+	}
+	return rs
+}
+
+// Len returns the number of rules in the set.
+func (rs *RuleSet) Len() int {
+	if rs == nil {
+		return 0
+	}
+	return len(rs.rules)
+}
+
+// Scan evaluates all the rules against the data of the given size and returns the matching non-private rules.
+// The data is read by chunks, it is never loaded as a whole.
+func (rs *RuleSet) Scan(data io.ReaderAt, size int64) ([]Match, error) {
+	if rs.Len() == 0 {
+		return nil, nil
+	}
+	ctx := &scanContext{
+		data:    data,
+		size:    size,
+		offsets: make(map[*stringPattern][]int, len(rs.patterns)),
+		results: make(map[string]bool),
+	}
+	if err := ctx.findStrings(rs.patterns, rs.nocase); err != nil {
+		return nil, err
+	}
+
+	// a failing global rule disables all the rules of its namespace
+	disabledNamespaces := make(map[string]bool)
+	for _, rule := range rs.rules {
+		if rule.Global && !ctx.evaluate(rule) {
+			disabledNamespaces[rule.Namespace] = true
+		}
+	}
+
+	var matches []Match
+	for _, rule := range rs.rules {
+		if disabledNamespaces[rule.Namespace] {
+			continue
+		}
+		if !ctx.evaluate(rule) || rule.Private {
+			continue
+		}
+		matches = append(matches, Match{
+			Rule:      rule.Name,
+			Namespace: rule.Namespace,
+			Tags:      rule.Tags,
+			Meta:      rule.Meta,
+		})
+	}
+	return matches, nil
+}
+
+type scanContext struct {
+	data    io.ReaderAt
+	size    int64
+	offsets map[*stringPattern][]int
+	results map[string]bool // key is namespace:rule
+}
+
+func (ctx *scanContext) evaluate(rule *Rule) bool {
+	key := rule.Namespace + ":" + rule.Name
+	if result, ok := ctx.results[key]; ok {
+		return result
+	}
+	result := rule.condition.eval(ctx) != 0
+	ctx.results[key] = result
+	return result
+}
+
+// matchOffsets returns the offsets at which the string matches
+func (ctx *scanContext) matchOffsets(s *stringPattern) []int {
+	return ctx.offsets[s]
+}
+
+// findStrings searches all the strings in a single pass over the data. A chunk keeps the last maxMatchLength
+// bytes of the previous one, the matches starting there are found in the next chunk.
+func (ctx *scanContext) findStrings(patterns []*stringPattern, nocase bool) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+	buf := make([]byte, scanChunkSize+maxMatchLength+matchContext)
+	var lower []byte
+	if nocase {
+		lower = make([]byte, len(buf))
+	}
+	var base int64 // offset of the chunk in the data
+	length, lo := 0, 0
+	for {
+		toRead := buf[length:]
+		if remaining := ctx.size - base - int64(length); remaining < int64(len(toRead)) {
+			toRead = toRead[:max(remaining, 0)]
+		}
+		n, err := ctx.data.ReadAt(toRead, base+int64(length))
+		length += n
+		eof := base+int64(length) >= ctx.size
+		if errors.Is(err, io.EOF) {
+			// the data was truncated while scanned
+			ctx.size, eof = base+int64(length), true
+		} else if err != nil {
+			return err
+		}
+
+		chunk := buf[:length]
+		hi := length
+		if !eof {
+			hi = length - maxMatchLength
+		}
+		if nocase {
+			asciiLower(lower[:length], chunk)
+		}
+		for _, pattern := range patterns {
+			ctx.offsets[pattern] = pattern.find(ctx.offsets[pattern], chunk, lower, lo, hi, int(base))
+		}
+		if eof {
+			return nil
+		}
+
+		keep := maxMatchLength + matchContext
+		copy(buf, buf[length-keep:length])
+		base += int64(length - keep)
+		length, lo = keep, matchContext
+	}
+}
+
+// asciiLower lowers the ASCII letters of src into dst, keeping the offsets of the data
+func asciiLower(dst, src []byte) []byte {
+	for i, c := range src {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		dst[i] = c
+	}
+	return dst[:len(src)]
+}
+
+type stringKind int
+
+const (
+	textString stringKind = iota
+	hexString
+	regexString
+)
+
+type stringPattern struct {
+	id       string
+	kind     stringKind
+	needles  [][]byte // text strings, one per encoding
+	nocase   bool
+	fullword bool
+	wide     bool
+	hex      []hexToken
+	regex    *regexp.Regexp
+}
+
+// find appends the offsets of the matches starting in chunk[lo:hi], base is the offset of the chunk in the data
+func (s *stringPattern) find(offsets []int, chunk, lower []byte, lo, hi, base int) []int {
+	switch s.kind {
+	case textString:
+		data := chunk
+		if s.nocase {
+			data = lower[:len(chunk)]
+		}
+		for _, needle := range s.needles {
+			for start := lo; start < hi && len(offsets) < maxMatchesPerString; {
+				i := bytes.Index(data[start:], needle)
+				if i < 0 || start+i >= hi {
+					break
+				}
+				offset := start + i
+				if !s.fullword || isFullword(chunk, offset, len(needle), s.wide) {
+					offsets = append(offsets, base+offset)
+				}
+				start = offset + 1
+			}
+		}
+	case hexString:
+		for start := lo; start < hi && len(offsets) < maxMatchesPerString; start++ {
+			// jump to the next candidate when the string starts with a fixed byte
+			if first := s.hex[0]; first.kind == hexByte && first.mask == 0xff {
+				i := bytes.IndexByte(chunk[start:hi], first.value)
+				if i < 0 {
+					break
+				}
+				start += i
+			}
+			if matchHexTokens(s.hex, chunk, start, func(int) bool { return true }) {
+				offsets = append(offsets, base+start)
+			}
+		}
+	case regexString:
+		for _, loc := range s.regex.FindAllIndex(chunk[lo:], maxMatchesPerString-len(offsets)) {
+			if lo+loc[0] >= hi {
+				break
+			}
+			offsets = append(offsets, base+lo+loc[0])
+		}
+	}
+	return offsets
+}
+
+// isFullword checks that the match is delimited by non-alphanumeric characters
+func isFullword(data []byte, offset, length int, wide bool) bool {
+	step := 1
+	if wide {
+		step = 2
+	}
+	if offset-step >= 0 && isAlphanumeric(data[offset-step]) {
+		return false
+	}
+	if end := offset + length; end < len(data) && isAlphanumeric(data[end]) {
+		return false
+	}
+	return true
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+type hexTokenKind int
+
+const (
+	hexByte hexTokenKind = iota
+	hexJump
+	hexAlternatives
+)
+
+type hexToken struct {
+	kind         hexTokenKind
+	value, mask  byte
+	min, max     int // max is -1 for unbounded jumps
+	alternatives [][]hexToken
+}
+
+// matchHexTokens matches the tokens at the position and calls next with the end position of every candidate match
+func matchHexTokens(tokens []hexToken, data []byte, pos int, next func(int) bool) bool {
+	if len(tokens) == 0 {
+		return next(pos)
+	}
+	token, rest := tokens[0], tokens[1:]
+	switch token.kind {
+	case hexByte:
+		if pos < len(data) && data[pos]&token.mask == token.value {
+			return matchHexTokens(rest, data, pos+1, next)
+		}
+	case hexJump:
+		maxJump := len(data) - pos
+		if token.max >= 0 && token.max < maxJump {
+			maxJump = token.max
+		}
+		for n := token.min; n <= maxJump; n++ {
+			if matchHexTokens(rest, data, pos+n, next) {
+				return true
+			}
+		}
+	case hexAlternatives:
+		for _, alternative := range token.alternatives {
+			if matchHexTokens(alternative, data, pos, func(end int) bool {
+				return matchHexTokens(rest, data, end, next)
+			}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expression is a node of a rule condition, booleans evaluate to 0 or 1
+type expression interface {
+	eval(ctx *scanContext) int64
+}
+
+type boolExpr bool
+
+func (e boolExpr) eval(_ *scanContext) int64 {
+	return boolToInt(bool(e))
+}
+
+type intExpr int64
+
+func (e intExpr) eval(_ *scanContext) int64 {
+	return int64(e)
+}
+
+type filesizeExpr struct{}
+
+func (e filesizeExpr) eval(ctx *scanContext) int64 {
+	return ctx.size
+}
+
+type notExpr struct {
+	operand expression
+}
+
+func (e notExpr) eval(ctx *scanContext) int64 {
+	return boolToInt(e.operand.eval(ctx) == 0)
+}
+
+type binaryExpr struct {
+	operator    string
+	left, right expression
+}
+
+func (e binaryExpr) eval(ctx *scanContext) int64 {
+	switch e.operator {
+	case "and":
+		return boolToInt(e.left.eval(ctx) != 0 && e.right.eval(ctx) != 0)
+	case "or":
+		return boolToInt(e.left.eval(ctx) != 0 || e.right.eval(ctx) != 0)
+	}
+	left, right := e.left.eval(ctx), e.right.eval(ctx)
+	switch e.operator {
+	case "==":
+		return boolToInt(left == right)
+	case "!=":
+		return boolToInt(left != right)
+	case "<":
+		return boolToInt(left < right)
+	case "<=":
+		return boolToInt(left <= right)
+	case ">":
+		return boolToInt(left > right)
+	case ">=":
+		return boolToInt(left >= right)
+	case "+":
+		return left + right
+	case "-":
+		return left - right
+	case "&":
+		return left & right
+	}
+	return 0
+}
+
+// stringExpr is $a, $a at <offset> or $a in (<start>..<end>)
+type stringExpr struct {
+	pattern    *stringPattern
+	at         expression
+	start, end expression
+}
+
+func (e stringExpr) eval(ctx *scanContext) int64 {
+	offsets := ctx.matchOffsets(e.pattern)
+	switch {
+	case e.at != nil:
+		at := int(e.at.eval(ctx))
+		for _, offset := range offsets {
+			if offset == at {
+				return 1
+			}
+		}
+		return 0
+	case e.start != nil:
+		start, end := int(e.start.eval(ctx)), int(e.end.eval(ctx))
+		for _, offset := range offsets {
+			if offset >= start && offset <= end {
+				return 1
+			}
+		}
+		return 0
+	}
+	return boolToInt(len(offsets) > 0)
+}
+
+type countExpr struct {
+	pattern *stringPattern
+}
+
+func (e countExpr) eval(ctx *scanContext) int64 {
+	return int64(len(ctx.matchOffsets(e.pattern)))
+}
+
+// ofExpr is <quantifier> of (<strings>), the quantifier is -1 for all and -2 for none
+type ofExpr struct {
+	quantifier expression
+	patterns   []*stringPattern
+}
+
+const (
+	quantifierAll  = -1
+	quantifierNone = -2
+)
+
+func (e ofExpr) eval(ctx *scanContext) int64 {
+	var matched int64
+	for _, pattern := range e.patterns {
+		if len(ctx.matchOffsets(pattern)) > 0 {
+			matched++
+		}
+	}
+	switch quantifier := e.quantifier.eval(ctx); quantifier {
+	case quantifierAll:
+		return boolToInt(matched == int64(len(e.patterns)))
+	case quantifierNone:
+		return boolToInt(matched == 0)
+	default:
+		return boolToInt(matched >= quantifier)
+	}
+}
+
+// readIntExpr is uint8/16/32(offset), the big endian variants end with "be"
+type readIntExpr struct {
+	size      int
+	bigEndian bool
+	offset    expression
+}
+
+func (e readIntExpr) eval(ctx *scanContext) int64 {
+	offset := e.offset.eval(ctx)
+	if offset < 0 || offset+int64(e.size) > ctx.size {
+		return 0
+	}
+	b := make([]byte, e.size)
+	if _, err := ctx.data.ReadAt(b, offset); err != nil {
+		return 0
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if e.bigEndian {
+		order = binary.BigEndian
+	}
+	switch e.size {
+	case 1:
+		return int64(b[0])
+	case 2:
+		return int64(order.Uint16(b))
+	default:
+		return int64(order.Uint32(b))
+	}
+}
+
+// ruleRefExpr references a rule declared earlier in the same namespace
+type ruleRefExpr struct {
+	rule *Rule
+}
+
+func (e ruleRefExpr) eval(ctx *scanContext) int64 {
+	return boolToInt(ctx.evaluate(e.rule))
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
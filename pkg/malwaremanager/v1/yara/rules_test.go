@@ -0,0 +1,233 @@
+package malwaremanager
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func matchedRules(t *testing.T, source string, data []byte) []string {
+	rules, rejected, err := CompileRules(source, "test")
+	if !assert.NoError(t, err) || !assert.Empty(t, rejected) {
+		return nil
+	}
+	matches, err := NewRuleSet(rules).Scan(bytes.NewReader(data), int64(len(data)))
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	var names []string
+	for _, match := range matches {
+		names = append(names, match.Rule)
+	}
+	return names
+}
+
+func TestRuleSetScan(t *testing.T) {
+	elf := append([]byte{0x7f, 'E', 'L', 'F', 0x02, 0x01}, []byte("....stratum+tcp://pool.example.com....x\x00m\x00r\x00i\x00g\x00....")...)
+
+	tests := []struct {
+		name   string
+		source string
+		data   []byte
+		want   []string
+	}{
+		{
+			name: "text string",
+			source: `rule miner : crypto miner {
+				meta:
+					description = "stratum URL"
+					score = 80
+				strings:
+					$a = "stratum+tcp://"
+				condition:
+					$a
+			}`,
+			data: elf,
+			want: []string{"miner"},
+		},
+		{
+			name:   "no match",
+			source: `rule miner { strings: $a = "stratum+ssl://" condition: $a }`,
+			data:   elf,
+		},
+		{
+			name:   "nocase and wide",
+			source: `rule xmrig { strings: $a = "XMRIG" nocase wide condition: $a }`,
+			data:   elf,
+			want:   []string{"xmrig"},
+		},
+		{
+			name:   "wide only does not match ascii",
+			source: `rule pool { strings: $a = "pool" wide condition: $a }`,
+			data:   elf,
+		},
+		{
+			name:   "fullword",
+			source: `rule fullword { strings: $a = "example" fullword $b = "exam" fullword condition: $a and not $b }`,
+			data:   elf,
+			want:   []string{"fullword"},
+		},
+		{
+			name:   "hex string with wildcards, jumps and alternatives",
+			source: `rule elf { strings: $h = { 7F 45 4C 46 (01 | 02) ?1 [4-8] 73 74 } condition: $h at 0 }`,
+			data:   elf,
+			want:   []string{"elf"},
+		},
+		{
+			name:   "hex string not at offset",
+			source: `rule elf { strings: $h = { 45 4C 46 } condition: $h at 0 }`,
+			data:   elf,
+		},
+		{
+			name:   "regex",
+			source: `rule url { strings: $r = /stratum\+(tcp|ssl):\/\/[a-z.]+/ condition: $r in (0..16) }`,
+			data:   elf,
+			want:   []string{"url"},
+		},
+		{
+			name:   "magic and filesize",
+			source: `rule small_elf { condition: uint32(0) == 0x464c457f and uint16be(4) == 0x0201 and filesize < 1KB }`,
+			data:   elf,
+			want:   []string{"small_elf"},
+		},
+		{
+			name:   "counts and of",
+			source: `rule dots { strings: $a = "...." $b = "missing" $c = "pool" condition: #a >= 2 and 2 of ($a, $b, $c) and any of ($c*) and not all of them }`,
+			data:   elf,
+			want:   []string{"dots"},
+		},
+		{
+			name:   "none of",
+			source: `rule clean { strings: $a = "evil" $b = "malware" condition: none of them }`,
+			data:   elf,
+			want:   []string{"clean"},
+		},
+		{
+			name: "private and rule references",
+			source: `private rule is_elf { condition: uint32(0) == 0x464c457f }
+			rule elf_miner { strings: $a = "stratum" condition: is_elf and $a }`,
+			data: elf,
+			want: []string{"elf_miner"},
+		},
+		{
+			name: "failing global rule disables the namespace",
+			source: `global rule big { condition: filesize > 1MB }
+			rule any_file { condition: true }`,
+			data: elf,
+		},
+		{
+			name: "comments",
+			source: `// a comment
+			/* a block
+			   comment */
+			rule commented { strings: $a = "pool" // trailing
+			condition: $a /* inline */ }`,
+			data: elf,
+			want: []string{"commented"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchedRules(t, tt.source, tt.data))
+		})
+	}
+}
+
+func TestRuleSetScanChunks(t *testing.T) {
+	// the strings crossing a chunk boundary are found once, and their offsets are kept
+	data := bytes.Repeat([]byte{'.'}, 3*scanChunkSize)
+	boundary := scanChunkSize + matchContext
+	copy(data[boundary-3:], "stratum+tcp://")
+	copy(data[2*scanChunkSize-2:], "X\x00M\x00R\x00I\x00G\x00")
+	data[len(data)-1] = 0x7f
+
+	source := `rule miner {
+		strings:
+			$a = "stratum+tcp://"
+			$b = "xmrig" nocase wide fullword
+			$h = { 73 74 [0-4096] 2F 2F }
+		condition:
+			#a == 1 and $a at ` + strconv.Itoa(boundary-3) + ` and #b == 1 and $h and uint8(filesize - 1) == 0x7f
+	}`
+	assert.Equal(t, []string{"miner"}, matchedRules(t, source, data))
+}
+
+func TestRuleMetadata(t *testing.T) {
+	rules, _, err := CompileRules(`rule miner : crypto miner { meta: description = "stratum URL" score = -1 enabled = true condition: true }`, "ns")
+	assert.NoError(t, err)
+	matches, err := NewRuleSet(rules).Scan(strings.NewReader("data"), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{{
+		Rule:      "miner",
+		Namespace: "ns",
+		Tags:      []string{"crypto", "miner"},
+		Meta:      map[string]string{"description": "stratum URL", "score": "-1", "enabled": "true"},
+	}}, matches)
+}
+
+func TestCompileRulesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"include", `include "other.yar"`},
+		{"unexpected token", `rule a { condition: true } }`},
+		{"rejected global rule", `global rule a { condition: pe.is_pe } rule b { condition: true }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := CompileRules(tt.source, "test")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCompileRulesRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		rejected string
+	}{
+		{"module", `import "pe" rule a { condition: pe.is_pe }`, "a"},
+		{"undefined string", `rule a { condition: $a }`, "a"},
+		{"undefined rule", `rule a { condition: b }`, "a"},
+		{"duplicated rule", `rule a { condition: true } rule a { condition: true }`, "a"},
+		{"duplicated string", `rule a { strings: $a = "x" $a = "y" condition: $a }`, "a"},
+		{"unsupported modifier", `rule a { strings: $a = "x" xor condition: $a }`, "a"},
+		{"invalid hex", `rule a { strings: $a = { 4G } condition: $a }`, "a"},
+		{"hex starting with a jump", `rule a { strings: $a = { [2] 41 } condition: $a }`, "a"},
+		{"invalid regex", `rule a { strings: $a = /(/ condition: $a }`, "a"},
+		{"empty set", `rule a { condition: any of them }`, "a"},
+		{"unterminated rule", `rule a { condition: true`, "a"},
+		{"string too long", `rule a { strings: $a = "` + strings.Repeat("x", maxMatchLength) + `" condition: $a }`, "a"},
+		{"for expression", `rule a { strings: $a = "x" condition: for any i in (1..#a): (@a[i] < 10) }`, "a"},
+		{"reference to a rejected rule", "rule a { condition: pe.is_pe }\nrule b { condition: a }", "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rejected, err := CompileRules(tt.source, "test")
+			assert.NoError(t, err)
+			if assert.NotEmpty(t, rejected) {
+				assert.Equal(t, tt.rejected, rejected[0].Name)
+				assert.Error(t, rejected[0].Err)
+			}
+		})
+	}
+
+	// the rules around a rejected rule are compiled
+	source := `import "pe"
+rule before { strings: $a = "before" condition: $a }
+rule unsupported { strings: $a = "x" condition: pe.is_pe and $a }
+rule after : tag { strings: $a = "after" condition: $a and before }`
+	rules, rejected, err := CompileRules(source, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, []RejectedRule{{Name: "unsupported", Err: rejected[0].Err}}, rejected)
+	var names []string
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	assert.Equal(t, []string{"before", "after"}, names)
+}
@@ -0,0 +1,267 @@
+package malwaremanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
+	traceropentype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/open/types"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/k8sclient"
+	"github.com/kubescape/node-agent/pkg/malwaremanager"
+	"github.com/kubescape/node-agent/pkg/metricsmanager"
+	"github.com/kubescape/node-agent/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// compiledSource holds the rules compiled from a single rule file or ConfigMap key
+type compiledSource struct {
+	hash     string
+	rules    []*Rule
+	rejected []RejectedRule
+}
+
+// YaraScanner matches the files against rules written in a restricted subset of YARA, see CompileRules.
+// It does not use libyara, the rules using unsupported features are rejected one by one, logged and reported in
+// the metrics.
+type YaraScanner struct {
+	mutex          sync.RWMutex
+	rules          *RuleSet
+	sources        map[string]compiledSource // key is the source name, used as the rules namespace
+	paths          []string
+	configMaps     []string
+	namespace      string
+	k8sClient      k8sclient.K8sClientInterface
+	metrics        metricsmanager.MetricsManager
+	reloadInterval time.Duration
+}
+
+var _ malwaremanager.MalwareScanner = (*YaraScanner)(nil)
+
+// CreateYaraScanner compiles the rules found in the paths and ConfigMaps and reloads them periodically until the context is done.
+// It fails when a rule source fails to compile as a whole, the rules outside of the supported YARA subset are skipped.
+func CreateYaraScanner(ctx context.Context, paths, configMaps []string, namespace string, k8sClient k8sclient.K8sClientInterface, metrics metricsmanager.MetricsManager, reloadInterval time.Duration) (*YaraScanner, error) {
+	scanner := &YaraScanner{
+		rules:          NewRuleSet(),
+		sources:        make(map[string]compiledSource),
+		paths:          paths,
+		configMaps:     configMaps,
+		namespace:      namespace,
+		k8sClient:      k8sClient,
+		metrics:        metrics,
+		reloadInterval: reloadInterval,
+	}
+	if err := scanner.Reload(ctx); err != nil {
+		return nil, err
+	}
+	if scanner.rules.Len() == 0 {
+		logger.L().Warning("YaraScanner - no rules were loaded")
+	}
+
+	if reloadInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(reloadInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := scanner.Reload(ctx); err != nil {
+						logger.L().Ctx(ctx).Error("YaraScanner - failed to compile rules", helpers.Error(err))
+					}
+				}
+			}
+		}()
+	}
+	return scanner, nil
+}
+
+// Reload reads all the rule sources and recompiles the changed ones, it returns the errors of the sources failing to
+// compile as a whole. Such a source keeps its previously compiled rules, so a bad update does not drop coverage.
+// The rejected rules of a source are left out, the other rules of the source are loaded.
+func (y *YaraScanner) Reload(ctx context.Context) error {
+	contents := make(map[string]string)
+	for _, path := range y.paths {
+		if err := readRulePath(path, contents); err != nil {
+			logger.L().Warning("YaraScanner - failed to read rules", helpers.Error(err), helpers.String("path", path))
+		}
+	}
+	for _, name := range y.configMaps {
+		if err := y.readRuleConfigMap(ctx, name, contents); err != nil {
+			logger.L().Warning("YaraScanner - failed to read rules", helpers.Error(err), helpers.String("configMap", name))
+		}
+	}
+
+	y.mutex.RLock()
+	previous := y.sources
+	y.mutex.RUnlock()
+
+	sources := make(map[string]compiledSource, len(contents))
+	changed := len(contents) != len(previous)
+	var errs []error
+	for name, content := range contents {
+		sum := sha256.Sum256([]byte(content))
+		hash := hex.EncodeToString(sum[:])
+		if source, ok := previous[name]; ok && source.hash == hash {
+			sources[name] = source
+			continue
+		}
+		changed = true
+		rules, rejected, err := CompileRules(content, name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("compiling %s: %w", name, err))
+			if source, ok := previous[name]; ok {
+				sources[name] = source
+			}
+			continue
+		}
+		for _, rule := range rejected {
+			logger.L().Warning("YaraScanner - rejected rule outside of the supported YARA subset", helpers.Error(rule.Err),
+				helpers.String("source", name), helpers.String("rule", rule.Name))
+		}
+		sources[name] = compiledSource{hash: hash, rules: rules, rejected: rejected}
+	}
+	if !changed {
+		return errors.Join(errs...)
+	}
+
+	// keep a stable evaluation order between reloads
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	rules := make([][]*Rule, 0, len(names))
+	rejected := make(map[string][]string) // key is the source name
+	rejectedCount := 0
+	for _, name := range names {
+		rules = append(rules, sources[name].rules)
+		for _, rule := range sources[name].rejected {
+			rejected[name] = append(rejected[name], rule.Name)
+			rejectedCount++
+		}
+	}
+	ruleSet := NewRuleSet(rules...)
+	y.metrics.ReportYaraRejectedRules(rejected)
+
+	y.mutex.Lock()
+	y.sources = sources
+	y.rules = ruleSet
+	y.mutex.Unlock()
+	logger.L().Info("YaraScanner - loaded rules", helpers.Int("sources", len(sources)), helpers.Int("rules", ruleSet.Len()), helpers.Int("rejected", rejectedCount))
+	return errors.Join(errs...)
+}
+
+// readRulePath reads a rule file or the .yar and .yara files of a directory
+func readRulePath(path string, contents map[string]string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		contents["file:"+path] = string(data)
+		return nil
+	}
+	return utils.WalkFiles(path, func(filePath string, _ fs.DirEntry) error {
+		if filepath.Ext(filePath) != ".yar" && filepath.Ext(filePath) != ".yara" {
+			return nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			logger.L().Warning("YaraScanner - failed to read rules", helpers.Error(err), helpers.String("path", filePath))
+			return nil
+		}
+		contents["file:"+filePath] = string(data)
+		return nil
+	})
+}
+
+func (y *YaraScanner) readRuleConfigMap(ctx context.Context, name string, contents map[string]string) error {
+	if y.k8sClient == nil {
+		return fmt.Errorf("kubernetes client is not set")
+	}
+	configMap, err := y.k8sClient.GetKubernetesClient().CoreV1().ConfigMaps(y.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for key, data := range configMap.Data {
+		contents[fmt.Sprintf("configmap:%s/%s", name, key)] = data
+	}
+	for key, data := range configMap.BinaryData {
+		contents[fmt.Sprintf("configmap:%s/%s", name, key)] = string(data)
+	}
+	return nil
+}
+
+func (y *YaraScanner) Scan(eventType utils.EventType, event utils.K8sEvent, containerPid uint32) malwaremanager.MalwareResult {
+	switch eventType {
+	case utils.ExecveEventType:
+		return y.handleExecEvent(event.(*tracerexectype.Event), containerPid)
+	case utils.OpenEventType, utils.FileSweepEventType:
+		return y.handleOpenEvent(event.(*traceropentype.Event), containerPid)
+	default:
+		return nil
+	}
+}
+
+// scanFile scans the file through the host path, it is read by chunks. Files larger than malwaremanager.MaxFileSize are skipped.
+func (y *YaraScanner) scanFile(hostFilePath string) ([]Match, error) {
+	y.mutex.RLock()
+	rules := y.rules
+	y.mutex.RUnlock()
+	if rules.Len() == 0 {
+		return nil, nil
+	}
+
+	file, err := os.Open(hostFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() || info.Size() == 0 || info.Size() > malwaremanager.MaxFileSize {
+		return nil, nil
+	}
+	return rules.Scan(file, info.Size())
+}
+
+// describeMatches returns the matched rule names, their tags and a description for the alert
+func describeMatches(matches []Match) ([]string, []string, string) {
+	var names, tags, descriptions []string
+	for _, match := range matches {
+		names = append(names, match.Rule)
+		for _, tag := range match.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		description := match.Rule
+		if len(match.Tags) > 0 {
+			description += " [" + strings.Join(match.Tags, ", ") + "]"
+		}
+		if d := match.Meta["description"]; d != "" {
+			description += ": " + d
+		}
+		descriptions = append(descriptions, description)
+	}
+	return names, tags, "YARA rules matched: " + strings.Join(descriptions, "; ")
+}
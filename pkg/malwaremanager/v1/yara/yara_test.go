@@ -0,0 +1,119 @@
+package malwaremanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
+	traceropentype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/open/types"
+	"github.com/kubescape/node-agent/pkg/k8sclient"
+	"github.com/kubescape/node-agent/pkg/metricsmanager"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const minerRule = `rule xmrig_miner : crypto miner {
+	meta:
+		description = "XMRig miner"
+	strings:
+		$a = "stratum+tcp://"
+		$b = "xmrig" nocase
+	condition:
+		all of them
+}`
+
+func TestYaraScanner(t *testing.T) {
+	rulesDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(rulesDir, "miner.yar"), []byte(minerRule), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(rulesDir, "README.md"), []byte("not a rule"), 0644))
+
+	scanner, err := CreateYaraScanner(context.Background(), []string{rulesDir}, nil, "", nil, metricsmanager.NewMetricsMock(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, scanner.rules.Len())
+
+	// the files are reached through /proc/<pid>/root of the test process
+	dir := t.TempDir()
+	malware := filepath.Join(dir, "xmrig")
+	assert.NoError(t, os.WriteFile(malware, []byte("XMRig 6.21 stratum+tcp://pool.example.com:3333"), 0755))
+	clean := filepath.Join(dir, "clean")
+	assert.NoError(t, os.WriteFile(clean, []byte("hello world"), 0755))
+	containerPid := uint32(os.Getpid())
+
+	exec := &tracerexectype.Event{Comm: "xmrig", Args: []string{malware}, Pid: 42}
+	result := scanner.Scan(utils.ExecveEventType, exec, containerPid)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, "xmrig_miner", result.GetBasicRuntimeAlert().AlertName)
+		assert.Equal(t, []string{"xmrig_miner"}, result.GetBasicRuntimeAlert().Arguments["rules"])
+		assert.Equal(t, []string{"crypto", "miner"}, result.GetBasicRuntimeAlert().Arguments["tags"])
+		assert.Equal(t, "YARA rules matched: xmrig_miner [crypto, miner]: XMRig miner", result.GetMalwareRuntimeAlert().MalwareDescription)
+		assert.NotEmpty(t, result.GetBasicRuntimeAlert().SHA256Hash)
+	}
+
+	exec.Args = []string{clean}
+	assert.Nil(t, scanner.Scan(utils.ExecveEventType, exec, containerPid))
+
+	open := &traceropentype.Event{FullPath: malware, Pid: 42}
+	assert.NotNil(t, scanner.Scan(utils.OpenEventType, open, containerPid))
+
+	// opens for writing are not scanned
+	open.FlagsRaw = unix.O_WRONLY
+	assert.Nil(t, scanner.Scan(utils.OpenEventType, open, containerPid))
+
+	// a rule update is picked up on reload
+	assert.NoError(t, os.WriteFile(filepath.Join(rulesDir, "hello.yara"), []byte(`rule hello { strings: $a = "hello" condition: $a }`), 0644))
+	assert.NoError(t, scanner.Reload(context.Background()))
+	exec.Args = []string{clean}
+	if result := scanner.Scan(utils.ExecveEventType, exec, containerPid); assert.NotNil(t, result) {
+		assert.Equal(t, "hello", result.GetBasicRuntimeAlert().AlertName)
+	}
+
+	// a broken update keeps the previously compiled rules
+	assert.NoError(t, os.WriteFile(filepath.Join(rulesDir, "hello.yara"), []byte(`rule hello { condition: true } }`), 0644))
+	assert.Error(t, scanner.Reload(context.Background()))
+	assert.NotNil(t, scanner.Scan(utils.ExecveEventType, exec, containerPid))
+
+	// a removed rule file is dropped on reload
+	assert.NoError(t, os.Remove(filepath.Join(rulesDir, "hello.yara")))
+	assert.NoError(t, scanner.Reload(context.Background()))
+	assert.Nil(t, scanner.Scan(utils.ExecveEventType, exec, containerPid))
+}
+
+func TestYaraScannerUnsupportedRules(t *testing.T) {
+	rulesDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(rulesDir, "pe.yar"), []byte(`import "pe" rule a { condition: pe.is_pe } `+minerRule), 0644))
+
+	// the unsupported rules are rejected and reported, the other rules are loaded
+	metrics := metricsmanager.NewMetricsMock()
+	scanner, err := CreateYaraScanner(context.Background(), []string{rulesDir}, nil, "", nil, metrics, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, scanner.rules.Len())
+	assert.Equal(t, []string{"a"}, metrics.YaraRejectedRules.Get("file:"+filepath.Join(rulesDir, "pe.yar")))
+
+	// the rejected rules are cleared once fixed
+	assert.NoError(t, os.WriteFile(filepath.Join(rulesDir, "pe.yar"), []byte(minerRule), 0644))
+	assert.NoError(t, scanner.Reload(context.Background()))
+	assert.Equal(t, 0, metrics.YaraRejectedRules.Len())
+
+	// a source failing to compile as a whole fails the creation
+	assert.NoError(t, os.WriteFile(filepath.Join(rulesDir, "include.yar"), []byte(`include "other.yar"`), 0644))
+	_, err = CreateYaraScanner(context.Background(), []string{rulesDir}, nil, "", nil, metrics, 0)
+	assert.ErrorContains(t, err, "include is not supported")
+}
+
+func TestYaraScannerConfigMap(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "yara-rules", Namespace: "kubescape"},
+		Data:       map[string]string{"miner.yar": minerRule},
+	}
+	k8sClient := k8sclient.NewK8sClientMock(configMap)
+
+	scanner, err := CreateYaraScanner(context.Background(), nil, []string{"yara-rules"}, "kubescape", k8sClient, metricsmanager.NewMetricsMock(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, scanner.rules.Len())
+	assert.Contains(t, scanner.sources, "configmap:yara-rules/miner.yar")
+}
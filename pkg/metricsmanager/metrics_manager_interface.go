@@ -10,4 +10,8 @@ type MetricsManager interface {
 	ReportFailedEvent()
 	ReportRuleProcessed(ruleID string)
 	ReportRuleAlert(ruleID string)
+	ReportProfileCompaction(category string, collapsed int, usage float64)
+	ReportSbomQueue(queued, running int, paused bool)
+	ReportSbomProcessed(result string)
+	ReportYaraRejectedRules(rejected map[string][]string)
 }
@@ -15,6 +15,10 @@ type MetricsMock struct {
 	RuleProcessedCounter maps.SafeMap[string, int]
 	RuleAlertCounter     maps.SafeMap[string, int]
 	EventCounter         maps.SafeMap[utils.EventType, int]
+	CollapsedCounter     maps.SafeMap[string, int]
+	SbomQueued           atomic.Int32
+	SbomProcessedCounter maps.SafeMap[string, int]
+	YaraRejectedRules    maps.SafeMap[string, []string]
 }
 
 func NewMetricsMock() *MetricsMock {
@@ -31,6 +35,10 @@ func (m *MetricsMock) Destroy() {
 	m.RuleProcessedCounter.Clear()
 	m.RuleAlertCounter.Clear()
 	m.EventCounter.Clear()
+	m.CollapsedCounter.Clear()
+	m.SbomQueued.Store(0)
+	m.SbomProcessedCounter.Clear()
+	m.YaraRejectedRules.Clear()
 }
 
 func (m *MetricsMock) ReportFailedEvent() {
@@ -48,3 +56,22 @@ func (m *MetricsMock) ReportRuleProcessed(ruleID string) {
 func (m *MetricsMock) ReportRuleAlert(ruleID string) {
 	m.RuleAlertCounter.Set(ruleID, m.RuleAlertCounter.Get(ruleID)+1)
 }
+
+func (m *MetricsMock) ReportProfileCompaction(category string, collapsed int, _ float64) {
+	m.CollapsedCounter.Set(category, m.CollapsedCounter.Get(category)+collapsed)
+}
+
+func (m *MetricsMock) ReportSbomQueue(queued, _ int, _ bool) {
+	m.SbomQueued.Store(int32(queued))
+}
+
+func (m *MetricsMock) ReportSbomProcessed(result string) {
+	m.SbomProcessedCounter.Set(result, m.SbomProcessedCounter.Get(result)+1)
+}
+
+func (m *MetricsMock) ReportYaraRejectedRules(rejected map[string][]string) {
+	m.YaraRejectedRules.Clear()
+	for source, rules := range rejected {
+		m.YaraRejectedRules.Set(source, rules)
+	}
+}
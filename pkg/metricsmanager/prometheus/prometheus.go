@@ -14,7 +14,11 @@ import (
 )
 
 const (
-	prometheusRuleIdLabel = "rule_id"
+	prometheusRuleIdLabel   = "rule_id"
+	prometheusCategoryLabel = "category"
+	prometheusResultLabel   = "result"
+	prometheusSourceLabel   = "source"
+	prometheusRuleLabel     = "rule"
 )
 
 var _ metricsmanager.MetricsManager = (*PrometheusMetric)(nil)
@@ -30,6 +34,13 @@ type PrometheusMetric struct {
 	ebpfFailedCounter     prometheus.Counter
 	ruleCounter           *prometheus.CounterVec
 	alertCounter          *prometheus.CounterVec
+	collapsedCounter      *prometheus.CounterVec
+	profileBudgetUsage    *prometheus.HistogramVec
+	sbomQueuedGauge       prometheus.Gauge
+	sbomRunningGauge      prometheus.Gauge
+	sbomPausedGauge       prometheus.Gauge
+	sbomProcessedCounter  *prometheus.CounterVec
+	yaraRejectedGauge     *prometheus.GaugeVec
 }
 
 func NewPrometheusMetric() *PrometheusMetric {
@@ -74,6 +85,35 @@ func NewPrometheusMetric() *PrometheusMetric {
 			Name: "node_agent_alert_counter",
 			Help: "The total number of alerts sent by the engine",
 		}, []string{prometheusRuleIdLabel}),
+		collapsedCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "node_agent_profile_collapsed_counter",
+			Help: "The total number of application profile entries collapsed to fit in the profile budget",
+		}, []string{prometheusCategoryLabel}),
+		profileBudgetUsage: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "node_agent_profile_budget_usage",
+			Help:    "The ratio of the profile budget used by a container when its profile is saved",
+			Buckets: []float64{0.1, 0.25, 0.5, 0.75, 0.9, 1},
+		}, []string{prometheusCategoryLabel}),
+		sbomQueuedGauge: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "node_agent_sbom_queued",
+			Help: "The number of images waiting for their SBOM",
+		}),
+		sbomRunningGauge: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "node_agent_sbom_running",
+			Help: "The number of SBOMs being generated",
+		}),
+		sbomPausedGauge: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "node_agent_sbom_paused",
+			Help: "Whether the SBOM generation is paused because of the node pressure or the memory budget",
+		}),
+		sbomProcessedCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "node_agent_sbom_processed_counter",
+			Help: "The total number of containers processed by the SBOM manager",
+		}, []string{prometheusResultLabel}),
+		yaraRejectedGauge: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "node_agent_yara_rejected_rules",
+			Help: "The YARA rules rejected because they use features outside of the supported subset",
+		}, []string{prometheusSourceLabel, prometheusRuleLabel}),
 	}
 }
 func (p *PrometheusMetric) Start() {
@@ -96,6 +136,13 @@ func (p *PrometheusMetric) Destroy() {
 	prometheus.Unregister(p.ebpfFailedCounter)
 	prometheus.Unregister(p.ruleCounter)
 	prometheus.Unregister(p.alertCounter)
+	prometheus.Unregister(p.collapsedCounter)
+	prometheus.Unregister(p.profileBudgetUsage)
+	prometheus.Unregister(p.sbomQueuedGauge)
+	prometheus.Unregister(p.sbomRunningGauge)
+	prometheus.Unregister(p.sbomPausedGauge)
+	prometheus.Unregister(p.sbomProcessedCounter)
+	prometheus.Unregister(p.yaraRejectedGauge)
 }
 
 func (p *PrometheusMetric) ReportEvent(eventType utils.EventType) {
@@ -128,3 +175,32 @@ func (p *PrometheusMetric) ReportRuleProcessed(ruleID string) {
 func (p *PrometheusMetric) ReportRuleAlert(ruleID string) {
 	p.alertCounter.With(prometheus.Labels{prometheusRuleIdLabel: ruleID}).Inc()
 }
+
+func (p *PrometheusMetric) ReportProfileCompaction(category string, collapsed int, usage float64) {
+	labels := prometheus.Labels{prometheusCategoryLabel: category}
+	p.collapsedCounter.With(labels).Add(float64(collapsed))
+	p.profileBudgetUsage.With(labels).Observe(usage)
+}
+
+func (p *PrometheusMetric) ReportSbomQueue(queued, running int, paused bool) {
+	p.sbomQueuedGauge.Set(float64(queued))
+	p.sbomRunningGauge.Set(float64(running))
+	if paused {
+		p.sbomPausedGauge.Set(1)
+	} else {
+		p.sbomPausedGauge.Set(0)
+	}
+}
+
+func (p *PrometheusMetric) ReportSbomProcessed(result string) {
+	p.sbomProcessedCounter.With(prometheus.Labels{prometheusResultLabel: result}).Inc()
+}
+
+func (p *PrometheusMetric) ReportYaraRejectedRules(rejected map[string][]string) {
+	p.yaraRejectedGauge.Reset()
+	for source, rules := range rejected {
+		for _, rule := range rules {
+			p.yaraRejectedGauge.With(prometheus.Labels{prometheusSourceLabel: source, prometheusRuleLabel: rule}).Set(1)
+		}
+	}
+}
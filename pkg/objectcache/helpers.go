@@ -84,3 +84,16 @@ func GetTerminationExitCode(k8sObjectsCache K8sObjectCache, namespace, podName,
 
 	return notFound
 }
+
+// GetParentExecPath returns the exec path of the parent process from the process tree, falling back to the parent
+// comm when the parent is unknown. The lineage is recorded and evaluated with the same resolution.
+func GetParentExecPath(processTreeCache ProcessTreeCache, containerID string, ppid uint32, pcomm string) string {
+	if tree, err := processTreeCache.GetProcessTreeForPID(containerID, int(ppid)); err == nil {
+		if parent := utils.GetProcessFromProcessTree(&tree, ppid); parent != nil {
+			if parentExecPath := utils.GetExecPathFromCmdline(parent.Cmdline); parentExecPath != "" {
+				return parentExecPath
+			}
+		}
+	}
+	return pcomm
+}
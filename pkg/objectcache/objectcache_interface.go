@@ -5,6 +5,8 @@ type ObjectCache interface {
 	ApplicationProfileCache() ApplicationProfileCache
 	NetworkNeighborhoodCache() NetworkNeighborhoodCache
 	DnsCache() DnsCache
+	ThreatIntelCache() ThreatIntelCache
+	ProcessTreeCache() ProcessTreeCache
 }
 
 var _ ObjectCache = (*ObjectCacheMock)(nil)
@@ -29,3 +31,11 @@ func (om *ObjectCacheMock) NetworkNeighborhoodCache() NetworkNeighborhoodCache {
 func (om *ObjectCacheMock) DnsCache() DnsCache {
 	return &DnsCacheMock{}
 }
+
+func (om *ObjectCacheMock) ThreatIntelCache() ThreatIntelCache {
+	return &ThreatIntelCacheMock{}
+}
+
+func (om *ObjectCacheMock) ProcessTreeCache() ProcessTreeCache {
+	return &ProcessTreeCacheMock{}
+}
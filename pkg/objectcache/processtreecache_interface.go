@@ -0,0 +1,21 @@
+package objectcache
+
+import (
+	"errors"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+)
+
+// ProcessTreeCache gives access to the process trees of the containers, maintained from the exec, fork and exit events
+type ProcessTreeCache interface {
+	GetProcessTreeForPID(containerID string, pid int) (apitypes.Process, error)
+}
+
+var _ ProcessTreeCache = (*ProcessTreeCacheMock)(nil)
+
+type ProcessTreeCacheMock struct {
+}
+
+func (pc *ProcessTreeCacheMock) GetProcessTreeForPID(_ string, _ int) (apitypes.Process, error) {
+	return apitypes.Process{}, errors.New("process not found")
+}
@@ -0,0 +1,173 @@
+package threatintelcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/objectcache"
+)
+
+// stixComparisonRegex matches the comparisons of a STIX pattern we know how to turn into indicators,
+// e.g. [ipv4-addr:value = '1.2.3.4'] or [file:hashes.'SHA-256' = '...']
+var stixComparisonRegex = regexp.MustCompile(`(ipv4-addr|ipv6-addr|domain-name|file):(value|hashes\.(?:'([^']+)'|([A-Za-z0-9-]+)))\s*(?:=|ISSUBSET)\s*'([^']*)'`)
+
+// stixObject is the subset of the STIX 2.1 indicator and cyber observable objects we use
+type stixObject struct {
+	Type        string            `json:"type"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Pattern     string            `json:"pattern"`
+	PatternType string            `json:"pattern_type"`
+	Revoked     bool              `json:"revoked"`
+	ValidUntil  string            `json:"valid_until"`
+	Value       string            `json:"value"`
+	Hashes      map[string]string `json:"hashes"`
+}
+
+// stixBundle is either a STIX bundle or a TAXII envelope, both carry the objects the same way
+type stixBundle struct {
+	Type    string       `json:"type"`
+	Objects []stixObject `json:"objects"`
+}
+
+// parseIndicators parses a STIX bundle (JSON) or a plain text list with one indicator per line
+func parseIndicators(data []byte, source string, indicators *indicatorSet) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseSTIXBundle(trimmed, source, indicators)
+	}
+	return parsePlainText(data, source, indicators)
+}
+
+// parsePlainText parses lines of "<indicator> [description]", comments start with #
+func parsePlainText(data []byte, source string, indicators *indicatorSet) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		value, description, _ := strings.Cut(strings.ReplaceAll(line, ",", " "), " ")
+		indicators.add(classifyIndicator(value), value, source, strings.TrimSpace(description))
+	}
+	return scanner.Err()
+}
+
+func parseSTIXBundle(data []byte, source string, indicators *indicatorSet) error {
+	var bundle stixBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("failed to parse STIX bundle: %w", err)
+	}
+	now := time.Now()
+	for _, object := range bundle.Objects {
+		switch object.Type {
+		case "indicator":
+			if object.Revoked || (object.PatternType != "" && object.PatternType != "stix") {
+				continue
+			}
+			if validUntil, err := time.Parse(time.RFC3339, object.ValidUntil); err == nil && validUntil.Before(now) {
+				continue
+			}
+			description := object.Name
+			if object.Description != "" {
+				description = strings.TrimSpace(object.Name + " " + object.Description)
+			}
+			for _, match := range stixComparisonRegex.FindAllStringSubmatch(object.Pattern, -1) {
+				indicatorType := stixIndicatorType(match[1], match[3]+match[4])
+				indicators.add(indicatorType, match[5], source, description)
+			}
+		case "ipv4-addr", "ipv6-addr", "domain-name":
+			indicators.add(stixIndicatorType(object.Type, ""), object.Value, source, object.Name)
+		case "file":
+			for algorithm, hash := range object.Hashes {
+				indicators.add(stixIndicatorType(object.Type, algorithm), hash, source, object.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// stixIndicatorType maps a STIX object type and hash algorithm to an indicator type
+func stixIndicatorType(objectType, hashAlgorithm string) objectcache.ThreatIndicatorType {
+	switch objectType {
+	case "ipv4-addr", "ipv6-addr":
+		return objectcache.ThreatIndicatorTypeIP
+	case "domain-name":
+		return objectcache.ThreatIndicatorTypeDomain
+	case "file":
+		switch strings.ToUpper(strings.ReplaceAll(hashAlgorithm, "-", "")) {
+		case "SHA256":
+			return objectcache.ThreatIndicatorTypeSHA256
+		case "SHA1":
+			return objectcache.ThreatIndicatorTypeSHA1
+		case "MD5":
+			return objectcache.ThreatIndicatorTypeMD5
+		}
+	}
+	return ""
+}
+
+// classifyIndicator guesses the type of a plain text indicator
+func classifyIndicator(value string) objectcache.ThreatIndicatorType {
+	if net.ParseIP(value) != nil {
+		return objectcache.ThreatIndicatorTypeIP
+	}
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return objectcache.ThreatIndicatorTypeCIDR
+	}
+	if _, err := hex.DecodeString(value); err == nil {
+		switch len(value) {
+		case 64:
+			return objectcache.ThreatIndicatorTypeSHA256
+		case 40:
+			return objectcache.ThreatIndicatorTypeSHA1
+		case 32:
+			return objectcache.ThreatIndicatorTypeMD5
+		}
+	}
+	if strings.Contains(value, ".") && !strings.ContainsAny(value, "/:@ ") {
+		return objectcache.ThreatIndicatorTypeDomain
+	}
+	return ""
+}
+
+// add stores an indicator, values not matching their type are ignored
+func (s *indicatorSet) add(indicatorType objectcache.ThreatIndicatorType, value, source, description string) {
+	indicator := objectcache.ThreatIndicator{
+		Type:        indicatorType,
+		Value:       value,
+		Source:      source,
+		Description: description,
+	}
+	switch indicatorType {
+	case objectcache.ThreatIndicatorTypeIP:
+		// STIX allows CIDRs as ipv4-addr values
+		if _, network, err := net.ParseCIDR(value); err == nil {
+			indicator.Type = objectcache.ThreatIndicatorTypeCIDR
+			s.cidrs = append(s.cidrs, cidrIndicator{network: network, indicator: indicator})
+		} else if ip := net.ParseIP(value); ip != nil {
+			s.ips[ip.String()] = indicator
+		}
+	case objectcache.ThreatIndicatorTypeCIDR:
+		if _, network, err := net.ParseCIDR(value); err == nil {
+			s.cidrs = append(s.cidrs, cidrIndicator{network: network, indicator: indicator})
+		}
+	case objectcache.ThreatIndicatorTypeDomain:
+		if domain := normalizeDomain(value); domain != "" {
+			s.domains[domain] = indicator
+		}
+	case objectcache.ThreatIndicatorTypeSHA256, objectcache.ThreatIndicatorTypeSHA1, objectcache.ThreatIndicatorTypeMD5:
+		s.hashes[strings.ToLower(value)] = indicator
+	}
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
@@ -0,0 +1,267 @@
+package threatintelcache
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/k8sclient"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ objectcache.ThreatIntelCache = (*ThreatIntelCacheImpl)(nil)
+
+type cidrIndicator struct {
+	network   *net.IPNet
+	indicator objectcache.ThreatIndicator
+}
+
+// indicatorSet holds all the indicators loaded during a reload, it is replaced as a whole
+type indicatorSet struct {
+	ips     map[string]objectcache.ThreatIndicator
+	cidrs   []cidrIndicator
+	domains map[string]objectcache.ThreatIndicator
+	hashes  map[string]objectcache.ThreatIndicator
+}
+
+func newIndicatorSet() *indicatorSet {
+	return &indicatorSet{
+		ips:     make(map[string]objectcache.ThreatIndicator),
+		domains: make(map[string]objectcache.ThreatIndicator),
+		hashes:  make(map[string]objectcache.ThreatIndicator),
+	}
+}
+
+func (s *indicatorSet) len() int {
+	return len(s.ips) + len(s.cidrs) + len(s.domains) + len(s.hashes)
+}
+
+// merge adds the indicators of another set, the indicators already in the set are replaced
+func (s *indicatorSet) merge(other *indicatorSet) {
+	for ip, indicator := range other.ips {
+		s.ips[ip] = indicator
+	}
+	s.cidrs = append(s.cidrs, other.cidrs...)
+	for domain, indicator := range other.domains {
+		s.domains[domain] = indicator
+	}
+	for hash, indicator := range other.hashes {
+		s.hashes[hash] = indicator
+	}
+}
+
+type ThreatIntelCacheImpl struct {
+	mutex          sync.RWMutex
+	indicators     *indicatorSet
+	sources        map[string]*indicatorSet // key is the source, "file:<path>" or "configmap:<name>/<key>"
+	paths          []string
+	configMaps     []string
+	namespace      string
+	k8sClient      k8sclient.K8sClientInterface
+	reloadInterval time.Duration
+}
+
+func NewThreatIntelCache(paths, configMaps []string, namespace string, k8sClient k8sclient.K8sClientInterface, reloadInterval time.Duration) *ThreatIntelCacheImpl {
+	return &ThreatIntelCacheImpl{
+		indicators:     newIndicatorSet(),
+		sources:        make(map[string]*indicatorSet),
+		paths:          paths,
+		configMaps:     configMaps,
+		namespace:      namespace,
+		k8sClient:      k8sClient,
+		reloadInterval: reloadInterval,
+	}
+}
+
+// Start loads the indicators and reloads them periodically until the context is done
+func (tc *ThreatIntelCacheImpl) Start(ctx context.Context) {
+	tc.Reload(ctx)
+	if tc.reloadInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(tc.reloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				tc.Reload(ctx)
+			}
+		}
+	}()
+}
+
+// Reload loads the indicators from all the sources, a failing source keeps the indicators of its previous load
+func (tc *ThreatIntelCacheImpl) Reload(ctx context.Context) {
+	tc.mutex.RLock()
+	previous := tc.sources
+	tc.mutex.RUnlock()
+
+	sources := make(map[string]*indicatorSet)
+	for _, path := range tc.paths {
+		if err := loadPath(path, previous, sources); err != nil {
+			logger.L().Warning("ThreatIntelCache - failed to load indicators", helpers.Error(err), helpers.String("path", path))
+			keepPrevious(previous, sources, "file:"+path)
+		}
+	}
+	for _, name := range tc.configMaps {
+		if err := tc.loadConfigMap(ctx, name, previous, sources); err != nil {
+			logger.L().Warning("ThreatIntelCache - failed to load indicators", helpers.Error(err), helpers.String("configMap", name))
+			keepPrevious(previous, sources, "configmap:"+name)
+		}
+	}
+
+	// merge in a stable order, an indicator listed by several sources is attributed to the last one
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	indicators := newIndicatorSet()
+	for _, name := range names {
+		indicators.merge(sources[name])
+	}
+
+	tc.mutex.Lock()
+	tc.indicators = indicators
+	tc.sources = sources
+	tc.mutex.Unlock()
+	logger.L().Info("ThreatIntelCache - loaded indicators",
+		helpers.Int("total", indicators.len()),
+		helpers.Int("ips", len(indicators.ips)),
+		helpers.Int("cidrs", len(indicators.cidrs)),
+		helpers.Int("domains", len(indicators.domains)),
+		helpers.Int("hashes", len(indicators.hashes)))
+}
+
+func (tc *ThreatIntelCacheImpl) LookupIP(ip string) (objectcache.ThreatIndicator, bool) {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return objectcache.ThreatIndicator{}, false
+	}
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+	if indicator, ok := tc.indicators.ips[parsedIP.String()]; ok {
+		return indicator, true
+	}
+	for _, cidr := range tc.indicators.cidrs {
+		if cidr.network.Contains(parsedIP) {
+			return cidr.indicator, true
+		}
+	}
+	return objectcache.ThreatIndicator{}, false
+}
+
+func (tc *ThreatIntelCacheImpl) LookupDomain(domain string) (objectcache.ThreatIndicator, bool) {
+	domain = normalizeDomain(domain)
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+	for domain != "" {
+		if indicator, ok := tc.indicators.domains[domain]; ok {
+			return indicator, true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return objectcache.ThreatIndicator{}, false
+}
+
+func (tc *ThreatIntelCacheImpl) LookupHash(hash string) (objectcache.ThreatIndicator, bool) {
+	if hash == "" {
+		return objectcache.ThreatIndicator{}, false
+	}
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+	indicator, ok := tc.indicators.hashes[strings.ToLower(hash)]
+	return indicator, ok
+}
+
+func (tc *ThreatIntelCacheImpl) HasHashes() bool {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+	return len(tc.indicators.hashes) > 0
+}
+
+// keepPrevious keeps the indicators previously loaded from a source, or from the sources under it
+func keepPrevious(previous, sources map[string]*indicatorSet, source string) {
+	for name, indicators := range previous {
+		if name == source || strings.HasPrefix(name, source+"/") {
+			sources[name] = indicators
+		}
+	}
+}
+
+// parseSource parses the indicators of a source, on failure the indicators previously loaded from it are kept
+func parseSource(data []byte, source string, previous, sources map[string]*indicatorSet) error {
+	indicators := newIndicatorSet()
+	if err := parseIndicators(data, source, indicators); err != nil {
+		if indicators, ok := previous[source]; ok {
+			sources[source] = indicators
+		}
+		return err
+	}
+	sources[source] = indicators
+	return nil
+}
+
+// loadPath loads a single feed file or all the feed files of a directory
+func loadPath(path string, previous, sources map[string]*indicatorSet) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return loadFile(path, previous, sources)
+	}
+	return utils.WalkFiles(path, func(filePath string, _ fs.DirEntry) error {
+		if err := loadFile(filePath, previous, sources); err != nil {
+			logger.L().Warning("ThreatIntelCache - failed to load indicators", helpers.Error(err), helpers.String("path", filePath))
+			keepPrevious(previous, sources, "file:"+filePath)
+		}
+		return nil
+	})
+}
+
+func loadFile(path string, previous, sources map[string]*indicatorSet) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return parseSource(data, "file:"+path, previous, sources)
+}
+
+func (tc *ThreatIntelCacheImpl) loadConfigMap(ctx context.Context, name string, previous, sources map[string]*indicatorSet) error {
+	if tc.k8sClient == nil {
+		return fmt.Errorf("kubernetes client is not set")
+	}
+	configMap, err := tc.k8sClient.GetKubernetesClient().CoreV1().ConfigMaps(tc.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for key, data := range configMap.Data {
+		if err := parseSource([]byte(data), fmt.Sprintf("configmap:%s/%s", name, key), previous, sources); err != nil {
+			logger.L().Warning("ThreatIntelCache - failed to parse indicators", helpers.Error(err), helpers.String("configMap", name), helpers.String("key", key))
+		}
+	}
+	for key, data := range configMap.BinaryData {
+		if err := parseSource(data, fmt.Sprintf("configmap:%s/%s", name, key), previous, sources); err != nil {
+			logger.L().Warning("ThreatIntelCache - failed to parse indicators", helpers.Error(err), helpers.String("configMap", name), helpers.String("key", key))
+		}
+	}
+	return nil
+}
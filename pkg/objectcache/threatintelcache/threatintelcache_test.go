@@ -0,0 +1,143 @@
+package threatintelcache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubescape/node-agent/pkg/k8sclient"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const plainTextFeed = `# test feed
+203.0.113.7 known c2 server
+198.51.100.0/24
+evil.example.com phishing
+d41d8cd98f00b204e9800998ecf8427e
+`
+
+const stixFeed = `{
+  "type": "bundle",
+  "id": "bundle--1",
+  "objects": [
+    {
+      "type": "indicator",
+      "name": "miner pool",
+      "pattern": "[domain-name:value = 'pool.example.net'] OR [ipv4-addr:value = '192.0.2.10']",
+      "pattern_type": "stix"
+    },
+    {
+      "type": "indicator",
+      "name": "dropper",
+      "pattern": "[file:hashes.'SHA-256' = 'E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855']",
+      "pattern_type": "stix"
+    },
+    {
+      "type": "indicator",
+      "name": "revoked",
+      "pattern": "[domain-name:value = 'revoked.example.org']",
+      "pattern_type": "stix",
+      "revoked": true
+    },
+    {
+      "type": "indicator",
+      "name": "expired",
+      "pattern": "[domain-name:value = 'expired.example.org']",
+      "pattern_type": "stix",
+      "valid_until": "2000-01-01T00:00:00Z"
+    },
+    {
+      "type": "ipv6-addr",
+      "value": "2001:db8::1"
+    }
+  ]
+}`
+
+func TestThreatIntelCacheFiles(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "feed.txt"), []byte(plainTextFeed), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "bundle.json"), []byte(stixFeed), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("10.0.0.1"), 0644))
+
+	tc := NewThreatIntelCache([]string{dir}, nil, "", nil, 0)
+	assert.False(t, tc.HasHashes())
+	tc.Start(context.Background())
+	assert.True(t, tc.HasHashes())
+
+	tests := []struct {
+		name   string
+		lookup func(string) (objectcache.ThreatIndicator, bool)
+		value  string
+		found  bool
+	}{
+		{"exact ip", tc.LookupIP, "203.0.113.7", true},
+		{"ip in cidr", tc.LookupIP, "198.51.100.42", true},
+		{"unknown ip", tc.LookupIP, "10.0.0.1", false},
+		{"invalid ip", tc.LookupIP, "not-an-ip", false},
+		{"stix ipv4", tc.LookupIP, "192.0.2.10", true},
+		{"stix ipv6 observable", tc.LookupIP, "2001:db8:0::1", true},
+		{"exact domain", tc.LookupDomain, "evil.example.com.", true},
+		{"subdomain", tc.LookupDomain, "a.b.EVIL.example.com", true},
+		{"parent domain", tc.LookupDomain, "example.com", false},
+		{"stix domain", tc.LookupDomain, "pool.example.net.", true},
+		{"revoked domain", tc.LookupDomain, "revoked.example.org", false},
+		{"expired domain", tc.LookupDomain, "expired.example.org", false},
+		{"md5", tc.LookupHash, "D41D8CD98F00B204E9800998ECF8427E", true},
+		{"stix sha256", tc.LookupHash, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", true},
+		{"empty hash", tc.LookupHash, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, found := tt.lookup(tt.value)
+			assert.Equal(t, tt.found, found)
+		})
+	}
+
+	indicator, _ := tc.LookupIP("203.0.113.7")
+	assert.Equal(t, "known c2 server", indicator.Description)
+	assert.Equal(t, "file:"+filepath.Join(dir, "feed.txt"), indicator.Source)
+
+	// indicators removed from the feed are gone after a reload
+	assert.NoError(t, os.Remove(filepath.Join(dir, "feed.txt")))
+	tc.Reload(context.Background())
+	_, found := tc.LookupIP("203.0.113.7")
+	assert.False(t, found)
+	_, found = tc.LookupDomain("pool.example.net")
+	assert.True(t, found)
+
+	// a feed failing to parse keeps the indicators of its previous load
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "bundle.json"), []byte(`{"type": "bundle",`), 0644))
+	tc.Reload(context.Background())
+	_, found = tc.LookupDomain("pool.example.net")
+	assert.True(t, found)
+}
+
+func TestThreatIntelCacheConfigMap(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "threat-intel", Namespace: "kubescape"},
+		Data:       map[string]string{"feed.txt": plainTextFeed},
+		BinaryData: map[string][]byte{"bundle.json": []byte(stixFeed)},
+	}
+	k8sClient := k8sclient.NewK8sClientMock(configMap)
+
+	tc := NewThreatIntelCache(nil, []string{"threat-intel", "missing"}, "kubescape", k8sClient, 0)
+	tc.Reload(context.Background())
+
+	indicator, found := tc.LookupDomain("evil.example.com")
+	assert.True(t, found)
+	assert.Equal(t, "configmap:threat-intel/feed.txt", indicator.Source)
+	_, found = tc.LookupIP("192.0.2.10")
+	assert.True(t, found)
+
+	// a ConfigMap failing to load keeps the indicators of its previous load
+	tc.k8sClient = nil
+	tc.Reload(context.Background())
+	_, found = tc.LookupDomain("evil.example.com")
+	assert.True(t, found)
+	_, found = tc.LookupIP("192.0.2.10")
+	assert.True(t, found)
+}
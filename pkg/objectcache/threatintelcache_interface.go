@@ -0,0 +1,52 @@
+package objectcache
+
+type ThreatIndicatorType string
+
+const (
+	ThreatIndicatorTypeIP     ThreatIndicatorType = "ip"
+	ThreatIndicatorTypeCIDR   ThreatIndicatorType = "cidr"
+	ThreatIndicatorTypeDomain ThreatIndicatorType = "domain"
+	ThreatIndicatorTypeSHA256 ThreatIndicatorType = "sha256"
+	ThreatIndicatorTypeSHA1   ThreatIndicatorType = "sha1"
+	ThreatIndicatorTypeMD5    ThreatIndicatorType = "md5"
+)
+
+// ThreatIndicator is an indicator of compromise loaded from a threat intel feed
+type ThreatIndicator struct {
+	Type        ThreatIndicatorType
+	Value       string
+	Source      string
+	Description string
+}
+
+type ThreatIntelCache interface {
+	// LookupIP returns the indicator matching the IP address, either directly or through a CIDR
+	LookupIP(ip string) (ThreatIndicator, bool)
+	// LookupDomain returns the indicator matching the domain or one of its parent domains
+	LookupDomain(domain string) (ThreatIndicator, bool)
+	// LookupHash returns the indicator matching the SHA256, SHA1 or MD5 hash
+	LookupHash(hash string) (ThreatIndicator, bool)
+	// HasHashes reports if hash indicators are loaded, the files are not hashed otherwise
+	HasHashes() bool
+}
+
+var _ ThreatIntelCache = (*ThreatIntelCacheMock)(nil)
+
+type ThreatIntelCacheMock struct {
+}
+
+func (tc *ThreatIntelCacheMock) LookupIP(_ string) (ThreatIndicator, bool) {
+	return ThreatIndicator{}, false
+}
+
+func (tc *ThreatIntelCacheMock) LookupDomain(_ string) (ThreatIndicator, bool) {
+	return ThreatIndicator{}, false
+}
+
+func (tc *ThreatIntelCacheMock) LookupHash(_ string) (ThreatIndicator, bool) {
+	return ThreatIndicator{}, false
+}
+
+func (tc *ThreatIntelCacheMock) HasHashes() bool {
+	return false
+}
@@ -11,14 +11,18 @@ type ObjectCacheImpl struct {
 	ap objectcache.ApplicationProfileCache
 	np objectcache.NetworkNeighborhoodCache
 	dc objectcache.DnsCache
+	tc objectcache.ThreatIntelCache
+	pt objectcache.ProcessTreeCache
 }
 
-func NewObjectCache(k objectcache.K8sObjectCache, ap objectcache.ApplicationProfileCache, np objectcache.NetworkNeighborhoodCache, dc objectcache.DnsCache) *ObjectCacheImpl {
+func NewObjectCache(k objectcache.K8sObjectCache, ap objectcache.ApplicationProfileCache, np objectcache.NetworkNeighborhoodCache, dc objectcache.DnsCache, tc objectcache.ThreatIntelCache, pt objectcache.ProcessTreeCache) *ObjectCacheImpl {
 	return &ObjectCacheImpl{
 		k:  k,
 		ap: ap,
 		np: np,
 		dc: dc,
+		tc: tc,
+		pt: pt,
 	}
 }
 
@@ -36,3 +40,11 @@ func (o *ObjectCacheImpl) NetworkNeighborhoodCache() objectcache.NetworkNeighbor
 func (o *ObjectCacheImpl) DnsCache() objectcache.DnsCache {
 	return o.dc
 }
+
+func (o *ObjectCacheImpl) ThreatIntelCache() objectcache.ThreatIntelCache {
+	return o.tc
+}
+
+func (o *ObjectCacheImpl) ProcessTreeCache() objectcache.ProcessTreeCache {
+	return o.pt
+}
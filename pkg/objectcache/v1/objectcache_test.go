@@ -10,18 +10,30 @@ import (
 
 func TestK8sObjectCache(t *testing.T) {
 	k := &objectcache.K8sObjectCacheMock{}
-	k8sObjectCache := NewObjectCache(k, nil, nil, nil)
+	k8sObjectCache := NewObjectCache(k, nil, nil, nil, nil, nil)
 	assert.NotNil(t, k8sObjectCache.K8sObjectCache())
 }
 
 func TestApplicationProfileCache(t *testing.T) {
 	ap := &objectcache.ApplicationProfileCacheMock{}
-	k8sObjectCache := NewObjectCache(nil, ap, nil, nil)
+	k8sObjectCache := NewObjectCache(nil, ap, nil, nil, nil, nil)
 	assert.NotNil(t, k8sObjectCache.ApplicationProfileCache())
 }
 
 func TestNetworkNeighborhoodCache(t *testing.T) {
 	nn := &objectcache.NetworkNeighborhoodCacheMock{}
-	k8sObjectCache := NewObjectCache(nil, nil, nn, nil)
+	k8sObjectCache := NewObjectCache(nil, nil, nn, nil, nil, nil)
 	assert.NotNil(t, k8sObjectCache.NetworkNeighborhoodCache())
 }
+
+func TestThreatIntelCache(t *testing.T) {
+	tc := &objectcache.ThreatIntelCacheMock{}
+	k8sObjectCache := NewObjectCache(nil, nil, nil, nil, tc, nil)
+	assert.NotNil(t, k8sObjectCache.ThreatIntelCache())
+}
+
+func TestProcessTreeCache(t *testing.T) {
+	pt := &objectcache.ProcessTreeCacheMock{}
+	k8sObjectCache := NewObjectCache(nil, nil, nil, nil, nil, pt)
+	assert.NotNil(t, k8sObjectCache.ProcessTreeCache())
+}
@@ -0,0 +1,65 @@
+package processmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+// HandlerPath is the path prefix of the read-only process API, served on the local API socket:
+// GET /processes/ lists the tracked containers,
+// GET /processes/<container ID> returns the current process tree of the container,
+// GET /processes/<container ID>/<PID> returns the ancestry of a process,
+// GET /processes/<container ID>/execs returns the recent execs of the container.
+const HandlerPath = "/processes/"
+
+const execsPath = "execs"
+
+// Handler serves the process API from the process manager
+type Handler struct {
+	client ProcessManagerClient
+}
+
+func NewHandler(client ProcessManagerClient) *Handler {
+	return &Handler{client: client}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	containerID, resource, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, HandlerPath), "/")
+
+	var response interface{}
+	var err error
+	switch {
+	case containerID == "":
+		response = h.client.ListContainers()
+	case resource == "":
+		response, err = h.client.GetContainerProcesses(containerID)
+	case resource == execsPath:
+		response, err = h.client.GetRecentExecs(containerID)
+	default:
+		pid, parseErr := strconv.ParseUint(resource, 10, 32)
+		if parseErr != nil || pid == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		response, err = h.client.GetProcessAncestry(containerID, int(pid))
+	}
+	if err != nil {
+		// the container is not tracked, or the process is gone and not retained
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.L().Debug("ProcessManager - failed to write response", helpers.Error(err), helpers.String("path", r.URL.Path))
+	}
+}
@@ -0,0 +1,122 @@
+package processmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type processManagerStub struct {
+	ProcessManagerMock
+}
+
+func (p *processManagerStub) GetProcessAncestry(containerID string, pid int) (apitypes.Process, error) {
+	if containerID != "abc" {
+		return apitypes.Process{}, ErrContainerNotFound
+	}
+	if pid != 20 {
+		return apitypes.Process{}, fmt.Errorf("process %d not found", pid)
+	}
+	return apitypes.Process{PID: 10, Comm: "sh", Children: []apitypes.Process{{PID: 20, PPID: 10, Comm: "curl"}}}, nil
+}
+
+func (p *processManagerStub) GetContainerProcesses(containerID string) ([]apitypes.Process, error) {
+	if containerID != "abc" {
+		return nil, ErrContainerNotFound
+	}
+	return []apitypes.Process{{PID: 10, Comm: "sh"}}, nil
+}
+
+func (p *processManagerStub) GetRecentExecs(containerID string) ([]ExecRecord, error) {
+	if containerID != "abc" {
+		return nil, ErrContainerNotFound
+	}
+	return []ExecRecord{{PID: 20, Comm: "curl"}}, nil
+}
+
+func (p *processManagerStub) ListContainers() []ContainerInfo {
+	return []ContainerInfo{{ContainerID: "abc", PodName: "pod", Namespace: "default"}}
+}
+
+func TestHandler(t *testing.T) {
+	handler := NewHandler(&processManagerStub{})
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		want       interface{}
+		got        interface{}
+	}{
+		{
+			name:       "list containers",
+			path:       "/processes/",
+			wantStatus: http.StatusOK,
+			want:       &[]ContainerInfo{{ContainerID: "abc", PodName: "pod", Namespace: "default"}},
+			got:        &[]ContainerInfo{},
+		},
+		{
+			name:       "container process tree",
+			path:       "/processes/abc",
+			wantStatus: http.StatusOK,
+			want:       &[]apitypes.Process{{PID: 10, Comm: "sh"}},
+			got:        &[]apitypes.Process{},
+		},
+		{
+			name:       "process ancestry",
+			path:       "/processes/abc/20",
+			wantStatus: http.StatusOK,
+			want:       &apitypes.Process{PID: 10, Comm: "sh", Children: []apitypes.Process{{PID: 20, PPID: 10, Comm: "curl"}}},
+			got:        &apitypes.Process{},
+		},
+		{
+			name:       "recent execs",
+			path:       "/processes/abc/execs",
+			wantStatus: http.StatusOK,
+			want:       &[]ExecRecord{{PID: 20, Comm: "curl"}},
+			got:        &[]ExecRecord{},
+		},
+		{
+			name:       "unknown container",
+			path:       "/processes/def",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unknown process",
+			path:       "/processes/abc/30",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid PID",
+			path:       "/processes/abc/sh",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "read-only",
+			method:     http.MethodDelete,
+			path:       "/processes/abc",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(method, tt.path, nil))
+			require.Equal(t, tt.wantStatus, recorder.Code)
+			if tt.want != nil {
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), tt.got))
+				assert.Equal(t, tt.want, tt.got)
+			}
+		})
+	}
+}
@@ -1,16 +1,29 @@
 package processmanager
 
 import (
+	"errors"
+	"time"
+
 	apitypes "github.com/armosec/armoapi-go/armotypes"
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
 	"github.com/kubescape/node-agent/pkg/utils"
 )
 
+var ErrContainerNotFound = errors.New("container not found")
+
 // ProcessManagerClient is the interface for the process manager client.
 // It provides methods to get process tree for a container or a PID.
 // The manager is responsible for maintaining the process tree for all containers.
 type ProcessManagerClient interface {
 	GetProcessTreeForPID(containerID string, pid int) (apitypes.Process, error)
+	// GetProcessAncestry returns the process and its ancestors without fetching the unknown processes from /proc.
+	GetProcessAncestry(containerID string, pid int) (apitypes.Process, error)
+	// GetContainerProcesses returns the current process tree of the container, from its top-level processes.
+	GetContainerProcesses(containerID string) ([]apitypes.Process, error)
+	// GetRecentExecs returns the latest execs of the container, oldest first.
+	GetRecentExecs(containerID string) ([]ExecRecord, error)
+	// ListContainers returns the containers whose processes are tracked.
+	ListContainers() []ContainerInfo
 	// PopulateInitialProcesses is called to populate the initial process tree (parsed from /proc) for all containers.
 	PopulateInitialProcesses() error
 
@@ -18,3 +31,24 @@ type ProcessManagerClient interface {
 	ReportEvent(eventType utils.EventType, event utils.K8sEvent)
 	ContainerCallback(notif containercollection.PubSubEvent)
 }
+
+// ContainerInfo identifies a container whose processes are tracked
+type ContainerInfo struct {
+	ContainerID   string `json:"containerID"`
+	ContainerName string `json:"containerName,omitempty"`
+	PodName       string `json:"podName,omitempty"`
+	Namespace     string `json:"namespace,omitempty"`
+	ContainerPID  uint32 `json:"containerPID"`
+}
+
+// ExecRecord is an exec of a container process
+type ExecRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	PID       uint32    `json:"pid"`
+	PPID      uint32    `json:"ppid"`
+	Comm      string    `json:"comm"`
+	Path      string    `json:"path,omitempty"`
+	Cmdline   string    `json:"cmdline,omitempty"`
+	Uid       uint32    `json:"uid"`
+	Gid       uint32    `json:"gid"`
+}
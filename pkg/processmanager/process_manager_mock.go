@@ -19,6 +19,22 @@ func (p *ProcessManagerMock) GetProcessTreeForPID(containerID string, pid int) (
 	return apitypes.Process{}, nil
 }
 
+func (p *ProcessManagerMock) GetProcessAncestry(containerID string, pid int) (apitypes.Process, error) {
+	return apitypes.Process{}, ErrContainerNotFound
+}
+
+func (p *ProcessManagerMock) GetContainerProcesses(containerID string) ([]apitypes.Process, error) {
+	return nil, ErrContainerNotFound
+}
+
+func (p *ProcessManagerMock) GetRecentExecs(containerID string) ([]ExecRecord, error) {
+	return nil, ErrContainerNotFound
+}
+
+func (p *ProcessManagerMock) ListContainers() []ContainerInfo {
+	return nil
+}
+
 func (p *ProcessManagerMock) PopulateInitialProcesses() error {
 	return nil
 }
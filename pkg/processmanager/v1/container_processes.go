@@ -0,0 +1,125 @@
+package processmanager
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	"github.com/kubescape/node-agent/pkg/processmanager"
+)
+
+const maxRecentExecs = 100
+
+// execHistory keeps the latest execs of a container
+type execHistory struct {
+	mutex   sync.Mutex
+	records []processmanager.ExecRecord
+}
+
+func (h *execHistory) add(record processmanager.ExecRecord) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if len(h.records) >= maxRecentExecs {
+		h.records = h.records[1:]
+	}
+	h.records = append(h.records, record)
+}
+
+func (h *execHistory) list() []processmanager.ExecRecord {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]processmanager.ExecRecord(nil), h.records...)
+}
+
+// GetContainerProcesses returns the current process tree of the container. The top-level processes are
+// the children of the shim in the mount namespace of the container, the shim may be shared by a pod.
+// The orphans of exited processes are attached to their nearest living ancestor.
+func (p *ProcessManager) GetContainerProcesses(containerID string) ([]apitypes.Process, error) {
+	shimPID, exists := p.containerIdToShimPid.Load(containerID)
+	if !exists {
+		return nil, fmt.Errorf("container ID %s: %w", containerID, processmanager.ErrContainerNotFound)
+	}
+	containerPID := p.containers.Get(containerID).ContainerPID
+
+	children := make(map[uint32][]apitypes.Process)
+	p.processTree.Range(func(_ uint32, process apitypes.Process) bool {
+		if ppid, found := p.livingParent(process.PPID, shimPID); found {
+			children[ppid] = append(children[ppid], process)
+		}
+		return true
+	})
+
+	var roots []apitypes.Process
+	for _, process := range children[shimPID] {
+		if process.PID == containerPID || sameMountNamespace(process.PID, containerPID) {
+			roots = append(roots, process)
+		}
+	}
+	return buildProcessTree(roots, children, 0), nil
+}
+
+// livingParent resolves the parent of a process in the process tree, through the exited processes
+func (p *ProcessManager) livingParent(ppid, shimPID uint32) (uint32, bool) {
+	for depth := 0; depth < maxTreeDepth && ppid != 0; depth++ {
+		if ppid == shimPID || p.processTree.Has(ppid) {
+			return ppid, true
+		}
+		exited, found := p.exited.find(ppid, 0)
+		if !found {
+			return 0, false
+		}
+		ppid = exited.process.PPID
+	}
+	return 0, false
+}
+
+func buildProcessTree(processes []apitypes.Process, children map[uint32][]apitypes.Process, depth int) []apitypes.Process {
+	if depth >= maxTreeDepth || len(processes) == 0 {
+		return nil
+	}
+	tree := make([]apitypes.Process, 0, len(processes))
+	for _, process := range processes {
+		process.Children = buildProcessTree(children[process.PID], children, depth+1)
+		tree = append(tree, process)
+	}
+	sort.Slice(tree, func(i, j int) bool {
+		return tree[i].PID < tree[j].PID
+	})
+	return tree
+}
+
+// sameMountNamespace checks if both processes are in the same mount namespace
+func sameMountNamespace(pid, otherPID uint32) bool {
+	mntns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/mnt", pid))
+	if err != nil {
+		return false
+	}
+	otherMntns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/mnt", otherPID))
+	return err == nil && mntns == otherMntns
+}
+
+// GetRecentExecs returns the latest execs of the container, oldest first
+func (p *ProcessManager) GetRecentExecs(containerID string) ([]processmanager.ExecRecord, error) {
+	history, exists := p.recentExecs.Load(containerID)
+	if !exists {
+		return nil, fmt.Errorf("container ID %s: %w", containerID, processmanager.ErrContainerNotFound)
+	}
+	return history.list(), nil
+}
+
+// ListContainers returns the containers whose processes are tracked, sorted by namespace, pod and container name
+func (p *ProcessManager) ListContainers() []processmanager.ContainerInfo {
+	containers := p.containers.Values()
+	sort.Slice(containers, func(i, j int) bool {
+		if containers[i].Namespace != containers[j].Namespace {
+			return containers[i].Namespace < containers[j].Namespace
+		}
+		if containers[i].PodName != containers[j].PodName {
+			return containers[i].PodName < containers[j].PodName
+		}
+		return containers[i].ContainerName < containers[j].ContainerName
+	})
+	return containers
+}
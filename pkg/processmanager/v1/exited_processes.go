@@ -0,0 +1,107 @@
+package processmanager
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+)
+
+const maxExitedProcesses = 10000
+
+// processKey identifies a process, the PIDs are reused by the kernel so they are qualified by the
+// start time in clock ticks after boot. A zero start time is unknown.
+type processKey struct {
+	PID       uint32
+	StartTime uint64
+}
+
+type exitedProcess struct {
+	process   apitypes.Process
+	startTime uint64
+	exitTime  time.Time
+}
+
+// exitedProcesses retains the exited processes during the retention window, to resolve the
+// ancestry of the processes whose parent already exited. The zero value retains nothing.
+type exitedProcesses struct {
+	mutex     sync.RWMutex
+	retention time.Duration
+	processes map[processKey]exitedProcess
+	byPID     map[uint32][]uint64 // start times of the exited processes, sorted
+	order     []processKey        // oldest exit first
+}
+
+// add retains the exited process, nothing is retained without a retention window
+func (e *exitedProcesses) add(process exitedProcess) {
+	if e.retention <= 0 {
+		return
+	}
+	process.process.Children = nil
+	key := processKey{PID: process.process.PID, StartTime: process.startTime}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.processes == nil {
+		e.processes = make(map[processKey]exitedProcess)
+		e.byPID = make(map[uint32][]uint64)
+	}
+	if _, exists := e.processes[key]; !exists {
+		startTimes := e.byPID[key.PID]
+		i := sort.Search(len(startTimes), func(i int) bool { return startTimes[i] >= key.StartTime })
+		e.byPID[key.PID] = append(startTimes[:i], append([]uint64{key.StartTime}, startTimes[i:]...)...)
+		e.order = append(e.order, key)
+	}
+	e.processes[key] = process
+	for len(e.order) > maxExitedProcesses {
+		e.removeOldest()
+	}
+}
+
+// find returns the latest exited process with the PID started at or before the given start time
+func (e *exitedProcesses) find(pid uint32, startedBefore uint64) (exitedProcess, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	startTimes := e.byPID[pid]
+	for i := len(startTimes) - 1; i >= 0; i-- {
+		if startTimes[i] <= startedBefore || startTimes[i] == 0 || startedBefore == 0 {
+			return e.processes[processKey{PID: pid, StartTime: startTimes[i]}], true
+		}
+	}
+	return exitedProcess{}, false
+}
+
+// prune removes the processes exited before the retention window
+func (e *exitedProcesses) prune(now time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for len(e.order) > 0 && now.Sub(e.processes[e.order[0]].exitTime) > e.retention {
+		e.removeOldest()
+	}
+}
+
+// removeOldest removes the first exited process, the caller must hold the lock
+func (e *exitedProcesses) removeOldest() {
+	key := e.order[0]
+	e.order = e.order[1:]
+	delete(e.processes, key)
+	startTimes := e.byPID[key.PID]
+	for i, startTime := range startTimes {
+		if startTime == key.StartTime {
+			startTimes = append(startTimes[:i], startTimes[i+1:]...)
+			break
+		}
+	}
+	if len(startTimes) == 0 {
+		delete(e.byPID, key.PID)
+	} else {
+		e.byPID[key.PID] = startTimes
+	}
+}
+
+func (e *exitedProcesses) len() int {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return len(e.processes)
+}
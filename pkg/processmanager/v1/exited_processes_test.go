@@ -0,0 +1,49 @@
+package processmanager
+
+import (
+	"testing"
+	"time"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitedProcesses(t *testing.T) {
+	now := time.Now()
+	e := exitedProcesses{retention: time.Minute}
+	e.add(exitedProcess{process: apitypes.Process{PID: 10, Comm: "first"}, startTime: 100, exitTime: now})
+	e.add(exitedProcess{process: apitypes.Process{PID: 10, Comm: "second"}, startTime: 200, exitTime: now.Add(time.Minute)})
+
+	process, found := e.find(10, 150)
+	require.True(t, found)
+	assert.Equal(t, "first", process.process.Comm)
+
+	process, found = e.find(10, 0)
+	require.True(t, found)
+	assert.Equal(t, "second", process.process.Comm, "the latest process is returned without a start time")
+
+	_, found = e.find(10, 50)
+	assert.False(t, found, "no process with the PID was started before")
+
+	e.prune(now.Add(90 * time.Second))
+	_, found = e.find(10, 150)
+	assert.False(t, found)
+	assert.Equal(t, 1, e.len())
+}
+
+func TestExitedProcessesLimits(t *testing.T) {
+	disabled := exitedProcesses{}
+	disabled.add(exitedProcess{process: apitypes.Process{PID: 10}, exitTime: time.Now()})
+	assert.Equal(t, 0, disabled.len(), "nothing is retained without a retention window")
+
+	e := exitedProcesses{retention: time.Hour}
+	for pid := uint32(1); pid <= maxExitedProcesses+10; pid++ {
+		e.add(exitedProcess{process: apitypes.Process{PID: pid}, startTime: 1, exitTime: time.Now()})
+	}
+	assert.Equal(t, maxExitedProcesses, e.len())
+	_, found := e.find(1, 0)
+	assert.False(t, found, "the oldest exited processes are removed first")
+	_, found = e.find(maxExitedProcesses+10, 0)
+	assert.True(t, found)
+}
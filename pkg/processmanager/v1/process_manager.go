@@ -14,6 +14,10 @@ import (
 	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
 	"github.com/kubescape/go-logger"
 	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/ebpf/events"
+	tracerprocesstype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/process/tracer/types"
+	"github.com/kubescape/node-agent/pkg/processmanager"
 	"github.com/kubescape/node-agent/pkg/utils"
 )
 
@@ -26,13 +30,22 @@ const (
 type ProcessManager struct {
 	containerIdToShimPid maps.SafeMap[string, uint32]
 	processTree          maps.SafeMap[uint32, apitypes.Process]
-	// For testing purposes we allow to override the function that gets process info from /proc.
+	// startTimes qualifies the PIDs of the process tree, see processKey
+	startTimes maps.SafeMap[uint32, uint64]
+	exited     exitedProcesses
+	// containers and recentExecs serve the process API
+	containers  maps.SafeMap[string, processmanager.ContainerInfo]
+	recentExecs maps.SafeMap[string, *execHistory]
+	// For testing purposes we allow to override the functions that get process info from /proc.
 	getProcessFromProc func(pid int) (apitypes.Process, error)
+	getStartTime       func(pid int) (uint64, error)
 }
 
-func CreateProcessManager(ctx context.Context) *ProcessManager {
+func CreateProcessManager(ctx context.Context, cfg config.Config) *ProcessManager {
 	pm := &ProcessManager{
+		exited:             exitedProcesses{retention: cfg.ProcessRetention},
 		getProcessFromProc: getProcessFromProc,
+		getStartTime:       getStartTime,
 	}
 	go pm.startCleanupRoutine(ctx)
 	return pm
@@ -123,6 +136,14 @@ func (p *ProcessManager) ContainerCallback(notif containercollection.PubSubEvent
 		if process, err := p.getProcessFromProc(int(containerPID)); err == nil {
 			shimPID := process.PPID
 			p.containerIdToShimPid.Set(containerID, shimPID)
+			p.containers.Set(containerID, processmanager.ContainerInfo{
+				ContainerID:   containerID,
+				ContainerName: notif.Container.K8s.ContainerName,
+				PodName:       notif.Container.K8s.PodName,
+				Namespace:     notif.Container.K8s.Namespace,
+				ContainerPID:  containerPID,
+			})
+			p.recentExecs.Set(containerID, &execHistory{})
 			p.addProcess(process)
 		} else {
 			logger.L().Warning("ProcessManager.ContainerCallback - failed to get container process info",
@@ -135,6 +156,8 @@ func (p *ProcessManager) ContainerCallback(notif containercollection.PubSubEvent
 			p.removeProcessesUnderShim(shimPID)
 			p.containerIdToShimPid.Delete(containerID)
 		}
+		p.containers.Delete(containerID)
+		p.recentExecs.Delete(containerID)
 	}
 }
 
@@ -150,15 +173,20 @@ func (p *ProcessManager) removeProcessesUnderShim(shimPID uint32) {
 
 		for currentPID != 0 && !visited[currentPID] {
 			visited[currentPID] = true
-			if proc, exists := p.processTree.Load(currentPID); exists {
-				if proc.PPID == shimPID {
-					pidsToRemove = append(pidsToRemove, pid)
+			proc, exists := p.processTree.Load(currentPID)
+			if !exists {
+				// the ancestors of the orphans are retained as exited processes
+				exited, found := p.exited.find(currentPID, 0)
+				if !found {
 					break
 				}
-				currentPID = proc.PPID
-			} else {
+				proc = exited.process
+			}
+			if proc.PPID == shimPID {
+				pidsToRemove = append(pidsToRemove, pid)
 				break
 			}
+			currentPID = proc.PPID
 		}
 		return true
 	})
@@ -173,6 +201,21 @@ func (p *ProcessManager) removeProcessesUnderShim(shimPID uint32) {
 // parent-child relationships between processes. If the process already exists
 // with a different parent, it updates the relationships accordingly.
 func (p *ProcessManager) addProcess(process apitypes.Process) {
+	startTime, _ := p.getStartTime(int(process.PID))
+	p.addProcessWithStartTime(process, startTime)
+}
+
+// addProcessWithStartTime adds the process, a known process with the same PID but another
+// start time exited unnoticed and its PID was reused: it is moved to the exited processes.
+// The start times of the fork events and of /proc/<pid>/stat are both the task start_boottime in clock ticks.
+func (p *ProcessManager) addProcessWithStartTime(process apitypes.Process, startTime uint64) {
+	if known, exists := p.startTimes.Load(process.PID); exists && known != 0 && startTime != 0 && known != startTime {
+		p.processExited(process.PID, time.Now())
+	}
+	if startTime != 0 || !p.startTimes.Has(process.PID) {
+		p.startTimes.Set(process.PID, startTime)
+	}
+
 	// First, check if the process already exists and has a different parent
 	if existingProc, exists := p.processTree.Load(process.PID); exists && existingProc.PPID != process.PPID {
 		// Remove from old parent's children list
@@ -230,24 +273,94 @@ func (p *ProcessManager) removeProcess(pid uint32) {
 		for _, child := range process.Children {
 			if childProcess, exists := p.processTree.Load(child.PID); exists {
 				childProcess.PPID = process.PPID
-				p.addProcess(childProcess)
+				p.addProcessWithStartTime(childProcess, p.startTimes.Get(child.PID))
 			}
 		}
 
 		p.processTree.Delete(pid)
+		p.startTimes.Delete(pid)
+	}
+}
+
+// processExited moves an exited process from the process tree to the exited processes.
+// Unlike removeProcess, the children are not reparented: their ancestry still goes through
+// the exited process during the retention window.
+func (p *ProcessManager) processExited(pid uint32, exitTime time.Time) {
+	process, exists := p.processTree.Load(pid)
+	if !exists {
+		return
+	}
+	p.exited.add(exitedProcess{
+		process:   process,
+		startTime: p.startTimes.Get(pid),
+		exitTime:  exitTime,
+	})
+
+	if parent, exists := p.processTree.Load(process.PPID); exists {
+		newChildren := make([]apitypes.Process, 0, len(parent.Children))
+		for _, child := range parent.Children {
+			if child.PID != pid {
+				newChildren = append(newChildren, child)
+			}
+		}
+		parent.Children = newChildren
+		p.processTree.Set(parent.PID, parent)
+	}
+	p.processTree.Delete(pid)
+	p.startTimes.Delete(pid)
+}
+
+// isTrackedProcess tells whether the PID and start time are the ones of the process tree, the exit of
+// an earlier process with the same PID is delivered late when the PID is reused.
+func (p *ProcessManager) isTrackedProcess(pid uint32, startTime uint64) bool {
+	known := p.startTimes.Get(pid)
+	return known == 0 || startTime == 0 || known == startTime
+}
+
+// processForked adds the child of a tracked process, with the process info from /proc
+// or, when the child is already gone, with the info inherited from its parent.
+func (p *ProcessManager) processForked(ppid, pid uint32, startTime uint64) {
+	parent, exists := p.processTree.Load(ppid)
+	if !exists || p.processTree.Has(pid) {
+		return
+	}
+	process, err := p.getProcessFromProc(int(pid))
+	if err != nil {
+		process = parent
+		process.PID = pid
+		process.PPID = ppid
+		process.Pcomm = parent.Comm
+		process.Children = []apitypes.Process{}
+	}
+	p.addProcessWithStartTime(process, startTime)
+}
+
+// loadProcess returns the process with the PID started at or before the given start time,
+// the exited processes are looked up when the PID is not in the tree or was reused.
+func (p *ProcessManager) loadProcess(pid uint32, startedBefore uint64) (apitypes.Process, uint64, bool) {
+	if process, exists := p.processTree.Load(pid); exists {
+		startTime := p.startTimes.Get(pid)
+		if startTime == 0 || startedBefore == 0 || startTime <= startedBefore {
+			return process, startTime, true
+		}
+	}
+	if exited, found := p.exited.find(pid, startedBefore); found {
+		return exited.process, exited.startTime, true
 	}
+	return apitypes.Process{}, 0, false
 }
 
 // GetProcessTreeForPID retrieves the process tree for a specific PID within a container.
 // It returns the process and all its ancestors up to the container's shim process.
+// The exited processes are part of the ancestry during the retention window.
 // If the process is not in the tree, it attempts to fetch it from /proc.
 func (p *ProcessManager) GetProcessTreeForPID(containerID string, pid int) (apitypes.Process, error) {
 	if !p.containerIdToShimPid.Has(containerID) {
-		return apitypes.Process{}, fmt.Errorf("container ID %s not found", containerID)
+		return apitypes.Process{}, fmt.Errorf("container ID %s: %w", containerID, processmanager.ErrContainerNotFound)
 	}
 
 	targetPID := uint32(pid)
-	if !p.processTree.Has(targetPID) {
+	if _, found := p.exited.find(targetPID, 0); !p.processTree.Has(targetPID) && !found {
 		process, err := p.getProcessFromProc(pid)
 		if err != nil {
 			return apitypes.Process{}, fmt.Errorf("process %d not found: %v", pid, err)
@@ -255,30 +368,11 @@ func (p *ProcessManager) GetProcessTreeForPID(containerID string, pid int) (apit
 		p.addProcess(process)
 	}
 
-	result := p.processTree.Get(targetPID)
-	currentPID := result.PPID
-	seen := make(map[uint32]bool)
-
-	for currentPID != p.containerIdToShimPid.Get(containerID) && currentPID != 0 {
-		if seen[currentPID] {
-			break
-		}
-		seen[currentPID] = true
-
-		if p.processTree.Has(currentPID) {
-			parent := p.processTree.Get(currentPID)
-			parentCopy := parent
-			parentCopy.Children = []apitypes.Process{result}
-			result = parentCopy
-			currentPID = parent.PPID
-		} else {
-			break
-		}
-	}
+	result := p.ancestry(containerID, targetPID)
 
 	// If the process is runc, try to fetch the real process info.
 	// Intentionally we are doing this only once the process is asked for to avoid unnecessary calls to /proc and give time for the process to be created.
-	if strings.HasPrefix(result.Comm, runCCommPrefix) {
+	if strings.HasPrefix(result.Comm, runCCommPrefix) && p.processTree.Has(result.PID) {
 		if process, err := p.getProcessFromProc(int(result.PID)); err == nil {
 			childerns := result.Children
 			upperLayer := result.UpperLayer
@@ -293,16 +387,71 @@ func (p *ProcessManager) GetProcessTreeForPID(containerID string, pid int) (apit
 	return result, nil
 }
 
+// GetProcessAncestry returns the process and its ancestors up to the container's shim process,
+// only the tracked and the retained exited processes are returned: the tree is left untouched.
+func (p *ProcessManager) GetProcessAncestry(containerID string, pid int) (apitypes.Process, error) {
+	if !p.containerIdToShimPid.Has(containerID) {
+		return apitypes.Process{}, fmt.Errorf("container ID %s: %w", containerID, processmanager.ErrContainerNotFound)
+	}
+	targetPID := uint32(pid)
+	if _, found := p.exited.find(targetPID, 0); !p.processTree.Has(targetPID) && !found {
+		return apitypes.Process{}, fmt.Errorf("process %d not found", pid)
+	}
+	return p.ancestry(containerID, targetPID), nil
+}
+
+// ancestry chains the process with its ancestors started before it, up to the container's shim process
+func (p *ProcessManager) ancestry(containerID string, targetPID uint32) apitypes.Process {
+	result, startTime, _ := p.loadProcess(targetPID, 0)
+	currentPID := result.PPID
+	seen := make(map[uint32]bool)
+
+	for currentPID != p.containerIdToShimPid.Get(containerID) && currentPID != 0 {
+		if seen[currentPID] {
+			break
+		}
+		seen[currentPID] = true
+
+		parent, parentStartTime, found := p.loadProcess(currentPID, startTime)
+		if !found {
+			break
+		}
+		parentCopy := parent
+		parentCopy.Children = []apitypes.Process{result}
+		result = parentCopy
+		startTime = parentStartTime
+		currentPID = parent.PPID
+	}
+	return result
+}
+
 // ReportEvent handles process execution events from the system.
-// It specifically processes execve events to track new process creations
-// and updates the process tree accordingly.
+// It processes execve events to track new process creations, and the fork and
+// exit events to track the lifetime of the processes, and updates the process tree accordingly.
 func (p *ProcessManager) ReportEvent(eventType utils.EventType, event utils.K8sEvent) {
-	if eventType != utils.ExecveEventType {
+	switch eventType {
+	case utils.ExecveEventType:
+	case utils.ForkEventType:
+		if forkEvent, ok := event.(*tracerprocesstype.Event); ok {
+			p.processForked(forkEvent.PPid, forkEvent.Pid, forkEvent.StartTime)
+		}
+		return
+	case utils.ExitEventType:
+		if exitEvent, ok := event.(*tracerprocesstype.Event); ok && p.isTrackedProcess(exitEvent.Pid, exitEvent.StartTime) {
+			p.processExited(exitEvent.Pid, time.Unix(0, int64(exitEvent.Timestamp)))
+		}
+		return
+	default:
 		return
 	}
 
-	execEvent, ok := event.(*tracerexectype.Event)
-	if !ok {
+	var execEvent *tracerexectype.Event
+	switch e := event.(type) {
+	case *events.ExecEvent:
+		execEvent = &e.Event
+	case *tracerexectype.Event:
+		execEvent = e
+	default:
 		return
 	}
 
@@ -322,12 +471,25 @@ func (p *ProcessManager) ReportEvent(eventType utils.EventType, event utils.K8sE
 	}
 
 	p.addProcess(process)
+
+	if history, exists := p.recentExecs.Load(execEvent.Runtime.ContainerID); exists {
+		history.add(processmanager.ExecRecord{
+			Timestamp: time.Unix(0, int64(execEvent.Timestamp)),
+			PID:       process.PID,
+			PPID:      process.PPID,
+			Comm:      process.Comm,
+			Path:      process.Path,
+			Cmdline:   process.Cmdline,
+			Uid:       execEvent.Uid,
+			Gid:       execEvent.Gid,
+		})
+	}
 }
 
 // startCleanupRoutine starts a goroutine that periodically runs the cleanup
 // function to remove dead processes from the process tree. It continues until
-// the context is cancelled.
-// TODO: Register eBPF tracer to get process exit events and remove dead processes immediately.
+// the context is cancelled. The exit events remove the processes immediately,
+// the cleanup catches the exits that were missed.
 func (p *ProcessManager) startCleanupRoutine(ctx context.Context) {
 	ticker := time.NewTicker(cleanupInterval)
 	defer ticker.Stop()
@@ -342,8 +504,9 @@ func (p *ProcessManager) startCleanupRoutine(ctx context.Context) {
 	}
 }
 
-// cleanup removes dead processes from the process tree by checking if each
-// process in the tree is still alive in the system.
+// cleanup moves dead processes from the process tree to the exited processes by checking
+// if each process in the tree is still alive in the system, and prunes the exited processes
+// older than the retention window.
 func (p *ProcessManager) cleanup() {
 	deadPids := make(map[uint32]bool)
 	p.processTree.Range(func(pid uint32, _ apitypes.Process) bool {
@@ -353,10 +516,12 @@ func (p *ProcessManager) cleanup() {
 		return true
 	})
 
+	now := time.Now()
 	for pid := range deadPids {
 		logger.L().Debug("ProcessManager - removing dead process", helpers.Int("pid", int(pid)))
-		p.removeProcess(pid)
+		p.processExited(pid, now)
 	}
+	p.exited.prune(now)
 }
 
 // getProcessFromProc retrieves process information from the /proc filesystem
@@ -422,6 +587,15 @@ func getProcessFromProc(pid int) (apitypes.Process, error) {
 	}, nil
 }
 
+// getStartTime retrieves the start time of the process in clock ticks after boot
+func getStartTime(pid int) (uint64, error) {
+	stat, err := utils.GetProcessStat(pid)
+	if err != nil {
+		return 0, err
+	}
+	return stat.Starttime, nil
+}
+
 // isProcessAlive checks if a process with the given PID is still running
 // by attempting to read its information from the /proc filesystem.
 func isProcessAlive(pid int) bool {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	apitypes "github.com/armosec/armoapi-go/armotypes"
 	"github.com/stretchr/testify/assert"
@@ -13,6 +14,9 @@ import (
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
 	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/node-agent/pkg/config"
+	tracerprocesstype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/process/tracer/types"
+	"github.com/kubescape/node-agent/pkg/processmanager"
 	"github.com/kubescape/node-agent/pkg/utils"
 )
 
@@ -22,13 +26,14 @@ type mockProcessAdder func(pid int, ppid uint32, comm string)
 // Updated setup function with correct return types
 func setupTestProcessManager(t *testing.T) (*ProcessManager, mockProcessAdder) {
 	ctx, cancel := context.WithCancel(context.Background())
-	pm := CreateProcessManager(ctx)
+	pm := CreateProcessManager(ctx, config.Config{ProcessRetention: time.Minute})
 
 	// Create process mock map
 	mockProcesses := make(map[int]apitypes.Process)
 
-	// Store original function
+	// Store original functions
 	originalGetProcessFromProc := pm.getProcessFromProc
+	originalGetStartTime := pm.getStartTime
 
 	// Replace with mock version
 	pm.getProcessFromProc = func(pid int) (apitypes.Process, error) {
@@ -37,11 +42,16 @@ func setupTestProcessManager(t *testing.T) (*ProcessManager, mockProcessAdder) {
 		}
 		return apitypes.Process{}, fmt.Errorf("mock process not found: %d", pid)
 	}
+	// The start times of the mock processes are unknown
+	pm.getStartTime = func(pid int) (uint64, error) {
+		return 0, fmt.Errorf("mock start time not found: %d", pid)
+	}
 
 	// Set up cleanup
 	t.Cleanup(func() {
 		cancel()
 		pm.getProcessFromProc = originalGetProcessFromProc
+		pm.getStartTime = originalGetStartTime
 	})
 
 	// Return the process manager and the mock process adder function
@@ -445,6 +455,18 @@ func TestErrorCases(t *testing.T) {
 		// Process should still be added
 		assert.True(t, pm.processTree.Has(2000))
 	})
+
+	t.Run("ancestry of an unknown process", func(t *testing.T) {
+		addMockProcess(3000, 1000, "unknown")
+		_, err := pm.GetProcessAncestry("test-container", 3000)
+		assert.Error(t, err)
+		// the API lookups never fetch the process from /proc
+		assert.False(t, pm.processTree.Has(3000))
+
+		tree, err := pm.GetProcessAncestry("test-container", 2000)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(2000), tree.PID)
+	})
 }
 
 func TestRaceConditions(t *testing.T) {
@@ -1044,3 +1066,290 @@ func TestIsDescendantOfShim(t *testing.T) {
 		})
 	}
 }
+
+func TestExitedProcessAncestry(t *testing.T) {
+	pm, addMockProcess := setupTestProcessManager(t)
+
+	containerID := "test-container"
+	shimPID := uint32(999)
+	containerPID := uint32(1000)
+	startTimes := map[int]uint64{int(containerPID): 10}
+	pm.getStartTime = func(pid int) (uint64, error) {
+		if startTime, exists := startTimes[pid]; exists {
+			return startTime, nil
+		}
+		return 0, fmt.Errorf("mock start time not found: %d", pid)
+	}
+
+	addMockProcess(int(containerPID), shimPID, "container-main")
+	pm.ContainerCallback(containercollection.PubSubEvent{
+		Type: containercollection.EventTypeAddContainer,
+		Container: &containercollection.Container{
+			Runtime: containercollection.RuntimeMetadata{
+				BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+					ContainerID:  containerID,
+					ContainerPID: containerPID,
+				},
+			},
+		},
+	})
+
+	// container-main -> shell -> payload
+	shellPID := uint32(2000)
+	payloadPID := uint32(2001)
+	startTimes[int(shellPID)] = 100
+	startTimes[int(payloadPID)] = 150
+	pm.ReportEvent(utils.ExecveEventType, &tracerexectype.Event{Pid: shellPID, Ppid: containerPID, Comm: "sh", Args: []string{"sh"}})
+	pm.ReportEvent(utils.ExecveEventType, &tracerexectype.Event{Pid: payloadPID, Ppid: shellPID, Comm: "payload", Args: []string{"payload"}})
+
+	t.Run("ancestry after the parent exited", func(t *testing.T) {
+		pm.ReportEvent(utils.ExitEventType, &tracerprocesstype.Event{Operation: tracerprocesstype.OperationExit, Pid: shellPID})
+
+		assert.False(t, pm.processTree.Has(shellPID))
+		payload, exists := pm.processTree.Load(payloadPID)
+		require.True(t, exists)
+		assert.Equal(t, shellPID, payload.PPID, "the orphan should not be reparented")
+
+		tree, err := pm.GetProcessTreeForPID(containerID, int(payloadPID))
+		require.NoError(t, err)
+		assert.Equal(t, containerPID, tree.PID)
+		require.Len(t, tree.Children, 1)
+		assert.Equal(t, "sh", tree.Children[0].Comm)
+		require.Len(t, tree.Children[0].Children, 1)
+		assert.Equal(t, "payload", tree.Children[0].Children[0].Comm)
+	})
+
+	t.Run("PID reuse does not corrupt the ancestry", func(t *testing.T) {
+		// a new process reuses the PID of the exited shell
+		startTimes[int(shellPID)] = 200
+		pm.ReportEvent(utils.ExecveEventType, &tracerexectype.Event{Pid: shellPID, Ppid: containerPID, Comm: "reused", Args: []string{"reused"}})
+
+		tree, err := pm.GetProcessTreeForPID(containerID, int(payloadPID))
+		require.NoError(t, err)
+		require.Len(t, tree.Children, 1)
+		assert.Equal(t, "sh", tree.Children[0].Comm, "the payload started before the PID was reused")
+
+		tree, err = pm.GetProcessTreeForPID(containerID, int(shellPID))
+		require.NoError(t, err)
+		require.Len(t, tree.Children, 1)
+		assert.Equal(t, "reused", tree.Children[0].Comm)
+	})
+
+	t.Run("exited process lineage", func(t *testing.T) {
+		pm.ReportEvent(utils.ExitEventType, &tracerprocesstype.Event{Operation: tracerprocesstype.OperationExit, Pid: payloadPID})
+
+		tree, err := pm.GetProcessTreeForPID(containerID, int(payloadPID))
+		require.NoError(t, err)
+		require.Len(t, tree.Children, 1)
+		require.Len(t, tree.Children[0].Children, 1)
+		assert.Equal(t, "payload", tree.Children[0].Children[0].Comm)
+	})
+
+	t.Run("container removal", func(t *testing.T) {
+		orphanPID := uint32(2002)
+		startTimes[int(orphanPID)] = 250
+		pm.ReportEvent(utils.ExecveEventType, &tracerexectype.Event{Pid: orphanPID, Ppid: shellPID, Comm: "orphan", Args: []string{"orphan"}})
+		pm.ReportEvent(utils.ExitEventType, &tracerprocesstype.Event{Operation: tracerprocesstype.OperationExit, Pid: shellPID})
+
+		pm.ContainerCallback(containercollection.PubSubEvent{
+			Type: containercollection.EventTypeRemoveContainer,
+			Container: &containercollection.Container{
+				Runtime: containercollection.RuntimeMetadata{
+					BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+						ContainerID:  containerID,
+						ContainerPID: containerPID,
+					},
+				},
+			},
+		})
+		assert.False(t, pm.processTree.Has(orphanPID), "the orphan of an exited process should be removed with its container")
+	})
+}
+
+func TestForkEvents(t *testing.T) {
+	pm, addMockProcess := setupTestProcessManager(t)
+
+	containerID := "test-container"
+	shimPID := uint32(999)
+	containerPID := uint32(1000)
+
+	addMockProcess(int(containerPID), shimPID, "container-main")
+	pm.ContainerCallback(containercollection.PubSubEvent{
+		Type: containercollection.EventTypeAddContainer,
+		Container: &containercollection.Container{
+			Runtime: containercollection.RuntimeMetadata{
+				BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+					ContainerID:  containerID,
+					ContainerPID: containerPID,
+				},
+			},
+		},
+	})
+
+	t.Run("fork of a tracked process", func(t *testing.T) {
+		// the child exited before its info was read from /proc, it inherits the parent info
+		pm.ReportEvent(utils.ForkEventType, &tracerprocesstype.Event{Operation: tracerprocesstype.OperationFork, Pid: 2000, PPid: containerPID, StartTime: 100})
+
+		child, exists := pm.processTree.Load(2000)
+		require.True(t, exists)
+		assert.Equal(t, containerPID, child.PPID)
+		assert.Equal(t, "container-main", child.Comm)
+		assert.Equal(t, "container-main", child.Pcomm)
+		assert.Equal(t, uint64(100), pm.startTimes.Get(2000))
+
+		parent, exists := pm.processTree.Load(containerPID)
+		require.True(t, exists)
+		require.Len(t, parent.Children, 1)
+		assert.Equal(t, uint32(2000), parent.Children[0].PID)
+	})
+
+	t.Run("fork of an untracked process", func(t *testing.T) {
+		pm.ReportEvent(utils.ForkEventType, &tracerprocesstype.Event{Operation: tracerprocesstype.OperationFork, Pid: 3001, PPid: 3000, StartTime: 100})
+		assert.False(t, pm.processTree.Has(3001))
+	})
+
+	t.Run("exec after fork", func(t *testing.T) {
+		pm.ReportEvent(utils.ExecveEventType, &tracerexectype.Event{Pid: 2000, Ppid: containerPID, Comm: "curl", Args: []string{"curl"}})
+		pm.ReportEvent(utils.ForkEventType, &tracerprocesstype.Event{Operation: tracerprocesstype.OperationFork, Pid: 2000, PPid: containerPID, StartTime: 100})
+
+		child, exists := pm.processTree.Load(2000)
+		require.True(t, exists)
+		assert.Equal(t, "curl", child.Comm, "a late fork event should not override the exec")
+		assert.Equal(t, uint64(100), pm.startTimes.Get(2000))
+	})
+
+	t.Run("exit of an earlier process with the same PID", func(t *testing.T) {
+		pm.ReportEvent(utils.ExitEventType, &tracerprocesstype.Event{Operation: tracerprocesstype.OperationExit, Pid: 2000, StartTime: 50})
+		assert.True(t, pm.processTree.Has(2000))
+		assert.Equal(t, 0, pm.exited.len())
+	})
+
+	t.Run("exited processes retention", func(t *testing.T) {
+		exitTime := time.Now()
+		pm.ReportEvent(utils.ExitEventType, &tracerprocesstype.Event{
+			Event:     types.Event{Timestamp: types.Time(exitTime.UnixNano())},
+			Operation: tracerprocesstype.OperationExit,
+			Pid:       2000,
+			StartTime: 100,
+		})
+		assert.False(t, pm.processTree.Has(2000))
+		assert.Equal(t, 1, pm.exited.len())
+
+		pm.exited.prune(exitTime.Add(30 * time.Second))
+		assert.Equal(t, 1, pm.exited.len())
+		pm.exited.prune(exitTime.Add(2 * time.Minute))
+		assert.Equal(t, 0, pm.exited.len())
+	})
+}
+
+func TestContainerProcesses(t *testing.T) {
+	pm, addMockProcess := setupTestProcessManager(t)
+
+	containerID := "test-container"
+	shimPID := uint32(999)
+	containerPID := uint32(1000)
+
+	addMockProcess(int(containerPID), shimPID, "container-main")
+	container := &containercollection.Container{
+		Runtime: containercollection.RuntimeMetadata{
+			BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+				ContainerID:  containerID,
+				ContainerPID: containerPID,
+			},
+		},
+		K8s: containercollection.K8sMetadata{
+			BasicK8sMetadata: types.BasicK8sMetadata{
+				Namespace:     "default",
+				PodName:       "test-pod",
+				ContainerName: "test",
+			},
+		},
+	}
+	pm.ContainerCallback(containercollection.PubSubEvent{Type: containercollection.EventTypeAddContainer, Container: container})
+
+	// another container of another pod
+	addMockProcess(5000, 4999, "other-main")
+	pm.ContainerCallback(containercollection.PubSubEvent{
+		Type: containercollection.EventTypeAddContainer,
+		Container: &containercollection.Container{
+			Runtime: containercollection.RuntimeMetadata{
+				BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+					ContainerID:  "other-container",
+					ContainerPID: 5000,
+				},
+			},
+		},
+	})
+
+	newExecEvent := func(pid, ppid uint32, comm string) *tracerexectype.Event {
+		event := &tracerexectype.Event{Pid: pid, Ppid: ppid, Comm: comm, Args: []string{comm, "-c"}}
+		event.Runtime.ContainerID = containerID
+		return event
+	}
+	pm.ReportEvent(utils.ExecveEventType, newExecEvent(2000, containerPID, "sh"))
+	pm.ReportEvent(utils.ExecveEventType, newExecEvent(2001, 2000, "sleep"))
+	pm.ReportEvent(utils.ExecveEventType, newExecEvent(2002, containerPID, "nginx"))
+
+	t.Run("list containers", func(t *testing.T) {
+		containers := pm.ListContainers()
+		require.Len(t, containers, 2)
+		assert.Equal(t, processmanager.ContainerInfo{
+			ContainerID:   containerID,
+			ContainerName: "test",
+			PodName:       "test-pod",
+			Namespace:     "default",
+			ContainerPID:  containerPID,
+		}, containers[1])
+	})
+
+	t.Run("process tree", func(t *testing.T) {
+		tree, err := pm.GetContainerProcesses(containerID)
+		require.NoError(t, err)
+		require.Len(t, tree, 1)
+		assert.Equal(t, containerPID, tree[0].PID)
+		require.Len(t, tree[0].Children, 2)
+		assert.Equal(t, "sh", tree[0].Children[0].Comm)
+		assert.Equal(t, "nginx", tree[0].Children[1].Comm)
+		require.Len(t, tree[0].Children[0].Children, 1)
+		assert.Equal(t, "sleep", tree[0].Children[0].Children[0].Comm)
+	})
+
+	t.Run("orphans are attached to their nearest living ancestor", func(t *testing.T) {
+		pm.ReportEvent(utils.ExitEventType, &tracerprocesstype.Event{Operation: tracerprocesstype.OperationExit, Pid: 2000})
+
+		tree, err := pm.GetContainerProcesses(containerID)
+		require.NoError(t, err)
+		require.Len(t, tree, 1)
+		require.Len(t, tree[0].Children, 2)
+		assert.Equal(t, "sleep", tree[0].Children[0].Comm)
+		assert.Equal(t, "nginx", tree[0].Children[1].Comm)
+	})
+
+	t.Run("recent execs", func(t *testing.T) {
+		execs, err := pm.GetRecentExecs(containerID)
+		require.NoError(t, err)
+		require.Len(t, execs, 3)
+		assert.Equal(t, "sh", execs[0].Comm)
+		assert.Equal(t, "sleep -c", execs[1].Cmdline)
+
+		for i := 0; i < maxRecentExecs; i++ {
+			pm.ReportEvent(utils.ExecveEventType, newExecEvent(3000, containerPID, fmt.Sprintf("exec-%d", i)))
+		}
+		execs, err = pm.GetRecentExecs(containerID)
+		require.NoError(t, err)
+		require.Len(t, execs, maxRecentExecs)
+		assert.Equal(t, "exec-0", execs[0].Comm)
+	})
+
+	t.Run("removed container", func(t *testing.T) {
+		pm.ContainerCallback(containercollection.PubSubEvent{Type: containercollection.EventTypeRemoveContainer, Container: container})
+
+		_, err := pm.GetContainerProcesses(containerID)
+		assert.ErrorIs(t, err, processmanager.ErrContainerNotFound)
+		_, err = pm.GetRecentExecs(containerID)
+		assert.ErrorIs(t, err, processmanager.ErrContainerNotFound)
+		_, err = pm.GetProcessTreeForPID(containerID, 2001)
+		assert.ErrorIs(t, err, processmanager.ErrContainerNotFound)
+		assert.Len(t, pm.ListContainers(), 1)
+	})
+}
@@ -0,0 +1,42 @@
+package responseactions
+
+import (
+	"context"
+	"encoding/json"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LabelPod adds the quarantine label to the pod, a NetworkPolicy selecting the label isolates the pod
+func LabelPod(ctx context.Context, client kubernetes.Interface, namespace, podName, label string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]string{label: "true"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.CoreV1().Pods(namespace).Patch(ctx, podName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// CordonNode marks the node unschedulable
+func CordonNode(ctx context.Context, client kubernetes.Interface, nodeName string) error {
+	patch := []byte(`{"spec":{"unschedulable":true}}`)
+	_, err := client.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// EvictPod evicts the pod through the eviction API, the PodDisruptionBudgets are honored
+func EvictPod(ctx context.Context, client kubernetes.Interface, namespace, podName string) error {
+	return client.PolicyV1().Evictions(namespace).Evict(ctx, &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: namespace,
+		},
+	})
+}
@@ -0,0 +1,38 @@
+package responseactions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubernetesActions(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default", Labels: map[string]string{"app": "nginx"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+	)
+
+	require.NoError(t, LabelPod(ctx, client, "default", "nginx", "kubescape.io/quarantine"))
+	pod, err := client.CoreV1().Pods("default").Get(ctx, "nginx", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": "nginx", "kubescape.io/quarantine": "true"}, pod.Labels)
+
+	require.NoError(t, CordonNode(ctx, client, "node-1"))
+	node, err := client.CoreV1().Nodes().Get(ctx, "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, node.Spec.Unschedulable)
+
+	require.NoError(t, EvictPod(ctx, client, "default", "nginx"))
+	actions := client.Actions()
+	last := actions[len(actions)-1]
+	assert.Equal(t, "create", last.GetVerb())
+	assert.Equal(t, "eviction", last.GetSubresource())
+
+	assert.Error(t, LabelPod(ctx, client, "default", "missing", "kubescape.io/quarantine"))
+}
@@ -0,0 +1,288 @@
+package responseactions
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/cgroups"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"golang.org/x/sys/unix"
+)
+
+// Action is an automated response to a detection
+type Action string
+
+const (
+	ActionKill       Action = "kill"
+	ActionQuarantine Action = "quarantine"
+	ActionFreeze     Action = "freeze"
+	ActionLabel      Action = "label"
+	ActionCordon     Action = "cordon"
+	ActionEvict      Action = "evict"
+
+	// OutcomesArgument is the alert argument listing the outcomes of the response actions
+	OutcomesArgument = "responseActions"
+
+	quarantineRecordSuffix = ".json"
+)
+
+var (
+	ErrNoProcess         = errors.New("no process to act on")
+	ErrProtectedProcess  = errors.New("refusing to act on a protected process")
+	ErrProcessNotInScope = errors.New("process is not running in the container")
+	ErrNoFile            = errors.New("no file to quarantine")
+	ErrNoHash            = errors.New("no SHA256 of the detected file")
+	ErrHashMismatch      = errors.New("file content differs from the detected one")
+)
+
+// Outcome is the result of a response action, it is audit-logged and attached to the alert
+type Outcome struct {
+	Action    Action    `json:"action"`
+	Target    string    `json:"target"`
+	Success   bool      `json:"success"`
+	DryRun    bool      `json:"dryRun,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewOutcome creates the outcome of an action on the target
+func NewOutcome(action Action, target string, err error) Outcome {
+	outcome := Outcome{
+		Action:    action,
+		Target:    target,
+		Success:   err == nil,
+		Timestamp: time.Now().UTC(),
+	}
+	if err != nil {
+		outcome.Error = err.Error()
+	}
+	return outcome
+}
+
+// Audit writes the outcome to the audit log with the identifying fields of the detection
+func Audit(outcome Outcome, details ...helpers.IDetails) {
+	fields := append([]helpers.IDetails{
+		helpers.String("action", string(outcome.Action)),
+		helpers.String("target", outcome.Target),
+		helpers.String("success", fmt.Sprintf("%t", outcome.Success)),
+		helpers.String("dry run", fmt.Sprintf("%t", outcome.DryRun)),
+	}, details...)
+	if outcome.Error != "" {
+		fields = append(fields, helpers.String("error", outcome.Error))
+	}
+	logger.L().Info("response action audit", fields...)
+}
+
+// KillProcess sends SIGKILL to the process. The process must share the mount namespace of the container init
+// process, it is not killed when the container is unknown.
+func KillProcess(pid, containerPid uint32) error {
+	if pid == 0 {
+		return ErrNoProcess
+	}
+	if pid == 1 || int(pid) == os.Getpid() {
+		return ErrProtectedProcess
+	}
+	if containerPid == 0 {
+		return ErrProcessNotInScope
+	}
+	if pid != containerPid {
+		same, err := sameMountNamespace(pid, containerPid)
+		if err != nil {
+			return err
+		}
+		if !same {
+			return ErrProcessNotInScope
+		}
+	}
+	return syscall.Kill(int(pid), syscall.SIGKILL)
+}
+
+func sameMountNamespace(pid, otherPid uint32) (bool, error) {
+	ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/mnt", pid))
+	if err != nil {
+		return false, err
+	}
+	otherNs, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/mnt", otherPid))
+	if err != nil {
+		return false, err
+	}
+	return ns == otherNs, nil
+}
+
+// FreezeCgroup freezes all the processes of the cgroup of the container init process.
+// It returns the path of the frozen cgroup.
+func FreezeCgroup(containerPid uint32) (string, error) {
+	if containerPid == 0 {
+		return "", ErrNoProcess
+	}
+	cgroupV1, cgroupV2, err := cgroups.GetCgroupPaths(int(containerPid))
+	if err != nil {
+		return "", err
+	}
+	if cgroupV2 != "" {
+		path, err := cgroups.CgroupPathV2AddMountpoint(cgroupV2)
+		if err == nil {
+			if _, err := os.Stat(filepath.Join(path, "cgroup.freeze")); err == nil {
+				return path, os.WriteFile(filepath.Join(path, "cgroup.freeze"), []byte("1"), 0)
+			}
+		}
+	}
+	if cgroupV1 == "" {
+		return "", fmt.Errorf("no freezable cgroup for pid %d", containerPid)
+	}
+	// the kubelet cgroup drivers use the same hierarchy in every cgroup v1 controller
+	path := filepath.Join("/sys/fs/cgroup/freezer", cgroupV1)
+	return path, os.WriteFile(filepath.Join(path, "freezer.state"), []byte("FROZEN"), 0)
+}
+
+// QuarantineRecord describes a quarantined file, it is stored next to the file
+type QuarantineRecord struct {
+	OriginalPath   string    `json:"originalPath"`
+	QuarantinePath string    `json:"quarantinePath"`
+	ContainerID    string    `json:"containerID,omitempty"`
+	SHA256         string    `json:"sha256"`
+	SHA1           string    `json:"sha1"`
+	MD5            string    `json:"md5"`
+	Size           int64     `json:"size"`
+	Mode           string    `json:"mode"`
+	Reason         string    `json:"reason,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// QuarantineFile moves the file at the path of the container root filesystem into the quarantine directory.
+// The path is resolved inside the root, so the symbolic links of the container can not lead out of it, and the
+// quarantined copy is read from the opened file. The file is quarantined only if it still has the detected content,
+// expectedSHA256 is required. It is stored under its SHA256 without permissions, with a JSON record of its origin
+// and hashes.
+func QuarantineFile(rootPath, path, containerID, reason, expectedSHA256, quarantineDir string) (QuarantineRecord, error) {
+	if rootPath == "" || path == "" {
+		return QuarantineRecord{}, ErrNoFile
+	}
+	if expectedSHA256 == "" {
+		return QuarantineRecord{}, ErrNoHash
+	}
+	root, err := os.OpenFile(rootPath, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return QuarantineRecord{}, err
+	}
+	defer func(root *os.File) {
+		_ = root.Close()
+	}(root)
+
+	// the parent directory is kept open to remove the entry of the opened file
+	parentFd, err := unix.Openat2(int(root.Fd()), filepath.Dir(path), &unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_DIRECTORY | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_IN_ROOT | unix.RESOLVE_NO_MAGICLINKS,
+	})
+	if err != nil {
+		return QuarantineRecord{}, fmt.Errorf("resolving %s: %w", path, err)
+	}
+	defer func(fd int) {
+		_ = unix.Close(fd)
+	}(parentFd)
+	name := filepath.Base(path)
+	fd, err := unix.Openat(parentFd, name, unix.O_RDONLY|unix.O_NOFOLLOW|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return QuarantineRecord{}, fmt.Errorf("opening %s: %w", path, err)
+	}
+	file := os.NewFile(uintptr(fd), path)
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	info, err := file.Stat()
+	if err != nil {
+		return QuarantineRecord{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return QuarantineRecord{}, fmt.Errorf("%s is not a regular file", path)
+	}
+
+	if err := os.MkdirAll(quarantineDir, 0700); err != nil {
+		return QuarantineRecord{}, err
+	}
+	tmpPath, hashes, err := copyToQuarantine(file, quarantineDir)
+	if err != nil {
+		return QuarantineRecord{}, err
+	}
+	if !strings.EqualFold(expectedSHA256, hashes.SHA256) {
+		_ = os.Remove(tmpPath)
+		return QuarantineRecord{}, fmt.Errorf("%w: %s has SHA256 %s", ErrHashMismatch, path, hashes.SHA256)
+	}
+	if err := unlinkOpenedFile(parentFd, name, info); err != nil {
+		_ = os.Remove(tmpPath)
+		return QuarantineRecord{}, err
+	}
+
+	record := QuarantineRecord{
+		OriginalPath:   path,
+		QuarantinePath: filepath.Join(quarantineDir, hashes.SHA256),
+		ContainerID:    containerID,
+		SHA256:         hashes.SHA256,
+		SHA1:           hashes.SHA1,
+		MD5:            hashes.MD5,
+		Size:           info.Size(),
+		Mode:           info.Mode().String(),
+		Reason:         reason,
+		Timestamp:      time.Now().UTC(),
+	}
+	if err := os.Rename(tmpPath, record.QuarantinePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return QuarantineRecord{}, err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return record, err
+	}
+	return record, os.WriteFile(record.QuarantinePath+quarantineRecordSuffix, data, 0600)
+}
+
+// copyToQuarantine copies the file into a temporary file of the quarantine directory, hashing the copied content
+func copyToQuarantine(file *os.File, quarantineDir string) (string, utils.FileHashes, error) {
+	out, err := os.CreateTemp(quarantineDir, ".quarantine-*")
+	if err != nil {
+		return "", utils.FileHashes{}, err
+	}
+	md5Hash, sha1Hash, sha256Hash := md5.New(), sha1.New(), sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, md5Hash, sha1Hash, sha256Hash), file)
+	if err == nil {
+		err = out.Chmod(0)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(out.Name())
+		return "", utils.FileHashes{}, err
+	}
+	return out.Name(), utils.FileHashes{
+		MD5:    hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA1:   hex.EncodeToString(sha1Hash.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}, nil
+}
+
+// unlinkOpenedFile removes the entry of the directory if it still is the opened file
+func unlinkOpenedFile(dirFd int, name string, info os.FileInfo) error {
+	var stat unix.Stat_t
+	if err := unix.Fstatat(dirFd, name, &stat, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return err
+	}
+	if opened, ok := info.Sys().(*syscall.Stat_t); !ok || opened.Dev != stat.Dev || opened.Ino != stat.Ino {
+		return fmt.Errorf("%s was replaced while quarantined", name)
+	}
+	return unix.Unlinkat(dirFd, name, 0)
+}
@@ -0,0 +1,103 @@
+package responseactions
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuarantineFile(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "tmp"), 0755))
+	file := filepath.Join(root, "tmp", "miner")
+	require.NoError(t, os.WriteFile(file, []byte("malware"), 0755))
+	quarantineDir := filepath.Join(t.TempDir(), "quarantine")
+	sha256 := "2f293f67aa33f2ce247b28d6fb2fef2623cfde731f96b3d7f84ae74e9e192bdd"
+
+	// the file changed since the detection
+	_, err := QuarantineFile(root, "/tmp/miner", "abc", "xmrig_miner", "0000", quarantineDir)
+	assert.ErrorIs(t, err, ErrHashMismatch)
+	assert.FileExists(t, file)
+
+	// the file is not quarantined without the detected hash
+	_, err = QuarantineFile(root, "/tmp/miner", "abc", "xmrig_miner", "", quarantineDir)
+	assert.ErrorIs(t, err, ErrNoHash)
+	assert.FileExists(t, file)
+
+	record, err := QuarantineFile(root, "/tmp/miner", "abc", "xmrig_miner", sha256, quarantineDir)
+	require.NoError(t, err)
+	assert.NoFileExists(t, file)
+	assert.Equal(t, "/tmp/miner", record.OriginalPath)
+	assert.Equal(t, sha256, record.SHA256)
+	assert.Equal(t, filepath.Join(quarantineDir, record.SHA256), record.QuarantinePath)
+	assert.Equal(t, int64(7), record.Size)
+
+	info, err := os.Stat(record.QuarantinePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0), info.Mode().Perm())
+	entries, err := os.ReadDir(quarantineDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	data, err := os.ReadFile(record.QuarantinePath + quarantineRecordSuffix)
+	require.NoError(t, err)
+	var stored QuarantineRecord
+	require.NoError(t, json.Unmarshal(data, &stored))
+	assert.Equal(t, record.SHA256, stored.SHA256)
+	assert.Equal(t, "abc", stored.ContainerID)
+	assert.Equal(t, "xmrig_miner", stored.Reason)
+
+	// the file is gone, nothing left to quarantine
+	_, err = QuarantineFile(root, "/tmp/miner", "abc", "xmrig_miner", sha256, quarantineDir)
+	assert.Error(t, err)
+	_, err = QuarantineFile(root, "", "abc", "xmrig_miner", sha256, quarantineDir)
+	assert.ErrorIs(t, err, ErrNoFile)
+	_, err = QuarantineFile(root, "/tmp", "abc", "xmrig_miner", sha256, quarantineDir)
+	assert.Error(t, err)
+
+	// the symbolic links of the container do not lead out of its root
+	outside := filepath.Join(t.TempDir(), "host")
+	require.NoError(t, os.WriteFile(outside, []byte("host file"), 0644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "tmp", "link")))
+	require.NoError(t, os.Symlink(filepath.Dir(outside), filepath.Join(root, "dir")))
+	_, err = QuarantineFile(root, "/tmp/link", "abc", "xmrig_miner", sha256, quarantineDir)
+	assert.Error(t, err)
+	_, err = QuarantineFile(root, "/dir/host", "abc", "xmrig_miner", sha256, quarantineDir)
+	assert.Error(t, err)
+	_, err = QuarantineFile(root, "/../../"+outside, "abc", "xmrig_miner", sha256, quarantineDir)
+	assert.Error(t, err)
+	assert.FileExists(t, outside)
+}
+
+func TestKillProcess(t *testing.T) {
+	assert.ErrorIs(t, KillProcess(0, 0), ErrNoProcess)
+	assert.ErrorIs(t, KillProcess(1, 0), ErrProtectedProcess)
+	assert.ErrorIs(t, KillProcess(uint32(os.Getpid()), 0), ErrProtectedProcess)
+
+	cmd := exec.Command("sleep", "60")
+	require.NoError(t, cmd.Start())
+	// the process is not killed without its container
+	assert.ErrorIs(t, KillProcess(uint32(cmd.Process.Pid), 0), ErrProcessNotInScope)
+	// the child shares the mount namespace of the test process
+	assert.NoError(t, KillProcess(uint32(cmd.Process.Pid), uint32(os.Getpid())))
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, "signal: killed", exitErr.Error())
+}
+
+func TestNewOutcome(t *testing.T) {
+	outcome := NewOutcome(ActionKill, "42", nil)
+	assert.True(t, outcome.Success)
+	assert.Empty(t, outcome.Error)
+
+	outcome = NewOutcome(ActionFreeze, "abc", ErrNoProcess)
+	assert.False(t, outcome.Success)
+	assert.Equal(t, ErrNoProcess.Error(), outcome.Error)
+}
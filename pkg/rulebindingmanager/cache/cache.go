@@ -354,6 +354,7 @@ func (c *RBCache) createRule(r *typesv1.RuntimeAlertRuleBindingRule) []ruleengin
 			if r.Parameters != nil {
 				ruleDesc.SetParameters(r.Parameters)
 			}
+			setResponseActions(ruleDesc, r.Actions)
 			return []ruleengine.RuleEvaluator{ruleDesc}
 		}
 	}
@@ -362,6 +363,7 @@ func (c *RBCache) createRule(r *typesv1.RuntimeAlertRuleBindingRule) []ruleengin
 			if r.Parameters != nil {
 				ruleDesc.SetParameters(r.Parameters)
 			}
+			setResponseActions(ruleDesc, r.Actions)
 			return []ruleengine.RuleEvaluator{ruleDesc}
 		}
 	}
@@ -371,6 +373,7 @@ func (c *RBCache) createRule(r *typesv1.RuntimeAlertRuleBindingRule) []ruleengin
 				if r.Parameters != nil {
 					ruleDesc.SetParameters(r.Parameters)
 				}
+				setResponseActions(ruleDesc, r.Actions)
 			}
 			return ruleTagsDescs
 		}
@@ -378,6 +381,15 @@ func (c *RBCache) createRule(r *typesv1.RuntimeAlertRuleBindingRule) []ruleengin
 	return []ruleengine.RuleEvaluator{}
 }
 
+func setResponseActions(rule ruleengine.RuleEvaluator, actions []typesv1.RuntimeAlertRuleBindingAction) {
+	if len(actions) == 0 {
+		return
+	}
+	if responder, ok := rule.(ruleengine.RuleResponder); ok {
+		responder.SetResponseActions(actions)
+	}
+}
+
 // Expose the rule creator to be able to create rules from third party.
 func (c *RBCache) GetRuleCreator() ruleengine.RuleCreator {
 	return c.ruleCreator
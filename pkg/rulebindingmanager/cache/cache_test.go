@@ -264,6 +264,14 @@ func TestCreateRule(t *testing.T) {
 			},
 			expected: []ruleengine.RuleEvaluator{&ruleengine.RuleMock{RuleName: "tag1", RuleParameters: map[string]interface{}{"param1": "value1"}}, &ruleengine.RuleMock{RuleName: "tag2", RuleParameters: map[string]interface{}{"param1": "value1"}}},
 		},
+		{
+			name: "Test with response actions",
+			rule: &typesv1.RuntimeAlertRuleBindingRule{
+				RuleID:  "rule-1",
+				Actions: []typesv1.RuntimeAlertRuleBindingAction{{Type: "kill"}, {Type: "label", DryRun: true}},
+			},
+			expected: []ruleengine.RuleEvaluator{&ruleengine.RuleMock{RuleID: "rule-1", RuleResponseActions: []typesv1.RuntimeAlertRuleBindingAction{{Type: "kill"}, {Type: "label", DryRun: true}}}},
+		},
 		{
 			name:     "Test with no RuleID, RuleName, or RuleTags",
 			rule:     &typesv1.RuntimeAlertRuleBindingRule{},
@@ -279,6 +287,7 @@ func TestCreateRule(t *testing.T) {
 				assert.Equal(t, tt.expected[i].Name(), result[i].Name())
 				assert.Equal(t, tt.expected[i].ID(), result[i].ID())
 				assert.Equal(t, tt.expected[i].GetParameters(), result[i].GetParameters())
+				assert.Equal(t, tt.expected[i].(ruleengine.RuleResponder).GetResponseActions(), result[i].(ruleengine.RuleResponder).GetResponseActions())
 			}
 		})
 	}
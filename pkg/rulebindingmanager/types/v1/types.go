@@ -27,11 +27,19 @@ type RuntimeAlertRuleBindingSpec struct {
 }
 
 type RuntimeAlertRuleBindingRule struct {
-	Parameters map[string]interface{} `json:"parameters" yaml:"parameters"`
-	RuleName   string                 `json:"ruleName" yaml:"ruleName"`
-	RuleID     string                 `json:"ruleID" yaml:"ruleID"`
-	Severity   string                 `json:"severity" yaml:"severity"`
-	RuleTags   []string               `json:"ruleTags" yaml:"ruleTags"`
+	Parameters map[string]interface{}          `json:"parameters" yaml:"parameters"`
+	RuleName   string                          `json:"ruleName" yaml:"ruleName"`
+	RuleID     string                          `json:"ruleID" yaml:"ruleID"`
+	Severity   string                          `json:"severity" yaml:"severity"`
+	RuleTags   []string                        `json:"ruleTags" yaml:"ruleTags"`
+	Actions    []RuntimeAlertRuleBindingAction `json:"actions,omitempty" yaml:"actions,omitempty"`
+}
+
+// RuntimeAlertRuleBindingAction is a response action run when the rule fails,
+// Type is one of "kill", "freeze", "label", "cordon" or "evict".
+type RuntimeAlertRuleBindingAction struct {
+	Type   string `json:"type" yaml:"type"`
+	DryRun bool   `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
 }
 
 func (r *RuntimeAlertRuleBindingRule) Equal(other *RuntimeAlertRuleBindingRule) bool {
@@ -50,5 +58,8 @@ func (r *RuntimeAlertRuleBindingRule) Equal(other *RuntimeAlertRuleBindingRule)
 	if !reflect.DeepEqual(r.Parameters, other.Parameters) {
 		return false
 	}
+	if !reflect.DeepEqual(r.Actions, other.Actions) {
+		return false
+	}
 	return true
 }
@@ -2,9 +2,11 @@ package ruleengine
 
 import (
 	"github.com/kubescape/node-agent/pkg/objectcache"
+	typesv1 "github.com/kubescape/node-agent/pkg/rulebindingmanager/types/v1"
 	"github.com/kubescape/node-agent/pkg/utils"
 
 	apitypes "github.com/armosec/armoapi-go/armotypes"
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
 	igtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
 
@@ -74,6 +76,26 @@ type RuleEvaluator interface {
 	GetParameters() map[string]interface{}
 }
 
+// RuleResponder is implemented by the rules carrying the response actions declared on their rule binding
+type RuleResponder interface {
+	SetResponseActions(actions []typesv1.RuntimeAlertRuleBindingAction)
+	GetResponseActions() []typesv1.RuntimeAlertRuleBindingAction
+}
+
+// RuleContainerTracker is implemented by the rules keeping a state per container, they are notified when the
+// monitored containers start and stop
+type RuleContainerTracker interface {
+	ContainerStarted(container *containercollection.Container)
+	ContainerStopped(containerID string)
+}
+
+// RuleEnrichedFailureEvaluator is implemented by the rules deciding on their failures once enriched by the rule
+// manager, such as the rules matching the file hashes computed by the enrichment
+type RuleEnrichedFailureEvaluator interface {
+	// EvaluateEnrichedFailure returns the failure to report, or nil to drop it
+	EvaluateEnrichedFailure(ruleFailure RuleFailure, objCache objectcache.ObjectCache) RuleFailure
+}
+
 type RuleCondition interface {
 	EvaluateRule(eventType utils.EventType, event utils.K8sEvent, k8sObjCache objectcache.K8sObjectCache) bool
 	ID() string
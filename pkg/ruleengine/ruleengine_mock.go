@@ -2,6 +2,7 @@ package ruleengine
 
 import (
 	"github.com/kubescape/node-agent/pkg/objectcache"
+	typesv1 "github.com/kubescape/node-agent/pkg/rulebindingmanager/types/v1"
 	"github.com/kubescape/node-agent/pkg/utils"
 )
 
@@ -33,12 +34,14 @@ func (r *RuleCreatorMock) CreateRulesByEventType(eventType utils.EventType) []Ru
 }
 
 var _ RuleEvaluator = (*RuleMock)(nil)
+var _ RuleResponder = (*RuleMock)(nil)
 
 type RuleMock struct {
-	RuleRequirements RuleSpec
-	RuleParameters   map[string]interface{}
-	RuleName         string
-	RuleID           string
+	RuleRequirements    RuleSpec
+	RuleParameters      map[string]interface{}
+	RuleName            string
+	RuleID              string
+	RuleResponseActions []typesv1.RuntimeAlertRuleBindingAction
 }
 
 func (rule *RuleMock) Name() string {
@@ -65,6 +68,12 @@ func (rule *RuleMock) GetParameters() map[string]interface{} {
 func (rule *RuleMock) SetParameters(p map[string]interface{}) {
 	rule.RuleParameters = p
 }
+func (rule *RuleMock) GetResponseActions() []typesv1.RuntimeAlertRuleBindingAction {
+	return rule.RuleResponseActions
+}
+func (rule *RuleMock) SetResponseActions(actions []typesv1.RuntimeAlertRuleBindingAction) {
+	rule.RuleResponseActions = actions
+}
 
 var _ RuleSpec = (*RuleSpecMock)(nil)
 
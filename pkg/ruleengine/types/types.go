@@ -17,6 +17,27 @@ type SyscallEvent struct {
 	SyscallName string `json:"syscallName,omitempty" column:"syscallName"`
 }
 
+// ImageVerificationEvent is the offline verification of the image of a starting container
+type ImageVerificationEvent struct {
+	eventtypes.Event
+
+	Pid        uint32 `json:"pid,omitempty" column:"pid,template:pid"`
+	ImageID    string `json:"imageId,omitempty" column:"imageId"`
+	Verified   bool   `json:"verified" column:"verified"`
+	Status     string `json:"status,omitempty" column:"status"`
+	Provenance string `json:"provenance,omitempty" column:"provenance"`
+	Reason     string `json:"reason,omitempty" column:"reason"`
+}
+
 type Enricher interface {
 	EnrichRuleFailure(rule ruleengine.RuleFailure)
 }
+
+// Enrichers calls several enrichers in order
+type Enrichers []Enricher
+
+func (e Enrichers) EnrichRuleFailure(rule ruleengine.RuleFailure) {
+	for _, enricher := range e {
+		enricher.EnrichRuleFailure(rule)
+	}
+}
@@ -25,6 +25,11 @@ func NewRuleCreator() *RuleCreatorImpl {
 			R0009EbpfProgramLoadRuleDescriptor,
 			R0010UnexpectedSensitiveFileAccessRuleDescriptor,
 			R0011UnexpectedEgressNetworkTrafficRuleDescriptor,
+			R0012UnexpectedProcessLineageRuleDescriptor,
+			R0013UnexpectedIngressNetworkTrafficRuleDescriptor,
+			R0014UnexpectedPortToKnownDestinationRuleDescriptor,
+			R0015DirectIPEgressNetworkTrafficRuleDescriptor,
+			R0016UnexpectedMetadataServiceAccessRuleDescriptor,
 			R1000ExecFromMaliciousSourceDescriptor,
 			R1001ExecBinaryNotInBaseImageRuleDescriptor,
 			R1002LoadKernelModuleRuleDescriptor,
@@ -39,6 +44,15 @@ func NewRuleCreator() *RuleCreatorImpl {
 			R1011LdPreloadHookRuleDescriptor,
 			R1012HardlinkCreatedOverSensitiveFileRuleDescriptor,
 			R1015MaliciousPtraceUsageRuleDescriptor,
+			R1016DNSTunnelingRuleDescriptor,
+			R1017ThreatIntelDomainRuleDescriptor,
+			R1018ThreatIntelIPRuleDescriptor,
+			R1019ThreatIntelFileHashRuleDescriptor,
+			R1020UnverifiedImageRuleDescriptor,
+			R1021SeccompViolationRuleDescriptor,
+			R1022FileIntegrityViolationRuleDescriptor,
+			R1023DropAndExecuteRuleDescriptor,
+			R1024ReverseShellRuleDescriptor,
 		},
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 
 	events "github.com/kubescape/node-agent/pkg/ebpf/events"
@@ -12,9 +13,12 @@ import (
 	"github.com/kubescape/go-logger"
 	"github.com/kubescape/go-logger/helpers"
 	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/utils"
 
 	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // SensitiveFiles is a list of sensitive files that should not be accessed by the application unexpectedly.
@@ -26,9 +30,20 @@ var SensitiveFiles = []string{
 	"/etc/pam.d",
 }
 
+// FileIntegrityPathSets are the named sets of paths watched by the file integrity rule, the rule bindings pick
+// the sets and add their own paths.
+var FileIntegrityPathSets = map[string][]string{
+	"system":   {"/etc"},
+	"binaries": {"/bin", "/sbin", "/usr/bin", "/usr/sbin", "/usr/local/bin", "/usr/local/sbin"},
+	"web":      {"/var/www", "/srv/www", "/usr/share/nginx/html", "/usr/local/apache2/htdocs"},
+}
+
 var (
 	ContainerNotFound = errors.New("container not found")
 	ProfileNotFound   = errors.New("application profile not found")
+
+	NetworkNeighborhoodNotFound = errors.New("network neighborhood not found")
+	NetworkNeighborhoodPartial  = errors.New("network neighborhood is partial")
 )
 
 func GetExecPathFromEvent(event *events.ExecEvent) string {
@@ -180,3 +195,67 @@ func IsAllowed(event *eventtypes.Event, objCache objectcache.ObjectCache, proces
 	logger.L().Debug("isAllowed - process is not allowed by policy", helpers.String("ruleID", ruleId), helpers.String("process", process))
 	return false, nil
 }
+
+// GetLearnedNetworkNeighborhoodContainer returns the network neighborhood of a container, skipping partially watched containers.
+func GetLearnedNetworkNeighborhoodContainer(event *eventtypes.Event, objCache objectcache.ObjectCache) (v1beta1.NetworkNeighborhoodContainer, error) {
+	nn := objCache.NetworkNeighborhoodCache().GetNetworkNeighborhood(event.Runtime.ContainerID)
+	if nn == nil {
+		return v1beta1.NetworkNeighborhoodContainer{}, NetworkNeighborhoodNotFound
+	}
+
+	if annotations := nn.GetAnnotations(); annotations != nil {
+		if annotations["kubescape.io/completion"] == string(utils.WatchedContainerCompletionStatusPartial) {
+			return v1beta1.NetworkNeighborhoodContainer{}, NetworkNeighborhoodPartial
+		}
+	}
+
+	return GetContainerFromNetworkNeighborhood(nn, event.GetContainer())
+}
+
+// NeighborMatchesEndpoint checks if a learned neighbor is the given peer, either by address, by resolved domain or by pod labels.
+func NeighborMatchesEndpoint(neighbor v1beta1.NetworkNeighbor, endpoint eventtypes.L3Endpoint, domain string) bool {
+	if neighbor.IPAddress != "" && neighbor.IPAddress == endpoint.Addr {
+		return true
+	}
+
+	if domain != "" && (neighbor.DNS == domain || slices.Contains(neighbor.DNSNames, domain)) {
+		return true
+	}
+
+	if neighbor.PodSelector != nil && len(endpoint.PodLabels) > 0 {
+		selector, err := metav1.LabelSelectorAsSelector(neighbor.PodSelector)
+		if err == nil && !selector.Empty() && selector.Matches(labels.Set(endpoint.PodLabels)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NeighborHasPort checks if a learned neighbor was reached using the given port and protocol.
+func NeighborHasPort(neighbor v1beta1.NetworkNeighbor, proto string, port uint16) bool {
+	for _, neighborPort := range neighbor.Ports {
+		if string(neighborPort.Protocol) == proto && neighborPort.Port != nil && *neighborPort.Port == int32(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// InterfaceToFloat64 converts a rule parameter to a float64, parameters coming from JSON are float64 while the ones set in code are often int.
+func InterfaceToFloat64(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
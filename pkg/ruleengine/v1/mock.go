@@ -2,7 +2,10 @@ package ruleengine
 
 import (
 	"context"
+	"errors"
+	"strings"
 
+	apitypes "github.com/armosec/armoapi-go/armotypes"
 	"github.com/goradd/maps"
 	"github.com/kubescape/node-agent/pkg/objectcache"
 	"github.com/kubescape/node-agent/pkg/utils"
@@ -16,6 +19,8 @@ var _ objectcache.ApplicationProfileCache = (*RuleObjectCacheMock)(nil)
 var _ objectcache.K8sObjectCache = (*RuleObjectCacheMock)(nil)
 var _ objectcache.NetworkNeighborhoodCache = (*RuleObjectCacheMock)(nil)
 var _ objectcache.DnsCache = (*RuleObjectCacheMock)(nil)
+var _ objectcache.ThreatIntelCache = (*RuleObjectCacheMock)(nil)
+var _ objectcache.ProcessTreeCache = (*RuleObjectCacheMock)(nil)
 
 type RuleObjectCacheMock struct {
 	profile                 *v1beta1.ApplicationProfile
@@ -23,6 +28,8 @@ type RuleObjectCacheMock struct {
 	podStatus               *corev1.PodStatus
 	nn                      *v1beta1.NetworkNeighborhood
 	dnsCache                map[string]string
+	threatIndicators        map[string]objectcache.ThreatIndicator
+	processTree             *apitypes.Process
 	containerIDToSharedData maps.SafeMap[string, *utils.WatchedContainerData]
 }
 
@@ -110,6 +117,57 @@ func (r *RuleObjectCacheMock) ResolveIpToDomain(ip string) string {
 	return ""
 }
 
+func (r *RuleObjectCacheMock) ThreatIntelCache() objectcache.ThreatIntelCache {
+	return r
+}
+
+func (r *RuleObjectCacheMock) SetThreatIndicators(indicators ...objectcache.ThreatIndicator) {
+	r.threatIndicators = make(map[string]objectcache.ThreatIndicator, len(indicators))
+	for _, indicator := range indicators {
+		r.threatIndicators[indicator.Value] = indicator
+	}
+}
+
+func (r *RuleObjectCacheMock) LookupIP(ip string) (objectcache.ThreatIndicator, bool) {
+	indicator, ok := r.threatIndicators[ip]
+	return indicator, ok
+}
+
+func (r *RuleObjectCacheMock) LookupDomain(domain string) (objectcache.ThreatIndicator, bool) {
+	indicator, ok := r.threatIndicators[strings.TrimSuffix(domain, ".")]
+	return indicator, ok
+}
+
+func (r *RuleObjectCacheMock) LookupHash(hash string) (objectcache.ThreatIndicator, bool) {
+	indicator, ok := r.threatIndicators[hash]
+	return indicator, ok
+}
+
+func (r *RuleObjectCacheMock) HasHashes() bool {
+	for _, indicator := range r.threatIndicators {
+		switch indicator.Type {
+		case objectcache.ThreatIndicatorTypeSHA256, objectcache.ThreatIndicatorTypeSHA1, objectcache.ThreatIndicatorTypeMD5:
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RuleObjectCacheMock) ProcessTreeCache() objectcache.ProcessTreeCache {
+	return r
+}
+
+func (r *RuleObjectCacheMock) SetProcessTree(processTree apitypes.Process) {
+	r.processTree = &processTree
+}
+
+func (r *RuleObjectCacheMock) GetProcessTreeForPID(_ string, pid int) (apitypes.Process, error) {
+	if r.processTree == nil || utils.GetProcessFromProcessTree(r.processTree, uint32(pid)) == nil {
+		return apitypes.Process{}, errors.New("process not found")
+	}
+	return *r.processTree, nil
+}
+
 func (r *RuleObjectCacheMock) WatchResources() []watcher.WatchResource {
 	return nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/kubescape/node-agent/pkg/utils"
 
 	apitypes "github.com/armosec/armoapi-go/armotypes"
+	"github.com/kubescape/storage/pkg/registry/file/dynamicpathdetector"
 )
 
 const (
@@ -80,7 +81,8 @@ func (rule *R0001UnexpectedProcessLaunched) ProcessEvent(eventType utils.EventTy
 	}
 
 	for _, execCall := range appProfileExecList.Execs {
-		if execCall.Path == execPath {
+		// the paths generalized to fit in the profile budget are matched as wildcards
+		if dynamicpathdetector.CompareDynamic(execCall.Path, execPath) {
 			// if enforceArgs is set to true, we need to compare the arguments as well
 			// if not set, we only compare the path
 			if !rule.enforceArgs || slices.Compare(execCall.Args, execEvent.Args) == 0 {
@@ -101,6 +101,23 @@ func TestR0001UnexpectedProcessLaunched(t *testing.T) {
 	if ruleResult != nil {
 		t.Errorf("Expected ruleResult to be nil since exec is whitelisted")
 	}
+
+	// Test with a path generalized to fit in the profile budget
+	profile.Spec.Containers[0].Execs = append(profile.Spec.Containers[0].Execs, v1beta1.ExecCalls{
+		Path: "/usr/bin/⋯",
+	})
+	objCache.SetApplicationProfile(profile)
+
+	e.Args = []string{"/usr/bin/id"}
+	ruleResult = r.ProcessEvent(utils.ExecveEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since exec is whitelisted by the wildcard")
+	}
+	e.Args = []string{"/usr/local/bin/id"}
+	ruleResult = r.ProcessEvent(utils.ExecveEventType, e, &objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since exec is not whitelisted by the wildcard")
+	}
 }
 
 func TestR0001UnexpectedProcessLaunchedArgCompare(t *testing.T) {
@@ -0,0 +1,134 @@
+package ruleengine
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	events "github.com/kubescape/node-agent/pkg/ebpf/events"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+)
+
+const (
+	R0012ID   = utils.ProcessLineageRuleID
+	R0012Name = "Unexpected process lineage"
+)
+
+var R0012UnexpectedProcessLineageRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R0012ID,
+	Name:        R0012Name,
+	Description: "Detecting exec calls whose parent to child relationship is not whitelisted by application profile",
+	Tags:        []string{"exec", "whitelisted", "lineage"},
+	Priority:    RulePriorityMed,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{utils.ExecveEventType},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR0012UnexpectedProcessLineage()
+	},
+}
+var _ ruleengine.RuleEvaluator = (*R0012UnexpectedProcessLineage)(nil)
+
+type R0012UnexpectedProcessLineage struct {
+	BaseRule
+}
+
+func CreateRuleR0012UnexpectedProcessLineage() *R0012UnexpectedProcessLineage {
+	return &R0012UnexpectedProcessLineage{}
+}
+
+func (rule *R0012UnexpectedProcessLineage) Name() string {
+	return R0012Name
+}
+
+func (rule *R0012UnexpectedProcessLineage) ID() string {
+	return R0012ID
+}
+
+func (rule *R0012UnexpectedProcessLineage) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, objectCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	if eventType != utils.ExecveEventType {
+		return nil
+	}
+
+	execEvent, ok := event.(*events.ExecEvent)
+	if !ok {
+		return nil
+	}
+
+	ap := objectCache.ApplicationProfileCache().GetApplicationProfile(execEvent.Runtime.ContainerID)
+	if ap == nil {
+		return nil
+	}
+
+	appProfile, err := GetContainerFromApplicationProfile(ap, execEvent.GetContainer())
+	if err != nil {
+		return nil
+	}
+
+	// profiles recorded without lineage can't be used to evaluate the rule, the learned lineage is marked even without
+	// edges
+	policy, ok := appProfile.PolicyByRuleId[R0012ID]
+	if !ok || policy.AllowedContainer || len(policy.AllowedProcesses) == 0 {
+		return nil
+	}
+
+	execPath := GetExecPathFromEvent(execEvent)
+	parentPath := objectcache.GetParentExecPath(objectCache.ProcessTreeCache(), execEvent.Runtime.ContainerID, execEvent.Ppid, execEvent.Pcomm)
+	if slices.Contains(policy.AllowedProcesses, utils.CreateProcessLineageEdge(parentPath, execPath)) {
+		return nil
+	}
+
+	upperLayer := execEvent.UpperLayer || execEvent.PupperLayer
+
+	ruleFailure := GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: execEvent.Pid,
+			Arguments: map[string]interface{}{
+				"retval": execEvent.Retval,
+				"exec":   execPath,
+				"args":   execEvent.Args,
+				"parent": parentPath,
+			},
+			Severity: R0012UnexpectedProcessLineageRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				Comm:       execEvent.Comm,
+				Gid:        &execEvent.Gid,
+				PID:        execEvent.Pid,
+				Uid:        &execEvent.Uid,
+				UpperLayer: &upperLayer,
+				PPID:       execEvent.Ppid,
+				Pcomm:      execEvent.Pcomm,
+				Cwd:        execEvent.Cwd,
+				Hardlink:   execEvent.ExePath,
+				Path:       GetExecFullPathFromEvent(execEvent),
+				Cmdline:    fmt.Sprintf("%s %s", execPath, strings.Join(utils.GetExecArgsFromEvent(&execEvent.Event), " ")),
+			},
+			ContainerID: execEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: execEvent.Event.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Unexpected process lineage: %s launched %s in: %s", parentPath, execPath, execEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   execEvent.GetPod(),
+			PodLabels: execEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+		Extra:  execEvent.GetExtra(),
+	}
+
+	return &ruleFailure
+}
+
+func (rule *R0012UnexpectedProcessLineage) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R0012UnexpectedProcessLineageRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
@@ -0,0 +1,112 @@
+package ruleengine
+
+import (
+	"testing"
+
+	events "github.com/kubescape/node-agent/pkg/ebpf/events"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+
+	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestR0012UnexpectedProcessLineage(t *testing.T) {
+	// Create a new rule
+	r := CreateRuleR0012UnexpectedProcessLineage()
+	// Assert r is not nil
+	if r == nil {
+		t.Errorf("Expected r to not be nil")
+	}
+
+	e := &events.ExecEvent{
+		Event: tracerexectype.Event{
+			Event: eventtypes.Event{
+				CommonData: eventtypes.CommonData{
+					K8s: eventtypes.K8sMetadata{
+						BasicK8sMetadata: eventtypes.BasicK8sMetadata{
+							ContainerName: "test",
+						},
+					},
+				},
+			},
+			Comm:  "sh",
+			Pcomm: "nginx",
+			Args:  []string{"/bin/sh", "-c", "id"},
+		},
+	}
+
+	objCache := RuleObjectCacheMock{}
+	profile := &v1beta1.ApplicationProfile{}
+	profile.Spec.Containers = append(profile.Spec.Containers, v1beta1.ApplicationProfileContainer{
+		Name: "test",
+		Execs: []v1beta1.ExecCalls{
+			{
+				Path: "/bin/sh",
+				Args: []string{"/bin/sh", "-c", "id"},
+			},
+		},
+	})
+	objCache.SetApplicationProfile(profile)
+
+	// Test with a profile without lineage
+	ruleResult := r.ProcessEvent(utils.ExecveEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the profile has no lineage")
+	}
+
+	// Test with a profile initialized without lineage learning
+	profile.Spec.Containers[0].PolicyByRuleId = map[string]v1beta1.RulePolicy{
+		R0012ID: {AllowedProcesses: []string{}},
+	}
+	objCache.SetApplicationProfile(profile)
+	ruleResult = r.ProcessEvent(utils.ExecveEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the profile has no lineage")
+	}
+
+	// Test with a learned lineage without edges, as when the parents of the container are outside of it
+	profile.Spec.Containers[0].PolicyByRuleId = map[string]v1beta1.RulePolicy{
+		R0012ID: {AllowedProcesses: []string{utils.ProcessLineageLearned}},
+	}
+	objCache.SetApplicationProfile(profile)
+	ruleResult = r.ProcessEvent(utils.ExecveEventType, e, &objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since no lineage was learned for /bin/sh")
+	}
+
+	// Test with an unexpected parent
+	profile.Spec.Containers[0].PolicyByRuleId = map[string]v1beta1.RulePolicy{
+		R0012ID: {
+			AllowedProcesses: []string{utils.CreateProcessLineageEdge("entrypoint.sh", "/bin/sh")},
+		},
+	}
+	objCache.SetApplicationProfile(profile)
+
+	ruleResult = r.ProcessEvent(utils.ExecveEventType, e, &objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since nginx is not an expected parent of /bin/sh")
+	}
+
+	// Test with an expected parent
+	e.Pcomm = "entrypoint.sh"
+	ruleResult = r.ProcessEvent(utils.ExecveEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since entrypoint.sh is an expected parent of /bin/sh")
+	}
+
+	// Test with a parent resolved through the process tree, as when the lineage is recorded
+	profile.Spec.Containers[0].PolicyByRuleId[R0012ID] = v1beta1.RulePolicy{
+		AllowedProcesses: []string{utils.CreateProcessLineageEdge("/usr/sbin/nginx", "/bin/sh")},
+	}
+	objCache.SetApplicationProfile(profile)
+	e.Ppid = 10
+	e.Pcomm = "nginx"
+	objCache.SetProcessTree(apitypes.Process{PID: 10, Comm: "nginx", Cmdline: "/usr/sbin/nginx -g daemon off;"})
+	ruleResult = r.ProcessEvent(utils.ExecveEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since /usr/sbin/nginx is an expected parent of /bin/sh")
+	}
+}
@@ -0,0 +1,130 @@
+package ruleengine
+
+import (
+	"fmt"
+	"time"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	"github.com/goradd/maps"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+)
+
+const (
+	R0013ID   = "R0013"
+	R0013Name = "Unexpected Ingress Network Traffic"
+)
+
+var R0013UnexpectedIngressNetworkTrafficRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R0013ID,
+	Name:        R0013Name,
+	Description: "Detecting ingress network traffic from sources that were never seen during learning.",
+	Tags:        []string{"whitelisted", "network", "ingress"},
+	Priority:    RulePriorityMed,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{utils.NetworkEventType},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR0013UnexpectedIngressNetworkTraffic()
+	},
+}
+var _ ruleengine.RuleEvaluator = (*R0013UnexpectedIngressNetworkTraffic)(nil)
+
+type R0013UnexpectedIngressNetworkTraffic struct {
+	BaseRule
+	alertedSources maps.SafeMap[string, bool]
+	startTime      time.Time
+}
+
+func CreateRuleR0013UnexpectedIngressNetworkTraffic() *R0013UnexpectedIngressNetworkTraffic {
+	return &R0013UnexpectedIngressNetworkTraffic{startTime: time.Now()}
+}
+
+func (rule *R0013UnexpectedIngressNetworkTraffic) Name() string {
+	return R0013Name
+}
+func (rule *R0013UnexpectedIngressNetworkTraffic) ID() string {
+	return R0013ID
+}
+
+func (rule *R0013UnexpectedIngressNetworkTraffic) DeleteRule() {
+}
+
+func (rule *R0013UnexpectedIngressNetworkTraffic) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	if eventType != utils.NetworkEventType {
+		return nil
+	}
+
+	networkEvent, ok := event.(*tracernetworktype.Event)
+	if !ok {
+		return nil
+	}
+
+	// For incoming packets the destination endpoint is the remote peer.
+	if networkEvent.PktType != "HOST" || networkEvent.K8s.HostNetwork || networkEvent.PodHostIP == networkEvent.DstEndpoint.Addr {
+		return nil
+	}
+
+	// Check if the container was pre-running.
+	if time.Unix(int64(networkEvent.Runtime.ContainerStartedAt), 0).Before(rule.startTime) {
+		return nil
+	}
+
+	// Check if we already alerted on this source.
+	source := fmt.Sprintf("%s:%s:%d:%s", networkEvent.Runtime.ContainerID, networkEvent.DstEndpoint.Addr, networkEvent.Port, networkEvent.Proto)
+	if rule.alertedSources.Has(source) {
+		return nil
+	}
+
+	nnContainer, err := GetLearnedNetworkNeighborhoodContainer(&networkEvent.Event, objCache)
+	if err != nil {
+		return nil
+	}
+
+	for _, ingress := range nnContainer.Ingress {
+		if NeighborMatchesEndpoint(ingress, networkEvent.DstEndpoint, "") {
+			return nil
+		}
+	}
+
+	rule.alertedSources.Set(source, true)
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: networkEvent.Pid,
+			Arguments: map[string]interface{}{
+				"ip":    networkEvent.DstEndpoint.Addr,
+				"port":  networkEvent.Port,
+				"proto": networkEvent.Proto,
+			},
+			Severity: R0013UnexpectedIngressNetworkTrafficRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				Comm: networkEvent.Comm,
+				Gid:  &networkEvent.Gid,
+				PID:  networkEvent.Pid,
+				Uid:  &networkEvent.Uid,
+			},
+			ContainerID: networkEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: networkEvent.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Unexpected ingress network communication from: %s on port %d using %s to: %s", networkEvent.DstEndpoint.Addr, networkEvent.Port, networkEvent.Proto, networkEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   networkEvent.GetPod(),
+			PodLabels: networkEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+	}
+}
+
+func (rule *R0013UnexpectedIngressNetworkTraffic) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R0013UnexpectedIngressNetworkTrafficRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
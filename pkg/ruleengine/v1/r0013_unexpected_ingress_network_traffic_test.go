@@ -0,0 +1,108 @@
+package ruleengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestR0013UnexpectedIngressNetworkTraffic(t *testing.T) {
+	// Create a new rule
+	r := CreateRuleR0013UnexpectedIngressNetworkTraffic()
+	// Assert r is not nil
+	if r == nil {
+		t.Errorf("Expected r to not be nil")
+	}
+
+	// Create an incoming network event
+	e := &tracernetworktype.Event{
+		Event: eventtypes.Event{
+			CommonData: eventtypes.CommonData{
+				K8s: eventtypes.K8sMetadata{
+					BasicK8sMetadata: eventtypes.BasicK8sMetadata{
+						ContainerName: "test",
+					},
+				},
+				Runtime: eventtypes.BasicRuntimeMetadata{
+					ContainerStartedAt: eventtypes.Time(time.Now().UnixNano()),
+				},
+			},
+		},
+		PktType: "HOST",
+		DstEndpoint: eventtypes.L3Endpoint{
+			Addr: "10.0.0.5",
+		},
+		Port:  8080,
+		Proto: "TCP",
+	}
+
+	// Test with nil network neighborhood.
+	ruleResult := r.ProcessEvent(utils.NetworkEventType, e, &RuleObjectCacheMock{})
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since no network neighborhood")
+	}
+
+	objCache := RuleObjectCacheMock{}
+	nn := &v1beta1.NetworkNeighborhood{}
+	nn.Spec.Containers = append(nn.Spec.Containers, v1beta1.NetworkNeighborhoodContainer{
+		Name: "test",
+		Ingress: []v1beta1.NetworkNeighbor{
+			{
+				IPAddress: "10.0.0.5",
+			},
+			{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}},
+			},
+		},
+	})
+	objCache.SetNetworkNeighborhood(nn)
+
+	// Test with a learned source address.
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the source is whitelisted")
+	}
+
+	// Test with a learned source pod.
+	e.DstEndpoint = eventtypes.L3Endpoint{Addr: "10.0.0.6", PodLabels: map[string]string{"app": "frontend", "pod-template-hash": "abc"}}
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the source pod is whitelisted")
+	}
+
+	// Test with an unknown source.
+	e.DstEndpoint = eventtypes.L3Endpoint{Addr: "10.0.0.7"}
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the source was never seen")
+	}
+
+	// Test with the same unknown source.
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since we already alerted on this source")
+	}
+
+	// Test with outgoing packet.
+	e.PktType = "OUTGOING"
+	e.DstEndpoint = eventtypes.L3Endpoint{Addr: "10.0.0.8"}
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since packet is outgoing")
+	}
+
+	// Test with partially watched container.
+	e.PktType = "HOST"
+	nn.Annotations = map[string]string{"kubescape.io/completion": string(utils.WatchedContainerCompletionStatusPartial)}
+	objCache.SetNetworkNeighborhood(nn)
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since it's a partially watched container")
+	}
+}
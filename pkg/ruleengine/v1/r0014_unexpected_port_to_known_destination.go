@@ -0,0 +1,140 @@
+package ruleengine
+
+import (
+	"fmt"
+	"time"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	"github.com/goradd/maps"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+)
+
+const (
+	R0014ID   = "R0014"
+	R0014Name = "Unexpected Port To Known Destination"
+)
+
+var R0014UnexpectedPortToKnownDestinationRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R0014ID,
+	Name:        R0014Name,
+	Description: "Detecting egress network traffic to a known destination using a port or protocol that was never seen during learning.",
+	Tags:        []string{"whitelisted", "network", "egress"},
+	Priority:    RulePriorityMed,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{utils.NetworkEventType},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR0014UnexpectedPortToKnownDestination()
+	},
+}
+var _ ruleengine.RuleEvaluator = (*R0014UnexpectedPortToKnownDestination)(nil)
+
+type R0014UnexpectedPortToKnownDestination struct {
+	BaseRule
+	alertedEndpoints maps.SafeMap[string, bool]
+	startTime        time.Time
+}
+
+func CreateRuleR0014UnexpectedPortToKnownDestination() *R0014UnexpectedPortToKnownDestination {
+	return &R0014UnexpectedPortToKnownDestination{startTime: time.Now()}
+}
+
+func (rule *R0014UnexpectedPortToKnownDestination) Name() string {
+	return R0014Name
+}
+func (rule *R0014UnexpectedPortToKnownDestination) ID() string {
+	return R0014ID
+}
+
+func (rule *R0014UnexpectedPortToKnownDestination) DeleteRule() {
+}
+
+func (rule *R0014UnexpectedPortToKnownDestination) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	if eventType != utils.NetworkEventType {
+		return nil
+	}
+
+	networkEvent, ok := event.(*tracernetworktype.Event)
+	if !ok {
+		return nil
+	}
+
+	if networkEvent.PktType != "OUTGOING" || networkEvent.K8s.HostNetwork {
+		return nil
+	}
+
+	// Check if the container was pre-running.
+	if time.Unix(int64(networkEvent.Runtime.ContainerStartedAt), 0).Before(rule.startTime) {
+		return nil
+	}
+
+	// Check if we already alerted on this endpoint.
+	endpoint := fmt.Sprintf("%s:%s:%d:%s", networkEvent.Runtime.ContainerID, networkEvent.DstEndpoint.Addr, networkEvent.Port, networkEvent.Proto)
+	if rule.alertedEndpoints.Has(endpoint) {
+		return nil
+	}
+
+	nnContainer, err := GetLearnedNetworkNeighborhoodContainer(&networkEvent.Event, objCache)
+	if err != nil {
+		return nil
+	}
+
+	// Unknown destinations are handled by R0011, only known ones are checked for their ports.
+	domain := objCache.DnsCache().ResolveIpToDomain(networkEvent.DstEndpoint.Addr)
+	known := false
+	for _, egress := range nnContainer.Egress {
+		if !NeighborMatchesEndpoint(egress, networkEvent.DstEndpoint, domain) {
+			continue
+		}
+		if NeighborHasPort(egress, networkEvent.Proto, networkEvent.Port) {
+			return nil
+		}
+		known = true
+	}
+	if !known {
+		return nil
+	}
+
+	rule.alertedEndpoints.Set(endpoint, true)
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: networkEvent.Pid,
+			Arguments: map[string]interface{}{
+				"ip":     networkEvent.DstEndpoint.Addr,
+				"domain": domain,
+				"port":   networkEvent.Port,
+				"proto":  networkEvent.Proto,
+			},
+			Severity: R0014UnexpectedPortToKnownDestinationRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				Comm: networkEvent.Comm,
+				Gid:  &networkEvent.Gid,
+				PID:  networkEvent.Pid,
+				Uid:  &networkEvent.Uid,
+			},
+			ContainerID: networkEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: networkEvent.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Unexpected port for known destination: %s:%d using %s from: %s", networkEvent.DstEndpoint.Addr, networkEvent.Port, networkEvent.Proto, networkEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   networkEvent.GetPod(),
+			PodLabels: networkEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+	}
+}
+
+func (rule *R0014UnexpectedPortToKnownDestination) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R0014UnexpectedPortToKnownDestinationRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
@@ -0,0 +1,97 @@
+package ruleengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"k8s.io/utils/ptr"
+)
+
+func TestR0014UnexpectedPortToKnownDestination(t *testing.T) {
+	// Create a new rule
+	r := CreateRuleR0014UnexpectedPortToKnownDestination()
+	// Assert r is not nil
+	if r == nil {
+		t.Errorf("Expected r to not be nil")
+	}
+
+	// Create a network request event
+	e := &tracernetworktype.Event{
+		Event: eventtypes.Event{
+			CommonData: eventtypes.CommonData{
+				K8s: eventtypes.K8sMetadata{
+					BasicK8sMetadata: eventtypes.BasicK8sMetadata{
+						ContainerName: "test",
+					},
+				},
+				Runtime: eventtypes.BasicRuntimeMetadata{
+					ContainerStartedAt: eventtypes.Time(time.Now().UnixNano()),
+				},
+			},
+		},
+		PktType: "OUTGOING",
+		DstEndpoint: eventtypes.L3Endpoint{
+			Addr: "1.1.1.1",
+		},
+		Port:  443,
+		Proto: "TCP",
+	}
+
+	objCache := RuleObjectCacheMock{}
+	nn := &v1beta1.NetworkNeighborhood{}
+	nn.Spec.Containers = append(nn.Spec.Containers, v1beta1.NetworkNeighborhoodContainer{
+		Name: "test",
+		Egress: []v1beta1.NetworkNeighbor{
+			{
+				IPAddress: "1.1.1.1",
+				Ports:     []v1beta1.NetworkPort{{Name: "TCP-443", Protocol: "TCP", Port: ptr.To(int32(443))}},
+			},
+			{
+				DNSNames: []string{"test.com."},
+				Ports:    []v1beta1.NetworkPort{{Name: "TCP-443", Protocol: "TCP", Port: ptr.To(int32(443))}},
+			},
+		},
+	})
+	objCache.SetNetworkNeighborhood(nn)
+
+	// Test with a learned port.
+	ruleResult := r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the port is whitelisted")
+	}
+
+	// Test with an unknown destination.
+	e.DstEndpoint.Addr = "2.2.2.2"
+	e.Port = 4444
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the destination is unknown")
+	}
+
+	// Test with a known domain on a new port.
+	objCache.SetDnsCache(map[string]string{"2.2.2.2": "test.com."})
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the port was never used with this domain")
+	}
+
+	// Test with a known address on a new protocol.
+	e.DstEndpoint.Addr = "1.1.1.1"
+	e.Port = 443
+	e.Proto = "UDP"
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the protocol was never used with this address")
+	}
+
+	// Test with the same endpoint.
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since we already alerted on this endpoint")
+	}
+}
@@ -0,0 +1,190 @@
+package ruleengine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	tracerdnstype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/dns/types"
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+)
+
+const (
+	R0015ID   = "R0015"
+	R0015Name = "Direct IP Egress Network Traffic"
+
+	// maxContainerAddresses bounds the number of resolved and alerted addresses kept per container
+	maxContainerAddresses = 10000
+)
+
+var R0015DirectIPEgressNetworkTrafficRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R0015ID,
+	Name:        R0015Name,
+	Description: "Detecting egress network traffic to public IPs that were never resolved via DNS by the container.",
+	Tags:        []string{"dns", "network", "egress", "c2"},
+	Priority:    RulePriorityMed,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{utils.NetworkEventType, utils.DnsEventType},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR0015DirectIPEgressNetworkTraffic()
+	},
+}
+var _ ruleengine.RuleEvaluator = (*R0015DirectIPEgressNetworkTraffic)(nil)
+var _ ruleengine.RuleContainerTracker = (*R0015DirectIPEgressNetworkTraffic)(nil)
+
+// egressContainer holds the addresses resolved by the DNS requests of a container, and the addresses alerted
+type egressContainer struct {
+	resolvedAddresses *lru.Cache[string, struct{}]
+	alertedAddresses  *lru.Cache[string, struct{}]
+}
+
+type R0015DirectIPEgressNetworkTraffic struct {
+	BaseRule
+	mutex      sync.Mutex
+	containers map[string]*egressContainer // key is containerID
+	startTime  time.Time
+}
+
+func CreateRuleR0015DirectIPEgressNetworkTraffic() *R0015DirectIPEgressNetworkTraffic {
+	return &R0015DirectIPEgressNetworkTraffic{
+		containers: make(map[string]*egressContainer),
+		startTime:  time.Now(),
+	}
+}
+
+func (rule *R0015DirectIPEgressNetworkTraffic) Name() string {
+	return R0015Name
+}
+func (rule *R0015DirectIPEgressNetworkTraffic) ID() string {
+	return R0015ID
+}
+
+func (rule *R0015DirectIPEgressNetworkTraffic) DeleteRule() {
+}
+
+func (rule *R0015DirectIPEgressNetworkTraffic) ContainerStarted(_ *containercollection.Container) {
+}
+
+// ContainerStopped forgets the addresses resolved and alerted for the container
+func (rule *R0015DirectIPEgressNetworkTraffic) ContainerStopped(containerID string) {
+	rule.mutex.Lock()
+	defer rule.mutex.Unlock()
+	delete(rule.containers, containerID)
+}
+
+// getContainer returns the addresses of the container, nil when none is tracked and create is false
+func (rule *R0015DirectIPEgressNetworkTraffic) getContainer(containerID string, create bool) *egressContainer {
+	rule.mutex.Lock()
+	defer rule.mutex.Unlock()
+	container, ok := rule.containers[containerID]
+	if !ok && create {
+		resolvedAddresses, _ := lru.New[string, struct{}](maxContainerAddresses)
+		alertedAddresses, _ := lru.New[string, struct{}](maxContainerAddresses)
+		container = &egressContainer{resolvedAddresses: resolvedAddresses, alertedAddresses: alertedAddresses}
+		rule.containers[containerID] = container
+	}
+	return container
+}
+
+func (rule *R0015DirectIPEgressNetworkTraffic) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	switch eventType {
+	case utils.DnsEventType:
+		// the addresses resolved by the container, the resolutions of the other containers of the node don't count
+		dnsEvent, ok := event.(*tracerdnstype.Event)
+		if !ok || len(dnsEvent.Addresses) == 0 {
+			return nil
+		}
+		container := rule.getContainer(dnsEvent.Runtime.ContainerID, true)
+		for _, address := range dnsEvent.Addresses {
+			container.resolvedAddresses.Add(address, struct{}{})
+		}
+	case utils.NetworkEventType:
+		networkEvent, ok := event.(*tracernetworktype.Event)
+		if !ok {
+			return nil
+		}
+		return rule.handleNetworkEvent(networkEvent, objCache)
+	}
+	return nil
+}
+
+func (rule *R0015DirectIPEgressNetworkTraffic) handleNetworkEvent(networkEvent *tracernetworktype.Event, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	if networkEvent.PktType != "OUTGOING" || isPrivateIP(networkEvent.DstEndpoint.Addr) {
+		return nil
+	}
+
+	// The DNS requests of the host network pods are not observed.
+	if networkEvent.K8s.HostNetwork {
+		return nil
+	}
+
+	// Check if the container was pre-running, its DNS requests were not observed.
+	if time.Unix(int64(networkEvent.Runtime.ContainerStartedAt), 0).Before(rule.startTime) {
+		return nil
+	}
+
+	container := rule.getContainer(networkEvent.Runtime.ContainerID, true)
+	if container.resolvedAddresses.Contains(networkEvent.DstEndpoint.Addr) {
+		return nil
+	}
+
+	// Check if we already alerted on this address.
+	if container.alertedAddresses.Contains(networkEvent.DstEndpoint.Addr) {
+		return nil
+	}
+
+	// Addresses learned without DNS are expected.
+	if nnContainer, err := GetLearnedNetworkNeighborhoodContainer(&networkEvent.Event, objCache); err == nil {
+		for _, egress := range nnContainer.Egress {
+			if egress.IPAddress == networkEvent.DstEndpoint.Addr {
+				return nil
+			}
+		}
+	}
+
+	container.alertedAddresses.Add(networkEvent.DstEndpoint.Addr, struct{}{})
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: networkEvent.Pid,
+			Arguments: map[string]interface{}{
+				"ip":    networkEvent.DstEndpoint.Addr,
+				"port":  networkEvent.Port,
+				"proto": networkEvent.Proto,
+			},
+			Severity: R0015DirectIPEgressNetworkTrafficRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				Comm: networkEvent.Comm,
+				Gid:  &networkEvent.Gid,
+				PID:  networkEvent.Pid,
+				Uid:  &networkEvent.Uid,
+			},
+			ContainerID: networkEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: networkEvent.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Egress network communication to an IP never resolved via DNS: %s:%d using %s from: %s", networkEvent.DstEndpoint.Addr, networkEvent.Port, networkEvent.Proto, networkEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   networkEvent.GetPod(),
+			PodLabels: networkEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+	}
+}
+
+func (rule *R0015DirectIPEgressNetworkTraffic) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R0015DirectIPEgressNetworkTrafficRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
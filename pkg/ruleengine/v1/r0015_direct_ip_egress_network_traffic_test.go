@@ -0,0 +1,121 @@
+package ruleengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracerdnstype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/dns/types"
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+func TestR0015DirectIPEgressNetworkTraffic(t *testing.T) {
+	// Create a new rule
+	r := CreateRuleR0015DirectIPEgressNetworkTraffic()
+	// Assert r is not nil
+	if r == nil {
+		t.Errorf("Expected r to not be nil")
+	}
+
+	commonData := eventtypes.CommonData{
+		K8s: eventtypes.K8sMetadata{
+			BasicK8sMetadata: eventtypes.BasicK8sMetadata{
+				ContainerName: "test",
+			},
+		},
+		Runtime: eventtypes.BasicRuntimeMetadata{
+			ContainerID:        "test",
+			ContainerStartedAt: eventtypes.Time(time.Now().UnixNano()),
+		},
+	}
+
+	// Create a network request event
+	e := &tracernetworktype.Event{
+		Event: eventtypes.Event{
+			CommonData: commonData,
+		},
+		PktType: "OUTGOING",
+		DstEndpoint: eventtypes.L3Endpoint{
+			Addr: "1.1.1.1",
+		},
+		Port:  443,
+		Proto: "TCP",
+	}
+
+	// Test without profile and without DNS resolution.
+	objCache := RuleObjectCacheMock{}
+	ruleResult := r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the address was never resolved")
+	}
+
+	// Test with an address resolved by another container.
+	dnsEvent := &tracerdnstype.Event{
+		Event: eventtypes.Event{
+			CommonData: eventtypes.CommonData{
+				Runtime: eventtypes.BasicRuntimeMetadata{ContainerID: "other"},
+			},
+		},
+		DNSName:   "test.com.",
+		Addresses: []string{"2.2.2.2"},
+	}
+	r.ProcessEvent(utils.DnsEventType, dnsEvent, &objCache)
+	e.DstEndpoint.Addr = "2.2.2.2"
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the address was resolved by another container")
+	}
+
+	// Test with an address resolved by the container.
+	dnsEvent.Runtime.ContainerID = "test"
+	dnsEvent.Addresses = []string{"5.5.5.5"}
+	r.ProcessEvent(utils.DnsEventType, dnsEvent, &objCache)
+	e.DstEndpoint.Addr = "5.5.5.5"
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the address was resolved")
+	}
+
+	// Test with the resolutions of a stopped container.
+	r.ContainerStopped("test")
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the resolutions of the stopped container are dropped")
+	}
+
+	// Test with a host network pod.
+	e.DstEndpoint.Addr = "4.4.4.4"
+	e.K8s.HostNetwork = true
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the pod uses the host network")
+	}
+	e.K8s.HostNetwork = false
+
+	// Test with a learned address.
+	nn := &v1beta1.NetworkNeighborhood{}
+	nn.Spec.Containers = append(nn.Spec.Containers, v1beta1.NetworkNeighborhoodContainer{
+		Name: "test",
+		Egress: []v1beta1.NetworkNeighbor{
+			{
+				IPAddress: "3.3.3.3",
+			},
+		},
+	})
+	objCache.SetNetworkNeighborhood(nn)
+	e.DstEndpoint.Addr = "3.3.3.3"
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the address is whitelisted")
+	}
+
+	// Test with private address.
+	e.DstEndpoint.Addr = "10.0.0.1"
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since address is private")
+	}
+}
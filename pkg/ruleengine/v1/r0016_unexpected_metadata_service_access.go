@@ -0,0 +1,135 @@
+package ruleengine
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	"github.com/goradd/maps"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+)
+
+const (
+	R0016ID   = "R0016"
+	R0016Name = "Unexpected Metadata Service Access"
+)
+
+// MetadataServiceAddresses are the addresses of the cloud providers instance metadata services.
+var MetadataServiceAddresses = []string{
+	"169.254.169.254",
+	"fd00:ec2::254",
+	"100.100.100.200",
+}
+
+var R0016UnexpectedMetadataServiceAccessRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R0016ID,
+	Name:        R0016Name,
+	Description: "Detecting access to the node metadata service from workloads that never used it during learning.",
+	Tags:        []string{"whitelisted", "network", "cloud", "credentials"},
+	Priority:    RulePriorityHigh,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{utils.NetworkEventType},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR0016UnexpectedMetadataServiceAccess()
+	},
+}
+var _ ruleengine.RuleEvaluator = (*R0016UnexpectedMetadataServiceAccess)(nil)
+
+type R0016UnexpectedMetadataServiceAccess struct {
+	BaseRule
+	alertedContainers maps.SafeMap[string, bool]
+	startTime         time.Time
+}
+
+func CreateRuleR0016UnexpectedMetadataServiceAccess() *R0016UnexpectedMetadataServiceAccess {
+	return &R0016UnexpectedMetadataServiceAccess{startTime: time.Now()}
+}
+
+func (rule *R0016UnexpectedMetadataServiceAccess) Name() string {
+	return R0016Name
+}
+func (rule *R0016UnexpectedMetadataServiceAccess) ID() string {
+	return R0016ID
+}
+
+func (rule *R0016UnexpectedMetadataServiceAccess) DeleteRule() {
+}
+
+func (rule *R0016UnexpectedMetadataServiceAccess) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	if eventType != utils.NetworkEventType {
+		return nil
+	}
+
+	networkEvent, ok := event.(*tracernetworktype.Event)
+	if !ok {
+		return nil
+	}
+
+	if networkEvent.PktType != "OUTGOING" || !slices.Contains(MetadataServiceAddresses, networkEvent.DstEndpoint.Addr) {
+		return nil
+	}
+
+	// Check if the container was pre-running.
+	if time.Unix(int64(networkEvent.Runtime.ContainerStartedAt), 0).Before(rule.startTime) {
+		return nil
+	}
+
+	if rule.alertedContainers.Has(networkEvent.Runtime.ContainerID) {
+		return nil
+	}
+
+	nnContainer, err := GetLearnedNetworkNeighborhoodContainer(&networkEvent.Event, objCache)
+	if err != nil {
+		return nil
+	}
+
+	for _, egress := range nnContainer.Egress {
+		if egress.IPAddress == networkEvent.DstEndpoint.Addr {
+			return nil
+		}
+	}
+
+	rule.alertedContainers.Set(networkEvent.Runtime.ContainerID, true)
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: networkEvent.Pid,
+			Arguments: map[string]interface{}{
+				"ip":    networkEvent.DstEndpoint.Addr,
+				"port":  networkEvent.Port,
+				"proto": networkEvent.Proto,
+			},
+			Severity: R0016UnexpectedMetadataServiceAccessRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				Comm: networkEvent.Comm,
+				Gid:  &networkEvent.Gid,
+				PID:  networkEvent.Pid,
+				Uid:  &networkEvent.Uid,
+			},
+			ContainerID: networkEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: networkEvent.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Unexpected metadata service access: %s:%d from: %s", networkEvent.DstEndpoint.Addr, networkEvent.Port, networkEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   networkEvent.GetPod(),
+			PodLabels: networkEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+	}
+}
+
+func (rule *R0016UnexpectedMetadataServiceAccess) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R0016UnexpectedMetadataServiceAccessRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
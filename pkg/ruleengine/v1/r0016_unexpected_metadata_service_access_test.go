@@ -0,0 +1,84 @@
+package ruleengine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+func TestR0016UnexpectedMetadataServiceAccess(t *testing.T) {
+	// Create a new rule
+	r := CreateRuleR0016UnexpectedMetadataServiceAccess()
+	// Assert r is not nil
+	if r == nil {
+		t.Errorf("Expected r to not be nil")
+	}
+
+	// Create a network request event
+	e := &tracernetworktype.Event{
+		Event: eventtypes.Event{
+			CommonData: eventtypes.CommonData{
+				K8s: eventtypes.K8sMetadata{
+					BasicK8sMetadata: eventtypes.BasicK8sMetadata{
+						ContainerName: "test",
+					},
+				},
+				Runtime: eventtypes.BasicRuntimeMetadata{
+					ContainerID:        "test",
+					ContainerStartedAt: eventtypes.Time(time.Now().UnixNano()),
+				},
+			},
+		},
+		PktType: "OUTGOING",
+		DstEndpoint: eventtypes.L3Endpoint{
+			Addr: "169.254.169.254",
+		},
+		Port:  80,
+		Proto: "TCP",
+	}
+
+	// Test with nil network neighborhood.
+	ruleResult := r.ProcessEvent(utils.NetworkEventType, e, &RuleObjectCacheMock{})
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since no network neighborhood")
+	}
+
+	objCache := RuleObjectCacheMock{}
+	nn := &v1beta1.NetworkNeighborhood{}
+	nn.Spec.Containers = append(nn.Spec.Containers, v1beta1.NetworkNeighborhoodContainer{
+		Name: "test",
+		Egress: []v1beta1.NetworkNeighbor{
+			{
+				IPAddress: "169.254.169.254",
+			},
+		},
+	})
+	objCache.SetNetworkNeighborhood(nn)
+
+	// Test with a workload that used the metadata service during learning.
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the metadata service is whitelisted")
+	}
+
+	// Test with a workload that never used the metadata service.
+	nn.Spec.Containers[0].Egress = nil
+	objCache.SetNetworkNeighborhood(nn)
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the metadata service was never used")
+	}
+
+	// Test with another address.
+	e.Runtime.ContainerID = "other"
+	e.DstEndpoint.Addr = "1.1.1.1"
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, &objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since it's not the metadata service")
+	}
+}
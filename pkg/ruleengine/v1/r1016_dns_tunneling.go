@@ -0,0 +1,329 @@
+package ruleengine
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	tracerdnstype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/dns/types"
+)
+
+const (
+	R1016ID   = "R1016"
+	R1016Name = "DNS Tunneling Or Generated Domain"
+
+	// maxTrackedContainers bounds the number of containers for which the query rate and the alerts are tracked
+	maxTrackedContainers = 10000
+	// defaultDNSAlertWindow is the time during which a container is alerted once, generated domains change the
+	// registered domain on every query
+	defaultDNSAlertWindow = 10 * time.Minute
+)
+
+var R1016DNSTunnelingRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R1016ID,
+	Name:        R1016Name,
+	Description: "Detecting DNS tunneling and domain generation algorithms by scoring query names and per-container query rate",
+	Tags:        []string{"network", "dns", "tunneling", "dga", "exfiltration"},
+	Priority:    RulePriorityHigh,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{
+			utils.DnsEventType,
+			utils.DnsNoAnswerEventType,
+		},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR1016DNSTunneling()
+	},
+}
+
+var _ ruleengine.RuleEvaluator = (*R1016DNSTunneling)(nil)
+
+// dnsQueryWindow counts the queries of a container during a fixed time window.
+type dnsQueryWindow struct {
+	mutex sync.Mutex
+	start time.Time
+	count int
+}
+
+type R1016DNSTunneling struct {
+	BaseRule
+	alertedContainers *expirable.LRU[string, string]      // key is containerID, value is the parent domain alerted
+	queryWindows      *lru.Cache[string, *dnsQueryWindow] // key is containerID
+
+	// parameters
+	entropyThreshold      float64
+	minEntropyLength      int
+	maxLabelLength        int
+	digitRatioThreshold   float64
+	vowelRatioThreshold   float64
+	queryRateThreshold    int
+	queryRateWindow       time.Duration
+	suspiciousRecordTypes []string
+	ignoredSuffixes       []string
+	scoreThreshold        int
+}
+
+func CreateRuleR1016DNSTunneling() *R1016DNSTunneling {
+	queryWindows, _ := lru.New[string, *dnsQueryWindow](maxTrackedContainers)
+	return &R1016DNSTunneling{
+		alertedContainers:     expirable.NewLRU[string, string](maxTrackedContainers, nil, defaultDNSAlertWindow),
+		queryWindows:          queryWindows,
+		entropyThreshold:      3.8,
+		minEntropyLength:      16,
+		maxLabelLength:        40,
+		digitRatioThreshold:   0.3,
+		vowelRatioThreshold:   0.2,
+		queryRateThreshold:    300,
+		queryRateWindow:       time.Minute,
+		suspiciousRecordTypes: []string{"TXT", "NULL"},
+		ignoredSuffixes:       []string{"cluster.local.", "in-addr.arpa.", "ip6.arpa."},
+		scoreThreshold:        3,
+	}
+}
+
+func (rule *R1016DNSTunneling) Name() string {
+	return R1016Name
+}
+
+func (rule *R1016DNSTunneling) ID() string {
+	return R1016ID
+}
+
+func (rule *R1016DNSTunneling) SetParameters(parameters map[string]interface{}) {
+	rule.BaseRule.SetParameters(parameters)
+	parameters = rule.GetParameters()
+
+	if v, ok := InterfaceToFloat64(parameters["entropyThreshold"]); ok {
+		rule.entropyThreshold = v
+	}
+	if v, ok := InterfaceToFloat64(parameters["minEntropyLength"]); ok {
+		rule.minEntropyLength = int(v)
+	}
+	if v, ok := InterfaceToFloat64(parameters["maxLabelLength"]); ok {
+		rule.maxLabelLength = int(v)
+	}
+	if v, ok := InterfaceToFloat64(parameters["digitRatioThreshold"]); ok {
+		rule.digitRatioThreshold = v
+	}
+	if v, ok := InterfaceToFloat64(parameters["vowelRatioThreshold"]); ok {
+		rule.vowelRatioThreshold = v
+	}
+	if v, ok := InterfaceToFloat64(parameters["queryRateThreshold"]); ok {
+		rule.queryRateThreshold = int(v)
+	}
+	if v, ok := InterfaceToFloat64(parameters["queryRateWindowSeconds"]); ok && v > 0 {
+		rule.queryRateWindow = time.Duration(v * float64(time.Second))
+	}
+	if v, ok := InterfaceToFloat64(parameters["alertWindowSeconds"]); ok && v > 0 {
+		rule.alertedContainers = expirable.NewLRU[string, string](maxTrackedContainers, nil, time.Duration(v*float64(time.Second)))
+	}
+	if v, ok := InterfaceToFloat64(parameters["scoreThreshold"]); ok {
+		rule.scoreThreshold = int(v)
+	}
+	if val := parameters["suspiciousRecordTypes"]; val != nil {
+		if recordTypes, ok := InterfaceToStringSlice(val); ok {
+			rule.suspiciousRecordTypes = recordTypes
+		} else {
+			logger.L().Warning("failed to convert suspiciousRecordTypes to []string", helpers.String("ruleID", rule.ID()))
+		}
+	}
+	if val := parameters["ignoredSuffixes"]; val != nil {
+		if suffixes, ok := InterfaceToStringSlice(val); ok {
+			rule.ignoredSuffixes = suffixes
+		} else {
+			logger.L().Warning("failed to convert ignoredSuffixes to []string", helpers.String("ruleID", rule.ID()))
+		}
+	}
+}
+
+func (rule *R1016DNSTunneling) DeleteRule() {
+}
+
+func (rule *R1016DNSTunneling) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, _ objectcache.ObjectCache) ruleengine.RuleFailure {
+	// the responses without answers (NXDOMAIN, TXT...) are only reported to this rule
+	if eventType != utils.DnsEventType && eventType != utils.DnsNoAnswerEventType {
+		return nil
+	}
+
+	dnsEvent, ok := event.(*tracerdnstype.Event)
+	if !ok {
+		return nil
+	}
+
+	name := strings.ToLower(dnsEvent.DNSName)
+	if name == "" {
+		return nil
+	}
+	for _, suffix := range rule.ignoredSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return nil
+		}
+	}
+
+	// the rate is tracked for every query, the name is scored afterward
+	rate := rule.countQuery(dnsEvent.Runtime.ContainerID)
+
+	score, reasons := rule.scoreQuery(name, dnsEvent.QType)
+	if rate > rule.queryRateThreshold {
+		score++
+		reasons = append(reasons, fmt.Sprintf("query rate %d in %s", rate, rule.queryRateWindow))
+	}
+	if score < rule.scoreThreshold {
+		return nil
+	}
+
+	// alert once per container during the alert window, tunneling generates a new name per query and generated
+	// domains a new registered domain
+	parentDomain := getParentDomain(name)
+	if rule.alertedContainers.Contains(dnsEvent.Runtime.ContainerID) {
+		return nil
+	}
+	rule.alertedContainers.Add(dnsEvent.Runtime.ContainerID, parentDomain)
+
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: dnsEvent.Pid,
+			Arguments: map[string]interface{}{
+				"domain":  dnsEvent.DNSName,
+				"parent":  parentDomain,
+				"qtype":   dnsEvent.QType,
+				"score":   score,
+				"reasons": reasons,
+			},
+			Severity: R1016DNSTunnelingRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				Comm:  dnsEvent.Comm,
+				Gid:   &dnsEvent.Gid,
+				PID:   dnsEvent.Pid,
+				Uid:   &dnsEvent.Uid,
+				Pcomm: dnsEvent.Pcomm,
+				Path:  dnsEvent.Exepath,
+				Cwd:   dnsEvent.Cwd,
+				PPID:  dnsEvent.Ppid,
+			},
+			ContainerID: dnsEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: dnsEvent.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Possible DNS tunneling or generated domain: %s (%s) in: %s", dnsEvent.DNSName, strings.Join(reasons, ", "), dnsEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   dnsEvent.GetPod(),
+			PodLabels: dnsEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+	}
+}
+
+func (rule *R1016DNSTunneling) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R1016DNSTunnelingRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
+
+// countQuery records a query of the container and returns the number of queries in the current window.
+func (rule *R1016DNSTunneling) countQuery(containerID string) int {
+	window, ok := rule.queryWindows.Get(containerID)
+	if !ok {
+		window = &dnsQueryWindow{start: time.Now()}
+		if found, _ := rule.queryWindows.ContainsOrAdd(containerID, window); found {
+			window, _ = rule.queryWindows.Get(containerID)
+		}
+	}
+	window.mutex.Lock()
+	defer window.mutex.Unlock()
+	if now := time.Now(); now.Sub(window.start) > rule.queryRateWindow {
+		window.start = now
+		window.count = 0
+	}
+	window.count++
+	return window.count
+}
+
+// scoreQuery returns one point per suspicious property of the query name and type, with the matching reasons.
+func (rule *R1016DNSTunneling) scoreQuery(name, qtype string) (int, []string) {
+	var reasons []string
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	// the TLD carries no information
+	if len(labels) > 1 {
+		labels = labels[:len(labels)-1]
+	}
+	payload := strings.Join(labels, "")
+
+	if len(payload) >= rule.minEntropyLength {
+		if entropy := shannonEntropy(payload); entropy >= rule.entropyThreshold {
+			reasons = append(reasons, fmt.Sprintf("entropy %.2f", entropy))
+		}
+	}
+
+	for _, label := range labels {
+		if len(label) > rule.maxLabelLength {
+			reasons = append(reasons, fmt.Sprintf("label length %d", len(label)))
+			break
+		}
+	}
+
+	var digits, letters, vowels int
+	for _, c := range payload {
+		switch {
+		case unicode.IsDigit(c):
+			digits++
+		case unicode.IsLetter(c):
+			letters++
+			if strings.ContainsRune("aeiouy", c) {
+				vowels++
+			}
+		}
+	}
+	if len(payload) > 0 && float64(digits)/float64(len(payload)) >= rule.digitRatioThreshold {
+		reasons = append(reasons, fmt.Sprintf("digit ratio %.2f", float64(digits)/float64(len(payload))))
+	} else if letters >= rule.minEntropyLength/2 && float64(vowels)/float64(letters) < rule.vowelRatioThreshold {
+		reasons = append(reasons, fmt.Sprintf("vowel ratio %.2f", float64(vowels)/float64(letters)))
+	}
+
+	if slices.Contains(rule.suspiciousRecordTypes, strings.ToUpper(qtype)) {
+		reasons = append(reasons, fmt.Sprintf("record type %s", qtype))
+	}
+
+	return len(reasons), reasons
+}
+
+// shannonEntropy returns the entropy of the string in bits per character.
+func shannonEntropy(s string) float64 {
+	frequencies := make(map[rune]int)
+	for _, c := range s {
+		frequencies[c]++
+	}
+	var entropy float64
+	length := float64(len([]rune(s)))
+	for _, count := range frequencies {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// getParentDomain returns the registered part of the domain (last two labels).
+func getParentDomain(name string) string {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return strings.Join(labels, ".") + "."
+}
@@ -0,0 +1,106 @@
+package ruleengine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracerdnstype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/dns/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestR1016DNSTunneling(t *testing.T) {
+	// Create a new rule
+	r := CreateRuleR1016DNSTunneling()
+	// Assert r is not nil
+	if r == nil {
+		t.Errorf("Expected r to not be nil")
+	}
+
+	// Create a dns event
+	e := &tracerdnstype.Event{
+		Event: eventtypes.Event{
+			CommonData: eventtypes.CommonData{
+				K8s: eventtypes.K8sMetadata{
+					BasicK8sMetadata: eventtypes.BasicK8sMetadata{
+						ContainerName: "test",
+					},
+				},
+				Runtime: eventtypes.BasicRuntimeMetadata{
+					ContainerID: "test",
+				},
+			},
+		},
+		DNSName: "www.google.com.",
+		QType:   "A",
+	}
+
+	// Test with a regular domain, no profile is needed.
+	ruleResult := r.ProcessEvent(utils.DnsEventType, e, &RuleObjectCacheMock{})
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the domain is regular")
+	}
+
+	// Test with an in-cluster domain.
+	e.DNSName = "x7k2q9z4w1v8b3n6m5c0l.default.svc.cluster.local."
+	ruleResult = r.ProcessEvent(utils.DnsEventType, e, &RuleObjectCacheMock{})
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since in-cluster domains are ignored")
+	}
+
+	// Test with a tunneling query without answer.
+	e.DNSName = "mzxw6ytboi4tmnrtgq3dknzqgi2tcmzxgm3donbvgy4a7k2q9z4w1v8b3n6.tunnel.evil.com."
+	e.QType = "TXT"
+	ruleResult = r.ProcessEvent(utils.DnsNoAnswerEventType, e, &RuleObjectCacheMock{})
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the query looks like tunneling")
+	}
+
+	// Test with another query to the same domain.
+	e.DNSName = "nbswy3dpeb3w64tmmqqgc3tfmqqhezlhnfzxi4tbo5sg3j8k2l4m9x.tunnel.evil.com."
+	ruleResult = r.ProcessEvent(utils.DnsEventType, e, &RuleObjectCacheMock{})
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since we already alerted on this domain")
+	}
+
+	// Test with another domain in the same container within the alert window.
+	e.DNSName = "nbswy3dpeb3w64tmmqqgc3tfmqqhezlhnfzxi4tbo5sg3j8k2l4m9x.tunnel.bad.org."
+	ruleResult = r.ProcessEvent(utils.DnsEventType, e, &RuleObjectCacheMock{})
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since we already alerted on this container")
+	}
+
+	// Test with a generated domain with a higher threshold.
+	r.SetParameters(map[string]interface{}{"scoreThreshold": 4})
+	e.Runtime.ContainerID = "generated"
+	e.DNSName = "xkqzwvtplmrbcdfghjtn.com."
+	e.QType = "A"
+	ruleResult = r.ProcessEvent(utils.DnsEventType, e, &RuleObjectCacheMock{})
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the score is below the threshold")
+	}
+
+	// Test with a generated domain with a lower threshold.
+	r.SetParameters(map[string]interface{}{"scoreThreshold": float64(2)})
+	ruleResult = r.ProcessEvent(utils.DnsEventType, e, &RuleObjectCacheMock{})
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the domain looks generated")
+	}
+
+	// Test with a high query rate.
+	r.SetParameters(map[string]interface{}{"scoreThreshold": 1, "queryRateThreshold": 10})
+	e.Runtime.ContainerID = "other"
+	for i := 0; i < 10; i++ {
+		e.DNSName = fmt.Sprintf("api%d.example.com.", i)
+		ruleResult = r.ProcessEvent(utils.DnsEventType, e, &RuleObjectCacheMock{})
+		if ruleResult != nil {
+			t.Errorf("Expected ruleResult to be nil since the query rate is below the threshold")
+		}
+	}
+	e.DNSName = "api.example.com."
+	ruleResult = r.ProcessEvent(utils.DnsEventType, e, &RuleObjectCacheMock{})
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the query rate is above the threshold")
+	}
+}
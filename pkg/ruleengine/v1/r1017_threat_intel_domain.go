@@ -0,0 +1,120 @@
+package ruleengine
+
+import (
+	"fmt"
+
+	"github.com/goradd/maps"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	tracerdnstype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/dns/types"
+)
+
+const (
+	R1017ID   = "R1017"
+	R1017Name = "Threat Intel Domain Match"
+)
+
+var R1017ThreatIntelDomainRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R1017ID,
+	Name:        R1017Name,
+	Description: "Detecting DNS queries to domains listed in the loaded threat intel feeds",
+	Tags:        []string{"network", "dns", "threat-intel", "malicious"},
+	Priority:    RulePriorityHigh,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{
+			utils.DnsEventType,
+		},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR1017ThreatIntelDomain()
+	},
+}
+
+var _ ruleengine.RuleEvaluator = (*R1017ThreatIntelDomain)(nil)
+
+type R1017ThreatIntelDomain struct {
+	BaseRule
+	alertedDomains maps.SafeMap[string, bool] // key is containerID/domain
+}
+
+func CreateRuleR1017ThreatIntelDomain() *R1017ThreatIntelDomain {
+	return &R1017ThreatIntelDomain{}
+}
+
+func (rule *R1017ThreatIntelDomain) Name() string {
+	return R1017Name
+}
+
+func (rule *R1017ThreatIntelDomain) ID() string {
+	return R1017ID
+}
+
+func (rule *R1017ThreatIntelDomain) DeleteRule() {
+}
+
+func (rule *R1017ThreatIntelDomain) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	if eventType != utils.DnsEventType {
+		return nil
+	}
+
+	dnsEvent, ok := event.(*tracerdnstype.Event)
+	if !ok || dnsEvent.DNSName == "" {
+		return nil
+	}
+
+	alertKey := dnsEvent.Runtime.ContainerID + "/" + dnsEvent.DNSName
+	if rule.alertedDomains.Has(alertKey) {
+		return nil
+	}
+
+	indicator, found := objCache.ThreatIntelCache().LookupDomain(dnsEvent.DNSName)
+	if !found {
+		return nil
+	}
+	rule.alertedDomains.Set(alertKey, true)
+
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: dnsEvent.Pid,
+			Arguments: map[string]interface{}{
+				"domain":      dnsEvent.DNSName,
+				"indicator":   indicator.Value,
+				"source":      indicator.Source,
+				"description": indicator.Description,
+			},
+			Severity: R1017ThreatIntelDomainRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				Comm:  dnsEvent.Comm,
+				Gid:   &dnsEvent.Gid,
+				PID:   dnsEvent.Pid,
+				Uid:   &dnsEvent.Uid,
+				Pcomm: dnsEvent.Pcomm,
+				Path:  dnsEvent.Exepath,
+				Cwd:   dnsEvent.Cwd,
+				PPID:  dnsEvent.Ppid,
+			},
+			ContainerID: dnsEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: dnsEvent.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("DNS query to a threat intel domain: %s (indicator %s from %s) in: %s", dnsEvent.DNSName, indicator.Value, indicator.Source, dnsEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   dnsEvent.GetPod(),
+			PodLabels: dnsEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+	}
+}
+
+func (rule *R1017ThreatIntelDomain) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R1017ThreatIntelDomainRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
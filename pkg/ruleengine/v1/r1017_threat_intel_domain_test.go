@@ -0,0 +1,69 @@
+package ruleengine
+
+import (
+	"testing"
+
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracerdnstype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/dns/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestR1017ThreatIntelDomain(t *testing.T) {
+	// Create a new rule
+	r := CreateRuleR1017ThreatIntelDomain()
+	// Assert r is not nil
+	if r == nil {
+		t.Errorf("Expected r to not be nil")
+	}
+
+	// Create a dns event
+	e := &tracerdnstype.Event{
+		Event: eventtypes.Event{
+			CommonData: eventtypes.CommonData{
+				Runtime: eventtypes.BasicRuntimeMetadata{
+					ContainerID: "test",
+				},
+			},
+		},
+		DNSName: "evil.example.com.",
+	}
+
+	objCache := &RuleObjectCacheMock{}
+
+	// Test without indicators
+	ruleResult := r.ProcessEvent(utils.DnsEventType, e, objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since no indicators are loaded")
+	}
+
+	objCache.SetThreatIndicators(objectcache.ThreatIndicator{
+		Type:   objectcache.ThreatIndicatorTypeDomain,
+		Value:  "evil.example.com",
+		Source: "test-feed",
+	})
+
+	// Test with a matching domain
+	ruleResult = r.ProcessEvent(utils.DnsEventType, e, objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the domain is in the feed")
+		return
+	}
+	if ruleResult.GetBaseRuntimeAlert().Arguments["source"] != "test-feed" {
+		t.Errorf("Expected the alert to carry the indicator source")
+	}
+
+	// Test with the same domain again
+	ruleResult = r.ProcessEvent(utils.DnsEventType, e, objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since we already alerted on this domain")
+	}
+
+	// Test with another domain
+	e.DNSName = "www.google.com."
+	ruleResult = r.ProcessEvent(utils.DnsEventType, e, objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the domain is not in the feed")
+	}
+}
@@ -0,0 +1,120 @@
+package ruleengine
+
+import (
+	"fmt"
+
+	"github.com/goradd/maps"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+)
+
+const (
+	R1018ID   = "R1018"
+	R1018Name = "Threat Intel IP Match"
+)
+
+var R1018ThreatIntelIPRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R1018ID,
+	Name:        R1018Name,
+	Description: "Detecting network traffic with addresses listed in the loaded threat intel feeds",
+	Tags:        []string{"network", "threat-intel", "malicious"},
+	Priority:    RulePriorityHigh,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{
+			utils.NetworkEventType,
+		},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR1018ThreatIntelIP()
+	},
+}
+
+var _ ruleengine.RuleEvaluator = (*R1018ThreatIntelIP)(nil)
+
+type R1018ThreatIntelIP struct {
+	BaseRule
+	alertedAddresses maps.SafeMap[string, bool] // key is containerID/address
+}
+
+func CreateRuleR1018ThreatIntelIP() *R1018ThreatIntelIP {
+	return &R1018ThreatIntelIP{}
+}
+
+func (rule *R1018ThreatIntelIP) Name() string {
+	return R1018Name
+}
+
+func (rule *R1018ThreatIntelIP) ID() string {
+	return R1018ID
+}
+
+func (rule *R1018ThreatIntelIP) DeleteRule() {
+}
+
+func (rule *R1018ThreatIntelIP) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	if eventType != utils.NetworkEventType {
+		return nil
+	}
+
+	networkEvent, ok := event.(*tracernetworktype.Event)
+	if !ok || networkEvent.DstEndpoint.Addr == "" {
+		return nil
+	}
+
+	// the remote peer is the destination endpoint for both directions
+	alertKey := networkEvent.Runtime.ContainerID + "/" + networkEvent.DstEndpoint.Addr
+	if rule.alertedAddresses.Has(alertKey) {
+		return nil
+	}
+
+	indicator, found := objCache.ThreatIntelCache().LookupIP(networkEvent.DstEndpoint.Addr)
+	if !found {
+		return nil
+	}
+	rule.alertedAddresses.Set(alertKey, true)
+
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: networkEvent.Pid,
+			Arguments: map[string]interface{}{
+				"ip":          networkEvent.DstEndpoint.Addr,
+				"port":        networkEvent.Port,
+				"proto":       networkEvent.Proto,
+				"pktType":     networkEvent.PktType,
+				"indicator":   indicator.Value,
+				"source":      indicator.Source,
+				"description": indicator.Description,
+			},
+			Severity: R1018ThreatIntelIPRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				Comm: networkEvent.Comm,
+				Gid:  &networkEvent.Gid,
+				PID:  networkEvent.Pid,
+				Uid:  &networkEvent.Uid,
+			},
+			ContainerID: networkEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: networkEvent.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Network traffic with a threat intel address: %s:%d (indicator %s from %s) in: %s", networkEvent.DstEndpoint.Addr, networkEvent.Port, indicator.Value, indicator.Source, networkEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   networkEvent.GetPod(),
+			PodLabels: networkEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+	}
+}
+
+func (rule *R1018ThreatIntelIP) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R1018ThreatIntelIPRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
@@ -0,0 +1,70 @@
+package ruleengine
+
+import (
+	"testing"
+
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestR1018ThreatIntelIP(t *testing.T) {
+	// Create a new rule
+	r := CreateRuleR1018ThreatIntelIP()
+	// Assert r is not nil
+	if r == nil {
+		t.Errorf("Expected r to not be nil")
+	}
+
+	// Create a network event
+	e := &tracernetworktype.Event{
+		Event: eventtypes.Event{
+			CommonData: eventtypes.CommonData{
+				Runtime: eventtypes.BasicRuntimeMetadata{
+					ContainerID: "test",
+				},
+			},
+		},
+		PktType: "OUTGOING",
+		DstEndpoint: eventtypes.L3Endpoint{
+			Addr: "203.0.113.7",
+		},
+		Port:  443,
+		Proto: "TCP",
+	}
+
+	objCache := &RuleObjectCacheMock{}
+
+	// Test without indicators
+	ruleResult := r.ProcessEvent(utils.NetworkEventType, e, objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since no indicators are loaded")
+	}
+
+	objCache.SetThreatIndicators(objectcache.ThreatIndicator{
+		Type:   objectcache.ThreatIndicatorTypeIP,
+		Value:  "203.0.113.7",
+		Source: "test-feed",
+	})
+
+	// Test with a matching address
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the address is in the feed")
+	}
+
+	// Test with the same address again
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since we already alerted on this address")
+	}
+
+	// Test with another address
+	e.DstEndpoint.Addr = "10.0.0.1"
+	ruleResult = r.ProcessEvent(utils.NetworkEventType, e, objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since the address is not in the feed")
+	}
+}
@@ -0,0 +1,215 @@
+package ruleengine
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/goradd/maps"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/ebpf/events"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+const (
+	R1019ID   = "R1019"
+	R1019Name = "Threat Intel File Hash Match"
+
+	// maxHashedFileSize bounds the size of the opened files hashed by the rule
+	maxHashedFileSize = 50 * 1024 * 1024
+)
+
+var R1019ThreatIntelFileHashRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R1019ID,
+	Name:        R1019Name,
+	Description: "Detecting executed or opened files whose hash is listed in the loaded threat intel feeds",
+	Tags:        []string{"exec", "files", "threat-intel", "malicious"},
+	Priority:    RulePriorityCritical,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{
+			utils.ExecveEventType,
+			utils.OpenEventType,
+		},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR1019ThreatIntelFileHash()
+	},
+}
+
+var _ ruleengine.RuleEvaluator = (*R1019ThreatIntelFileHash)(nil)
+var _ ruleengine.RuleEnrichedFailureEvaluator = (*R1019ThreatIntelFileHash)(nil)
+
+type R1019ThreatIntelFileHash struct {
+	BaseRule
+	alertedFiles maps.SafeMap[string, bool] // key is containerID/sha256
+
+	// parameters
+	hashOpens bool
+}
+
+func CreateRuleR1019ThreatIntelFileHash() *R1019ThreatIntelFileHash {
+	return &R1019ThreatIntelFileHash{}
+}
+
+func (rule *R1019ThreatIntelFileHash) Name() string {
+	return R1019Name
+}
+
+func (rule *R1019ThreatIntelFileHash) ID() string {
+	return R1019ID
+}
+
+func (rule *R1019ThreatIntelFileHash) SetParameters(parameters map[string]interface{}) {
+	rule.BaseRule.SetParameters(parameters)
+	parameters = rule.GetParameters()
+
+	if val := parameters["hashOpens"]; val != nil {
+		if hashOpens, ok := val.(bool); ok {
+			rule.hashOpens = hashOpens
+		} else {
+			logger.L().Warning("failed to convert hashOpens to bool", helpers.String("ruleID", rule.ID()))
+		}
+	}
+}
+
+func (rule *R1019ThreatIntelFileHash) DeleteRule() {
+}
+
+func (rule *R1019ThreatIntelFileHash) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	switch eventType {
+	case utils.ExecveEventType:
+		execEvent, ok := event.(*events.ExecEvent)
+		if !ok {
+			return nil
+		}
+		fullPath := GetExecFullPathFromEvent(execEvent)
+		upperLayer := execEvent.UpperLayer || execEvent.PupperLayer
+		process := apitypes.Process{
+			Comm:       execEvent.Comm,
+			Gid:        &execEvent.Gid,
+			PID:        execEvent.Pid,
+			Uid:        &execEvent.Uid,
+			UpperLayer: &upperLayer,
+			PPID:       execEvent.Ppid,
+			Pcomm:      execEvent.Pcomm,
+			Cwd:        execEvent.Cwd,
+			Hardlink:   execEvent.ExePath,
+			Path:       fullPath,
+			Cmdline:    fmt.Sprintf("%s %s", GetExecPathFromEvent(execEvent), strings.Join(utils.GetExecArgsFromEvent(&execEvent.Event), " ")),
+		}
+		return rule.checkFile(execEvent.Event.Event, execEvent.Pid, fullPath, "exec", process, execEvent.GetExtra(), objCache)
+	case utils.OpenEventType:
+		if !rule.hashOpens {
+			return nil
+		}
+		openEvent, ok := event.(*events.OpenEvent)
+		if !ok {
+			return nil
+		}
+		process := apitypes.Process{
+			Comm: openEvent.Comm,
+			Gid:  &openEvent.Gid,
+			PID:  openEvent.Pid,
+			Uid:  &openEvent.Uid,
+		}
+		return rule.checkFile(openEvent.Event.Event, openEvent.Pid, openEvent.FullPath, "open", process, openEvent.GetExtra(), objCache)
+	}
+	return nil
+}
+
+// checkFile reports the file as a candidate, it is matched against the feeds once the rule manager enrichment
+// computed its hashes.
+func (rule *R1019ThreatIntelFileHash) checkFile(event eventtypes.Event, pid uint32, path, operation string, process apitypes.Process, extra interface{}, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	// the files are matched only when hash indicators are loaded
+	if path == "" || !objCache.ThreatIntelCache().HasHashes() {
+		return nil
+	}
+
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: pid,
+			Arguments: map[string]interface{}{
+				"path":      path,
+				"operation": operation,
+			},
+			Severity: R1019ThreatIntelFileHashRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: process,
+			ContainerID: event.Runtime.ContainerID,
+		},
+		TriggerEvent: event,
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   event.GetPod(),
+			PodLabels: event.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+		Extra:  extra,
+	}
+}
+
+// EvaluateEnrichedFailure looks the hashes of the candidate file up in the threat intel feeds. The executed files
+// are hashed by the enrichment, the opened files are not the process executable and are hashed here.
+func (rule *R1019ThreatIntelFileHash) EvaluateEnrichedFailure(ruleFailure ruleengine.RuleFailure, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	baseRuntimeAlert := ruleFailure.GetBaseRuntimeAlert()
+	path, _ := baseRuntimeAlert.Arguments["path"].(string)
+	operation, _ := baseRuntimeAlert.Arguments["operation"].(string)
+	if operation == "open" {
+		hostPath := filepath.Join("/proc", fmt.Sprintf("/%d/root/%s", baseRuntimeAlert.InfectedPID, path))
+		size, err := utils.GetFileSize(hostPath)
+		if err != nil || size == 0 || size > maxHashedFileSize {
+			return nil
+		}
+		hashes, err := utils.GetFileHashes(hostPath)
+		if err != nil {
+			return nil
+		}
+		baseRuntimeAlert.MD5Hash = hashes.MD5
+		baseRuntimeAlert.SHA1Hash = hashes.SHA1
+		baseRuntimeAlert.SHA256Hash = hashes.SHA256
+	}
+	if baseRuntimeAlert.SHA256Hash == "" {
+		return nil
+	}
+
+	triggerEvent := ruleFailure.GetTriggerEvent()
+	alertKey := triggerEvent.Runtime.ContainerID + "/" + baseRuntimeAlert.SHA256Hash
+	if rule.alertedFiles.Has(alertKey) {
+		return nil
+	}
+
+	threatIntelCache := objCache.ThreatIntelCache()
+	var indicator objectcache.ThreatIndicator
+	found := false
+	for _, hash := range []string{baseRuntimeAlert.SHA256Hash, baseRuntimeAlert.SHA1Hash, baseRuntimeAlert.MD5Hash} {
+		if indicator, found = threatIntelCache.LookupHash(hash); found {
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+	rule.alertedFiles.Set(alertKey, true)
+
+	baseRuntimeAlert.Arguments["indicator"] = indicator.Value
+	baseRuntimeAlert.Arguments["source"] = indicator.Source
+	baseRuntimeAlert.Arguments["description"] = indicator.Description
+	ruleFailure.SetBaseRuntimeAlert(baseRuntimeAlert)
+	ruleFailure.SetRuleAlert(apitypes.RuleAlert{
+		RuleDescription: fmt.Sprintf("File with a threat intel hash (%s): %s (indicator %s from %s) in: %s", operation, path, indicator.Value, indicator.Source, triggerEvent.GetContainer()),
+	})
+	return ruleFailure
+}
+
+func (rule *R1019ThreatIntelFileHash) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R1019ThreatIntelFileHashRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
@@ -0,0 +1,141 @@
+package ruleengine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	events "github.com/kubescape/node-agent/pkg/ebpf/events"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
+	traceropentype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/open/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestR1019ThreatIntelFileHash(t *testing.T) {
+	// Create a new rule
+	r := CreateRuleR1019ThreatIntelFileHash()
+	// Assert r is not nil
+	if r == nil {
+		t.Errorf("Expected r to not be nil")
+	}
+
+	// Create a file, it is reached through /proc/<pid>/root of the test process
+	content := []byte("malicious payload")
+	path := filepath.Join(t.TempDir(), "payload")
+	if err := os.WriteFile(path, content, 0755); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	sum := sha256.Sum256(content)
+
+	// Create an exec event
+	e := &events.ExecEvent{
+		Event: tracerexectype.Event{
+			Event: eventtypes.Event{
+				CommonData: eventtypes.CommonData{
+					Runtime: eventtypes.BasicRuntimeMetadata{
+						ContainerID: "test",
+					},
+				},
+			},
+			Pid:  uint32(os.Getpid()),
+			Comm: "payload",
+			Args: []string{path},
+		},
+	}
+
+	objCache := &RuleObjectCacheMock{}
+
+	// Test without indicators
+	ruleResult := r.ProcessEvent(utils.ExecveEventType, e, objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since no indicators are loaded")
+	}
+
+	objCache.SetThreatIndicators(objectcache.ThreatIndicator{
+		Type:   objectcache.ThreatIndicatorTypeSHA256,
+		Value:  hex.EncodeToString(sum[:]),
+		Source: "test-feed",
+	})
+
+	// Test with a candidate not hashed by the enrichment
+	ruleResult = r.ProcessEvent(utils.ExecveEventType, e, objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the file is a candidate")
+		return
+	}
+	if r.EvaluateEnrichedFailure(ruleResult, objCache) != nil {
+		t.Errorf("Expected ruleResult to be nil since the file was not hashed")
+	}
+
+	// Test with a matching hash computed by the enrichment
+	enrich := func(ruleFailure ruleengine.RuleFailure) ruleengine.RuleFailure {
+		hashes, err := utils.GetFileHashes(path)
+		if err != nil {
+			t.Fatalf("failed to hash file: %v", err)
+		}
+		baseRuntimeAlert := ruleFailure.GetBaseRuntimeAlert()
+		baseRuntimeAlert.MD5Hash = hashes.MD5
+		baseRuntimeAlert.SHA1Hash = hashes.SHA1
+		baseRuntimeAlert.SHA256Hash = hashes.SHA256
+		ruleFailure.SetBaseRuntimeAlert(baseRuntimeAlert)
+		return ruleFailure
+	}
+	ruleResult = r.EvaluateEnrichedFailure(enrich(r.ProcessEvent(utils.ExecveEventType, e, objCache)), objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the file hash is in the feed")
+		return
+	}
+	if ruleResult.GetBaseRuntimeAlert().Arguments["source"] != "test-feed" {
+		t.Errorf("Expected the alert to carry the indicator source")
+	}
+
+	// Test with the same file again
+	ruleResult = r.EvaluateEnrichedFailure(enrich(r.ProcessEvent(utils.ExecveEventType, e, objCache)), objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since we already alerted on this file")
+	}
+
+	// Create an open event of the same file in another container
+	o := &events.OpenEvent{
+		Event: traceropentype.Event{
+			Event: eventtypes.Event{
+				CommonData: eventtypes.CommonData{
+					Runtime: eventtypes.BasicRuntimeMetadata{
+						ContainerID: "other",
+					},
+				},
+			},
+			Pid:      uint32(os.Getpid()),
+			Comm:     "cat",
+			FullPath: path,
+		},
+	}
+
+	// Test with opens disabled
+	ruleResult = r.ProcessEvent(utils.OpenEventType, o, objCache)
+	if ruleResult != nil {
+		t.Errorf("Expected ruleResult to be nil since opens are not hashed by default")
+	}
+
+	// Test with opens enabled, the opened file is hashed by the rule
+	r.SetParameters(map[string]interface{}{"hashOpens": true})
+	ruleResult = r.ProcessEvent(utils.OpenEventType, o, objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the file is a candidate")
+		return
+	}
+	ruleResult = r.EvaluateEnrichedFailure(ruleResult, objCache)
+	if ruleResult == nil {
+		t.Errorf("Expected ruleResult to not be nil since the opened file hash is in the feed")
+		return
+	}
+	if ruleResult.GetBaseRuntimeAlert().SHA256Hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected the alert to carry the opened file SHA256 hash")
+	}
+}
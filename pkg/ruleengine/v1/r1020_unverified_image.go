@@ -0,0 +1,105 @@
+package ruleengine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	ruleenginetypes "github.com/kubescape/node-agent/pkg/ruleengine/types"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+)
+
+const (
+	R1020ID   = "R1020"
+	R1020Name = "Unverified Image"
+)
+
+var R1020UnverifiedImageRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R1020ID,
+	Name:        R1020Name,
+	Description: "Detecting containers started from images without a valid signature or provenance attestation.",
+	Tags:        []string{"image", "signature", "supply-chain"},
+	Priority:    RulePriorityHigh,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{
+			utils.ImageVerificationEventType,
+		},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR1020UnverifiedImage()
+	},
+}
+var _ ruleengine.RuleEvaluator = (*R1020UnverifiedImage)(nil)
+
+type R1020UnverifiedImage struct {
+	BaseRule
+}
+
+func CreateRuleR1020UnverifiedImage() *R1020UnverifiedImage {
+	return &R1020UnverifiedImage{}
+}
+
+func (rule *R1020UnverifiedImage) Name() string {
+	return R1020Name
+}
+
+func (rule *R1020UnverifiedImage) ID() string {
+	return R1020ID
+}
+
+func (rule *R1020UnverifiedImage) DeleteRule() {
+}
+
+func (rule *R1020UnverifiedImage) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, _ objectcache.ObjectCache) ruleengine.RuleFailure {
+	if eventType != utils.ImageVerificationEventType {
+		return nil
+	}
+
+	verificationEvent, ok := event.(*ruleenginetypes.ImageVerificationEvent)
+	if !ok || verificationEvent.Verified {
+		return nil
+	}
+
+	imageDigest := verificationEvent.ImageID
+	if _, digest, found := strings.Cut(verificationEvent.ImageID, "@"); found {
+		imageDigest = digest
+	}
+
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName: rule.Name(),
+			Arguments: map[string]interface{}{
+				"imageID": verificationEvent.ImageID,
+				"status":  verificationEvent.Status,
+				"reason":  verificationEvent.Reason,
+			},
+			InfectedPID: verificationEvent.Pid,
+			Severity:    R1020UnverifiedImageRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				PID: verificationEvent.Pid,
+			},
+			ContainerID: verificationEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: verificationEvent.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Image %s is %s in: %s", verificationEvent.ImageID, verificationEvent.Status, verificationEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			ImageDigest: imageDigest,
+			PodName:     verificationEvent.GetPod(),
+			PodLabels:   verificationEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+	}
+}
+
+func (rule *R1020UnverifiedImage) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R1020UnverifiedImageRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
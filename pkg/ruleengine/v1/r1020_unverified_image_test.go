@@ -0,0 +1,49 @@
+package ruleengine
+
+import (
+	"testing"
+
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	ruleenginetypes "github.com/kubescape/node-agent/pkg/ruleengine/types"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestR1020UnverifiedImage(t *testing.T) {
+	r := CreateRuleR1020UnverifiedImage()
+
+	newEvent := func(verified bool, status string) *ruleenginetypes.ImageVerificationEvent {
+		return &ruleenginetypes.ImageVerificationEvent{
+			Event: eventtypes.Event{
+				CommonData: eventtypes.CommonData{
+					K8s: eventtypes.K8sMetadata{
+						BasicK8sMetadata: eventtypes.BasicK8sMetadata{
+							ContainerName: "test",
+						},
+					},
+					Runtime: eventtypes.BasicRuntimeMetadata{
+						ContainerID: "test",
+					},
+				},
+			},
+			Pid:      1234,
+			ImageID:  "docker.io/library/nginx@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+			Verified: verified,
+			Status:   status,
+			Reason:   "no mirrored signature nor attestation",
+		}
+	}
+
+	objCache := RuleObjectCacheMock{}
+	assert.Nil(t, r.ProcessEvent(utils.ImageVerificationEventType, newEvent(true, "verified"), &objCache))
+
+	ruleResult := r.ProcessEvent(utils.ImageVerificationEventType, newEvent(false, "unsigned"), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "unsigned", ruleResult.GetBaseRuntimeAlert().Arguments["status"])
+	assert.Equal(t, "sha256:1111111111111111111111111111111111111111111111111111111111111111", ruleResult.GetRuntimeAlertK8sDetails().ImageDigest)
+	assert.Equal(t, uint32(1234), ruleResult.GetBaseRuntimeAlert().InfectedPID)
+
+	// other events are ignored
+	assert.Nil(t, r.ProcessEvent(utils.ExecveEventType, newEvent(false, "mismatch"), &objCache))
+}
@@ -0,0 +1,156 @@
+package ruleengine
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+
+	tracerseccompaudittype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer/types"
+)
+
+const (
+	R1021ID   = "R1021"
+	R1021Name = "Seccomp Violation"
+)
+
+var R1021SeccompViolationRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R1021ID,
+	Name:        R1021Name,
+	Description: "Detecting syscalls denied by the seccomp profile of the container.",
+	Tags:        []string{"syscall", "seccomp", "exploit"},
+	Priority:    RulePriorityHigh,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{
+			utils.SeccompEventType,
+		},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR1021SeccompViolation()
+	},
+}
+var _ ruleengine.RuleEvaluator = (*R1021SeccompViolation)(nil)
+var _ ruleengine.RuleContainerTracker = (*R1021SeccompViolation)(nil)
+
+type R1021SeccompViolation struct {
+	BaseRule
+	alertedSyscalls mapset.Set[string] // key is containerID/syscall
+}
+
+func CreateRuleR1021SeccompViolation() *R1021SeccompViolation {
+	return &R1021SeccompViolation{
+		alertedSyscalls: mapset.NewSet[string](),
+	}
+}
+
+func (rule *R1021SeccompViolation) Name() string {
+	return R1021Name
+}
+
+func (rule *R1021SeccompViolation) ID() string {
+	return R1021ID
+}
+
+func (rule *R1021SeccompViolation) DeleteRule() {
+}
+
+func (rule *R1021SeccompViolation) ContainerStarted(_ *containercollection.Container) {
+}
+
+// ContainerStopped forgets the syscalls alerted for the container
+func (rule *R1021SeccompViolation) ContainerStopped(containerID string) {
+	for _, key := range rule.alertedSyscalls.ToSlice() {
+		if strings.HasPrefix(key, containerID+"/") {
+			rule.alertedSyscalls.Remove(key)
+		}
+	}
+}
+
+func (rule *R1021SeccompViolation) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	if eventType != utils.SeccompEventType {
+		return nil
+	}
+
+	seccompEvent, ok := event.(*tracerseccompaudittype.Event)
+	if !ok || !seccompEvent.Action.Denied() {
+		// the complain profiles only log the syscalls, they are fed back as suggestions
+		return nil
+	}
+
+	syscall := seccompEvent.Syscall
+	if syscall == "" {
+		// a syscall of a foreign architecture, e.g. a 32-bit syscall from a 64-bit container
+		syscall = "compat_" + strconv.Itoa(seccompEvent.SyscallNr)
+	}
+
+	alertKey := seccompEvent.Runtime.ContainerID + "/" + syscall
+	if rule.alertedSyscalls.ContainsOne(alertKey) {
+		return nil
+	}
+
+	// a syscall learned in the application profile is denied by a too tight profile, an unknown one is suspicious
+	reason := "no application profile to compare with"
+	severity := RulePriorityMed
+	var profilePath string
+	if ap := objCache.ApplicationProfileCache().GetApplicationProfile(seccompEvent.Runtime.ContainerID); ap != nil {
+		if container, err := GetContainerFromApplicationProfile(ap, seccompEvent.GetContainer()); err == nil {
+			profilePath = container.SeccompProfile.Path
+			if slices.Contains(container.Syscalls, syscall) {
+				reason = "syscall learned in the application profile, the seccomp profile is too tight"
+			} else {
+				reason = "syscall never used by the container, possible exploit attempt"
+				severity = R1021SeccompViolationRuleDescriptor.Priority
+			}
+		}
+	}
+
+	rule.alertedSyscalls.Add(alertKey)
+
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName: rule.Name(),
+			Arguments: map[string]interface{}{
+				"syscall": syscall,
+				"action":  string(seccompEvent.Action),
+				"errno":   seccompEvent.Errno,
+				"profile": profilePath,
+				"reason":  reason,
+			},
+			InfectedPID: seccompEvent.Pid,
+			Severity:    severity,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				Comm: seccompEvent.Comm,
+				PID:  seccompEvent.Pid,
+				Uid:  &seccompEvent.Uid,
+				Gid:  &seccompEvent.Gid,
+				Path: seccompEvent.ExePath,
+			},
+			ContainerID: seccompEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: seccompEvent.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Seccomp %s of syscall %s from: %s in: %s", seccompEvent.Action, syscall, seccompEvent.Comm, seccompEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   seccompEvent.GetPod(),
+			PodLabels: seccompEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+	}
+}
+
+func (rule *R1021SeccompViolation) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R1021SeccompViolationRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
@@ -0,0 +1,81 @@
+package ruleengine
+
+import (
+	"testing"
+
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracerseccompaudittype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/seccompaudit/tracer/types"
+)
+
+func TestR1021SeccompViolation(t *testing.T) {
+	r := CreateRuleR1021SeccompViolation()
+
+	newEvent := func(syscall string, action tracerseccompaudittype.Action) *tracerseccompaudittype.Event {
+		return &tracerseccompaudittype.Event{
+			Event: eventtypes.Event{
+				CommonData: eventtypes.CommonData{
+					K8s: eventtypes.K8sMetadata{
+						BasicK8sMetadata: eventtypes.BasicK8sMetadata{
+							ContainerName: "test",
+						},
+					},
+					Runtime: eventtypes.BasicRuntimeMetadata{
+						ContainerID: "test",
+					},
+				},
+			},
+			Pid:     1234,
+			Comm:    "nginx",
+			ExePath: "/usr/sbin/nginx",
+			Syscall: syscall,
+			Action:  action,
+			Errno:   1,
+		}
+	}
+
+	// no application profile
+	objCache := RuleObjectCacheMock{}
+	ruleResult := r.ProcessEvent(utils.SeccompEventType, newEvent("bpf", tracerseccompaudittype.ActionErrno), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, RulePriorityMed, ruleResult.GetBaseRuntimeAlert().Severity)
+
+	objCache.SetApplicationProfile(&v1beta1.ApplicationProfile{
+		Spec: v1beta1.ApplicationProfileSpec{
+			Containers: []v1beta1.ApplicationProfileContainer{
+				{
+					Name:           "test",
+					Syscalls:       []string{"accept4", "read", "write"},
+					SeccompProfile: v1beta1.SingleSeccompProfile{Path: "kubescape/default/replicaset-nginx-nginx-enforce.json"},
+				},
+			},
+		},
+	})
+
+	// the complain profiles only log the syscalls
+	assert.Nil(t, r.ProcessEvent(utils.SeccompEventType, newEvent("ptrace", tracerseccompaudittype.ActionLog), &objCache))
+
+	// a learned syscall denied by a too tight profile
+	ruleResult = r.ProcessEvent(utils.SeccompEventType, newEvent("accept4", tracerseccompaudittype.ActionErrno), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, RulePriorityMed, ruleResult.GetBaseRuntimeAlert().Severity)
+	assert.Equal(t, "kubescape/default/replicaset-nginx-nginx-enforce.json", ruleResult.GetBaseRuntimeAlert().Arguments["profile"])
+
+	// a syscall never used by the container
+	ruleResult = r.ProcessEvent(utils.SeccompEventType, newEvent("ptrace", tracerseccompaudittype.ActionKillProcess), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, RulePriorityHigh, ruleResult.GetBaseRuntimeAlert().Severity)
+	assert.Equal(t, "kill_process", ruleResult.GetBaseRuntimeAlert().Arguments["action"])
+
+	// the syscalls are reported once per container
+	assert.Nil(t, r.ProcessEvent(utils.SeccompEventType, newEvent("ptrace", tracerseccompaudittype.ActionErrno), &objCache))
+
+	// the alerted syscalls of a stopped container are forgotten
+	r.ContainerStopped("test")
+	assert.True(t, r.alertedSyscalls.IsEmpty())
+	assert.NotNil(t, r.ProcessEvent(utils.SeccompEventType, newEvent("ptrace", tracerseccompaudittype.ActionErrno), &objCache))
+}
@@ -0,0 +1,459 @@
+package ruleengine
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/ebpf/events"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+
+	tracerfileopstype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/fileops/types"
+	tracerhardlinktype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/hardlink/types"
+	tracersymlinktype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/symlink/types"
+)
+
+const (
+	R1022ID   = "R1022"
+	R1022Name = "File Integrity Violation"
+
+	// maxBaselineFiles bounds the number of files hashed per container for the baseline
+	maxBaselineFiles = 5000
+	// baselineRootTimeout bounds the wait for the root of a starting container before its baseline
+	baselineRootTimeout = time.Minute
+)
+
+var R1022FileIntegrityViolationRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R1022ID,
+	Name:        R1022Name,
+	Description: "Detecting changes to the files of the watched paths, such as the system configuration, the binaries and the web roots.",
+	Tags:        []string{"files", "integrity", "persistence"},
+	Priority:    RulePriorityHigh,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{
+			utils.OpenEventType,
+			utils.SymlinkEventType,
+			utils.HardlinkEventType,
+			utils.UnlinkEventType,
+			utils.RenameEventType,
+			utils.ChmodEventType,
+			utils.ChownEventType,
+			utils.MkdirEventType,
+		},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR1022FileIntegrityViolation()
+	},
+}
+
+var _ ruleengine.RuleEvaluator = (*R1022FileIntegrityViolation)(nil)
+var _ ruleengine.RuleContainerTracker = (*R1022FileIntegrityViolation)(nil)
+
+// integrityContainer is the state of the rule for a container, dropped when the container stops
+type integrityContainer struct {
+	pid             uint32             // the files of the container are read through the root of this process
+	baselineStarted bool               // the baseline is taken in the background
+	hashes          map[string]string  // path in the container -> sha256, nil until the baseline is taken
+	alertedChanges  mapset.Set[string] // key is operation/path
+}
+
+// fileChange is a change of a file reported by one of the tracers
+type fileChange struct {
+	event     eventtypes.Event
+	path      string
+	operation string
+	target    string // the target of the links
+	process   apitypes.Process
+	extra     interface{}
+}
+
+type R1022FileIntegrityViolation struct {
+	BaseRule
+	mutex      sync.Mutex                     // guards the containers and the baseline parameter
+	containers map[string]*integrityContainer // key is containerID
+
+	// parameters
+	watchedPaths  []string
+	excludedPaths []string
+	baseline      bool
+}
+
+func CreateRuleR1022FileIntegrityViolation() *R1022FileIntegrityViolation {
+	rule := &R1022FileIntegrityViolation{
+		containers: make(map[string]*integrityContainer),
+	}
+	for _, paths := range FileIntegrityPathSets {
+		rule.watchedPaths = append(rule.watchedPaths, paths...)
+	}
+	return rule
+}
+
+func (rule *R1022FileIntegrityViolation) Name() string {
+	return R1022Name
+}
+
+func (rule *R1022FileIntegrityViolation) ID() string {
+	return R1022ID
+}
+
+// SetParameters scopes the watched paths to the path sets and the paths of the rule binding
+func (rule *R1022FileIntegrityViolation) SetParameters(parameters map[string]interface{}) {
+	rule.BaseRule.SetParameters(parameters)
+	parameters = rule.GetParameters()
+
+	if val := parameters["pathSets"]; val != nil {
+		if pathSets, ok := InterfaceToStringSlice(val); ok {
+			rule.watchedPaths = nil
+			for _, pathSet := range pathSets {
+				paths, found := FileIntegrityPathSets[pathSet]
+				if !found {
+					logger.L().Warning("unknown file integrity path set", helpers.String("ruleID", rule.ID()), helpers.String("pathSet", pathSet))
+					continue
+				}
+				rule.watchedPaths = append(rule.watchedPaths, paths...)
+			}
+		} else {
+			logger.L().Warning("failed to convert pathSets to []string", helpers.String("ruleID", rule.ID()))
+		}
+	}
+
+	if val := parameters["paths"]; val != nil {
+		if paths, ok := InterfaceToStringSlice(val); ok {
+			rule.watchedPaths = append(rule.watchedPaths, paths...)
+		} else {
+			logger.L().Warning("failed to convert paths to []string", helpers.String("ruleID", rule.ID()))
+		}
+	}
+
+	if val := parameters["excludedPaths"]; val != nil {
+		if excludedPaths, ok := InterfaceToStringSlice(val); ok {
+			rule.excludedPaths = excludedPaths
+		} else {
+			logger.L().Warning("failed to convert excludedPaths to []string", helpers.String("ruleID", rule.ID()))
+		}
+	}
+
+	if val := parameters["baseline"]; val != nil {
+		if baseline, ok := val.(bool); ok {
+			rule.mutex.Lock()
+			rule.baseline = baseline
+			rule.mutex.Unlock()
+		} else {
+			logger.L().Warning("failed to convert baseline to bool", helpers.String("ruleID", rule.ID()))
+		}
+	}
+}
+
+func (rule *R1022FileIntegrityViolation) DeleteRule() {
+}
+
+// ContainerStarted keeps the pid of the container and starts taking its baseline
+func (rule *R1022FileIntegrityViolation) ContainerStarted(container *containercollection.Container) {
+	rule.mutex.Lock()
+	defer rule.mutex.Unlock()
+	integrity := &integrityContainer{
+		pid:            container.ContainerPid(),
+		alertedChanges: mapset.NewThreadUnsafeSet[string](),
+	}
+	rule.containers[container.Runtime.ContainerID] = integrity
+	rule.startBaseline(container.Runtime.ContainerID, integrity)
+}
+
+// ContainerStopped drops the baseline and the alerted changes of the container
+func (rule *R1022FileIntegrityViolation) ContainerStopped(containerID string) {
+	rule.mutex.Lock()
+	defer rule.mutex.Unlock()
+	delete(rule.containers, containerID)
+}
+
+// getContainer returns the state of the container, the containers started before the rule use the pid of their event
+func (rule *R1022FileIntegrityViolation) getContainer(containerID string, pid uint32) *integrityContainer {
+	container, ok := rule.containers[containerID]
+	if !ok {
+		container = &integrityContainer{
+			pid:            pid,
+			alertedChanges: mapset.NewThreadUnsafeSet[string](),
+		}
+		rule.containers[containerID] = container
+	}
+	return container
+}
+
+// startBaseline takes the baseline of the container in the background, the caller holds the mutex
+func (rule *R1022FileIntegrityViolation) startBaseline(containerID string, container *integrityContainer) {
+	if !rule.baseline || container.baselineStarted {
+		return
+	}
+	container.baselineStarted = true
+	go rule.takeBaseline(containerID, container)
+}
+
+// takeBaseline hashes the watched files once the root of the container is ready, the changes are compared
+// to the baseline once it is taken
+func (rule *R1022FileIntegrityViolation) takeBaseline(containerID string, container *integrityContainer) {
+	root := fmt.Sprintf("/proc/%d/root", container.pid)
+	if err := backoff.Retry(func() error {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("root of container %s is not mounted yet", containerID)
+		}
+		return nil
+	}, backoff.NewExponentialBackOff(backoff.WithMaxElapsedTime(baselineRootTimeout))); err != nil {
+		logger.L().Debug("R1022FileIntegrityViolation - container root not ready for the baseline", helpers.Error(err),
+			helpers.String("container ID", containerID))
+		return
+	}
+	hashes := rule.hashBaseline(containerID, container.pid)
+
+	rule.mutex.Lock()
+	defer rule.mutex.Unlock()
+	container.hashes = hashes
+}
+
+// hashBaseline hashes the regular files of the watched paths through the root of the container
+func (rule *R1022FileIntegrityViolation) hashBaseline(containerID string, pid uint32) map[string]string {
+	root := fmt.Sprintf("/proc/%d/root", pid)
+	hashes := make(map[string]string)
+	for _, watchedPath := range rule.watchedPaths {
+		_ = filepath.WalkDir(filepath.Join(root, watchedPath), func(hostPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if len(hashes) >= maxBaselineFiles {
+				return filepath.SkipAll
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			path := strings.TrimPrefix(hostPath, root)
+			if rule.isExcluded(path) {
+				return nil
+			}
+			if info, err := entry.Info(); err != nil || info.Size() > maxHashedFileSize {
+				return nil
+			}
+			if fileHashes, err := utils.GetFileHashes(hostPath); err == nil {
+				hashes[path] = fileHashes.SHA256
+			}
+			return nil
+		})
+	}
+	logger.L().Debug("R1022FileIntegrityViolation - hashed the baseline of the watched files", helpers.String("container ID", containerID),
+		helpers.Int("files", len(hashes)))
+	return hashes
+}
+
+func (rule *R1022FileIntegrityViolation) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, _ objectcache.ObjectCache) ruleengine.RuleFailure {
+	switch eventType {
+	case utils.OpenEventType:
+		openEvent, ok := event.(*events.OpenEvent)
+		if !ok {
+			return nil
+		}
+		return rule.checkChange(fileChange{
+			event:     openEvent.Event.Event,
+			path:      openEvent.FullPath,
+			operation: openOperation(openEvent.Flags),
+			process:   apitypes.Process{Comm: openEvent.Comm, Gid: &openEvent.Gid, PID: openEvent.Pid, Uid: &openEvent.Uid},
+			extra:     openEvent.GetExtra(),
+		})
+	case utils.SymlinkEventType:
+		symlinkEvent, ok := event.(*tracersymlinktype.Event)
+		if !ok {
+			return nil
+		}
+		return rule.checkChange(fileChange{
+			event:     symlinkEvent.Event,
+			path:      symlinkEvent.NewPath,
+			operation: "symlink",
+			target:    symlinkEvent.OldPath,
+			process:   apitypes.Process{Comm: symlinkEvent.Comm, Gid: &symlinkEvent.Gid, PID: symlinkEvent.Pid, Uid: &symlinkEvent.Uid, Path: symlinkEvent.ExePath},
+			extra:     symlinkEvent.GetExtra(),
+		})
+	case utils.HardlinkEventType:
+		hardlinkEvent, ok := event.(*tracerhardlinktype.Event)
+		if !ok {
+			return nil
+		}
+		return rule.checkChange(fileChange{
+			event:     hardlinkEvent.Event,
+			path:      hardlinkEvent.NewPath,
+			operation: "hardlink",
+			target:    hardlinkEvent.OldPath,
+			process:   apitypes.Process{Comm: hardlinkEvent.Comm, Gid: &hardlinkEvent.Gid, PID: hardlinkEvent.Pid, Uid: &hardlinkEvent.Uid, Path: hardlinkEvent.ExePath},
+			extra:     hardlinkEvent.GetExtra(),
+		})
+	case utils.UnlinkEventType, utils.RenameEventType, utils.ChmodEventType, utils.ChownEventType, utils.MkdirEventType:
+		fileOpsEvent, ok := event.(*tracerfileopstype.Event)
+		if !ok {
+			return nil
+		}
+		change := fileChange{
+			event:     fileOpsEvent.Event,
+			path:      fileOpsEvent.Path,
+			operation: string(fileOpsEvent.Operation),
+			process:   apitypes.Process{Comm: fileOpsEvent.Comm, Gid: &fileOpsEvent.Gid, PID: fileOpsEvent.Pid, Uid: &fileOpsEvent.Uid},
+			extra:     fileOpsEvent.GetExtra(),
+		}
+		if fileOpsEvent.Operation != tracerfileopstype.OperationRename {
+			return rule.checkChange(change)
+		}
+		// a file moved into the watched paths replaces its destination, a file moved out of them is removed
+		moveIn := change
+		moveIn.path, moveIn.target = fileOpsEvent.NewPath, fileOpsEvent.Path
+		moveOut := change
+		moveOut.target = fileOpsEvent.NewPath
+		return mergeMoves(rule.checkChange(moveIn), rule.checkChange(moveOut))
+	}
+	return nil
+}
+
+// mergeMoves reports the move-in and the move-out of a rename within the watched paths in a single alert,
+// the arguments of the move-out are kept under "movedOut"
+func mergeMoves(moveIn, moveOut ruleengine.RuleFailure) ruleengine.RuleFailure {
+	if moveIn == nil {
+		return moveOut
+	}
+	if moveOut == nil {
+		return moveIn
+	}
+	baseRuntimeAlert := moveIn.GetBaseRuntimeAlert()
+	baseRuntimeAlert.Arguments["movedOut"] = moveOut.GetBaseRuntimeAlert().Arguments
+	moveIn.SetBaseRuntimeAlert(baseRuntimeAlert)
+	triggerEvent := moveIn.GetTriggerEvent()
+	ruleAlert := moveIn.GetRuleAlert()
+	ruleAlert.RuleDescription = fmt.Sprintf("Watched file moved (rename): %s to %s by: %s in: %s", baseRuntimeAlert.Arguments["target"],
+		baseRuntimeAlert.Arguments["path"], moveIn.GetRuntimeProcessDetails().ProcessTree.Comm, triggerEvent.GetContainer())
+	moveIn.SetRuleAlert(ruleAlert)
+	return moveIn
+}
+
+// openOperation returns the change made by opening a file with the flags, nothing for the reads
+func openOperation(flags []string) string {
+	switch {
+	case slices.Contains(flags, "O_CREAT"):
+		return "create"
+	case slices.Contains(flags, "O_TRUNC"):
+		return "truncate"
+	case slices.Contains(flags, "O_WRONLY"), slices.Contains(flags, "O_RDWR"):
+		return "write"
+	}
+	return ""
+}
+
+// checkChange alerts on the changes of the watched files, and on the files whose content differs from the baseline
+func (rule *R1022FileIntegrityViolation) checkChange(change fileChange) ruleengine.RuleFailure {
+	if change.path == "" {
+		return nil
+	}
+	path := filepath.Clean(change.path)
+	if !isWatchedPath(path, rule.watchedPaths) || rule.isExcluded(path) {
+		return nil
+	}
+
+	rule.mutex.Lock()
+	defer rule.mutex.Unlock()
+
+	containerID := change.event.Runtime.ContainerID
+	container := rule.getContainer(containerID, change.process.PID)
+	rule.startBaseline(containerID, container)
+
+	arguments := map[string]interface{}{
+		"path":      path,
+		"operation": change.operation,
+	}
+	if change.target != "" {
+		arguments["target"] = change.target
+	}
+
+	var hashes utils.FileHashes
+	contentChanged := false
+	if baselineHash, found := container.hashes[path]; found {
+		// the file is read through the root of the container, the process of the event may be gone
+		current, err := utils.GetFileHashes(filepath.Join(fmt.Sprintf("/proc/%d/root", container.pid), path))
+		if err == nil && current.SHA256 != baselineHash {
+			hashes = current
+			contentChanged = !container.alertedChanges.ContainsOne("content/" + path + "/" + current.SHA256)
+			arguments["baselineSHA256"] = baselineHash
+		}
+	}
+
+	alertKey := change.operation + "/" + path
+	if change.operation == "" || container.alertedChanges.ContainsOne(alertKey) {
+		if !contentChanged {
+			return nil
+		}
+		// a read of a file modified since the baseline, or a change already reported
+		arguments["operation"] = "modify"
+	} else {
+		container.alertedChanges.Add(alertKey)
+	}
+	if contentChanged {
+		container.alertedChanges.Add("content/" + path + "/" + hashes.SHA256)
+	}
+
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			Arguments:   arguments,
+			InfectedPID: change.process.PID,
+			Severity:    R1022FileIntegrityViolationRuleDescriptor.Priority,
+			MD5Hash:     hashes.MD5,
+			SHA1Hash:    hashes.SHA1,
+			SHA256Hash:  hashes.SHA256,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: change.process,
+			ContainerID: containerID,
+		},
+		TriggerEvent: change.event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Watched file changed (%s): %s by: %s in: %s", arguments["operation"], path, change.process.Comm, change.event.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   change.event.GetPod(),
+			PodLabels: change.event.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+		Extra:  change.extra,
+	}
+}
+
+func (rule *R1022FileIntegrityViolation) isExcluded(path string) bool {
+	return isWatchedPath(path, rule.excludedPaths)
+}
+
+// isWatchedPath checks if the path is one of the paths or is within one of them
+func isWatchedPath(path string, paths []string) bool {
+	for _, watchedPath := range paths {
+		watchedPath = filepath.Clean(watchedPath)
+		if path == watchedPath || strings.HasPrefix(path, strings.TrimSuffix(watchedPath, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *R1022FileIntegrityViolation) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R1022FileIntegrityViolationRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
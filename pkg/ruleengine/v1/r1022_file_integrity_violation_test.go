@@ -0,0 +1,188 @@
+package ruleengine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/kubescape/node-agent/pkg/ebpf/events"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	traceropentype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/open/types"
+	tracerfileopstype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/fileops/types"
+	tracersymlinktype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/symlink/types"
+)
+
+func openEventForPath(path string, flags ...string) *events.OpenEvent {
+	return &events.OpenEvent{
+		Event: traceropentype.Event{
+			Event: eventtypes.Event{
+				CommonData: eventtypes.CommonData{
+					Runtime: eventtypes.BasicRuntimeMetadata{ContainerID: "test"},
+					K8s: eventtypes.K8sMetadata{
+						BasicK8sMetadata: eventtypes.BasicK8sMetadata{
+							ContainerName: "test",
+						},
+					},
+				},
+			},
+			Comm:     "sed",
+			FullPath: path,
+			Flags:    flags,
+		},
+	}
+}
+
+func TestR1022FileIntegrityViolation(t *testing.T) {
+	r := CreateRuleR1022FileIntegrityViolation()
+	objCache := RuleObjectCacheMock{}
+
+	// reads and files outside of the watched paths are ignored
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/etc/passwd", "O_RDONLY"), &objCache))
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/tmp/file", "O_WRONLY", "O_CREAT"), &objCache))
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/etcetera/file", "O_WRONLY"), &objCache))
+
+	ruleResult := r.ProcessEvent(utils.OpenEventType, openEventForPath("/etc/passwd", "O_WRONLY", "O_TRUNC"), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "truncate", ruleResult.GetBaseRuntimeAlert().Arguments["operation"])
+	assert.Equal(t, "/etc/passwd", ruleResult.GetBaseRuntimeAlert().Arguments["path"])
+	// the same change is reported once
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/etc/passwd", "O_WRONLY", "O_TRUNC"), &objCache))
+
+	ruleResult = r.ProcessEvent(utils.OpenEventType, openEventForPath("/usr/bin/ls", "O_RDWR"), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "write", ruleResult.GetBaseRuntimeAlert().Arguments["operation"])
+
+	symlinkEvent := &tracersymlinktype.Event{
+		Event:   openEventForPath("").Event.Event,
+		Comm:    "ln",
+		OldPath: "/tmp/payload",
+		NewPath: "/usr/local/bin/kubectl",
+	}
+	ruleResult = r.ProcessEvent(utils.SymlinkEventType, symlinkEvent, &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "symlink", ruleResult.GetBaseRuntimeAlert().Arguments["operation"])
+	assert.Equal(t, "/tmp/payload", ruleResult.GetBaseRuntimeAlert().Arguments["target"])
+
+	// a rule binding scopes the watched paths of its workloads
+	r = CreateRuleR1022FileIntegrityViolation()
+	r.SetParameters(map[string]interface{}{
+		"pathSets":      []interface{}{"web"},
+		"paths":         []interface{}{"/app/config"},
+		"excludedPaths": []interface{}{"/var/www/uploads"},
+	})
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/etc/passwd", "O_WRONLY"), &objCache))
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/var/www/uploads/image.png", "O_WRONLY", "O_CREAT"), &objCache))
+	assert.NotNil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/var/www/index.php", "O_WRONLY", "O_CREAT"), &objCache))
+	assert.NotNil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/app/config/settings.yaml", "O_WRONLY"), &objCache))
+
+	// the changes of a stopped container are forgotten
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/var/www/index.php", "O_WRONLY", "O_CREAT"), &objCache))
+	r.ContainerStopped("test")
+	assert.NotNil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/var/www/index.php", "O_WRONLY", "O_CREAT"), &objCache))
+}
+
+func TestR1022FileIntegrityViolationBaseline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nginx.conf")
+	require.NoError(t, os.WriteFile(path, []byte("worker_processes 1;"), 0644))
+
+	r := CreateRuleR1022FileIntegrityViolation()
+	r.SetParameters(map[string]interface{}{
+		"pathSets": []interface{}{},
+		"paths":    []interface{}{dir},
+		"baseline": true,
+	})
+	// the root of the current process is the root of the test files
+	r.ContainerStarted(&containercollection.Container{
+		Runtime: containercollection.RuntimeMetadata{
+			BasicRuntimeMetadata: eventtypes.BasicRuntimeMetadata{ContainerID: "test", ContainerPID: uint32(os.Getpid())},
+		},
+	})
+	objCache := RuleObjectCacheMock{}
+
+	// the baseline is taken in the background when the container starts
+	waitForBaseline(t, r, "test")
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath(path, "O_RDONLY"), &objCache))
+
+	require.NoError(t, os.WriteFile(path, []byte("worker_processes 64;"), 0644))
+	ruleResult := r.ProcessEvent(utils.OpenEventType, openEventForPath(path, "O_RDONLY"), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "modify", ruleResult.GetBaseRuntimeAlert().Arguments["operation"])
+	assert.NotEmpty(t, ruleResult.GetBaseRuntimeAlert().Arguments["baselineSHA256"])
+	assert.NotEmpty(t, ruleResult.GetBaseRuntimeAlert().SHA256Hash)
+	// the same content is reported once
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath(path, "O_RDONLY"), &objCache))
+
+	// the baseline of a container whose start was missed is taken through the process of its first event
+	r.ContainerStopped("test")
+	event := openEventForPath(path, "O_RDONLY")
+	event.Pid = uint32(os.Getpid())
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, event, &objCache))
+	waitForBaseline(t, r, "test")
+	require.NoError(t, os.WriteFile(path, []byte("worker_processes 2;"), 0644))
+	assert.NotNil(t, r.ProcessEvent(utils.OpenEventType, event, &objCache))
+}
+
+// waitForBaseline waits for the baseline of the container taken in the background
+func waitForBaseline(t *testing.T, r *R1022FileIntegrityViolation, containerID string) {
+	require.Eventually(t, func() bool {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		container, found := r.containers[containerID]
+		return found && container.hashes != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestR1022FileIntegrityViolationFileOperations(t *testing.T) {
+	r := CreateRuleR1022FileIntegrityViolation()
+	objCache := RuleObjectCacheMock{}
+
+	fileOpsEvent := func(operation tracerfileopstype.Operation, path, newPath string) *tracerfileopstype.Event {
+		return &tracerfileopstype.Event{
+			Event:     openEventForPath("").Event.Event,
+			Comm:      "mv",
+			Operation: operation,
+			Path:      path,
+			NewPath:   newPath,
+		}
+	}
+
+	assert.Nil(t, r.ProcessEvent(utils.UnlinkEventType, fileOpsEvent(tracerfileopstype.OperationUnlink, "/tmp/file", ""), &objCache))
+
+	ruleResult := r.ProcessEvent(utils.ChmodEventType, fileOpsEvent(tracerfileopstype.OperationChmod, "/usr/bin/passwd", ""), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "chmod", ruleResult.GetBaseRuntimeAlert().Arguments["operation"])
+
+	// a payload moved over a binary is reported with its source
+	ruleResult = r.ProcessEvent(utils.RenameEventType, fileOpsEvent(tracerfileopstype.OperationRename, "/tmp/payload", "/usr/local/bin/kubectl"), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "rename", ruleResult.GetBaseRuntimeAlert().Arguments["operation"])
+	assert.Equal(t, "/usr/local/bin/kubectl", ruleResult.GetBaseRuntimeAlert().Arguments["path"])
+	assert.Equal(t, "/tmp/payload", ruleResult.GetBaseRuntimeAlert().Arguments["target"])
+
+	// a watched file moved out of the watched paths
+	ruleResult = r.ProcessEvent(utils.RenameEventType, fileOpsEvent(tracerfileopstype.OperationRename, "/etc/shadow", "/tmp/shadow"), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "/etc/shadow", ruleResult.GetBaseRuntimeAlert().Arguments["path"])
+	assert.Equal(t, "/tmp/shadow", ruleResult.GetBaseRuntimeAlert().Arguments["target"])
+	assert.NotContains(t, ruleResult.GetBaseRuntimeAlert().Arguments, "movedOut")
+
+	// a rename within the watched paths reports both the move-in and the move-out
+	ruleResult = r.ProcessEvent(utils.RenameEventType, fileOpsEvent(tracerfileopstype.OperationRename, "/etc/passwd", "/etc/passwd-"), &objCache)
+	require.NotNil(t, ruleResult)
+	arguments := ruleResult.GetBaseRuntimeAlert().Arguments
+	assert.Equal(t, "/etc/passwd-", arguments["path"])
+	assert.Equal(t, "/etc/passwd", arguments["target"])
+	require.Contains(t, arguments, "movedOut")
+	assert.Equal(t, "/etc/passwd", arguments["movedOut"].(map[string]interface{})["path"])
+	assert.Equal(t, "/etc/passwd-", arguments["movedOut"].(map[string]interface{})["target"])
+	assert.Contains(t, ruleResult.GetRuleAlert().RuleDescription, "/etc/passwd to /etc/passwd-")
+	// both moves are reported once
+	assert.Nil(t, r.ProcessEvent(utils.RenameEventType, fileOpsEvent(tracerfileopstype.OperationRename, "/etc/passwd", "/etc/passwd-"), &objCache))
+}
@@ -0,0 +1,308 @@
+package ruleengine
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/ebpf/events"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+
+	tracerfileopstype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/fileops/types"
+)
+
+const (
+	R1023ID   = "R1023"
+	R1023Name = "Drop And Execute"
+
+	// maxDroppedFiles bounds the number of written files tracked per container
+	maxDroppedFiles = 1000
+	// maxLineageDepth bounds the number of ancestors recorded for the writer
+	maxLineageDepth = 10
+)
+
+var R1023DropAndExecuteRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R1023ID,
+	Name:        R1023Name,
+	Description: "Detecting the execution or the executable mapping of files written inside the container.",
+	Tags:        []string{"exec", "files", "malicious", "dropper"},
+	Priority:    RulePriorityCritical,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{
+			utils.OpenEventType,
+			utils.ExecveEventType,
+			utils.RenameEventType,
+			utils.UnlinkEventType,
+			utils.MmapEventType,
+		},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR1023DropAndExecute()
+	},
+}
+
+var _ ruleengine.RuleEvaluator = (*R1023DropAndExecute)(nil)
+var _ ruleengine.RuleContainerTracker = (*R1023DropAndExecute)(nil)
+
+// droppedFile is a file written inside the container, with the process that wrote it
+type droppedFile struct {
+	writer    apitypes.Process
+	lineage   []string // comm(pid) of the writer and its ancestors in the container, oldest first
+	writtenAt time.Time
+}
+
+type R1023DropAndExecute struct {
+	BaseRule
+	mutex        sync.Mutex
+	droppedFiles map[string]*lru.Cache[string, droppedFile] // key is containerID, then path
+
+	// parameters
+	excludedPaths   []string
+	excludedWriters []string
+}
+
+func CreateRuleR1023DropAndExecute() *R1023DropAndExecute {
+	return &R1023DropAndExecute{
+		droppedFiles: make(map[string]*lru.Cache[string, droppedFile]),
+		// the pseudo file systems are written all the time and never executed
+		excludedPaths: []string{"/dev", "/proc", "/sys"},
+	}
+}
+
+func (rule *R1023DropAndExecute) Name() string {
+	return R1023Name
+}
+
+func (rule *R1023DropAndExecute) ID() string {
+	return R1023ID
+}
+
+func (rule *R1023DropAndExecute) SetParameters(parameters map[string]interface{}) {
+	rule.BaseRule.SetParameters(parameters)
+	parameters = rule.GetParameters()
+
+	if val := parameters["excludedPaths"]; val != nil {
+		if excludedPaths, ok := InterfaceToStringSlice(val); ok {
+			rule.excludedPaths = append(rule.excludedPaths, excludedPaths...)
+		} else {
+			logger.L().Warning("failed to convert excludedPaths to []string", helpers.String("ruleID", rule.ID()))
+		}
+	}
+
+	if val := parameters["excludedWriters"]; val != nil {
+		if excludedWriters, ok := InterfaceToStringSlice(val); ok {
+			rule.excludedWriters = excludedWriters
+		} else {
+			logger.L().Warning("failed to convert excludedWriters to []string", helpers.String("ruleID", rule.ID()))
+		}
+	}
+}
+
+func (rule *R1023DropAndExecute) DeleteRule() {
+}
+
+func (rule *R1023DropAndExecute) ContainerStarted(_ *containercollection.Container) {
+}
+
+// ContainerStopped forgets the files written by the container
+func (rule *R1023DropAndExecute) ContainerStopped(containerID string) {
+	rule.mutex.Lock()
+	defer rule.mutex.Unlock()
+	delete(rule.droppedFiles, containerID)
+}
+
+// containerFiles returns the files written by the container, nil when none is tracked and create is false
+func (rule *R1023DropAndExecute) containerFiles(containerID string, create bool) *lru.Cache[string, droppedFile] {
+	rule.mutex.Lock()
+	defer rule.mutex.Unlock()
+	files, ok := rule.droppedFiles[containerID]
+	if !ok && create {
+		files, _ = lru.New[string, droppedFile](maxDroppedFiles)
+		rule.droppedFiles[containerID] = files
+	}
+	return files
+}
+
+func (rule *R1023DropAndExecute) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, objCache objectcache.ObjectCache) ruleengine.RuleFailure {
+	switch eventType {
+	case utils.OpenEventType:
+		openEvent, ok := event.(*events.OpenEvent)
+		if !ok || openOperation(openEvent.Flags) == "" {
+			return nil
+		}
+		rule.trackWrite(openEvent, objCache)
+	case utils.ExecveEventType:
+		execEvent, ok := event.(*events.ExecEvent)
+		if !ok {
+			return nil
+		}
+		fullPath := GetExecFullPathFromEvent(execEvent)
+		upperLayer := execEvent.UpperLayer || execEvent.PupperLayer
+		process := apitypes.Process{
+			Comm:       execEvent.Comm,
+			Gid:        &execEvent.Gid,
+			PID:        execEvent.Pid,
+			Uid:        &execEvent.Uid,
+			UpperLayer: &upperLayer,
+			PPID:       execEvent.Ppid,
+			Pcomm:      execEvent.Pcomm,
+			Cwd:        execEvent.Cwd,
+			Hardlink:   execEvent.ExePath,
+			Path:       fullPath,
+			Cmdline:    fmt.Sprintf("%s %s", GetExecPathFromEvent(execEvent), strings.Join(utils.GetExecArgsFromEvent(&execEvent.Event), " ")),
+		}
+		return rule.checkDropped(execEvent.Event.Event, fullPath, "exec", process, execEvent.GetExtra())
+	case utils.MmapEventType:
+		// the shared libraries are mapped executable by the loader, the plain reads of the files are not loads
+		fileOpsEvent, ok := event.(*tracerfileopstype.Event)
+		if !ok {
+			return nil
+		}
+		process := apitypes.Process{Comm: fileOpsEvent.Comm, Gid: &fileOpsEvent.Gid, PID: fileOpsEvent.Pid, Uid: &fileOpsEvent.Uid}
+		return rule.checkDropped(fileOpsEvent.Event, fileOpsEvent.Path, "load", process, fileOpsEvent.GetExtra())
+	case utils.RenameEventType, utils.UnlinkEventType:
+		fileOpsEvent, ok := event.(*tracerfileopstype.Event)
+		if !ok {
+			return nil
+		}
+		files := rule.containerFiles(fileOpsEvent.Runtime.ContainerID, false)
+		if files == nil {
+			return nil
+		}
+		key := filepath.Clean(fileOpsEvent.Path)
+		dropped, found := files.Peek(key)
+		if !found {
+			return nil
+		}
+		files.Remove(key)
+		// the payloads moved to paths looking legitimate keep their writer
+		if fileOpsEvent.Operation == tracerfileopstype.OperationRename && fileOpsEvent.NewPath != "" {
+			files.Add(filepath.Clean(fileOpsEvent.NewPath), dropped)
+		}
+	}
+	return nil
+}
+
+// trackWrite records the file written by the event, with the writer and its lineage from the process tree
+func (rule *R1023DropAndExecute) trackWrite(openEvent *events.OpenEvent, objCache objectcache.ObjectCache) {
+	if openEvent.FullPath == "" || isWatchedPath(filepath.Clean(openEvent.FullPath), rule.excludedPaths) || slices.Contains(rule.excludedWriters, openEvent.Comm) {
+		return
+	}
+	files := rule.containerFiles(openEvent.Runtime.ContainerID, true)
+	key := filepath.Clean(openEvent.FullPath)
+	if dropped, found := files.Get(key); found && dropped.writer.PID == openEvent.Pid {
+		return
+	}
+	writer := apitypes.Process{Comm: openEvent.Comm, Gid: &openEvent.Gid, PID: openEvent.Pid, Uid: &openEvent.Uid}
+	var lineage []string
+	if tree, err := objCache.ProcessTreeCache().GetProcessTreeForPID(openEvent.Runtime.ContainerID, int(openEvent.Pid)); err == nil {
+		lineage = processTreeLineage(&tree, openEvent.Pid)
+		if process := utils.GetProcessFromProcessTree(&tree, openEvent.Pid); process != nil {
+			writer.Cmdline = process.Cmdline
+		}
+	}
+	files.Add(key, droppedFile{
+		writer:    writer,
+		lineage:   lineage,
+		writtenAt: time.Now(),
+	})
+}
+
+// checkDropped alerts when the executed or loaded file was written inside the container
+func (rule *R1023DropAndExecute) checkDropped(event eventtypes.Event, path, operation string, process apitypes.Process, extra interface{}) ruleengine.RuleFailure {
+	if path == "" {
+		return nil
+	}
+	files := rule.containerFiles(event.Runtime.ContainerID, false)
+	if files == nil {
+		return nil
+	}
+	key := filepath.Clean(path)
+	dropped, found := files.Peek(key)
+	if !found {
+		return nil
+	}
+	// the file is reported once, until it is written again
+	files.Remove(key)
+
+	var hashes utils.FileHashes
+	hostPath := filepath.Join("/proc", fmt.Sprintf("/%d/root/%s", process.PID, path))
+	if size, err := utils.GetFileSize(hostPath); err == nil && size > 0 && size <= maxHashedFileSize {
+		hashes, _ = utils.GetFileHashes(hostPath)
+	}
+
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: process.PID,
+			Arguments: map[string]interface{}{
+				"path":          path,
+				"operation":     operation,
+				"writerComm":    dropped.writer.Comm,
+				"writerPID":     dropped.writer.PID,
+				"writerCmdline": dropped.writer.Cmdline,
+				"writerLineage": dropped.lineage,
+				"writtenAt":     dropped.writtenAt,
+			},
+			Severity:   R1023DropAndExecuteRuleDescriptor.Priority,
+			MD5Hash:    hashes.MD5,
+			SHA1Hash:   hashes.SHA1,
+			SHA256Hash: hashes.SHA256,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: process,
+			ContainerID: event.Runtime.ContainerID,
+		},
+		TriggerEvent: event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("File written in the container then executed (%s): %s written by: %s in: %s", operation, path, dropped.writer.Comm, event.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   event.GetPod(),
+			PodLabels: event.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+		Extra:  extra,
+	}
+}
+
+func (rule *R1023DropAndExecute) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R1023DropAndExecuteRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
+
+// processTreeLineage returns the comm(pid) of the process and its ancestors in the tree of the container, oldest first
+func processTreeLineage(tree *apitypes.Process, pid uint32) []string {
+	var lineage []string
+	for node := tree; node != nil; {
+		lineage = append(lineage, fmt.Sprintf("%s(%d)", node.Comm, node.PID))
+		if node.PID == pid {
+			break
+		}
+		var next *apitypes.Process
+		for i := range node.Children {
+			if utils.GetProcessFromProcessTree(&node.Children[i], pid) != nil {
+				next = &node.Children[i]
+				break
+			}
+		}
+		node = next
+	}
+	if len(lineage) > maxLineageDepth {
+		lineage = lineage[len(lineage)-maxLineageDepth:]
+	}
+	return lineage
+}
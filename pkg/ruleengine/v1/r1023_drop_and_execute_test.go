@@ -0,0 +1,126 @@
+package ruleengine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubescape/node-agent/pkg/ebpf/events"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	tracerexectype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/exec/types"
+	tracerfileopstype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/fileops/types"
+)
+
+func execEventForPath(path string) *events.ExecEvent {
+	return &events.ExecEvent{
+		Event: tracerexectype.Event{
+			Event: openEventForPath("").Event.Event,
+			Pid:   uint32(os.Getpid()),
+			Comm:  filepath.Base(path),
+			Args:  []string{path},
+		},
+	}
+}
+
+func TestR1023DropAndExecute(t *testing.T) {
+	r := CreateRuleR1023DropAndExecute()
+	objCache := RuleObjectCacheMock{}
+
+	// the file is reached through /proc/<pid>/root of the test process
+	content := []byte("payload")
+	path := filepath.Join(t.TempDir(), "payload")
+	require.NoError(t, os.WriteFile(path, content, 0755))
+	sum := sha256.Sum256(content)
+
+	// files of the image are not reported
+	assert.Nil(t, r.ProcessEvent(utils.ExecveEventType, execEventForPath(path), &objCache))
+
+	// the lineage of the writer comes from the process tree
+	objCache.SetProcessTree(apitypes.Process{
+		PID:  1,
+		Comm: "sh",
+		Children: []apitypes.Process{
+			{PID: uint32(os.Getpid()), Comm: "curl", Cmdline: "curl -o payload http://example.com"},
+		},
+	})
+	write := openEventForPath(path, "O_WRONLY", "O_CREAT", "O_TRUNC")
+	write.Pid = uint32(os.Getpid())
+	write.Comm = "curl"
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, write, &objCache))
+	// reading the file is not executing it
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath(path, "O_RDONLY"), &objCache))
+
+	ruleResult := r.ProcessEvent(utils.ExecveEventType, execEventForPath(path), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "exec", ruleResult.GetBaseRuntimeAlert().Arguments["operation"])
+	assert.Equal(t, "curl", ruleResult.GetBaseRuntimeAlert().Arguments["writerComm"])
+	assert.Equal(t, []string{"sh(1)", fmt.Sprintf("curl(%d)", os.Getpid())}, ruleResult.GetBaseRuntimeAlert().Arguments["writerLineage"])
+	assert.Equal(t, "curl -o payload http://example.com", ruleResult.GetBaseRuntimeAlert().Arguments["writerCmdline"])
+	assert.Equal(t, hex.EncodeToString(sum[:]), ruleResult.GetBaseRuntimeAlert().SHA256Hash)
+	// the file is reported once
+	assert.Nil(t, r.ProcessEvent(utils.ExecveEventType, execEventForPath(path), &objCache))
+
+	// a payload moved to a legitimate looking path
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/tmp/x", "O_WRONLY", "O_CREAT"), &objCache))
+	rename := &tracerfileopstype.Event{
+		Event:     openEventForPath("").Event.Event,
+		Comm:      "mv",
+		Operation: tracerfileopstype.OperationRename,
+		Path:      "/tmp/x",
+		NewPath:   "/usr/bin/top",
+	}
+	assert.Nil(t, r.ProcessEvent(utils.RenameEventType, rename, &objCache))
+	assert.Nil(t, r.ProcessEvent(utils.ExecveEventType, execEventForPath("/tmp/x"), &objCache))
+	ruleResult = r.ProcessEvent(utils.ExecveEventType, execEventForPath("/usr/bin/top"), &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "/usr/bin/top", ruleResult.GetBaseRuntimeAlert().Arguments["path"])
+	assert.Equal(t, "sed", ruleResult.GetBaseRuntimeAlert().Arguments["writerComm"])
+
+	// a library written then mapped executable by the loader, reading it is not loading it
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/app/libevil.so.1", "O_WRONLY", "O_CREAT"), &objCache))
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/app/libevil.so.1", "O_RDONLY", "O_CLOEXEC"), &objCache))
+	mmap := &tracerfileopstype.Event{
+		Event:     openEventForPath("").Event.Event,
+		Comm:      "app",
+		Operation: tracerfileopstype.OperationMmap,
+		Path:      "/app/libevil.so.1",
+	}
+	ruleResult = r.ProcessEvent(utils.MmapEventType, mmap, &objCache)
+	require.NotNil(t, ruleResult)
+	assert.Equal(t, "load", ruleResult.GetBaseRuntimeAlert().Arguments["operation"])
+
+	// a deleted file is forgotten
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/tmp/y", "O_WRONLY", "O_CREAT"), &objCache))
+	unlink := &tracerfileopstype.Event{
+		Event:     openEventForPath("").Event.Event,
+		Operation: tracerfileopstype.OperationUnlink,
+		Path:      "/tmp/y",
+	}
+	assert.Nil(t, r.ProcessEvent(utils.UnlinkEventType, unlink, &objCache))
+	assert.Nil(t, r.ProcessEvent(utils.ExecveEventType, execEventForPath("/tmp/y"), &objCache))
+
+	// the files of a stopped container are forgotten
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/tmp/z", "O_WRONLY", "O_CREAT"), &objCache))
+	r.ContainerStopped("test")
+	assert.Nil(t, r.ProcessEvent(utils.ExecveEventType, execEventForPath("/tmp/z"), &objCache))
+
+	// excluded writers and paths
+	r = CreateRuleR1023DropAndExecute()
+	r.SetParameters(map[string]interface{}{
+		"excludedPaths":   []interface{}{"/app/cache"},
+		"excludedWriters": []interface{}{"sed"},
+	})
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, openEventForPath("/tmp/x", "O_WRONLY", "O_CREAT"), &objCache))
+	assert.Nil(t, r.ProcessEvent(utils.ExecveEventType, execEventForPath("/tmp/x"), &objCache))
+	write = openEventForPath("/app/cache/tool", "O_WRONLY", "O_CREAT")
+	write.Comm = "curl"
+	assert.Nil(t, r.ProcessEvent(utils.OpenEventType, write, &objCache))
+	assert.Nil(t, r.ProcessEvent(utils.ExecveEventType, execEventForPath("/app/cache/tool"), &objCache))
+}
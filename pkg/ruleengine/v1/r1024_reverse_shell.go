@@ -0,0 +1,239 @@
+package ruleengine
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	tracerreverseshelltype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/reverseshell/types"
+	"github.com/kubescape/node-agent/pkg/objectcache"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/utils"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+)
+
+const (
+	R1024ID   = "R1024"
+	R1024Name = "Reverse Shell"
+
+	// maxTrackedConnections bounds the number of process connections kept by the rule
+	maxTrackedConnections = 10000
+)
+
+// ReverseShellBinaries are the shells and the interpreters alerted when executed with a network socket as a standard
+// stream, the versions are ignored so python3.11 is matched by python
+var ReverseShellBinaries = []string{
+	"sh", "bash", "dash", "zsh", "ash", "ksh", "mksh", "csh", "tcsh", "fish", "busybox",
+	"python", "perl", "ruby", "php", "node", "lua",
+}
+
+var R1024ReverseShellRuleDescriptor = ruleengine.RuleDescriptor{
+	ID:          R1024ID,
+	Name:        R1024Name,
+	Description: "Detecting shells and interpreters whose standard input, output or error is a network socket.",
+	Tags:        []string{"exec", "network", "shell", "malicious"},
+	Priority:    RulePriorityCritical,
+	Requirements: &RuleRequirements{
+		EventTypes: []utils.EventType{
+			utils.ReverseShellEventType,
+			utils.NetworkEventType,
+		},
+	},
+	RuleCreationFunc: func() ruleengine.RuleEvaluator {
+		return CreateRuleR1024ReverseShell()
+	},
+}
+
+var _ ruleengine.RuleEvaluator = (*R1024ReverseShell)(nil)
+var _ ruleengine.RuleContainerTracker = (*R1024ReverseShell)(nil)
+
+// connection is the latest outgoing connection of a process seen in the network events
+type connection struct {
+	addr  string
+	port  uint16
+	proto string
+}
+
+type R1024ReverseShell struct {
+	BaseRule
+	connections *lru.Cache[string, connection] // key is containerID/pid
+
+	// parameters
+	allowedParents   []string
+	allowedAddresses []string
+}
+
+func CreateRuleR1024ReverseShell() *R1024ReverseShell {
+	connections, _ := lru.New[string, connection](maxTrackedConnections)
+	return &R1024ReverseShell{
+		connections: connections,
+		// the remote management daemons serving shells over their connections, matched on the parent executable so a
+		// process renaming itself is not allowed
+		allowedParents: []string{
+			"/usr/sbin/sshd", "/usr/bin/sshd", "/usr/sbin/dropbear", "/usr/bin/dropbear",
+			"/usr/sbin/inetd", "/usr/bin/inetd", "/usr/sbin/xinetd", "/usr/bin/xinetd",
+		},
+	}
+}
+
+func (rule *R1024ReverseShell) Name() string {
+	return R1024Name
+}
+
+func (rule *R1024ReverseShell) ID() string {
+	return R1024ID
+}
+
+// SetParameters adds the parent executables allowed to serve shells over the network, and the allowed remote addresses
+func (rule *R1024ReverseShell) SetParameters(parameters map[string]interface{}) {
+	rule.BaseRule.SetParameters(parameters)
+	parameters = rule.GetParameters()
+
+	if val := parameters["allowedParents"]; val != nil {
+		if allowedParents, ok := InterfaceToStringSlice(val); ok {
+			rule.allowedParents = append(rule.allowedParents, allowedParents...)
+		} else {
+			logger.L().Warning("failed to convert allowedParents to []string", helpers.String("ruleID", rule.ID()))
+		}
+	}
+
+	if val := parameters["allowedAddresses"]; val != nil {
+		if allowedAddresses, ok := InterfaceToStringSlice(val); ok {
+			rule.allowedAddresses = allowedAddresses
+		} else {
+			logger.L().Warning("failed to convert allowedAddresses to []string", helpers.String("ruleID", rule.ID()))
+		}
+	}
+}
+
+func (rule *R1024ReverseShell) DeleteRule() {
+}
+
+func (rule *R1024ReverseShell) ContainerStarted(_ *containercollection.Container) {
+}
+
+// ContainerStopped forgets the connections of the container processes
+func (rule *R1024ReverseShell) ContainerStopped(containerID string) {
+	for _, key := range rule.connections.Keys() {
+		if strings.HasPrefix(key, containerID+"/") {
+			rule.connections.Remove(key)
+		}
+	}
+}
+
+func (rule *R1024ReverseShell) ProcessEvent(eventType utils.EventType, event utils.K8sEvent, _ objectcache.ObjectCache) ruleengine.RuleFailure {
+	switch eventType {
+	case utils.NetworkEventType:
+		networkEvent, ok := event.(*tracernetworktype.Event)
+		if !ok || networkEvent.PktType != "OUTGOING" {
+			return nil
+		}
+		rule.connections.Add(connectionKey(networkEvent.Runtime.ContainerID, networkEvent.Pid), connection{
+			addr:  networkEvent.DstEndpoint.Addr,
+			port:  networkEvent.Port,
+			proto: networkEvent.Proto,
+		})
+	case utils.ReverseShellEventType:
+		reverseShellEvent, ok := event.(*tracerreverseshelltype.Event)
+		if !ok {
+			return nil
+		}
+		return rule.handleReverseShellEvent(reverseShellEvent)
+	}
+	return nil
+}
+
+func (rule *R1024ReverseShell) handleReverseShellEvent(reverseShellEvent *tracerreverseshelltype.Event) ruleengine.RuleFailure {
+	if !isReverseShellBinary(reverseShellEvent.ExePath) || slices.Contains(rule.allowedParents, reverseShellEvent.ParentExePath) {
+		return nil
+	}
+	// the gadget only reports the connected sockets, the check keeps the listening ones out
+	remote := reverseShellEvent.Remote
+	if remote.Port == 0 || isUnspecifiedAddress(remote.Addr) || slices.Contains(rule.allowedAddresses, remote.Addr) {
+		return nil
+	}
+
+	proto := gadgets.ProtoString(int(remote.Proto))
+	arguments := map[string]interface{}{
+		"exec":          reverseShellEvent.ExePath,
+		"parentExec":    reverseShellEvent.ParentExePath,
+		"streams":       reverseShellEvent.Streams,
+		"remoteAddress": remote.Addr,
+		"remotePort":    remote.Port,
+		"proto":         proto,
+	}
+	// the shells connecting themselves, and the ones spawned by a connected process such as nc -e
+	for _, pid := range []uint32{reverseShellEvent.Pid, reverseShellEvent.PPid} {
+		if conn, ok := rule.connections.Get(connectionKey(reverseShellEvent.Runtime.ContainerID, pid)); ok {
+			arguments["connectionPID"] = pid
+			arguments["connectionAddress"] = conn.addr
+			arguments["connectionPort"] = conn.port
+			arguments["connectionProto"] = conn.proto
+			break
+		}
+	}
+
+	return &GenericRuleFailure{
+		BaseRuntimeAlert: apitypes.BaseRuntimeAlert{
+			AlertName:   rule.Name(),
+			InfectedPID: reverseShellEvent.Pid,
+			Arguments:   arguments,
+			Severity:    R1024ReverseShellRuleDescriptor.Priority,
+		},
+		RuntimeProcessDetails: apitypes.ProcessTree{
+			ProcessTree: apitypes.Process{
+				Comm:       reverseShellEvent.Comm,
+				Gid:        &reverseShellEvent.Gid,
+				PID:        reverseShellEvent.Pid,
+				Uid:        &reverseShellEvent.Uid,
+				UpperLayer: &reverseShellEvent.UpperLayer,
+				PPID:       reverseShellEvent.PPid,
+				Pcomm:      reverseShellEvent.Pcomm,
+				Hardlink:   reverseShellEvent.ExePath,
+				Path:       reverseShellEvent.ExePath,
+			},
+			ContainerID: reverseShellEvent.Runtime.ContainerID,
+		},
+		TriggerEvent: reverseShellEvent.Event,
+		RuleAlert: apitypes.RuleAlert{
+			RuleDescription: fmt.Sprintf("Reverse shell: %s connected to %s:%d (%s) in: %s", reverseShellEvent.ExePath, remote.Addr, remote.Port, proto, reverseShellEvent.GetContainer()),
+		},
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{
+			PodName:   reverseShellEvent.GetPod(),
+			PodLabels: reverseShellEvent.K8s.PodLabels,
+		},
+		RuleID: rule.ID(),
+		Extra:  reverseShellEvent.GetExtra(),
+	}
+}
+
+func (rule *R1024ReverseShell) Requirements() ruleengine.RuleSpec {
+	return &RuleRequirements{
+		EventTypes: R1024ReverseShellRuleDescriptor.Requirements.RequiredEventTypes(),
+	}
+}
+
+func connectionKey(containerID string, pid uint32) string {
+	return containerID + "/" + strconv.FormatUint(uint64(pid), 10)
+}
+
+// isReverseShellBinary checks if the executed file is a shell or an interpreter, ignoring its version
+func isReverseShellBinary(execPath string) bool {
+	name := strings.TrimRight(filepath.Base(execPath), "0123456789.")
+	return slices.Contains(ReverseShellBinaries, name)
+}
+
+// isUnspecifiedAddress checks for the addresses of the unconnected sockets
+func isUnspecifiedAddress(addr string) bool {
+	return addr == "" || addr == "0.0.0.0" || addr == "::"
+}
@@ -0,0 +1,114 @@
+package ruleengine
+
+import (
+	"testing"
+
+	tracerreverseshelltype "github.com/kubescape/node-agent/pkg/ebpf/gadgets/reverseshell/types"
+	"github.com/kubescape/node-agent/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tracernetworktype "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestR1024ReverseShell(t *testing.T) {
+	r := CreateRuleR1024ReverseShell()
+	objCache := RuleObjectCacheMock{}
+
+	// a shell executed with its standard streams connected to a TCP endpoint
+	e := &tracerreverseshelltype.Event{
+		Event: eventtypes.Event{
+			CommonData: eventtypes.CommonData{
+				Runtime: eventtypes.BasicRuntimeMetadata{ContainerID: "test"},
+			},
+		},
+		Pid:           4321,
+		PPid:          1234,
+		Comm:          "bash",
+		Pcomm:         "nc",
+		ExePath:       "/bin/bash",
+		ParentExePath: "/usr/bin/nc",
+		Streams:       []string{"stdin", "stdout"},
+		Remote: eventtypes.L4Endpoint{
+			L3Endpoint: eventtypes.L3Endpoint{Addr: "10.0.0.7", Version: 4},
+			Port:       4444,
+			Proto:      6,
+		},
+	}
+
+	// a connection of the parent, such as nc -e
+	networkEvent := &tracernetworktype.Event{
+		Event:       e.Event,
+		Pid:         1234,
+		PktType:     "OUTGOING",
+		Proto:       "TCP",
+		Port:        4444,
+		DstEndpoint: eventtypes.L3Endpoint{Addr: "10.0.0.7"},
+	}
+	assert.Nil(t, r.ProcessEvent(utils.NetworkEventType, networkEvent, &objCache))
+
+	ruleResult := r.ProcessEvent(utils.ReverseShellEventType, e, &objCache)
+	require.NotNil(t, ruleResult)
+	arguments := ruleResult.GetBaseRuntimeAlert().Arguments
+	assert.Equal(t, "10.0.0.7", arguments["remoteAddress"])
+	assert.Equal(t, uint16(4444), arguments["remotePort"])
+	assert.Equal(t, "TCP", arguments["proto"])
+	assert.Equal(t, []string{"stdin", "stdout"}, arguments["streams"])
+	assert.Equal(t, uint32(1234), arguments["connectionPID"])
+
+	// other binaries are not checked
+	e.ExePath = "/usr/bin/curl"
+	assert.Nil(t, r.ProcessEvent(utils.ReverseShellEventType, e, &objCache))
+	// versioned interpreters are checked
+	e.ExePath = "/usr/bin/python3.11"
+	assert.NotNil(t, r.ProcessEvent(utils.ReverseShellEventType, e, &objCache))
+
+	// the unconnected sockets
+	e.Remote.Addr, e.Remote.Port = "0.0.0.0", 0
+	assert.Nil(t, r.ProcessEvent(utils.ReverseShellEventType, e, &objCache))
+	e.Remote.Addr, e.Remote.Port = "10.0.0.7", 4444
+
+	// the remote management daemons are matched on their executable, not on their name
+	e.ParentExePath = "/usr/sbin/sshd"
+	assert.Nil(t, r.ProcessEvent(utils.ReverseShellEventType, e, &objCache))
+	e.Pcomm, e.ParentExePath = "sshd", "/tmp/sshd"
+	assert.NotNil(t, r.ProcessEvent(utils.ReverseShellEventType, e, &objCache))
+	r.SetParameters(map[string]interface{}{
+		"allowedParents": []interface{}{"/tmp/sshd"},
+	})
+	assert.Nil(t, r.ProcessEvent(utils.ReverseShellEventType, e, &objCache))
+
+	// the allowed addresses
+	e.ParentExePath = "/usr/bin/nc"
+	r.SetParameters(map[string]interface{}{
+		"allowedAddresses": []interface{}{"10.0.0.7"},
+	})
+	assert.Nil(t, r.ProcessEvent(utils.ReverseShellEventType, e, &objCache))
+}
+
+func TestR1024ReverseShellContainerStopped(t *testing.T) {
+	r := CreateRuleR1024ReverseShell()
+	objCache := RuleObjectCacheMock{}
+
+	for _, containerID := range []string{"test", "other"} {
+		networkEvent := &tracernetworktype.Event{
+			Event: eventtypes.Event{
+				CommonData: eventtypes.CommonData{
+					Runtime: eventtypes.BasicRuntimeMetadata{ContainerID: containerID},
+				},
+			},
+			Pid:         1234,
+			PktType:     "OUTGOING",
+			Proto:       "TCP",
+			Port:        4444,
+			DstEndpoint: eventtypes.L3Endpoint{Addr: "10.0.0.7"},
+		}
+		r.ProcessEvent(utils.NetworkEventType, networkEvent, &objCache)
+	}
+
+	// the connections of the stopped container are dropped
+	r.ContainerStopped("test")
+	assert.False(t, r.connections.Contains(connectionKey("test", 1234)))
+	assert.True(t, r.connections.Contains(connectionKey("other", 1234)))
+}
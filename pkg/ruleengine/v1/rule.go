@@ -1,6 +1,7 @@
 package ruleengine
 
 import (
+	typesv1 "github.com/kubescape/node-agent/pkg/rulebindingmanager/types/v1"
 	"github.com/kubescape/node-agent/pkg/ruleengine"
 	"github.com/kubescape/node-agent/pkg/utils"
 
@@ -31,6 +32,16 @@ func (r *RuleRequirements) RequiredEventTypes() []utils.EventType {
 type BaseRule struct {
 	// Mutex for protecting rule parameters.
 	parameters maps.SafeMap[string, interface{}]
+	// Response actions of the rule binding, set once when the rule is created.
+	responseActions []typesv1.RuntimeAlertRuleBindingAction
+}
+
+func (br *BaseRule) SetResponseActions(actions []typesv1.RuntimeAlertRuleBindingAction) {
+	br.responseActions = actions
+}
+
+func (br *BaseRule) GetResponseActions() []typesv1.RuntimeAlertRuleBindingAction {
+	return br.responseActions
 }
 
 func (br *BaseRule) SetParameters(parameters map[string]interface{}) {
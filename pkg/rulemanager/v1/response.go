@@ -0,0 +1,148 @@
+package rulemanager
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/responseactions"
+	typesv1 "github.com/kubescape/node-agent/pkg/rulebindingmanager/types/v1"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"golang.org/x/time/rate"
+)
+
+const maxRecentResponses = 10000
+
+var (
+	errRateLimited = errors.New("rate limited")
+	errCoolingDown = errors.New("already done during the cooldown")
+)
+
+// responseLimits bounds the response actions of the node: a rate limit for all the actions
+// and a cooldown during which the same action on the same target is not repeated.
+type responseLimits struct {
+	limiter *rate.Limiter
+	recent  *expirable.LRU[string, struct{}] // nil without a cooldown
+}
+
+func newResponseLimits(cfg config.RuntimeResponseConfig) *responseLimits {
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if cfg.MaxActionsPerMinute > 0 {
+		limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(cfg.MaxActionsPerMinute)), cfg.MaxActionsPerMinute)
+	}
+	limits := &responseLimits{limiter: limiter}
+	if cfg.Cooldown > 0 {
+		limits.recent = expirable.NewLRU[string, struct{}](maxRecentResponses, nil, cfg.Cooldown)
+	}
+	return limits
+}
+
+// allow checks the cooldown and the rate limit of the action on the target.
+// A dry run reports the limits that would apply without using them up.
+func (l *responseLimits) allow(action responseactions.Action, target string, dryRun bool) error {
+	key := fmt.Sprintf("%s/%s", action, target)
+	if l.recent != nil && l.recent.Contains(key) {
+		return errCoolingDown
+	}
+	if dryRun {
+		if l.limiter.Limit() != rate.Inf && l.limiter.Tokens() < 1 {
+			return errRateLimited
+		}
+		return nil
+	}
+	if !l.limiter.Allow() {
+		return errRateLimited
+	}
+	if l.recent != nil {
+		l.recent.Add(key, struct{}{})
+	}
+	return nil
+}
+
+// respond runs the response actions declared on the rule binding of the failed rule.
+// The actions run only in the allowed namespaces, the outcomes are audit-logged and attached to the alert arguments.
+func (rm *RuleManager) respond(rule ruleengine.RuleEvaluator, ruleFailure ruleengine.RuleFailure) {
+	responder, ok := rule.(ruleengine.RuleResponder)
+	if !ok || len(responder.GetResponseActions()) == 0 {
+		return
+	}
+	k8sDetails := ruleFailure.GetRuntimeAlertK8sDetails()
+	if !slices.Contains(rm.cfg.RuntimeResponse.Namespaces, k8sDetails.Namespace) {
+		return
+	}
+
+	outcomes := make([]responseactions.Outcome, 0, len(responder.GetResponseActions()))
+	for _, action := range responder.GetResponseActions() {
+		outcome := rm.runResponseAction(action, ruleFailure)
+		responseactions.Audit(outcome,
+			helpers.String("rule ID", ruleFailure.GetRuleId()),
+			helpers.String("alert", ruleFailure.GetBaseRuntimeAlert().AlertName),
+			helpers.String("namespace", k8sDetails.Namespace),
+			helpers.String("pod", k8sDetails.PodName),
+			helpers.String("container ID", k8sDetails.ContainerID))
+		outcomes = append(outcomes, outcome)
+	}
+
+	baseRuntimeAlert := ruleFailure.GetBaseRuntimeAlert()
+	if baseRuntimeAlert.Arguments == nil {
+		baseRuntimeAlert.Arguments = make(map[string]interface{})
+	}
+	baseRuntimeAlert.Arguments[responseactions.OutcomesArgument] = outcomes
+	ruleFailure.SetBaseRuntimeAlert(baseRuntimeAlert)
+}
+
+func (rm *RuleManager) runResponseAction(action typesv1.RuntimeAlertRuleBindingAction, ruleFailure ruleengine.RuleFailure) responseactions.Outcome {
+	k8sDetails := ruleFailure.GetRuntimeAlertK8sDetails()
+	containerPid := rm.containerIdToPid.Get(k8sDetails.ContainerID)
+
+	var target string
+	var run func() error
+	switch responseactions.Action(action.Type) {
+	case responseactions.ActionKill:
+		pid := ruleFailure.GetBaseRuntimeAlert().InfectedPID
+		target = fmt.Sprintf("%d", pid)
+		run = func() error {
+			return responseactions.KillProcess(pid, containerPid)
+		}
+	case responseactions.ActionFreeze:
+		target = k8sDetails.ContainerID
+		run = func() error {
+			_, err := responseactions.FreezeCgroup(containerPid)
+			return err
+		}
+	case responseactions.ActionLabel:
+		target = fmt.Sprintf("%s/%s", k8sDetails.Namespace, k8sDetails.PodName)
+		run = func() error {
+			return responseactions.LabelPod(rm.ctx, rm.k8sClient.GetKubernetesClient(), k8sDetails.Namespace, k8sDetails.PodName, rm.cfg.RuntimeResponse.QuarantineLabel)
+		}
+	case responseactions.ActionCordon:
+		target = rm.nodeName
+		run = func() error {
+			return responseactions.CordonNode(rm.ctx, rm.k8sClient.GetKubernetesClient(), rm.nodeName)
+		}
+	case responseactions.ActionEvict:
+		target = fmt.Sprintf("%s/%s", k8sDetails.Namespace, k8sDetails.PodName)
+		run = func() error {
+			return responseactions.EvictPod(rm.ctx, rm.k8sClient.GetKubernetesClient(), k8sDetails.Namespace, k8sDetails.PodName)
+		}
+	default:
+		return responseactions.NewOutcome(responseactions.Action(action.Type), "", fmt.Errorf("unknown response action %q", action.Type))
+	}
+
+	dryRun := action.DryRun || rm.cfg.RuntimeResponse.DryRun
+	if err := rm.responseLimits.allow(responseactions.Action(action.Type), target, dryRun); err != nil {
+		outcome := responseactions.NewOutcome(responseactions.Action(action.Type), target, err)
+		outcome.DryRun = dryRun
+		return outcome
+	}
+	if dryRun {
+		outcome := responseactions.NewOutcome(responseactions.Action(action.Type), target, nil)
+		outcome.DryRun = true
+		return outcome
+	}
+	return responseactions.NewOutcome(responseactions.Action(action.Type), target, run())
+}
@@ -0,0 +1,120 @@
+package rulemanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/k8sclient"
+	"github.com/kubescape/node-agent/pkg/responseactions"
+	typesv1 "github.com/kubescape/node-agent/pkg/rulebindingmanager/types/v1"
+	ruleenginev1 "github.com/kubescape/node-agent/pkg/ruleengine/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// k8sClientFake shares one fake clientset between the calls, so the test can check the applied changes
+type k8sClientFake struct {
+	*k8sclient.K8sClientMock
+	client kubernetes.Interface
+}
+
+func (k *k8sClientFake) GetKubernetesClient() kubernetes.Interface {
+	return k.client
+}
+
+func TestRespond(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+	)
+	cfg := config.RuntimeResponseConfig{
+		Namespaces:          []string{"default"},
+		MaxActionsPerMinute: 2,
+		Cooldown:            time.Minute,
+		QuarantineLabel:     "kubescape.io/quarantine",
+	}
+	rm := &RuleManager{
+		ctx:            context.Background(),
+		cfg:            config.Config{RuntimeResponse: cfg},
+		k8sClient:      &k8sClientFake{K8sClientMock: k8sclient.NewK8sClientMock(), client: client},
+		nodeName:       "node-1",
+		responseLimits: newResponseLimits(cfg),
+	}
+
+	newFailure := func(namespace string) *ruleenginev1.GenericRuleFailure {
+		return &ruleenginev1.GenericRuleFailure{
+			BaseRuntimeAlert:       apitypes.BaseRuntimeAlert{AlertName: "Unexpected process launched"},
+			RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{Namespace: namespace, PodName: "nginx"},
+			RuleID:                 "R0001",
+		}
+	}
+	outcomesOf := func(failure *ruleenginev1.GenericRuleFailure) []responseactions.Outcome {
+		outcomes, _ := failure.GetBaseRuntimeAlert().Arguments[responseactions.OutcomesArgument].([]responseactions.Outcome)
+		return outcomes
+	}
+
+	// rules without actions do nothing
+	rule := ruleenginev1.CreateRuleR0001UnexpectedProcessLaunched()
+	failure := newFailure("default")
+	rm.respond(rule, failure)
+	assert.Nil(t, failure.GetBaseRuntimeAlert().Arguments)
+
+	rule.SetResponseActions([]typesv1.RuntimeAlertRuleBindingAction{
+		{Type: "label"},
+		{Type: "cordon", DryRun: true},
+		{Type: "unknown"},
+	})
+
+	// the namespace is not allowed
+	failure = newFailure("kube-system")
+	rm.respond(rule, failure)
+	assert.Empty(t, outcomesOf(failure))
+
+	failure = newFailure("default")
+	rm.respond(rule, failure)
+	outcomes := outcomesOf(failure)
+	require.Len(t, outcomes, 3)
+	assert.True(t, outcomes[0].Success)
+	assert.Equal(t, "default/nginx", outcomes[0].Target)
+	assert.True(t, outcomes[1].Success)
+	assert.True(t, outcomes[1].DryRun)
+	assert.False(t, outcomes[2].Success)
+
+	pod, err := client.CoreV1().Pods("default").Get(context.Background(), "nginx", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "true", pod.Labels["kubescape.io/quarantine"])
+	node, err := client.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.False(t, node.Spec.Unschedulable)
+
+	// the same actions on the same targets are not repeated during the cooldown, the dry runs are not recorded
+	failure = newFailure("default")
+	rm.respond(rule, failure)
+	outcomes = outcomesOf(failure)
+	require.Len(t, outcomes, 3)
+	assert.Equal(t, errCoolingDown.Error(), outcomes[0].Error)
+	assert.True(t, outcomes[1].Success)
+	assert.True(t, outcomes[1].DryRun)
+
+	// the rate limit is exhausted by the label and the evict actions, the dry runs did not use it up
+	rule.SetResponseActions([]typesv1.RuntimeAlertRuleBindingAction{
+		{Type: "evict"},
+		{Type: "cordon"},
+		{Type: "cordon", DryRun: true},
+	})
+	failure = newFailure("default")
+	rm.respond(rule, failure)
+	outcomes = outcomesOf(failure)
+	require.Len(t, outcomes, 3)
+	assert.True(t, outcomes[0].Success)
+	assert.Equal(t, errRateLimited.Error(), outcomes[1].Error)
+	assert.Equal(t, errRateLimited.Error(), outcomes[2].Error)
+	assert.True(t, outcomes[2].DryRun)
+}
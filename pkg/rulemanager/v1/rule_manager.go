@@ -19,6 +19,7 @@ import (
 	"github.com/kubescape/node-agent/pkg/config"
 	"github.com/kubescape/node-agent/pkg/dnsmanager"
 	"github.com/kubescape/node-agent/pkg/exporters"
+	"github.com/kubescape/node-agent/pkg/forensics"
 	"github.com/kubescape/node-agent/pkg/k8sclient"
 	"github.com/kubescape/node-agent/pkg/metricsmanager"
 	"github.com/kubescape/node-agent/pkg/objectcache"
@@ -27,6 +28,7 @@ import (
 	"github.com/kubescape/node-agent/pkg/ruleengine"
 	ruleenginetypes "github.com/kubescape/node-agent/pkg/ruleengine/types"
 	"github.com/kubescape/node-agent/pkg/rulemanager"
+	"github.com/kubescape/node-agent/pkg/sbommanager"
 	"github.com/kubescape/node-agent/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -54,11 +56,14 @@ type RuleManager struct {
 	enricher             ruleenginetypes.Enricher
 	processManager       processmanager.ProcessManagerClient
 	dnsManager           dnsmanager.DNSResolver
+	sbomManager          sbommanager.SbomManagerClient
+	responseLimits       *responseLimits
+	evidenceCollector    *forensics.Collector // nil when the forensic capture is disabled
 }
 
 var _ rulemanager.RuleManagerClient = (*RuleManager)(nil)
 
-func CreateRuleManager(ctx context.Context, cfg config.Config, k8sClient k8sclient.K8sClientInterface, ruleBindingCache bindingcache.RuleBindingCache, objectCache objectcache.ObjectCache, exporter exporters.Exporter, metrics metricsmanager.MetricsManager, nodeName string, clusterName string, processManager processmanager.ProcessManagerClient, dnsManager dnsmanager.DNSResolver, enricher ruleenginetypes.Enricher) (*RuleManager, error) {
+func CreateRuleManager(ctx context.Context, cfg config.Config, k8sClient k8sclient.K8sClientInterface, ruleBindingCache bindingcache.RuleBindingCache, objectCache objectcache.ObjectCache, exporter exporters.Exporter, metrics metricsmanager.MetricsManager, nodeName string, clusterName string, processManager processmanager.ProcessManagerClient, dnsManager dnsmanager.DNSResolver, sbomManager sbommanager.SbomManagerClient, enricher ruleenginetypes.Enricher, evidenceCollector *forensics.Collector) (*RuleManager, error) {
 	return &RuleManager{
 		cfg:               cfg,
 		ctx:               ctx,
@@ -73,6 +78,9 @@ func CreateRuleManager(ctx context.Context, cfg config.Config, k8sClient k8sclie
 		enricher:          enricher,
 		processManager:    processManager,
 		dnsManager:        dnsManager,
+		sbomManager:       sbomManager,
+		responseLimits:    newResponseLimits(cfg.RuntimeResponse),
+		evidenceCollector: evidenceCollector,
 	}, nil
 }
 
@@ -169,6 +177,12 @@ func (rm *RuleManager) startRuleManager(container *containercollection.Container
 		}
 	}
 
+	for _, rule := range rm.ruleBindingCache.ListRulesForPod(container.K8s.Namespace, container.K8s.PodName) {
+		if tracker, ok := rule.(ruleengine.RuleContainerTracker); ok {
+			tracker.ContainerStarted(container)
+		}
+	}
+
 	if err := rm.monitorContainer(container, k8sContainerID); err != nil {
 		logger.L().Debug("RuleManager - stop monitor on container", helpers.String("reason", err.Error()),
 			helpers.String("container ID", container.Runtime.ContainerID),
@@ -204,6 +218,11 @@ func (rm *RuleManager) ContainerCallback(notif containercollection.PubSubEvent)
 		go rm.startRuleManager(notif.Container, k8sContainerID)
 	case containercollection.EventTypeRemoveContainer:
 		rm.trackedContainers.Remove(k8sContainerID)
+		for _, rule := range rm.ruleBindingCache.ListRulesForPod(notif.Container.K8s.Namespace, notif.Container.K8s.PodName) {
+			if tracker, ok := rule.(ruleengine.RuleContainerTracker); ok {
+				tracker.ContainerStopped(notif.Container.Runtime.ContainerID)
+			}
+		}
 		rm.podToWlid.Delete(utils.CreateK8sPodID(notif.Container.K8s.Namespace, notif.Container.K8s.PodName))
 		rm.containerIdToShimPid.Delete(notif.Container.Runtime.ContainerID)
 		rm.containerIdToPid.Delete(notif.Container.Runtime.ContainerID)
@@ -247,8 +266,17 @@ func (rm *RuleManager) processEvent(eventType utils.EventType, event utils.K8sEv
 		res := rule.ProcessEvent(eventType, event, rm.objectCache)
 		if res != nil {
 			res = rm.enrichRuleFailure(res)
+			// the rules matching on the file hashes decide once the hashes are computed by the enrichment
+			if evaluator, ok := rule.(ruleengine.RuleEnrichedFailureEvaluator); ok {
+				res = evaluator.EvaluateEnrichedFailure(res, rm.objectCache)
+			}
+		}
+		if res != nil {
 			res.SetWorkloadDetails(rm.podToWlid.Get(utils.CreateK8sPodID(res.GetRuntimeAlertK8sDetails().Namespace, res.GetRuntimeAlertK8sDetails().PodName)))
+			rm.respond(rule, res)
 			rm.exporter.SendRuleAlert(res)
+			// the SBOMs of the containers with alerts are generated first
+			rm.sbomManager.PrioritizeContainer(res.GetTriggerEvent().Runtime.ContainerID)
 
 			rm.metrics.ReportRuleAlert(rule.Name())
 		}
@@ -257,17 +285,13 @@ func (rm *RuleManager) processEvent(eventType utils.EventType, event utils.K8sEv
 }
 func (rm *RuleManager) enrichRuleFailure(ruleFailure ruleengine.RuleFailure) ruleengine.RuleFailure {
 	var err error
-	var path string
 	var hostPath string
-	if ruleFailure.GetRuntimeProcessDetails().ProcessTree.Path == "" {
+	// the file hashed is the executable reported by the rule, or the one of the process
+	path := ruleFailure.GetRuntimeProcessDetails().ProcessTree.Path
+	if path == "" {
 		path, err = utils.GetPathFromPid(ruleFailure.GetRuntimeProcessDetails().ProcessTree.PID)
 	}
-
-	if err != nil {
-		if ruleFailure.GetRuntimeProcessDetails().ProcessTree.Path != "" {
-			hostPath = filepath.Join("/proc", fmt.Sprintf("/%d/root/%s", rm.containerIdToPid.Get(ruleFailure.GetTriggerEvent().Runtime.ContainerID), ruleFailure.GetRuntimeProcessDetails().ProcessTree.Path))
-		}
-	} else {
+	if err == nil {
 		hostPath = filepath.Join("/proc", fmt.Sprintf("/%d/root/%s", ruleFailure.GetRuntimeProcessDetails().ProcessTree.PID, path))
 	}
 
@@ -288,11 +312,16 @@ func (rm *RuleManager) enrichRuleFailure(ruleFailure ruleengine.RuleFailure) rul
 	}
 
 	if size != 0 && size < maxFileSize && hostPath != "" {
-		if baseRuntimeAlert.MD5Hash == "" || baseRuntimeAlert.SHA1Hash == "" {
-			sha1hash, md5hash, err := utils.CalculateFileHashes(hostPath)
+		if baseRuntimeAlert.MD5Hash == "" || baseRuntimeAlert.SHA1Hash == "" || baseRuntimeAlert.SHA256Hash == "" {
+			hashes, err := utils.GetFileHashes(hostPath)
 			if err == nil {
-				baseRuntimeAlert.MD5Hash = md5hash
-				baseRuntimeAlert.SHA1Hash = sha1hash
+				if baseRuntimeAlert.MD5Hash == "" || baseRuntimeAlert.SHA1Hash == "" {
+					baseRuntimeAlert.MD5Hash = hashes.MD5
+					baseRuntimeAlert.SHA1Hash = hashes.SHA1
+				}
+				if baseRuntimeAlert.SHA256Hash == "" {
+					baseRuntimeAlert.SHA256Hash = hashes.SHA256
+				}
 			}
 		}
 	}
@@ -375,9 +404,40 @@ func (rm *RuleManager) enrichRuleFailure(ruleFailure ruleengine.RuleFailure) rul
 		rm.enricher.EnrichRuleFailure(ruleFailure)
 	}
 
+	if rm.evidenceCollector != nil && rm.evidenceCollector.ShouldCollect(ruleFailure.GetBaseRuntimeAlert().Severity) {
+		rm.collectEvidence(ruleFailure)
+	}
+
 	return ruleFailure
 }
 
+// collectEvidence snapshots the offending process before the response actions run, the stored evidence bundle is
+// referenced in the alert arguments
+func (rm *RuleManager) collectEvidence(ruleFailure ruleengine.RuleFailure) {
+	baseRuntimeAlert := ruleFailure.GetBaseRuntimeAlert()
+	pid := baseRuntimeAlert.InfectedPID
+	if pid == 0 {
+		pid = ruleFailure.GetRuntimeProcessDetails().ProcessTree.PID
+	}
+	k8sDetails := ruleFailure.GetRuntimeAlertK8sDetails()
+	bundleID, err := rm.evidenceCollector.Collect(pid, forensics.AlertDetails{
+		RuleID:      ruleFailure.GetRuleId(),
+		AlertName:   baseRuntimeAlert.AlertName,
+		Namespace:   k8sDetails.Namespace,
+		PodName:     k8sDetails.PodName,
+		ContainerID: k8sDetails.ContainerID,
+	})
+	if err != nil {
+		logger.L().Debug("RuleManager - failed to collect evidence", helpers.Error(err), helpers.Int("pid", int(pid)), helpers.String("rule ID", ruleFailure.GetRuleId()))
+		return
+	}
+	if baseRuntimeAlert.Arguments == nil {
+		baseRuntimeAlert.Arguments = make(map[string]interface{})
+	}
+	baseRuntimeAlert.Arguments[forensics.BundleIDArgument] = bundleID
+	ruleFailure.SetBaseRuntimeAlert(baseRuntimeAlert)
+}
+
 // Checks if the event type is relevant to the rule.
 func isEventRelevant(ruleSpec ruleengine.RuleSpec, eventType utils.EventType) bool {
 	for _, i := range ruleSpec.RequiredEventTypes() {
@@ -4,4 +4,7 @@ import containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/con
 
 type SbomManagerClient interface {
 	ContainerCallback(notif containercollection.PubSubEvent)
+	ReportFileExec(containerID, path string)
+	ReportFileOpen(containerID, path string)
+	PrioritizeContainer(containerID string)
 }
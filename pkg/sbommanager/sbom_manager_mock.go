@@ -13,3 +13,15 @@ func CreateSbomManagerMock() *SbomManagerMock {
 func (s SbomManagerMock) ContainerCallback(_ containercollection.PubSubEvent) {
 	// noop
 }
+
+func (s SbomManagerMock) ReportFileExec(_, _ string) {
+	// noop
+}
+
+func (s SbomManagerMock) ReportFileOpen(_, _ string) {
+	// noop
+}
+
+func (s SbomManagerMock) PrioritizeContainer(_ string) {
+	// noop
+}
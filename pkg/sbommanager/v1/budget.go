@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/spf13/afero"
+)
+
+// workerCount returns the number of SBOM workers allowed by the parallelism and the CPU budget
+func workerCount(cfg config.SBOMQueueConfig) int {
+	workers := cfg.Parallelism
+	if cfg.CPUBudget > 0 {
+		workers = min(workers, int(math.Floor(cfg.CPUBudget)))
+	}
+	return max(1, min(workers, runtime.NumCPU()))
+}
+
+// readPressure returns the "some avg10" percentage of a PSI file, e.g. /proc/pressure/memory
+func readPressure(appFs afero.Fs, path string) (float64, error) {
+	f, err := appFs.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if value, found := strings.CutPrefix(field, "avg10="); found {
+				return strconv.ParseFloat(value, 64)
+			}
+		}
+	}
+	return 0, fmt.Errorf("no some avg10 in %s", path)
+}
+
+// reserveMemory waits until the image fits in the memory budget next to the images being cataloged, the
+// weight is the image size capped by the budget so the largest images are processed alone. It returns the
+// function releasing the reservation.
+func (s *SbomManager) reserveMemory(imageSize int64) (func(), error) {
+	if s.memory == nil {
+		return func() {}, nil
+	}
+	weight := min(max(imageSize, 1), s.cfg.SBOMQueue.MemoryBudget)
+	if err := s.memory.Acquire(s.ctx, weight); err != nil {
+		return nil, err
+	}
+	return func() { s.memory.Release(weight) }, nil
+}
+
+// pressureReason returns why the SBOM generation should pause, empty if it can go on
+func (s *SbomManager) pressureReason() string {
+	if s.cfg.SBOMQueue.PressureThreshold > 0 {
+		for _, resource := range []string{"cpu", "memory"} {
+			// nodes without PSI are never considered under pressure
+			pressure, err := readPressure(s.appFs, filepath.Join(s.procDir, "pressure", resource))
+			if err == nil && pressure > s.cfg.SBOMQueue.PressureThreshold {
+				return "node " + resource + " pressure"
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/semaphore"
+)
+
+func TestWorkerCount(t *testing.T) {
+	assert.Equal(t, 1, workerCount(config.SBOMQueueConfig{Parallelism: 4, CPUBudget: 1.5}))
+	assert.Equal(t, min(2, runtime.NumCPU()), workerCount(config.SBOMQueueConfig{Parallelism: 2}))
+	assert.Equal(t, 1, workerCount(config.SBOMQueueConfig{Parallelism: 0, CPUBudget: 4}))
+	assert.Equal(t, 1, workerCount(config.SBOMQueueConfig{Parallelism: 4, CPUBudget: 0.5}))
+}
+
+func TestPressureReason(t *testing.T) {
+	appFs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(appFs, "/host/proc/pressure/cpu", []byte("some avg10=12.50 avg60=3.00 avg300=1.00 total=123\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"), 0644))
+	require.NoError(t, afero.WriteFile(appFs, "/host/proc/pressure/memory", []byte("some avg10=75.10 avg60=20.00 avg300=5.00 total=456\nfull avg10=60.00 avg60=10.00 avg300=2.00 total=400\n"), 0644))
+
+	pressure, err := readPressure(appFs, "/host/proc/pressure/cpu")
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, pressure)
+
+	s := &SbomManager{appFs: appFs, procDir: "/host/proc", cfg: config.Config{SBOMQueue: config.SBOMQueueConfig{PressureThreshold: 40}}}
+	assert.Equal(t, "node memory pressure", s.pressureReason())
+	s.cfg.SBOMQueue.PressureThreshold = 80
+	assert.Empty(t, s.pressureReason())
+	// nodes without PSI are never under pressure
+	s.procDir = "/missing"
+	s.cfg.SBOMQueue.PressureThreshold = 1
+	assert.Empty(t, s.pressureReason())
+}
+
+func TestReserveMemory(t *testing.T) {
+	s := &SbomManager{ctx: context.Background(), cfg: config.Config{SBOMQueue: config.SBOMQueueConfig{MemoryBudget: 100}}}
+	// without a budget the reservations never wait
+	release, err := s.reserveMemory(1000)
+	require.NoError(t, err)
+	release()
+
+	s.memory = semaphore.NewWeighted(s.cfg.SBOMQueue.MemoryBudget)
+	release, err = s.reserveMemory(60)
+	require.NoError(t, err)
+	assert.False(t, s.memory.TryAcquire(50))
+	assert.True(t, s.memory.TryAcquire(40))
+	s.memory.Release(40)
+	release()
+	// the images larger than the budget are processed alone
+	release, err = s.reserveMemory(1000)
+	require.NoError(t, err)
+	assert.False(t, s.memory.TryAcquire(1))
+	release()
+
+	// the agent stops while the worker waits
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ctx = ctx
+	_, err = s.reserveMemory(60)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,208 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/anchore/syft/syft/format/cyclonedxjson"
+	"github.com/anchore/syft/syft/format/spdxjson"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	helpersv1 "github.com/kubescape/k8s-interface/instanceidhandler/v1/helpers"
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/storage"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// FormatSPDX and FormatCycloneDX are the additional SBOM formats, the Syft JSON format is always stored
+	FormatSPDX      = "spdx-json"
+	FormatCycloneDX = "cyclonedx-json"
+
+	// FormatsMetadataKey lists the additional formats of the SBOM, the ones fitting their size limit
+	FormatsMetadataKey = "kubescape.io/sbom-formats"
+
+	// FormatsHandlerPath is the path prefix of the read-only SBOM documents API, served on the local socket:
+	// GET /sbom/ lists the documents, GET /sbom/<SBOM name>/<format> downloads a document.
+	FormatsHandlerPath = "/sbom/"
+
+	spdxVersion      = "2.3"
+	cycloneDXVersion = "1.5"
+)
+
+var (
+	ErrSBOMTooLarge      = errors.New("SBOM exceeds the size limit")
+	ErrDocumentNotFound  = errors.New("SBOM document not found")
+	errUnsupportedFormat = errors.New("unsupported SBOM format")
+
+	// documentNameRegexp matches the SBOM names, they are DNS subdomain names
+	documentNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	supportedFormats   = []string{FormatSPDX, FormatCycloneDX}
+)
+
+// newFormatEncoder returns the encoder of the format, in the version required by the compliance tools
+func newFormatEncoder(format string) (sbom.FormatEncoder, error) {
+	switch format {
+	case FormatSPDX:
+		return spdxjson.NewFormatEncoderWithConfig(spdxjson.EncoderConfig{Version: spdxVersion})
+	case FormatCycloneDX:
+		return cyclonedxjson.NewFormatEncoderWithConfig(cyclonedxjson.EncoderConfig{Version: cycloneDXVersion})
+	default:
+		return nil, fmt.Errorf("%w %q", errUnsupportedFormat, format)
+	}
+}
+
+// limitedBuffer fails the writes beyond its limit, a zero limit is unlimited
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 && b.Len()+len(p) > b.limit {
+		return 0, ErrSBOMTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// encodeSBOM encodes the SBOM in the format, the encoding stops as soon as the size limit is exceeded
+func encodeSBOM(s sbom.SBOM, format string, maxSize int) ([]byte, error) {
+	encoder, err := newFormatEncoder(format)
+	if err != nil {
+		return nil, err
+	}
+	buffer := &limitedBuffer{limit: maxSize}
+	if err := encoder.Encode(buffer, s); err != nil {
+		if errors.Is(err, ErrSBOMTooLarge) {
+			return nil, ErrSBOMTooLarge
+		}
+		return nil, fmt.Errorf("failed to encode SBOM in %s: %w", format, err)
+	}
+	return buffer.Bytes(), nil
+}
+
+// decodeSyftDocument converts the Syft JSON document kept in the storage back to the SBOM
+func decodeSyftDocument(document v1beta1.SyftDocument) (*sbom.SBOM, error) {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("encoding SBOM: %w", err)
+	}
+	syftSBOM, _, _, err := syftjson.NewFormatDecoder().Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding SBOM: %w", err)
+	}
+	return syftSBOM, nil
+}
+
+// DocumentStore serves the SBOM documents in the additional formats. The storage keeps the Syft JSON SBOM
+// and, in its annotations, the formats fitting their size limit: the documents are encoded from the storage
+// SBOM when requested, nothing is kept on the node.
+type DocumentStore struct {
+	formats       []string
+	maxSizes      map[string]int
+	storageClient storage.StorageClient
+}
+
+func NewDocumentStore(cfg config.SBOMFormatsConfig, storageClient storage.StorageClient) *DocumentStore {
+	return &DocumentStore{
+		formats:       cfg.Formats,
+		maxSizes:      cfg.MaxSizes,
+		storageClient: storageClient,
+	}
+}
+
+// storedFormats returns the formats of the ready SBOM listed in its annotations
+func storedFormats(sbomMeta *v1beta1.SBOMSyft) []string {
+	if sbomMeta.Annotations[helpersv1.StatusMetadataKey] != helpersv1.Ready || sbomMeta.Annotations[FormatsMetadataKey] == "" {
+		return nil
+	}
+	return strings.Split(sbomMeta.Annotations[FormatsMetadataKey], ",")
+}
+
+// Get returns the document of the SBOM in the format, encoded from the Syft JSON SBOM of the storage
+func (d *DocumentStore) Get(sbomName, format string) ([]byte, error) {
+	if !documentNameRegexp.MatchString(sbomName) || !slices.Contains(d.formats, format) {
+		return nil, ErrDocumentNotFound
+	}
+	sbomMeta, err := d.storageClient.GetSBOMMeta(sbomName)
+	if k8serrors.IsNotFound(err) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting SBOM metadata: %w", err)
+	}
+	if !slices.Contains(storedFormats(sbomMeta), format) {
+		return nil, ErrDocumentNotFound
+	}
+	syftSBOM, err := d.storageClient.GetSBOM(sbomName)
+	if err != nil {
+		return nil, fmt.Errorf("getting SBOM: %w", err)
+	}
+	decoded, err := decodeSyftDocument(syftSBOM.Spec.Syft)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSBOM(*decoded, format, d.maxSizes[format])
+}
+
+// List returns the documents of the storage SBOMs as <SBOM name>/<format>
+func (d *DocumentStore) List() ([]string, error) {
+	sboms, err := d.storageClient.ListSBOMMetas()
+	if err != nil {
+		return nil, err
+	}
+	documents := []string{}
+	for i := range sboms {
+		for _, format := range storedFormats(&sboms[i]) {
+			if slices.Contains(d.formats, format) {
+				documents = append(documents, sboms[i].Name+"/"+format)
+			}
+		}
+	}
+	return documents, nil
+}
+
+func (d *DocumentStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	document := strings.TrimPrefix(r.URL.Path, FormatsHandlerPath)
+	if document == "" {
+		documents, err := d.List()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(documents); err != nil {
+			logger.L().Debug("SbomManager - failed to write SBOM document list", helpers.Error(err))
+		}
+		return
+	}
+
+	sbomName, format, _ := strings.Cut(document, "/")
+	data, err := d.Get(sbomName, format)
+	if errors.Is(err, ErrDocumentNotFound) || errors.Is(err, ErrSBOMTooLarge) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.L().Debug("SbomManager - failed to encode SBOM document", helpers.Error(err), helpers.String("document", document))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		logger.L().Debug("SbomManager - failed to write SBOM document", helpers.Error(err), helpers.String("document", document))
+	}
+}
@@ -0,0 +1,187 @@
+package v1
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	helpersv1 "github.com/kubescape/k8s-interface/instanceidhandler/v1/helpers"
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/storage"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func testSBOM() *sbom.SBOM {
+	p := pkg.Package{
+		Name:    "openssl",
+		Version: "3.0.11-1",
+		Type:    pkg.DebPkg,
+		PURL:    "pkg:deb/debian/openssl@3.0.11-1",
+	}
+	p.SetID()
+	return &sbom.SBOM{
+		Artifacts: sbom.Artifacts{
+			Packages: pkg.NewCollection(p),
+		},
+		Source: source.Description{
+			Name:     "docker.io/library/nginx",
+			Version:  "latest",
+			Metadata: source.ImageMetadata{UserInput: "docker.io/library/nginx:latest"},
+		},
+		Descriptor: sbom.Descriptor{
+			Name:    "syft",
+			Version: "v1.18.1",
+		},
+	}
+}
+
+func TestEncodeSBOM(t *testing.T) {
+	data, err := encodeSBOM(*testSBOM(), FormatSPDX, 0)
+	require.NoError(t, err)
+	var spdx struct {
+		SPDXVersion string `json:"spdxVersion"`
+		Packages    []struct {
+			Name string `json:"name"`
+		} `json:"packages"`
+	}
+	require.NoError(t, json.Unmarshal(data, &spdx))
+	assert.Equal(t, "SPDX-2.3", spdx.SPDXVersion)
+	assert.Contains(t, string(data), "openssl")
+
+	data, err = encodeSBOM(*testSBOM(), FormatCycloneDX, 0)
+	require.NoError(t, err)
+	var cyclonedx struct {
+		BOMFormat   string `json:"bomFormat"`
+		SpecVersion string `json:"specVersion"`
+		Components  []struct {
+			Name string `json:"name"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &cyclonedx))
+	assert.Equal(t, "CycloneDX", cyclonedx.BOMFormat)
+	assert.Equal(t, "1.5", cyclonedx.SpecVersion)
+	require.NotEmpty(t, cyclonedx.Components)
+	assert.Equal(t, "openssl", cyclonedx.Components[0].Name)
+
+	_, err = encodeSBOM(*testSBOM(), FormatCycloneDX, 100)
+	assert.ErrorIs(t, err, ErrSBOMTooLarge)
+
+	_, err = encodeSBOM(*testSBOM(), "spdx-tag-value", 0)
+	assert.Error(t, err)
+}
+
+func TestDocumentFormats(t *testing.T) {
+	s := &SbomManager{
+		cfg: config.Config{
+			SBOMFormats: config.SBOMFormatsConfig{
+				Formats:  []string{FormatSPDX, FormatCycloneDX},
+				MaxSizes: map[string]int{FormatSPDX: 10 * 1024 * 1024, FormatCycloneDX: 10 * 1024 * 1024},
+			},
+		},
+	}
+	s.documentStore = NewDocumentStore(s.cfg.SBOMFormats, nil)
+	sbomName := "docker.io-library-nginx-latest-28402d"
+
+	assert.Equal(t, []string{FormatSPDX, FormatCycloneDX}, s.documentFormats(sbomName, testSBOM()))
+
+	// a document exceeding its size limit is not served
+	s.cfg.SBOMFormats.MaxSizes[FormatCycloneDX] = 100
+	assert.Equal(t, []string{FormatSPDX}, s.documentFormats(sbomName, testSBOM()))
+
+	// the manager without additional formats serves nothing
+	assert.Empty(t, (&SbomManager{}).documentFormats(sbomName, testSBOM()))
+}
+
+// sbomStorageStub keeps the SBOMs by name
+type sbomStorageStub struct {
+	storage.StorageHttpClientMock
+	sboms map[string]*v1beta1.SBOMSyft
+}
+
+func (s *sbomStorageStub) GetSBOM(name string) (*v1beta1.SBOMSyft, error) {
+	if sbom, ok := s.sboms[name]; ok {
+		return sbom, nil
+	}
+	return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "sbomsyfts"}, name)
+}
+
+func (s *sbomStorageStub) GetSBOMMeta(name string) (*v1beta1.SBOMSyft, error) {
+	return s.GetSBOM(name)
+}
+
+func (s *sbomStorageStub) ListSBOMMetas() ([]v1beta1.SBOMSyft, error) {
+	var sboms []v1beta1.SBOMSyft
+	for _, sbom := range s.sboms {
+		sboms = append(sboms, *sbom)
+	}
+	return sboms, nil
+}
+
+func TestDocumentStoreHandler(t *testing.T) {
+	sbomName := "docker.io-library-nginx-latest-28402d"
+	storageClient := &sbomStorageStub{sboms: map[string]*v1beta1.SBOMSyft{
+		sbomName: {
+			ObjectMeta: metav1.ObjectMeta{
+				Name: sbomName,
+				Annotations: map[string]string{
+					helpersv1.StatusMetadataKey: helpersv1.Ready,
+					FormatsMetadataKey:          FormatSPDX,
+				},
+			},
+			Spec: v1beta1.SBOMSyftSpec{Syft: toSyftDocument(testSBOM())},
+		},
+		"incomplete-28402d": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "incomplete-28402d",
+				Annotations: map[string]string{
+					helpersv1.StatusMetadataKey: helpersv1.Incomplete,
+					FormatsMetadataKey:          FormatSPDX,
+				},
+			},
+		},
+	}}
+	store := NewDocumentStore(config.SBOMFormatsConfig{Formats: []string{FormatSPDX, FormatCycloneDX}}, storageClient)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "list", path: "/sbom/", wantStatus: http.StatusOK, wantBody: "[\"" + sbomName + "/spdx-json\"]\n"},
+		{name: "download", path: "/sbom/" + sbomName + "/spdx-json", wantStatus: http.StatusOK, wantBody: "openssl"},
+		{name: "format over its size limit", path: "/sbom/" + sbomName + "/cyclonedx-json", wantStatus: http.StatusNotFound},
+		{name: "unknown format", path: "/sbom/" + sbomName + "/syft-json", wantStatus: http.StatusNotFound},
+		{name: "incomplete SBOM", path: "/sbom/incomplete-28402d/spdx-json", wantStatus: http.StatusNotFound},
+		{name: "deleted SBOM", path: "/sbom/deleted-28402d/spdx-json", wantStatus: http.StatusNotFound},
+		{name: "invalid name", path: "/sbom/..%2Fnginx/spdx-json", wantStatus: http.StatusNotFound},
+		{name: "read-only", method: http.MethodPut, path: "/sbom/" + sbomName + "/spdx-json", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			recorder := httptest.NewRecorder()
+			store.ServeHTTP(recorder, httptest.NewRequest(method, tt.path, nil))
+			require.Equal(t, tt.wantStatus, recorder.Code)
+			if tt.wantBody != "" {
+				body, err := io.ReadAll(recorder.Body)
+				require.NoError(t, err)
+				assert.Contains(t, string(body), tt.wantBody)
+			}
+		})
+	}
+}
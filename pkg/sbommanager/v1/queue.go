@@ -0,0 +1,200 @@
+package v1
+
+import (
+	"container/heap"
+	"slices"
+	"sync"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+)
+
+// priorities of the containers waiting for their SBOM
+const (
+	priorityBacklog = iota // containers running before the agent started
+	priorityNew            // containers started after the agent
+	priorityAlerted        // containers with alerts
+)
+
+type queueItem struct {
+	image    string
+	index    int
+	notifs   []containercollection.PubSubEvent // the running containers of the image, the first one is processed
+	priority int
+	seq      uint64
+}
+
+type queueHeap []*queueItem
+
+var _ heap.Interface = (*queueHeap)(nil)
+
+func (h queueHeap) Len() int {
+	return len(h)
+}
+
+func (h queueHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h queueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *queueHeap) Push(x any) {
+	item := x.(*queueItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *queueHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// sbomQueue holds the containers waiting for their SBOM, the most prioritized first and then in arrival order.
+// The containers of an image share a single queued item and the images already processed are skipped,
+// as well as the containers whose SBOM was read from their rootfs.
+type sbomQueue struct {
+	closed     bool
+	cond       *sync.Cond
+	containers map[string]*queueItem // container ID -> queued item of its image
+	done       mapset.Set[string]    // image keys and container IDs
+	images     map[string]*queueItem
+	items      queueHeap
+	mutex      sync.Mutex
+	seq        uint64
+}
+
+func newSbomQueue() *sbomQueue {
+	q := &sbomQueue{
+		containers: make(map[string]*queueItem),
+		done:       mapset.NewSet[string](),
+		images:     make(map[string]*queueItem),
+	}
+	q.cond = sync.NewCond(&q.mutex)
+	return q
+}
+
+// imageKey identifies the image of the container in the queue
+func imageKey(notif containercollection.PubSubEvent) string {
+	if notif.Container.Runtime.ContainerImageDigest != "" {
+		return notif.Container.Runtime.ContainerImageDigest
+	}
+	return notif.Container.Runtime.ContainerImageName
+}
+
+// push queues the container, it returns false if its image was already processed or is already queued
+func (q *sbomQueue) push(notif containercollection.PubSubEvent, priority int) bool {
+	image := imageKey(notif)
+	if q.done.Contains(image) || q.done.Contains(notif.Container.Runtime.ContainerID) {
+		return false
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return false
+	}
+	if item, ok := q.images[image]; ok {
+		if _, ok := q.containers[notif.Container.Runtime.ContainerID]; !ok {
+			item.notifs = append(item.notifs, notif)
+			q.containers[notif.Container.Runtime.ContainerID] = item
+		}
+		q.raise(item, priority)
+		return false
+	}
+	q.seq++
+	item := &queueItem{
+		image:    image,
+		notifs:   []containercollection.PubSubEvent{notif},
+		priority: priority,
+		seq:      q.seq,
+	}
+	heap.Push(&q.items, item)
+	q.images[image] = item
+	q.containers[notif.Container.Runtime.ContainerID] = item
+	q.cond.Signal()
+	return true
+}
+
+// boost raises the priority of the image of the container if it is queued
+func (q *sbomQueue) boost(containerID string, priority int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if item, ok := q.containers[containerID]; ok {
+		q.raise(item, priority)
+	}
+}
+
+// forget removes the stopped container, its image stays queued with its other containers if any
+func (q *sbomQueue) forget(containerID string) {
+	q.done.Remove(containerID)
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	item, ok := q.containers[containerID]
+	if !ok {
+		return
+	}
+	delete(q.containers, containerID)
+	item.notifs = slices.DeleteFunc(item.notifs, func(notif containercollection.PubSubEvent) bool {
+		return notif.Container.Runtime.ContainerID == containerID
+	})
+	if len(item.notifs) == 0 {
+		heap.Remove(&q.items, item.index)
+		delete(q.images, item.image)
+	}
+}
+
+// raise increases the priority of a queued item, the caller holds the mutex
+func (q *sbomQueue) raise(item *queueItem, priority int) {
+	if priority > item.priority {
+		item.priority = priority
+		heap.Fix(&q.items, item.index)
+	}
+}
+
+// pop waits for the most prioritized image, it returns its containers and priority, the first container is processed.
+// It returns false once the queue is closed.
+func (q *sbomQueue) pop() ([]containercollection.PubSubEvent, int, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, 0, false
+	}
+	item := heap.Pop(&q.items).(*queueItem)
+	delete(q.images, item.image)
+	for _, notif := range item.notifs {
+		delete(q.containers, notif.Container.Runtime.ContainerID)
+	}
+	return item.notifs, item.priority, true
+}
+
+// markDone skips the future containers of the image, or the container itself for a container ID
+func (q *sbomQueue) markDone(key string) {
+	q.done.Add(key)
+}
+
+func (q *sbomQueue) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.items)
+}
+
+// close wakes up the waiting workers, the queued containers are dropped
+func (q *sbomQueue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
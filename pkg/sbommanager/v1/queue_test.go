@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"testing"
+
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testNotif(containerID, image string) containercollection.PubSubEvent {
+	return containercollection.PubSubEvent{
+		Type: containercollection.EventTypeAddContainer,
+		Container: &containercollection.Container{
+			Runtime: containercollection.RuntimeMetadata{
+				BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+					ContainerID:          containerID,
+					ContainerImageDigest: image,
+				},
+			},
+		},
+	}
+}
+
+func popContainerID(t *testing.T, q *sbomQueue) string {
+	notifs, _, ok := q.pop()
+	require.True(t, ok)
+	return notifs[0].Container.Runtime.ContainerID
+}
+
+func TestSbomQueue(t *testing.T) {
+	q := newSbomQueue()
+	assert.True(t, q.push(testNotif("old-1", "sha256:old1"), priorityBacklog))
+	assert.True(t, q.push(testNotif("old-2", "sha256:old2"), priorityBacklog))
+	assert.True(t, q.push(testNotif("new-1", "sha256:new1"), priorityNew))
+	// a second container of a queued image shares its item and raises its priority
+	assert.False(t, q.push(testNotif("new-2", "sha256:old2"), priorityNew))
+	assert.True(t, q.push(testNotif("old-3", "sha256:old3"), priorityBacklog))
+	assert.Equal(t, 4, q.len())
+
+	// the alerted containers come first
+	q.boost("old-3", priorityAlerted)
+	assert.Equal(t, "old-3", popContainerID(t, q))
+	// then the new containers in arrival order
+	assert.Equal(t, "old-2", popContainerID(t, q))
+	assert.Equal(t, "new-1", popContainerID(t, q))
+	assert.Equal(t, "old-1", popContainerID(t, q))
+	assert.Equal(t, 0, q.len())
+
+	// the processed images are skipped
+	q.markDone("sha256:old1")
+	assert.False(t, q.push(testNotif("old-4", "sha256:old1"), priorityNew))
+	assert.Equal(t, 0, q.len())
+
+	// the containers with a rootfs SBOM are skipped, not the other containers of their image
+	assert.True(t, q.push(testNotif("rootfs-1", "sha256:rootfs"), priorityNew))
+	assert.False(t, q.push(testNotif("rootfs-2", "sha256:rootfs"), priorityNew))
+	notifs, priority, ok := q.pop()
+	require.True(t, ok)
+	require.Len(t, notifs, 2)
+	assert.Equal(t, priorityNew, priority)
+	q.markDone("rootfs-1")
+	assert.False(t, q.push(notifs[0], priority))
+	assert.True(t, q.push(notifs[1], priority))
+	assert.Equal(t, "rootfs-2", popContainerID(t, q))
+	// a stopped container is forgotten
+	q.forget("rootfs-1")
+	assert.True(t, q.push(notifs[0], priority))
+	assert.Equal(t, "rootfs-1", popContainerID(t, q))
+
+	q.close()
+	_, _, ok = q.pop()
+	assert.False(t, ok)
+	assert.False(t, q.push(testNotif("new-3", "sha256:new3"), priorityNew))
+}
+
+func TestSbomQueueForget(t *testing.T) {
+	q := newSbomQueue()
+	assert.True(t, q.push(testNotif("a-1", "sha256:a"), priorityNew))
+	assert.False(t, q.push(testNotif("a-2", "sha256:a"), priorityNew))
+	assert.True(t, q.push(testNotif("b-1", "sha256:b"), priorityNew))
+
+	// the image stays queued with its other container
+	q.forget("a-1")
+	assert.Equal(t, 2, q.len())
+	// the image is dropped with its last container
+	q.forget("b-1")
+	assert.Equal(t, 1, q.len())
+	q.forget("unknown")
+	assert.Equal(t, "a-2", popContainerID(t, q))
+	assert.Equal(t, 0, q.len())
+}
@@ -0,0 +1,390 @@
+package v1
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/goradd/maps"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	helpersv1 "github.com/kubescape/k8s-interface/instanceidhandler/v1/helpers"
+	"github.com/kubescape/k8s-interface/names"
+	"github.com/kubescape/node-agent/pkg/filehandler"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AccessMetadataKey annotates the evidence locations of the relevant SBOM packages with the file accesses: "exec", "open" or "exec,open"
+	AccessMetadataKey = "kubescape.io/access"
+	// FirstSeenMetadataKey annotates the evidence locations with the first access to the file (RFC 3339)
+	FirstSeenMetadataKey = "kubescape.io/first-seen"
+	// SBOMNameMetadataKey annotates the relevant SBOM with the name of the SBOM it is filtered from
+	SBOMNameMetadataKey = "kubescape.io/sbom-name"
+	// relevantSBOMSuffix tells the relevant SBOMs apart from the filtered SBOMs named after the instance ID
+	relevantSBOMSuffix = "-relevant"
+
+	AccessExec = "exec"
+	AccessOpen = "open"
+
+	containsRelationship = "contains"
+	maxRelevantFiles     = 10000 // per container, before the SBOM is ready
+	ownershipCacheSize   = 10
+	ownershipCacheTTL    = time.Hour
+)
+
+// fileAccess is the evidence of a file accessed by a container
+type fileAccess struct {
+	Executed  bool
+	Opened    bool
+	FirstSeen time.Time
+}
+
+func (f fileAccess) access() string {
+	var access []string
+	if f.Executed {
+		access = append(access, AccessExec)
+	}
+	if f.Opened {
+		access = append(access, AccessOpen)
+	}
+	return strings.Join(access, ",")
+}
+
+// relevantContainer accumulates the files accessed by a container, the relevant SBOM is saved when changed
+type relevantContainer struct {
+	mutex     sync.Mutex
+	namespace string
+	pid       uint32 // the opened paths are resolved through its root
+	files     map[string]fileAccess
+	changed   bool
+}
+
+// merge adds the new accesses, it returns true if the evidence changed
+func (c *relevantContainer) merge(paths map[string]bool, executed bool, now time.Time) bool {
+	changed := false
+	for path := range paths {
+		access, exists := c.files[path]
+		if !exists {
+			if len(c.files) >= maxRelevantFiles {
+				continue
+			}
+			access.FirstSeen = now
+		}
+		if executed && !access.Executed {
+			access.Executed = true
+			changed = true
+		}
+		if !executed && !access.Opened {
+			access.Opened = true
+			changed = true
+		}
+		c.files[path] = access
+	}
+	return changed
+}
+
+// ownershipIndex maps the file paths of an image to the packages owning them
+type ownershipIndex struct {
+	resourceVersion string
+	document        v1beta1.SyftDocument
+	owners          map[string][]int // path -> indexes in document.Artifacts
+}
+
+// newOwnershipIndex indexes the package locations and the files of the "contains" relationships
+func newOwnershipIndex(document v1beta1.SyftDocument, resourceVersion string) *ownershipIndex {
+	index := &ownershipIndex{
+		resourceVersion: resourceVersion,
+		document:        document,
+		owners:          make(map[string][]int),
+	}
+	addOwner := func(path string, i int) {
+		if path == "" {
+			return
+		}
+		for _, owner := range index.owners[path] {
+			if owner == i {
+				return
+			}
+		}
+		index.owners[path] = append(index.owners[path], i)
+	}
+	packages := make(map[string]int, len(document.Artifacts))
+	for i, p := range document.Artifacts {
+		packages[p.ID] = i
+		for _, location := range p.Locations {
+			addOwner(location.RealPath, i)
+			addOwner(location.VirtualPath, i)
+		}
+	}
+	files := make(map[string]string, len(document.Files))
+	for _, f := range document.Files {
+		files[f.ID] = f.Location.RealPath
+	}
+	for _, relationship := range document.ArtifactRelationships {
+		if relationship.Type != containsRelationship {
+			continue
+		}
+		if i, ok := packages[relationship.Parent]; ok {
+			addOwner(files[relationship.Child], i)
+		}
+	}
+	return index
+}
+
+// filterRelevantSBOM returns the SBOM of the packages owning the accessed files, the accesses are added
+// as evidence locations of the packages. The files and relationships are limited to the relevant ones.
+func filterRelevantSBOM(index *ownershipIndex, files map[string]fileAccess) v1beta1.SyftDocument {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	evidence := make(map[int][]v1beta1.Location)
+	for _, path := range paths {
+		for _, i := range index.owners[path] {
+			evidence[i] = append(evidence[i], v1beta1.Location{
+				LocationData: v1beta1.LocationData{
+					Coordinates: v1beta1.Coordinates{RealPath: path},
+					VirtualPath: path,
+				},
+				LocationMetadata: v1beta1.LocationMetadata{
+					Annotations: map[string]string{
+						AccessMetadataKey:    files[path].access(),
+						FirstSeenMetadataKey: files[path].FirstSeen.UTC().Format(time.RFC3339),
+					},
+				},
+			})
+		}
+	}
+
+	document := index.document
+	document.Artifacts = nil
+	document.Files = nil
+	document.ArtifactRelationships = nil
+	relevant := make(map[string]bool)
+	for i, p := range index.document.Artifacts {
+		locations, found := evidence[i]
+		if !found {
+			continue
+		}
+		p.Locations = append(append([]v1beta1.Location(nil), p.Locations...), locations...)
+		document.Artifacts = append(document.Artifacts, p)
+		relevant[p.ID] = true
+	}
+	for _, f := range index.document.Files {
+		if _, found := files[f.Location.RealPath]; found {
+			document.Files = append(document.Files, f)
+			relevant[f.ID] = true
+		}
+	}
+	for _, relationship := range index.document.ArtifactRelationships {
+		if relevant[relationship.Parent] && relevant[relationship.Child] {
+			document.ArtifactRelationships = append(document.ArtifactRelationships, relationship)
+		}
+	}
+	return document
+}
+
+// relevancy tracks the files accessed by the containers and saves their relevant SBOM
+type relevancy struct {
+	containers maps.SafeMap[string, *relevantContainer]
+	execs      filehandler.FileHandler
+	opens      filehandler.FileHandler
+	ownership  *expirable.LRU[string, *ownershipIndex]
+}
+
+// addRelevantContainer starts collecting the file accesses of the container
+func (s *SbomManager) addRelevantContainer(containerID, namespace string, pid uint32) {
+	s.relevancy.containers.Set(containerID, &relevantContainer{
+		namespace: namespace,
+		pid:       pid,
+		files:     make(map[string]fileAccess),
+	})
+}
+
+// resolvePaths resolves the symlinks of the opened paths through the root of the container, the packages own
+// the resolved files. The paths are kept as opened when the container root is gone.
+func (s *SbomManager) resolvePaths(pid uint32, paths map[string]bool) map[string]bool {
+	root := filepath.Join(s.procDir, strconv.Itoa(int(pid)), "root")
+	if _, err := os.Stat(root); err != nil {
+		return paths
+	}
+	resolved := make(map[string]bool, len(paths))
+	for path := range paths {
+		hostPath, err := securejoin.SecureJoin(root, path)
+		if err != nil {
+			resolved[path] = true
+			continue
+		}
+		resolved[filepath.Join("/", strings.TrimPrefix(hostPath, root))] = true
+	}
+	return resolved
+}
+
+// removeRelevantContainer saves the last file accesses of the container and stops collecting them
+func (s *SbomManager) removeRelevantContainer(containerID string) {
+	if container, found := s.relevancy.containers.Load(containerID); found {
+		s.updateRelevantSBOM(containerID, container)
+	}
+	s.relevancy.containers.Delete(containerID)
+	_ = s.relevancy.execs.RemoveBucket(containerID)
+	_ = s.relevancy.opens.RemoveBucket(containerID)
+}
+
+// ReportFileExec records a file executed by the container, for the relevant SBOM
+func (s *SbomManager) ReportFileExec(containerID, path string) {
+	if s.relevancy == nil || !filepath.IsAbs(path) || !s.relevancy.containers.Has(containerID) {
+		return
+	}
+	s.relevancy.execs.AddFile(containerID, filepath.Clean(path))
+}
+
+// ReportFileOpen records a file opened by the container, for the relevant SBOM
+func (s *SbomManager) ReportFileOpen(containerID, path string) {
+	if s.relevancy == nil || !filepath.IsAbs(path) || !s.relevancy.containers.Has(containerID) {
+		return
+	}
+	s.relevancy.opens.AddFile(containerID, filepath.Clean(path))
+}
+
+// startRelevancy periodically saves the relevant SBOM of the containers with new file accesses
+func (s *SbomManager) startRelevancy() {
+	period := s.cfg.UpdateDataPeriod
+	if period <= 0 {
+		period = time.Minute
+	}
+	ticker := time.NewTicker(period)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				for _, containerID := range s.relevancy.containers.Keys() {
+					if container, found := s.relevancy.containers.Load(containerID); found {
+						s.updateRelevantSBOM(containerID, container)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// updateRelevantSBOM collects the new file accesses and saves the relevant SBOM once the SBOM is ready
+func (s *SbomManager) updateRelevantSBOM(containerID string, container *relevantContainer) {
+	container.mutex.Lock()
+	defer container.mutex.Unlock()
+	now := time.Now()
+	if execs, err := s.relevancy.execs.GetAndDeleteFiles(containerID); err == nil && container.merge(execs, true, now) {
+		container.changed = true
+	}
+	if opens, err := s.relevancy.opens.GetAndDeleteFiles(containerID); err == nil && container.merge(s.resolvePaths(container.pid, opens), false, now) {
+		container.changed = true
+	}
+	if !container.changed {
+		return
+	}
+	sharedData := s.k8sObjectCache.GetSharedContainerData(containerID)
+	if sharedData == nil || sharedData.InstanceID == nil {
+		return
+	}
+	sbomName, err := names.ImageInfoToSlug(sharedData.ImageTag, sharedData.ImageID)
+	if err != nil {
+		return
+	}
+	index, err := s.getOwnershipIndex(sbomName)
+	if err != nil {
+		// the SBOM is not ready yet, retry on the next update
+		logger.L().Debug("SbomManager - SBOM not available for the relevant SBOM", helpers.Error(err), helpers.String("sbomName", sbomName))
+		return
+	}
+	// only the files owned by a package are relevant
+	for path := range container.files {
+		if len(index.owners[path]) == 0 {
+			delete(container.files, path)
+		}
+	}
+	slug, err := sharedData.InstanceID.GetSlug(false)
+	if err != nil {
+		return
+	}
+	filtered := &v1beta1.SBOMSyftFiltered{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      slug + relevantSBOMSuffix,
+			Namespace: container.namespace,
+			Annotations: map[string]string{
+				helpersv1.ContextMetadataKey:    helpersv1.ContextMetadataKeyFiltered,
+				helpersv1.ImageIDMetadataKey:    normalizeImageID(sharedData.ImageTag, sharedData.ImageID),
+				helpersv1.ImageTagMetadataKey:   sharedData.ImageTag,
+				helpersv1.InstanceIDMetadataKey: sharedData.InstanceID.GetStringFormatted(),
+				helpersv1.StatusMetadataKey:     helpersv1.Ready,
+				helpersv1.WlidMetadataKey:       sharedData.Wlid,
+				SBOMNameMetadataKey:             sbomName,
+				ToolVersionMetadataKey:          s.version,
+			},
+			Labels: sharedData.InstanceID.GetLabels(),
+		},
+		Spec: v1beta1.SBOMSyftSpec{
+			Syft: filterRelevantSBOM(index, container.files),
+		},
+	}
+	filtered.Spec.Metadata.Tool.Name = "syft"
+	filtered.Spec.Metadata.Tool.Version = s.version
+	if err := s.saveRelevantSBOM(filtered); err != nil {
+		logger.L().Debug("SbomManager - failed to save relevant SBOM", helpers.Error(err),
+			helpers.String("namespace", filtered.Namespace),
+			helpers.String("name", filtered.Name))
+		return
+	}
+	container.changed = false
+}
+
+// getOwnershipIndex returns the ownership index of the SBOM, it is rebuilt when the SBOM changes
+func (s *SbomManager) getOwnershipIndex(sbomName string) (*ownershipIndex, error) {
+	meta, err := s.storageClient.GetSBOMMeta(sbomName)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil || meta.Annotations[helpersv1.StatusMetadataKey] != helpersv1.Ready {
+		return nil, fmt.Errorf("SBOM %s is not ready", sbomName)
+	}
+	if index, found := s.relevancy.ownership.Get(sbomName); found && index.resourceVersion == meta.ResourceVersion {
+		return index, nil
+	}
+	syftSBOM, err := s.storageClient.GetSBOM(sbomName)
+	if err != nil {
+		return nil, err
+	}
+	index := newOwnershipIndex(syftSBOM.Spec.Syft, meta.ResourceVersion)
+	s.relevancy.ownership.Add(sbomName, index)
+	return index, nil
+}
+
+// saveRelevantSBOM creates or replaces the relevant SBOM, a filtered SBOM of another component is not replaced
+func (s *SbomManager) saveRelevantSBOM(filtered *v1beta1.SBOMSyftFiltered) error {
+	_, err := s.storageClient.CreateFilteredSBOM(filtered)
+	if !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := s.storageClient.GetFilteredSBOMMeta(filtered.Namespace, filtered.Name)
+	if err != nil {
+		return err
+	}
+	if _, found := existing.Annotations[SBOMNameMetadataKey]; !found {
+		return fmt.Errorf("filtered SBOM %s/%s is not a relevant SBOM", filtered.Namespace, filtered.Name)
+	}
+	filtered.ResourceVersion = existing.ResourceVersion
+	_, err = s.storageClient.ReplaceFilteredSBOM(filtered)
+	return err
+}
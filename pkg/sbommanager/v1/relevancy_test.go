@@ -0,0 +1,151 @@
+package v1
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kubescape/node-agent/pkg/storage"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func testLocation(path string) v1beta1.Location {
+	return v1beta1.Location{LocationData: v1beta1.LocationData{Coordinates: v1beta1.Coordinates{RealPath: path}, VirtualPath: path}}
+}
+
+func testSyftDocument() v1beta1.SyftDocument {
+	return v1beta1.SyftDocument{
+		Artifacts: []v1beta1.SyftPackage{
+			{PackageBasicData: v1beta1.PackageBasicData{ID: "curl", Name: "curl", Locations: []v1beta1.Location{testLocation("/var/lib/dpkg/status")}}},
+			{PackageBasicData: v1beta1.PackageBasicData{ID: "openssl", Name: "openssl", Locations: []v1beta1.Location{testLocation("/var/lib/dpkg/status")}}},
+			{PackageBasicData: v1beta1.PackageBasicData{ID: "requests", Name: "requests", Locations: []v1beta1.Location{testLocation("/usr/lib/python3/dist-packages/requests/__init__.py")}}},
+		},
+		Files: []v1beta1.SyftFile{
+			{ID: "file-curl", Location: v1beta1.Coordinates{RealPath: "/usr/bin/curl"}},
+			{ID: "file-libssl", Location: v1beta1.Coordinates{RealPath: "/usr/lib/libssl.so.3"}},
+		},
+		ArtifactRelationships: []v1beta1.SyftRelationship{
+			{Parent: "curl", Child: "file-curl", Type: containsRelationship},
+			{Parent: "openssl", Child: "file-libssl", Type: containsRelationship},
+			{Parent: "curl", Child: "openssl", Type: "dependency-of"},
+		},
+	}
+}
+
+func TestOwnershipIndex(t *testing.T) {
+	index := newOwnershipIndex(testSyftDocument(), "1")
+	assert.Equal(t, []int{0}, index.owners["/usr/bin/curl"])
+	assert.Equal(t, []int{1}, index.owners["/usr/lib/libssl.so.3"])
+	assert.Equal(t, []int{2}, index.owners["/usr/lib/python3/dist-packages/requests/__init__.py"])
+	assert.Equal(t, []int{0, 1}, index.owners["/var/lib/dpkg/status"])
+	assert.Empty(t, index.owners["/etc/passwd"])
+}
+
+func TestFilterRelevantSBOM(t *testing.T) {
+	index := newOwnershipIndex(testSyftDocument(), "1")
+	firstSeen := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	container := &relevantContainer{files: make(map[string]fileAccess)}
+	assert.True(t, container.merge(map[string]bool{"/usr/bin/curl": true}, true, firstSeen))
+	assert.True(t, container.merge(map[string]bool{"/usr/bin/curl": true, "/usr/lib/libssl.so.3": true}, false, firstSeen.Add(time.Minute)))
+	assert.False(t, container.merge(map[string]bool{"/usr/lib/libssl.so.3": true}, false, firstSeen.Add(time.Hour)))
+
+	document := filterRelevantSBOM(index, container.files)
+	require.Len(t, document.Artifacts, 2)
+	curl := document.Artifacts[0]
+	assert.Equal(t, "curl", curl.ID)
+	// the original locations are kept, the evidence is appended
+	require.Len(t, curl.Locations, 2)
+	assert.Equal(t, "/var/lib/dpkg/status", curl.Locations[0].RealPath)
+	assert.Equal(t, "/usr/bin/curl", curl.Locations[1].RealPath)
+	assert.Equal(t, "exec,open", curl.Locations[1].Annotations[AccessMetadataKey])
+	assert.Equal(t, "2024-01-02T03:04:05Z", curl.Locations[1].Annotations[FirstSeenMetadataKey])
+	openssl := document.Artifacts[1]
+	assert.Equal(t, "openssl", openssl.ID)
+	assert.Equal(t, AccessOpen, openssl.Locations[1].Annotations[AccessMetadataKey])
+	assert.Equal(t, "2024-01-02T03:05:05Z", openssl.Locations[1].Annotations[FirstSeenMetadataKey])
+	assert.Len(t, document.Files, 2)
+	assert.Len(t, document.ArtifactRelationships, 3)
+	// the indexed document is not modified
+	assert.Len(t, index.document.Artifacts, 3)
+	assert.Len(t, index.document.Artifacts[0].Locations, 1)
+}
+
+func TestResolvePaths(t *testing.T) {
+	procDir := t.TempDir()
+	root := filepath.Join(procDir, "42", "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "usr", "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "usr", "lib", "libssl.so.3"), nil, 0644))
+	// absolute symlinks point into the container root
+	require.NoError(t, os.Symlink("/usr/lib/libssl.so.3", filepath.Join(root, "usr", "lib", "libssl.so")))
+	require.NoError(t, os.Symlink("usr/lib", filepath.Join(root, "lib")))
+	s := &SbomManager{procDir: procDir}
+
+	paths := map[string]bool{"/usr/lib/libssl.so": true, "/lib/libssl.so.3": true, "/etc/missing": true}
+	assert.Equal(t, map[string]bool{"/usr/lib/libssl.so.3": true, "/etc/missing": true}, s.resolvePaths(42, paths))
+	// the paths are kept when the container is gone
+	assert.Equal(t, paths, s.resolvePaths(43, paths))
+}
+
+func TestSaveRelevantSBOM(t *testing.T) {
+	storageClient := &filteredStorageStub{filtered: map[string]*v1beta1.SBOMSyftFiltered{
+		"default/replicaset-nginx-1234-nginx-relevant": {ObjectMeta: metav1.ObjectMeta{
+			Name:        "replicaset-nginx-1234-nginx-relevant",
+			Namespace:   "default",
+			Annotations: map[string]string{SBOMNameMetadataKey: "nginx-28402d"},
+		}},
+		"default/replicaset-other-1234-other-relevant": {ObjectMeta: metav1.ObjectMeta{
+			Name:      "replicaset-other-1234-other-relevant",
+			Namespace: "default",
+		}},
+	}}
+	s := &SbomManager{storageClient: storageClient}
+	newFiltered := func(name string) *v1beta1.SBOMSyftFiltered {
+		return &v1beta1.SBOMSyftFiltered{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: map[string]string{SBOMNameMetadataKey: "nginx-28402d"},
+		}}
+	}
+
+	require.NoError(t, s.saveRelevantSBOM(newFiltered("replicaset-redis-1234-redis-relevant")))
+	require.NoError(t, s.saveRelevantSBOM(newFiltered("replicaset-nginx-1234-nginx-relevant")))
+	assert.Equal(t, []string{"replicaset-nginx-1234-nginx-relevant"}, storageClient.replaced)
+	// a filtered SBOM of another component is not replaced
+	assert.Error(t, s.saveRelevantSBOM(newFiltered("replicaset-other-1234-other-relevant")))
+	assert.Len(t, storageClient.replaced, 1)
+}
+
+// filteredStorageStub keeps the filtered SBOMs by namespace and name
+type filteredStorageStub struct {
+	storage.StorageHttpClientMock
+	filtered map[string]*v1beta1.SBOMSyftFiltered
+	replaced []string
+}
+
+func (s *filteredStorageStub) CreateFilteredSBOM(filtered *v1beta1.SBOMSyftFiltered) (*v1beta1.SBOMSyftFiltered, error) {
+	key := filtered.Namespace + "/" + filtered.Name
+	if _, found := s.filtered[key]; found {
+		return nil, k8serrors.NewAlreadyExists(schema.GroupResource{Resource: "sbomsyftfiltereds"}, filtered.Name)
+	}
+	s.filtered[key] = filtered
+	return filtered, nil
+}
+
+func (s *filteredStorageStub) GetFilteredSBOMMeta(namespace, name string) (*v1beta1.SBOMSyftFiltered, error) {
+	if filtered, found := s.filtered[namespace+"/"+name]; found {
+		return filtered, nil
+	}
+	return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "sbomsyftfiltereds"}, name)
+}
+
+func (s *filteredStorageStub) ReplaceFilteredSBOM(filtered *v1beta1.SBOMSyftFiltered) (*v1beta1.SBOMSyftFiltered, error) {
+	s.filtered[filtered.Namespace+"/"+filtered.Name] = filtered
+	s.replaced = append(s.replaced, filtered.Name)
+	return filtered, nil
+}
@@ -0,0 +1,237 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	stereoscopefile "github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/syft/syft/file"
+	"github.com/anchore/syft/syft/source"
+	"github.com/bmatcuk/doublestar/v4"
+	securejoin "github.com/cyphar/filepath-securejoin"
+)
+
+const (
+	// SourceMetadataKey tells where the SBOM packages were read from
+	SourceMetadataKey = "kubescape.io/sbom-source"
+	// SourceImage is the source of the SBOMs read from the image layers
+	SourceImage = "image"
+	// SourceRuntime is the source of the SBOMs read from the rootfs of a running container, they can contain
+	// files written by the container after it started
+	SourceRuntime = "runtime-derived"
+	// ContainerIDMetadataKey is the container whose rootfs was read for a runtime-derived SBOM
+	ContainerIDMetadataKey = "kubescape.io/container-id"
+	// runtimeSBOMContainerIDLength is the length of the container ID suffix of the runtime-derived SBOM names
+	runtimeSBOMContainerIDLength = 12
+)
+
+// RootfsResolver is a NodeResolver variant reading the files of a running container from its rootfs, e.g.
+// /proc/<pid>/root, when the image layers cannot be used. The rootfs is indexed once and the symlinks are
+// resolved inside it.
+type RootfsResolver struct {
+	index map[string]file.Metadata // path inside the container -> metadata
+	paths []string                 // sorted paths inside the container
+	root  string
+}
+
+var _ file.Resolver = (*RootfsResolver)(nil)
+
+// NewRootfsResolver indexes the rootfs, skipping the excluded mount points, and returns the total size of its
+// regular files. It fails with ErrImageTooLarge as soon as the size exceeds maxSize.
+func NewRootfsResolver(root string, excludes []string, maxSize int64) (*RootfsResolver, int64, error) {
+	r := &RootfsResolver{
+		index: make(map[string]file.Metadata),
+		root:  root,
+	}
+	var totalSize int64
+	// the trailing slash follows the /proc/<pid>/root magic link
+	err := filepath.WalkDir(root+string(filepath.Separator), func(hostPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// unreadable files are skipped, like the directory source does
+			return nil
+		}
+		path := r.containerPath(hostPath)
+		if path != "/" && slices.Contains(excludes, path) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			totalSize += info.Size()
+			if totalSize > maxSize {
+				return ErrImageTooLarge
+			}
+		}
+		metadata := stereoscopefile.NewMetadataFromPath(hostPath, info)
+		metadata.Path = path
+		if metadata.Type == stereoscopefile.TypeSymLink {
+			metadata.LinkDestination, _ = os.Readlink(hostPath)
+		}
+		r.index[path] = metadata
+		r.paths = append(r.paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, totalSize, err
+	}
+	slices.Sort(r.paths)
+	return r, totalSize, nil
+}
+
+// containerPath returns the path inside the container of a path of the rootfs
+func (r *RootfsResolver) containerPath(hostPath string) string {
+	rel, err := filepath.Rel(r.root, hostPath)
+	if err != nil || rel == "." {
+		return "/"
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+// resolve follows the symlinks of the path inside the rootfs and returns the location of the indexed file
+func (r *RootfsResolver) resolve(path string) (file.Location, bool) {
+	hostPath, err := securejoin.SecureJoin(r.root, path)
+	if err != nil {
+		return file.Location{}, false
+	}
+	realPath := r.containerPath(hostPath)
+	metadata, ok := r.index[realPath]
+	if !ok || metadata.Type == stereoscopefile.TypeDirectory {
+		return file.Location{}, false
+	}
+	if realPath == path {
+		return file.NewLocation(realPath), true
+	}
+	return file.NewVirtualLocation(realPath, path), true
+}
+
+func (r *RootfsResolver) FileContentsByLocation(location file.Location) (io.ReadCloser, error) {
+	metadata, ok := r.index[location.RealPath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	if metadata.Type == stereoscopefile.TypeDirectory {
+		return nil, fmt.Errorf("cannot read contents of a directory: %s", location.RealPath)
+	}
+	hostPath, err := securejoin.SecureJoin(r.root, location.RealPath)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(hostPath)
+}
+
+func (r *RootfsResolver) HasPath(path string) bool {
+	if _, ok := r.index[path]; ok {
+		return true
+	}
+	_, ok := r.resolve(path)
+	return ok
+}
+
+func (r *RootfsResolver) FilesByPath(paths ...string) ([]file.Location, error) {
+	var allLocations = make([]file.Location, 0)
+	for _, path := range paths {
+		if location, ok := r.resolve(path); ok {
+			allLocations = append(allLocations, location)
+		}
+	}
+	return allLocations, nil
+}
+
+func (r *RootfsResolver) FilesByGlob(patterns ...string) ([]file.Location, error) {
+	var allLocations = make([]file.Location, 0)
+	for _, pattern := range patterns {
+		for _, path := range r.paths {
+			if matches, err := doublestar.Match(pattern, path); err != nil || !matches {
+				continue
+			}
+			if location, ok := r.resolve(path); ok {
+				allLocations = append(allLocations, location)
+			}
+		}
+	}
+	return allLocations, nil
+}
+
+func (r *RootfsResolver) FilesByMIMEType(types ...string) ([]file.Location, error) {
+	var allLocations = make([]file.Location, 0)
+	for _, path := range r.paths {
+		if slices.Contains(types, r.index[path].MIMEType) {
+			allLocations = append(allLocations, file.NewLocation(path))
+		}
+	}
+	return allLocations, nil
+}
+
+func (r *RootfsResolver) RelativeFileByPath(_ file.Location, path string) *file.Location {
+	if location, ok := r.resolve(path); ok {
+		return &location
+	}
+	return nil
+}
+
+func (r *RootfsResolver) AllLocations(ctx context.Context) <-chan file.Location {
+	results := make(chan file.Location)
+	go func() {
+		defer close(results)
+		for _, path := range r.paths {
+			select {
+			case <-ctx.Done():
+				return
+			case results <- file.NewLocation(path):
+			}
+		}
+	}()
+	return results
+}
+
+func (r *RootfsResolver) FileMetadataByLocation(location file.Location) (file.Metadata, error) {
+	metadata, ok := r.index[location.RealPath]
+	if !ok {
+		return file.Metadata{}, os.ErrNotExist
+	}
+	return metadata, nil
+}
+
+// NewRootfsSource creates a source reading the packages from the rootfs of a running container, it does not
+// depend on the CRI image service nor on the snapshotter of the runtime
+func NewRootfsSource(imageName, imageDigest, imageID, root string, excludes []string, maxImageSize int64) (*NodeSource, error) {
+	resolver, totalSize, err := NewRootfsResolver(root, excludes, maxImageSize)
+	if err != nil {
+		if errors.Is(err, ErrImageTooLarge) {
+			return nil, ErrImageTooLarge
+		}
+		return nil, fmt.Errorf("unable to index container rootfs: %w", err)
+	}
+	var tags []string
+	if imageName != "" {
+		tags = []string{imageName}
+	}
+	return &NodeSource{
+		description: source.Description{
+			ID:      strings.Replace(imageDigest, "sha256:", "", 1),
+			Name:    imageName,
+			Version: imageDigest,
+			Metadata: source.ImageMetadata{
+				UserInput: imageID,
+				ID:        imageDigest,
+				Tags:      tags,
+				Size:      totalSize,
+			},
+		},
+		resolver: resolver,
+		mutex:    &sync.Mutex{},
+	}, nil
+}
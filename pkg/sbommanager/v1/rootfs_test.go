@@ -0,0 +1,89 @@
+package v1
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anchore/syft/syft"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDpkgStatus = `Package: curl
+Status: install ok installed
+Priority: optional
+Section: web
+Installed-Size: 500
+Maintainer: Alessandro Ghedini <ghedo@debian.org>
+Architecture: amd64
+Version: 7.88.1-10+deb12u5
+Description: command line tool for transferring data with URL syntax
+`
+
+// createTestRootfs writes a minimal Debian rootfs with a volume mounted on /data
+func createTestRootfs(t *testing.T) string {
+	root := t.TempDir()
+	files := map[string]string{
+		"etc/os-release":        "ID=debian\nVERSION_ID=\"12\"\n",
+		"var/lib/dpkg/status":   testDpkgStatus,
+		"usr/bin/curl":          "curl",
+		"usr/lib/libcurl.so.4":  "libcurl",
+		"data/secret/README.md": "volume",
+	}
+	for path, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(content), 0644))
+	}
+	require.NoError(t, os.Symlink("usr/bin", filepath.Join(root, "bin")))
+	// absolute symlinks are resolved inside the rootfs
+	require.NoError(t, os.Symlink("/usr/lib", filepath.Join(root, "lib")))
+	return root
+}
+
+func TestRootfsResolver(t *testing.T) {
+	root := createTestRootfs(t)
+	resolver, size, err := NewRootfsResolver(root, []string{"/data"}, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("ID=debian\nVERSION_ID=\"12\"\n")+len(testDpkgStatus)+len("curl")+len("libcurl")), size)
+
+	// the volumes are not indexed
+	assert.False(t, resolver.HasPath("/data/secret/README.md"))
+
+	locations, err := resolver.FilesByPath("/bin/curl", "/lib/libcurl.so.4", "/usr/bin", "/missing")
+	require.NoError(t, err)
+	require.Len(t, locations, 2)
+	assert.Equal(t, "/usr/bin/curl", locations[0].RealPath)
+	assert.Equal(t, "/bin/curl", locations[0].AccessPath)
+	assert.Equal(t, "/usr/lib/libcurl.so.4", locations[1].RealPath)
+
+	locations, err = resolver.FilesByGlob("**/dpkg/status")
+	require.NoError(t, err)
+	require.Len(t, locations, 1)
+	reader, err := resolver.FileContentsByLocation(locations[0])
+	require.NoError(t, err)
+	defer func() {
+		_ = reader.Close()
+	}()
+	metadata, err := resolver.FileMetadataByLocation(locations[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(testDpkgStatus)), metadata.Size())
+
+	_, _, err = NewRootfsResolver(root, nil, 10)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+}
+
+func TestRootfsSource(t *testing.T) {
+	src, err := NewRootfsSource("docker.io/library/debian:12", "sha256:7b34f2fc561c06e26d69d7a5a58a2d6a1c4cf8b7f5b6a2a5b8d2c0c3d4e5f6a7", "docker.io/library/debian@sha256:7b34f2fc561c06e26d69d7a5a58a2d6a1c4cf8b7f5b6a2a5b8d2c0c3d4e5f6a7", createTestRootfs(t), []string{"/data"}, 1<<20)
+	require.NoError(t, err)
+	syftSBOM, err := syft.CreateSBOM(context.Background(), src, syft.DefaultCreateSBOMConfig())
+	require.NoError(t, err)
+	require.NotNil(t, syftSBOM.Artifacts.LinuxDistribution)
+	assert.Equal(t, "debian", syftSBOM.Artifacts.LinuxDistribution.ID)
+	packages := syftSBOM.Artifacts.Packages.PackagesByName("curl")
+	require.Len(t, packages, 1)
+	assert.Equal(t, pkg.DebPkg, packages[0].Type)
+	assert.Equal(t, "7.88.1-10+deb12u5", packages[0].Version)
+}
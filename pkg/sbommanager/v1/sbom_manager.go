@@ -12,6 +12,7 @@ import (
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/DmitriyVTitov/size"
@@ -26,21 +27,27 @@ import (
 	securejoin "github.com/cyphar/filepath-securejoin"
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/distribution/distribution/reference"
-	"github.com/gammazero/workerpool"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/goradd/maps"
+	"github.com/hashicorp/golang-lru/v2/expirable"
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
 	"github.com/kubescape/go-logger"
 	"github.com/kubescape/go-logger/helpers"
 	helpersv1 "github.com/kubescape/k8s-interface/instanceidhandler/v1/helpers"
 	"github.com/kubescape/k8s-interface/names"
 	"github.com/kubescape/node-agent/pkg/config"
+	filehandlerv1 "github.com/kubescape/node-agent/pkg/filehandler/v1"
+	"github.com/kubescape/node-agent/pkg/imageverifier"
+	"github.com/kubescape/node-agent/pkg/metricsmanager"
 	"github.com/kubescape/node-agent/pkg/objectcache"
 	"github.com/kubescape/node-agent/pkg/sbommanager"
 	"github.com/kubescape/node-agent/pkg/storage"
+	"github.com/kubescape/node-agent/pkg/vulnerabilities"
 	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
 	"github.com/moby/sys/mountinfo"
 	"github.com/opencontainers/go-digest"
 	"github.com/spf13/afero"
+	"golang.org/x/sync/semaphore"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -53,25 +60,43 @@ const (
 	digestDelim            = "@"
 	NodeNameMetadataKey    = "kubescape.io/node-name"
 	ToolVersionMetadataKey = "kubescape.io/tool-version"
+	maxStatusMessageLength = 1024
+	budgetCheckInterval    = 10 * time.Second
+)
+
+// results of the container processing besides the SBOM statuses
+const (
+	resultFailed  = "failed"  // the SBOM could not be saved
+	resultSkipped = "skipped" // the SBOM is processed by another worker or node
 )
 
 type SbomManager struct {
 	appFs              afero.Fs
 	cfg                config.Config
 	ctx                context.Context
+	documentStore      *DocumentStore // nil without additional formats
 	hostRoot           string
 	imageServiceClient runtime.ImageServiceClient
 	k8sObjectCache     objectcache.K8sObjectCache
-	pool               *workerpool.WorkerPool
+	memory             *semaphore.Weighted // nil when the memory budget is disabled
+	metrics            metricsmanager.MetricsManager
+	paused             atomic.Bool
 	procDir            string
 	processing         mapset.Set[string]
+	queue              *sbomQueue
+	relevancy          *relevancy // nil when the relevant SBOM is disabled
+	running            atomic.Int32
+	runtimeSboms       maps.SafeMap[string, string] // container ID -> name of the SBOM read from its rootfs
+	started            time.Time
 	storageClient      storage.StorageClient
 	version            string
+	verifier           *imageverifier.Verifier  // nil when the image verification is disabled
+	vulnerabilities    *vulnerabilities.Matcher // nil when the vulnerability matching is disabled
 }
 
 var _ sbommanager.SbomManagerClient = (*SbomManager)(nil)
 
-func CreateSbomManager(ctx context.Context, cfg config.Config, socketPath string, storageClient storage.StorageClient, k8sObjectCache objectcache.K8sObjectCache) (*SbomManager, error) {
+func CreateSbomManager(ctx context.Context, cfg config.Config, socketPath string, storageClient storage.StorageClient, k8sObjectCache objectcache.K8sObjectCache, vulnerabilityMatcher *vulnerabilities.Matcher, verifier *imageverifier.Verifier, metrics metricsmanager.MetricsManager) (*SbomManager, error) {
 	// read HOST_ROOT from env
 	hostRoot, exists := os.LookupEnv("HOST_ROOT")
 	if !exists {
@@ -82,6 +107,16 @@ func CreateSbomManager(ctx context.Context, cfg config.Config, socketPath string
 	if err != nil {
 		return nil, fmt.Errorf("failed to get proc dir: %w", err)
 	}
+	// prepare the store of the additional formats
+	var documentStore *DocumentStore
+	if len(cfg.SBOMFormats.Formats) > 0 {
+		for _, format := range cfg.SBOMFormats.Formats {
+			if _, err := newFormatEncoder(format); err != nil {
+				return nil, err
+			}
+		}
+		documentStore = NewDocumentStore(cfg.SBOMFormats, storageClient)
+	}
 	// connect to CRI socket
 	conn, _ := grpc.Dial(
 		socketPath,
@@ -91,19 +126,53 @@ func CreateSbomManager(ctx context.Context, cfg config.Config, socketPath string
 			return d.DialContext(ctx, "unix", socketPath)
 		}),
 	)
-	return &SbomManager{
+	s := &SbomManager{
 		appFs:              afero.NewOsFs(),
 		cfg:                cfg,
 		ctx:                ctx,
+		documentStore:      documentStore,
 		hostRoot:           hostRoot,
 		imageServiceClient: runtime.NewImageServiceClient(conn),
 		k8sObjectCache:     k8sObjectCache,
-		pool:               workerpool.New(1),
+		metrics:            metrics,
 		procDir:            procDir,
 		processing:         mapset.NewSet[string](),
+		queue:              newSbomQueue(),
+		started:            time.Now(),
 		storageClient:      storageClient,
 		version:            packageVersion("github.com/anchore/syft"),
-	}, nil
+		verifier:           verifier,
+		vulnerabilities:    vulnerabilityMatcher,
+	}
+	// collect the file accesses for the relevant SBOM
+	if cfg.EnableRelevantSBOM {
+		execs, _ := filehandlerv1.CreateInMemoryFileHandler()
+		opens, _ := filehandlerv1.CreateInMemoryFileHandler()
+		s.relevancy = &relevancy{
+			execs:     execs,
+			opens:     opens,
+			ownership: expirable.NewLRU[string, *ownershipIndex](ownershipCacheSize, nil, ownershipCacheTTL),
+		}
+		s.startRelevancy()
+	}
+	// the images cataloged at once are bounded by the memory budget
+	if cfg.SBOMQueue.MemoryBudget > 0 {
+		s.memory = semaphore.NewWeighted(cfg.SBOMQueue.MemoryBudget)
+	}
+	// start the workers, bounded by the CPU budget
+	for range workerCount(cfg.SBOMQueue) {
+		go s.worker()
+	}
+	go func() {
+		<-ctx.Done()
+		s.queue.close()
+	}()
+	return s, nil
+}
+
+// DocumentStore returns the store of the additional SBOM formats, nil when none is configured
+func (s *SbomManager) DocumentStore() *DocumentStore {
+	return s.documentStore
 }
 
 func (s *SbomManager) getImageStatus(imageID string) (*runtime.ImageStatusResponse, error) {
@@ -146,7 +215,133 @@ func (s *SbomManager) getMountedVolumes(pid string) ([]string, error) {
 	return nil, fmt.Errorf("failed to find lowerdir in %s", mounts[0].VFSOptions)
 }
 
+// getMountPoints returns the mount points of the container, except its root
+func (s *SbomManager) getMountPoints(pid string) ([]string, error) {
+	f, err := s.appFs.Open(filepath.Join(s.procDir, pid, "mountinfo"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /proc/%s/mountinfo: %w", pid, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	mounts, err := mountinfo.GetMountsFromReader(f, func(info *mountinfo.Info) (skip, stop bool) {
+		return info.Mountpoint == "/", false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mounts: %w", err)
+	}
+	mountPoints := make([]string, 0, len(mounts))
+	for _, mount := range mounts {
+		mountPoints = append(mountPoints, mount.Mountpoint)
+	}
+	return mountPoints, nil
+}
+
+// newLayersSource creates a source reading the image layers mounted by the runtime, it needs the CRI image service
+// and an overlay snapshotter
+func (s *SbomManager) newLayersSource(notif containercollection.PubSubEvent, pid, imageID string) (*NodeSource, error) {
+	mounts, err := s.getMountedVolumes(pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mounted volumes: %w", err)
+	}
+	imageStatus, err := s.getImageStatus(notif.Container.Runtime.ContainerImageName) // use original name to ask the CRI
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image layers: %w", err)
+	}
+	sharedData := s.k8sObjectCache.GetSharedContainerData(notif.Container.Runtime.ContainerID)
+	return NewSource(sharedData.ImageTag, sharedData.ImageID, imageID, imageStatus, mounts, s.cfg.MaxImageSize)
+}
+
+// newRootfsSource creates a source reading the rootfs of the running container, the volumes are skipped
+func (s *SbomManager) newRootfsSource(notif containercollection.PubSubEvent, pid, imageID string) (*NodeSource, error) {
+	mountPoints, err := s.getMountPoints(pid)
+	if err != nil {
+		return nil, err
+	}
+	sharedData := s.k8sObjectCache.GetSharedContainerData(notif.Container.Runtime.ContainerID)
+	return NewRootfsSource(sharedData.ImageTag, sharedData.ImageID, imageID, filepath.Join(s.procDir, pid, "root"), mountPoints, s.cfg.MaxImageSize)
+}
+
+// StatusReasonMetadataKey, StatusMessageMetadataKey and StatusTimeMetadataKey form the condition of a failed
+// SBOM generation: its reason, the error message and when it failed
+const (
+	StatusReasonMetadataKey  = "kubescape.io/status-reason"
+	StatusMessageMetadataKey = "kubescape.io/status-message"
+	StatusTimeMetadataKey    = "kubescape.io/status-time"
+	// ReasonSourceUnavailable is the reason of a failure to read both the image layers and the container rootfs
+	ReasonSourceUnavailable = "SourceUnavailable"
+	// ReasonLayersUnavailable is the reason of an image SBOM whose layers could not be read, the SBOMs of the
+	// containers of the image are read from their rootfs
+	ReasonLayersUnavailable = "LayersUnavailable"
+	// ReasonGenerationFailed is the reason of a failure of the SBOM generation itself
+	ReasonGenerationFailed = "GenerationFailed"
+)
+
+// saveFailure records the condition of a failed SBOM generation, the SBOM is retried on the next container start
+func (s *SbomManager) saveFailure(wipSbom *v1beta1.SBOMSyft, reason string, err error) {
+	delete(wipSbom.Annotations, NodeNameMetadataKey)
+	wipSbom.Annotations[helpersv1.StatusMetadataKey] = helpersv1.Incomplete
+	wipSbom.Annotations[StatusReasonMetadataKey] = reason
+	wipSbom.Annotations[StatusMessageMetadataKey] = truncate.Truncate(err.Error(), maxStatusMessageLength, "...", truncate.PositionEnd)
+	wipSbom.Annotations[StatusTimeMetadataKey] = time.Now().UTC().Format(time.RFC3339)
+	_, _ = s.storageClient.ReplaceSBOM(wipSbom)
+}
+
+// runtimeSBOMName returns the name of the SBOM read from the rootfs of the container, the image SBOM name
+// suffixed with the container ID
+func runtimeSBOMName(sbomName, containerID string) string {
+	suffix := "-" + containerID[:min(len(containerID), runtimeSBOMContainerIDLength)]
+	if len(sbomName)+len(suffix) > validation.DNS1123SubdomainMaxLength {
+		sbomName = strings.TrimRight(sbomName[:validation.DNS1123SubdomainMaxLength-len(suffix)], "-.")
+	}
+	return sbomName + suffix
+}
+
+// reserveRuntimeSBOM records on the image SBOM that its layers could not be read, and creates the SBOM of the
+// container rootfs with the metadata of the image
+func (s *SbomManager) reserveRuntimeSBOM(imageSbom *v1beta1.SBOMSyft, layersErr error, containerID string) (*v1beta1.SBOMSyft, error) {
+	runtimeSbom := &v1beta1.SBOMSyft{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: runtimeSBOMName(imageSbom.Name, containerID),
+			Annotations: map[string]string{
+				helpersv1.ImageIDMetadataKey:  imageSbom.Annotations[helpersv1.ImageIDMetadataKey],
+				helpersv1.ImageTagMetadataKey: imageSbom.Annotations[helpersv1.ImageTagMetadataKey],
+				helpersv1.StatusMetadataKey:   helpersv1.Initializing,
+				NodeNameMetadataKey:           s.cfg.NodeName,
+				ToolVersionMetadataKey:        s.version,
+				SourceMetadataKey:             SourceRuntime,
+				ContainerIDMetadataKey:        containerID,
+			},
+			Labels: imageSbom.Labels,
+		},
+	}
+	s.saveFailure(imageSbom, ReasonLayersUnavailable, layersErr)
+	created, err := s.storageClient.CreateSBOM(runtimeSbom)
+	if !k8serrors.IsAlreadyExists(err) {
+		return created, err
+	}
+	// the container was processed before, e.g. by a previous agent
+	existing, err := s.storageClient.GetSBOMMeta(runtimeSbom.Name)
+	if err != nil {
+		return nil, err
+	}
+	runtimeSbom.ResourceVersion = existing.ResourceVersion
+	return runtimeSbom, nil
+}
+
 func (s *SbomManager) ContainerCallback(notif containercollection.PubSubEvent) {
+	// save the relevant SBOM of the stopped containers
+	if notif.Type == containercollection.EventTypeRemoveContainer {
+		s.queue.forget(notif.Container.Runtime.ContainerID)
+		s.reportQueue()
+		if s.relevancy != nil {
+			go s.removeRelevantContainer(notif.Container.Runtime.ContainerID)
+		}
+		if runtimeSbomName, found := s.runtimeSboms.Load(notif.Container.Runtime.ContainerID); found {
+			s.runtimeSboms.Delete(notif.Container.Runtime.ContainerID)
+			go s.deleteRuntimeSBOM(runtimeSbomName)
+		}
+	}
 	// only consider container start events
 	if notif.Type != containercollection.EventTypeAddContainer {
 		return
@@ -155,17 +350,96 @@ func (s *SbomManager) ContainerCallback(notif containercollection.PubSubEvent) {
 	if s.cfg.SkipNamespace(notif.Container.K8s.Namespace) {
 		return
 	}
-	// enqueue the container for processing
-	s.pool.Submit(func() {
-		s.processContainer(notif)
-	})
+	if s.relevancy != nil {
+		s.addRelevantContainer(notif.Container.Runtime.ContainerID, notif.Container.K8s.Namespace, notif.Container.Runtime.ContainerPID)
+	}
+	// enqueue the container for processing, the containers started after the agent come first
+	priority := priorityNew
+	if startedAt := notif.Container.Runtime.ContainerStartedAt; startedAt != 0 && time.Unix(0, int64(startedAt)).Before(s.started) {
+		priority = priorityBacklog
+	}
+	if s.queue.push(notif, priority) {
+		s.reportQueue()
+	}
+}
+
+// deleteRuntimeSBOM deletes the SBOM read from the rootfs of a stopped container, it describes no image
+func (s *SbomManager) deleteRuntimeSBOM(name string) {
+	if err := s.storageClient.DeleteSBOM(name); err != nil && !k8serrors.IsNotFound(err) {
+		logger.L().Ctx(s.ctx).Warning("SbomManager - failed to delete the runtime-derived SBOM",
+			helpers.Error(err),
+			helpers.String("sbomName", name))
+	}
+}
+
+// PrioritizeContainer generates the SBOM of the container first, it is called for the containers with alerts
+func (s *SbomManager) PrioritizeContainer(containerID string) {
+	s.queue.boost(containerID, priorityAlerted)
+}
+
+// worker processes the queued containers while the budgets allow it
+func (s *SbomManager) worker() {
+	for {
+		notifs, priority, ok := s.queue.pop()
+		if !ok {
+			return
+		}
+		notif := notifs[0]
+		s.waitForBudget()
+		s.running.Add(1)
+		s.reportQueue()
+		result := s.processContainer(notif)
+		s.running.Add(-1)
+		// the failed SBOMs are retried on the next container start
+		if result == helpersv1.Ready || result == helpersv1.TooLarge {
+			if s.runtimeSboms.Has(notif.Container.Runtime.ContainerID) {
+				// the rootfs SBOM describes this container only, the other containers of the image get their own
+				s.queue.markDone(notif.Container.Runtime.ContainerID)
+				for _, other := range notifs[1:] {
+					s.queue.push(other, priority)
+				}
+			} else {
+				s.queue.markDone(imageKey(notif))
+			}
+		}
+		s.metrics.ReportSbomProcessed(result)
+		s.reportQueue()
+	}
 }
 
-func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
+// waitForBudget pauses the worker while the node is under pressure
+func (s *SbomManager) waitForBudget() {
+	for {
+		reason := s.pressureReason()
+		if reason == "" {
+			if s.paused.CompareAndSwap(true, false) {
+				logger.L().Info("SbomManager - resuming SBOM generation")
+				s.reportQueue()
+			}
+			return
+		}
+		if s.paused.CompareAndSwap(false, true) {
+			logger.L().Info("SbomManager - pausing SBOM generation", helpers.String("reason", reason))
+			s.reportQueue()
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(budgetCheckInterval):
+		}
+	}
+}
+
+func (s *SbomManager) reportQueue() {
+	s.metrics.ReportSbomQueue(s.queue.len(), int(s.running.Load()), s.paused.Load())
+}
+
+// processContainer generates the SBOM of the container image, it returns the result reported in the metrics
+func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) string {
 	if err := s.waitForSharedContainerData(notif.Container.Runtime.ContainerID); err != nil {
 		logger.L().Error("SbomManager - container not found in shared data",
 			helpers.String("container ID", notif.Container.Runtime.ContainerID))
-		return
+		return resultFailed
 	}
 	// prepare SBOM name
 	sbomName, err := names.ImageInfoToSlug(s.k8sObjectCache.GetSharedContainerData(notif.Container.Runtime.ContainerID).ImageTag, s.k8sObjectCache.GetSharedContainerData(notif.Container.Runtime.ContainerID).ImageID)
@@ -177,7 +451,7 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 			helpers.String("container", notif.Container.K8s.ContainerName),
 			helpers.String("imageName", s.k8sObjectCache.GetSharedContainerData(notif.Container.Runtime.ContainerID).ImageTag),
 			helpers.String("imageDigest", s.k8sObjectCache.GetSharedContainerData(notif.Container.Runtime.ContainerID).ImageID))
-		return
+		return resultFailed
 	}
 	// try to create a SBOM with initializing status to reserve our slot
 	imageID := normalizeImageID(s.k8sObjectCache.GetSharedContainerData(notif.Container.Runtime.ContainerID).ImageTag, s.k8sObjectCache.GetSharedContainerData(notif.Container.Runtime.ContainerID).ImageID)
@@ -207,7 +481,7 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 				helpers.String("pod", notif.Container.K8s.PodName),
 				helpers.String("container", notif.Container.K8s.ContainerName),
 				helpers.String("sbomName", sbomName))
-			return
+			return resultFailed
 		}
 		switch {
 		case wipSbom.Annotations[helpersv1.StatusMetadataKey] == helpersv1.TooLarge:
@@ -217,7 +491,7 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 				helpers.String("container", notif.Container.K8s.ContainerName),
 				helpers.String("sbomName", sbomName),
 				helpers.String("nodeName", wipSbom.Annotations[NodeNameMetadataKey]))
-			return
+			return helpersv1.TooLarge
 		case wipSbom.Annotations[helpersv1.StatusMetadataKey] == helpersv1.Ready:
 			// only skip if the SBOM was created with the same version of tool
 			if wipSbom.Annotations[ToolVersionMetadataKey] == s.version {
@@ -226,7 +500,13 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 					helpers.String("pod", notif.Container.K8s.PodName),
 					helpers.String("container", notif.Container.K8s.ContainerName),
 					helpers.String("sbomName", sbomName))
-				return
+				// the vulnerabilities are matched once per image since the agent started
+				if s.vulnerabilities != nil && !s.vulnerabilities.Matched(imageID) {
+					if readySbom, err := s.storageClient.GetSBOM(sbomName); err == nil {
+						s.matchVulnerabilities(readySbom)
+					}
+				}
+				return helpersv1.Ready
 			}
 			logger.L().Debug("SbomManager - SBOM was created with an different version of tool, recreating it",
 				helpers.String("namespace", notif.Container.K8s.Namespace),
@@ -238,6 +518,17 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 			// update the version of the tool
 			wipSbom.Annotations[ToolVersionMetadataKey] = s.version
 			// continue to create SBOM
+		case wipSbom.Annotations[helpersv1.StatusMetadataKey] == helpersv1.Incomplete && !s.processing.Contains(sbomName):
+			logger.L().Debug("SbomManager - SBOM processing failed previously, retrying",
+				helpers.String("namespace", notif.Container.K8s.Namespace),
+				helpers.String("pod", notif.Container.K8s.PodName),
+				helpers.String("container", notif.Container.K8s.ContainerName),
+				helpers.String("sbomName", sbomName),
+				helpers.String("reason", wipSbom.Annotations[StatusMessageMetadataKey]))
+			// take over the SBOM
+			wipSbom.Annotations[NodeNameMetadataKey] = s.cfg.NodeName
+			wipSbom.Annotations[ToolVersionMetadataKey] = s.version
+			// continue to create SBOM
 		case wipSbom.Annotations[NodeNameMetadataKey] != s.cfg.NodeName:
 			logger.L().Debug("SbomManager - SBOM is already being processed by another node, skipping",
 				helpers.String("namespace", notif.Container.K8s.Namespace),
@@ -245,14 +536,14 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 				helpers.String("container", notif.Container.K8s.ContainerName),
 				helpers.String("sbomName", sbomName),
 				helpers.String("nodeName", wipSbom.Annotations[NodeNameMetadataKey]))
-			return
+			return resultSkipped
 		case s.processing.Contains(sbomName):
 			logger.L().Debug("SbomManager - SBOM is already being processed by this node, skipping",
 				helpers.String("namespace", notif.Container.K8s.Namespace),
 				helpers.String("pod", notif.Container.K8s.PodName),
 				helpers.String("container", notif.Container.K8s.ContainerName),
 				helpers.String("sbomName", sbomName))
-			return
+			return resultSkipped
 		default:
 			logger.L().Debug("SbomManager - SBOM processing was interrupted, retrying",
 				helpers.String("namespace", notif.Container.K8s.Namespace),
@@ -268,7 +559,7 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 			helpers.String("pod", notif.Container.K8s.PodName),
 			helpers.String("container", notif.Container.K8s.ContainerName),
 			helpers.String("sbomName", sbomName))
-		return
+		return resultFailed
 	default:
 		logger.L().Debug("SbomManager - created empty SBOM, start processing",
 			helpers.String("namespace", notif.Container.K8s.Namespace),
@@ -280,31 +571,44 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 	// track SBOM as processing in internal state to prevent concurrent processing
 	s.processing.Add(sbomName)
 	defer s.processing.Remove(sbomName)
-	// get container mounts
+	// prepare the source from the image layers, fall back to the container rootfs
 	pid := strconv.Itoa(int(notif.Container.ContainerPid()))
-	mounts, err := s.getMountedVolumes(pid)
-	if err != nil {
-		logger.L().Ctx(s.ctx).Error("SbomManager - failed to get mounted volumes",
+	src, err := s.newLayersSource(notif, pid, imageID)
+	if err != nil && !errors.Is(err, ErrImageTooLarge) {
+		logger.L().Debug("SbomManager - failed to use the image layers, falling back to the container rootfs",
 			helpers.Error(err),
 			helpers.String("namespace", notif.Container.K8s.Namespace),
 			helpers.String("pod", notif.Container.K8s.PodName),
 			helpers.String("container", notif.Container.K8s.ContainerName),
 			helpers.String("sbomName", sbomName))
-		return
+		layersErr := err
+		src, err = s.newRootfsSource(notif, pid, imageID)
+		if err != nil {
+			err = fmt.Errorf("image layers: %w, container rootfs: %w", layersErr, err)
+		} else {
+			// the rootfs holds the files written by the container, its SBOM is kept apart from the image one
+			runtimeSbom, err := s.reserveRuntimeSBOM(wipSbom, layersErr, notif.Container.Runtime.ContainerID)
+			if err != nil {
+				logger.L().Ctx(s.ctx).Error("SbomManager - failed to create the runtime-derived SBOM",
+					helpers.Error(err),
+					helpers.String("namespace", notif.Container.K8s.Namespace),
+					helpers.String("pod", notif.Container.K8s.PodName),
+					helpers.String("container", notif.Container.K8s.ContainerName),
+					helpers.String("sbomName", sbomName))
+				return resultFailed
+			}
+			s.runtimeSboms.Set(notif.Container.Runtime.ContainerID, runtimeSbom.Name)
+			wipSbom = runtimeSbom
+			sbomName = runtimeSbom.Name
+			s.processing.Add(sbomName)
+			defer s.processing.Remove(sbomName)
+		}
+	} else if err == nil {
+		wipSbom.Annotations[SourceMetadataKey] = SourceImage
 	}
-	// get image layers
-	imageStatus, err := s.getImageStatus(notif.Container.Runtime.ContainerImageName) // use original name to ask the CRI
-	if err != nil {
-		logger.L().Ctx(s.ctx).Error("SbomManager - failed to get image layers",
-			helpers.Error(err),
-			helpers.String("namespace", notif.Container.K8s.Namespace),
-			helpers.String("pod", notif.Container.K8s.PodName),
-			helpers.String("container", notif.Container.K8s.ContainerName),
-			helpers.String("sbomName", sbomName))
-		return
+	if s.verifier != nil {
+		s.verifier.Verify(imageID).Annotate(wipSbom.Annotations)
 	}
-	// prepare image source
-	src, err := NewSource(s.k8sObjectCache.GetSharedContainerData(notif.Container.Runtime.ContainerID).ImageTag, s.k8sObjectCache.GetSharedContainerData(notif.Container.Runtime.ContainerID).ImageID, imageID, imageStatus, mounts, s.cfg.MaxImageSize)
 	if err != nil {
 		logger.L().Ctx(s.ctx).Error("SbomManager - failed to create image source",
 			helpers.Error(err),
@@ -316,14 +620,21 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 			delete(wipSbom.Annotations, NodeNameMetadataKey)
 			wipSbom.Annotations[helpersv1.StatusMetadataKey] = helpersv1.TooLarge
 			_, _ = s.storageClient.ReplaceSBOM(wipSbom)
+			return helpersv1.TooLarge
 		}
-		return
+		s.saveFailure(wipSbom, ReasonSourceUnavailable, err)
+		return helpersv1.Incomplete
 	}
 	// create the SBOM
 	cfg := syft.DefaultCreateSBOMConfig()
 	cfg.ToolName = "syft"
 	cfg.ToolVersion = s.version
+	release, err := s.reserveMemory(src.Size())
+	if err != nil {
+		return resultFailed
+	}
 	syftSBOM, err := syft.CreateSBOM(context.Background(), src, cfg)
+	release()
 	if err != nil {
 		logger.L().Ctx(s.ctx).Error("SbomManager - failed to generate SBOM",
 			helpers.Error(err),
@@ -331,11 +642,20 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 			helpers.String("pod", notif.Container.K8s.PodName),
 			helpers.String("container", notif.Container.K8s.ContainerName),
 			helpers.String("sbomName", sbomName))
-		// TODO we could save the error in a status field
-		return
+		s.saveFailure(wipSbom, ReasonGenerationFailed, err)
+		return helpersv1.Incomplete
+	}
+	// list the additional formats
+	if formats := s.documentFormats(sbomName, syftSBOM); len(formats) > 0 {
+		wipSbom.Annotations[FormatsMetadataKey] = strings.Join(formats, ",")
+	} else {
+		delete(wipSbom.Annotations, FormatsMetadataKey)
 	}
 	// prepare the SBOM
 	delete(wipSbom.Annotations, NodeNameMetadataKey)
+	delete(wipSbom.Annotations, StatusReasonMetadataKey)
+	delete(wipSbom.Annotations, StatusMessageMetadataKey)
+	delete(wipSbom.Annotations, StatusTimeMetadataKey)
 	wipSbom.Spec.Metadata.Report.CreatedAt = wipSbom.CreationTimestamp
 	wipSbom.Spec.Metadata.Tool.Name = "syft"
 	wipSbom.Spec.Metadata.Tool.Version = s.version
@@ -364,13 +684,49 @@ func (s *SbomManager) processContainer(notif containercollection.PubSubEvent) {
 			helpers.String("pod", notif.Container.K8s.PodName),
 			helpers.String("container", notif.Container.K8s.ContainerName),
 			helpers.String("sbomName", sbomName))
-		return
+		return resultFailed
 	}
 	logger.L().Debug("SbomManager - saved SBOM after successful processing",
 		helpers.String("namespace", notif.Container.K8s.Namespace),
 		helpers.String("pod", notif.Container.K8s.PodName),
 		helpers.String("container", notif.Container.K8s.ContainerName),
 		helpers.String("sbomName", sbomName))
+	if s.vulnerabilities != nil && wipSbom.Annotations[helpersv1.StatusMetadataKey] == helpersv1.Ready {
+		s.matchVulnerabilities(wipSbom)
+	}
+	return wipSbom.Annotations[helpersv1.StatusMetadataKey]
+}
+
+// matchVulnerabilities matches the SBOM against the vulnerability database, failures are only logged
+func (s *SbomManager) matchVulnerabilities(syftSBOM *v1beta1.SBOMSyft) {
+	if err := s.vulnerabilities.Match(syftSBOM); err != nil {
+		logger.L().Ctx(s.ctx).Error("SbomManager - failed to match vulnerabilities",
+			helpers.Error(err),
+			helpers.String("sbomName", syftSBOM.Name))
+		return
+	}
+	logger.L().Debug("SbomManager - saved vulnerability manifest",
+		helpers.String("sbomName", syftSBOM.Name))
+}
+
+// documentFormats encodes the SBOM in the additional formats, it returns the formats served for the SBOM.
+// The documents exceeding the size limit of their format are not served.
+func (s *SbomManager) documentFormats(sbomName string, syftSBOM *sbom.SBOM) []string {
+	if s.documentStore == nil {
+		return nil
+	}
+	var formats []string
+	for _, format := range s.cfg.SBOMFormats.Formats {
+		if _, err := encodeSBOM(*syftSBOM, format, s.cfg.SBOMFormats.MaxSizes[format]); err != nil {
+			logger.L().Debug("SbomManager - SBOM document not served",
+				helpers.Error(err),
+				helpers.String("sbomName", sbomName),
+				helpers.String("format", format))
+			continue
+		}
+		formats = append(formats, format)
+	}
+	return formats
 }
 
 func (s *SbomManager) waitForSharedContainerData(containerID string) error {
@@ -1,9 +1,21 @@
 package v1
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+
+	helpersv1 "github.com/kubescape/k8s-interface/instanceidhandler/v1/helpers"
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/metricsmanager"
+	"github.com/kubescape/node-agent/pkg/storage"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestNormalizeimageDigest(t *testing.T) {
@@ -62,3 +74,76 @@ func TestNormalizeimageDigest(t *testing.T) {
 		})
 	}
 }
+
+func TestReserveRuntimeSBOM(t *testing.T) {
+	storageClient := &storage.StorageHttpClientMock{}
+	s := &SbomManager{
+		cfg:           config.Config{NodeName: "node"},
+		storageClient: storageClient,
+		version:       "v1.18.1",
+	}
+	imageSbom := &v1beta1.SBOMSyft{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "docker.io-library-nginx-latest-28402d",
+			Annotations: map[string]string{
+				helpersv1.ImageIDMetadataKey: "docker.io/library/nginx@sha256:28402d",
+				helpersv1.StatusMetadataKey:  helpersv1.Initializing,
+				NodeNameMetadataKey:          "node",
+			},
+		},
+	}
+	runtimeSbom, err := s.reserveRuntimeSBOM(imageSbom, errors.New("no overlay"), "0123456789abcdef")
+	require.NoError(t, err)
+	assert.Equal(t, "docker.io-library-nginx-latest-28402d-0123456789ab", runtimeSbom.Name)
+	assert.Equal(t, SourceRuntime, runtimeSbom.Annotations[SourceMetadataKey])
+	assert.Equal(t, "0123456789abcdef", runtimeSbom.Annotations[ContainerIDMetadataKey])
+	assert.Equal(t, "docker.io/library/nginx@sha256:28402d", runtimeSbom.Annotations[helpersv1.ImageIDMetadataKey])
+	// the image SBOM is not overwritten by the files of the container
+	assert.Equal(t, helpersv1.Incomplete, imageSbom.Annotations[helpersv1.StatusMetadataKey])
+	assert.Equal(t, ReasonLayersUnavailable, imageSbom.Annotations[StatusReasonMetadataKey])
+	require.Len(t, storageClient.SyftSBOMs, 2)
+
+	long := runtimeSBOMName(strings.Repeat("a", 239)+"-.b", "0123456789abcdef")
+	assert.Equal(t, strings.Repeat("a", 239)+"-0123456789ab", long)
+}
+
+// deleteStorageStub reports the deleted SBOMs
+type deleteStorageStub struct {
+	storage.StorageHttpClientMock
+	deleted chan string
+}
+
+func (s *deleteStorageStub) DeleteSBOM(name string) error {
+	s.deleted <- name
+	return nil
+}
+
+func TestRemoveRuntimeSBOM(t *testing.T) {
+	storageClient := &deleteStorageStub{deleted: make(chan string, 1)}
+	s := &SbomManager{
+		cfg:           config.Config{NodeName: "node"},
+		metrics:       metricsmanager.NewMetricsMock(),
+		queue:         newSbomQueue(),
+		storageClient: storageClient,
+	}
+	imageSbom := &v1beta1.SBOMSyft{ObjectMeta: metav1.ObjectMeta{Name: "nginx-28402d", Annotations: map[string]string{}}}
+	runtimeSbom, err := s.reserveRuntimeSBOM(imageSbom, errors.New("no overlay"), "0123456789abcdef")
+	require.NoError(t, err)
+	s.runtimeSboms.Set("0123456789abcdef", runtimeSbom.Name)
+
+	// the SBOM of the rootfs is deleted with its container
+	notif := testNotif("0123456789abcdef", "sha256:28402d")
+	notif.Type = containercollection.EventTypeRemoveContainer
+	s.ContainerCallback(notif)
+	assert.False(t, s.runtimeSboms.Has("0123456789abcdef"))
+	select {
+	case name := <-storageClient.deleted:
+		assert.Equal(t, "nginx-28402d-0123456789ab", name)
+	case <-time.After(time.Second):
+		t.Fatal("runtime SBOM not deleted")
+	}
+
+	// the containers without a rootfs SBOM delete nothing
+	s.ContainerCallback(notif)
+	assert.Empty(t, storageClient.deleted)
+}
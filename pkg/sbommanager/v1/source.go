@@ -213,6 +213,14 @@ func (n *NodeSource) Describe() source.Description {
 	return n.description
 }
 
+// Size returns the size of the image layers or of the container rootfs
+func (n *NodeSource) Size() int64 {
+	if metadata, ok := n.description.Metadata.(source.ImageMetadata); ok {
+		return metadata.Size
+	}
+	return 0
+}
+
 func (n *NodeSource) Close() error {
 	return nil
 }
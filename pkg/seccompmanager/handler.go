@@ -0,0 +1,47 @@
+package seccompmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+)
+
+// HandlerPath is the path prefix of the read-only generated seccomp profiles API, served on the local socket:
+// GET /seccomp/ lists the workloads with generated profiles and whether their pods can switch to them,
+// GET /seccomp/suggestions lists the syscalls logged or denied by the Localhost profiles.
+const HandlerPath = "/seccomp/"
+
+const suggestionsPath = "suggestions"
+
+// Handler serves the generated seccomp profiles API from the seccomp manager
+type Handler struct {
+	client SeccompManagerClient
+}
+
+func NewHandler(client SeccompManagerClient) *Handler {
+	return &Handler{client: client}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var response interface{}
+	switch strings.TrimPrefix(r.URL.Path, HandlerPath) {
+	case "":
+		response = h.client.ListGeneratedProfiles()
+	case suggestionsPath:
+		response = h.client.ListSuggestions()
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.L().Debug("SeccompManager - failed to write response", helpers.Error(err), helpers.String("path", r.URL.Path))
+	}
+}
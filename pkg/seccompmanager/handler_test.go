@@ -0,0 +1,55 @@
+package seccompmanager
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type seccompManagerStub struct {
+	SeccompManagerMock
+}
+
+func (s *seccompManagerStub) ListGeneratedProfiles() []WorkloadProfiles {
+	return []WorkloadProfiles{{
+		Namespace:  "default",
+		Workload:   "replicaset-nginx-77b4fdf86c",
+		Pods:       []string{"nginx-77b4fdf86c-abcde"},
+		Containers: map[string]map[Mode]string{"nginx": {ModeEnforce: "kubescape/default/replicaset-nginx-77b4fdf86c-nginx-enforce.json"}},
+		Switchable: true,
+	}}
+}
+
+func (s *seccompManagerStub) ListSuggestions() []ProfileSuggestions {
+	return []ProfileSuggestions{{Path: "custom/redis.json", Syscalls: []string{"bpf"}}}
+}
+
+func TestHandler(t *testing.T) {
+	handler := NewHandler(&seccompManagerStub{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, HandlerPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got []WorkloadProfiles
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, (&seccompManagerStub{}).ListGeneratedProfiles(), got)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, HandlerPath+"suggestions", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var suggestions []ProfileSuggestions
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &suggestions))
+	assert.Equal(t, (&seccompManagerStub{}).ListSuggestions(), suggestions)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, HandlerPath+"default", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, HandlerPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
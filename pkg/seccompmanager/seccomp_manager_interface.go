@@ -9,4 +9,45 @@ type SeccompManagerClient interface {
 	AddSeccompProfile(obj *v1beta1api.SeccompProfile) error
 	DeleteSeccompProfile(obj *v1beta1api.SeccompProfile) error
 	GetSeccompProfile(name string, path *string) (v1beta1.SingleSeccompProfile, error)
+	GenerateSeccompProfile(profile GeneratedProfile) (map[Mode]string, error)
+	ListGeneratedProfiles() []WorkloadProfiles
+	SuggestSyscall(path, syscall string)
+	ListSuggestions() []ProfileSuggestions
+}
+
+// Mode is the variant of a generated seccomp profile
+type Mode string
+
+const (
+	// ModeComplain logs the syscalls missing from the profile (SCMP_ACT_LOG) without blocking them
+	ModeComplain Mode = "complain"
+	// ModeEnforce denies the syscalls missing from the profile with EPERM
+	ModeEnforce Mode = "enforce"
+)
+
+// GeneratedProfile is a container whose learned syscalls are turned into seccomp profiles
+type GeneratedProfile struct {
+	Namespace        string
+	Workload         string // slug of the application profile
+	PodName          string
+	Container        string
+	PodContainers    []string // all the containers of the pod, they all need a profile before the pod can switch
+	LocalhostProfile *string  // the Localhost profile currently used by the container
+	Syscalls         []string
+}
+
+// WorkloadProfiles are the generated profiles of a workload, and whether its pods can switch to them
+type WorkloadProfiles struct {
+	Namespace  string                     `json:"namespace"`
+	Workload   string                     `json:"workload"`
+	Pods       []string                   `json:"pods"`
+	Containers map[string]map[Mode]string `json:"containers"` // container name -> mode -> localhost profile
+	Switchable bool                       `json:"switchable"`
+	Reason     string                     `json:"reason,omitempty"`
+}
+
+// ProfileSuggestions are the syscalls logged or denied by a Localhost profile, to review before allowing them
+type ProfileSuggestions struct {
+	Path     string   `json:"path"` // localhost profile
+	Syscalls []string `json:"syscalls"`
 }
@@ -25,3 +25,18 @@ func (s *SeccompManagerMock) DeleteSeccompProfile(_ *v1beta1api.SeccompProfile)
 func (s *SeccompManagerMock) GetSeccompProfile(_ string, _ *string) (v1beta1.SingleSeccompProfile, error) {
 	return v1beta1.SingleSeccompProfile{}, nil
 }
+
+func (s *SeccompManagerMock) GenerateSeccompProfile(_ GeneratedProfile) (map[Mode]string, error) {
+	return nil, nil
+}
+
+func (s *SeccompManagerMock) ListGeneratedProfiles() []WorkloadProfiles {
+	return nil
+}
+
+func (s *SeccompManagerMock) SuggestSyscall(_, _ string) {
+}
+
+func (s *SeccompManagerMock) ListSuggestions() []ProfileSuggestions {
+	return nil
+}
@@ -0,0 +1,175 @@
+package seccompmanager
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/containers/common/pkg/seccomp"
+	securejoin "github.com/cyphar/filepath-securejoin"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/seccompmanager"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/spf13/afero"
+)
+
+const (
+	// generatedProfilesDir is the directory of the generated profiles inside the kubelet seccomp directory
+	generatedProfilesDir = "kubescape"
+	// flagLog logs the denied syscalls to the audit log, they are reported as violations
+	flagLog v1beta1.Flag = "SECCOMP_FILTER_FLAG_LOG"
+)
+
+// runtimeSyscalls are needed by the container runtime between loading the profile and starting the
+// entrypoint, they are never observed in the container
+var runtimeSyscalls = []string{"capget", "capset", "close", "execve", "exit", "exit_group", "fchdir", "fstat", "futex", "getdents64", "newfstatat", "prctl", "rt_sigreturn", "setgid", "setgroups", "setuid"}
+
+// workloadProfiles tracks the generated profiles of a workload
+type workloadProfiles struct {
+	pods       mapset.Set[string]
+	containers map[string]map[seccompmanager.Mode]string // container name -> mode -> localhost profile
+	expected   mapset.Set[string]                        // all the containers of the pods
+	localhost  mapset.Set[string]                        // containers already using a Localhost profile
+}
+
+// buildProfile returns the profile allowing the syscalls, the other syscalls are logged in complain mode and
+// denied in enforce mode
+func buildProfile(syscalls []string, mode seccompmanager.Mode) v1beta1.SingleSeccompProfileSpec {
+	names := mapset.NewThreadUnsafeSet[string](syscalls...)
+	names.Append(runtimeSyscalls...)
+	allowed := names.ToSlice()
+	sort.Strings(allowed)
+	defaultAction := seccomp.ActErrno
+	if mode == seccompmanager.ModeComplain {
+		defaultAction = seccomp.ActLog
+	}
+	return v1beta1.SingleSeccompProfileSpec{
+		DefaultAction: defaultAction,
+		Architectures: architectures(),
+		Flags:         []v1beta1.Flag{flagLog},
+		Syscalls: []*v1beta1.Syscall{{
+			Names:  allowed,
+			Action: seccomp.ActAllow,
+		}},
+	}
+}
+
+// architectures returns the seccomp architectures of the node, including the compatibility ones
+func architectures() []v1beta1.Arch {
+	switch runtime.GOARCH {
+	case "amd64":
+		return []v1beta1.Arch{v1beta1.Arch(seccomp.ArchX86_64), v1beta1.Arch(seccomp.ArchX86), v1beta1.Arch(seccomp.ArchX32)}
+	case "arm64":
+		return []v1beta1.Arch{v1beta1.Arch(seccomp.ArchAARCH64), v1beta1.Arch(seccomp.ArchARM)}
+	default:
+		return nil
+	}
+}
+
+// localhostProfile returns the path of a generated profile relative to the kubelet seccomp directory, as used
+// in the localhostProfile field of the pods
+func localhostProfile(profile seccompmanager.GeneratedProfile, mode seccompmanager.Mode) string {
+	return filepath.Join(generatedProfilesDir, profile.Namespace, fmt.Sprintf("%s-%s-%s.json", profile.Workload, profile.Container, mode))
+}
+
+// GenerateSeccompProfile writes the complain and enforce profiles of the container and returns their
+// localhost profiles
+func (s *SeccompManager) GenerateSeccompProfile(profile seccompmanager.GeneratedProfile) (map[seccompmanager.Mode]string, error) {
+	paths := make(map[seccompmanager.Mode]string)
+	for _, mode := range []seccompmanager.Mode{seccompmanager.ModeComplain, seccompmanager.ModeEnforce} {
+		localhost := localhostProfile(profile, mode)
+		profilePath, err := securejoin.SecureJoin(s.seccompProfilesDir, localhost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to join seccomp profile path: %w", err)
+		}
+		profileBytes, err := json.MarshalIndent(buildProfile(profile.Syscalls, mode), "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal seccomp profile for %s: %w", profile.Container, err)
+		}
+		if err := s.appFs.MkdirAll(filepath.Dir(profilePath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to make dirs: %w", err)
+		}
+		if err := afero.WriteFile(s.appFs, profilePath, profileBytes, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write seccomp profile: %w", err)
+		}
+		paths[mode] = localhost
+	}
+	logger.L().Debug("SeccompManager - generated seccomp profiles", helpers.String("namespace", profile.Namespace),
+		helpers.String("workload", profile.Workload), helpers.String("container", profile.Container),
+		helpers.Int("syscalls", len(profile.Syscalls)))
+
+	s.generatedMutex.Lock()
+	defer s.generatedMutex.Unlock()
+	key := profile.Namespace + "/" + profile.Workload
+	workload, ok := s.generated[key]
+	if !ok {
+		workload = &workloadProfiles{
+			pods:       mapset.NewThreadUnsafeSet[string](),
+			containers: make(map[string]map[seccompmanager.Mode]string),
+			expected:   mapset.NewThreadUnsafeSet[string](),
+			localhost:  mapset.NewThreadUnsafeSet[string](),
+		}
+		s.generated[key] = workload
+	}
+	wasSwitchable, _ := workload.switchable()
+	workload.pods.Add(profile.PodName)
+	workload.containers[profile.Container] = paths
+	workload.expected.Append(profile.PodContainers...)
+	if profile.LocalhostProfile != nil {
+		workload.localhost.Add(profile.Container)
+	}
+	if switchable, _ := workload.switchable(); switchable && !wasSwitchable {
+		logger.L().Info("SeccompManager - pods can switch to the generated seccomp profiles",
+			helpers.String("namespace", profile.Namespace), helpers.String("workload", profile.Workload),
+			helpers.Interface("pods", workload.pods.ToSlice()))
+	}
+	return paths, nil
+}
+
+// switchable tells whether the pods of the workload can switch to Localhost profiles, or why they cannot
+func (w *workloadProfiles) switchable() (bool, string) {
+	if !w.localhost.IsEmpty() {
+		return false, "already using a Localhost profile"
+	}
+	expected := w.expected.ToSlice()
+	sort.Strings(expected)
+	for _, container := range expected {
+		if _, ok := w.containers[container]; !ok {
+			return false, "no profile generated for container " + container
+		}
+	}
+	return len(w.containers) > 0, ""
+}
+
+// ListGeneratedProfiles reports the generated profiles by workload
+func (s *SeccompManager) ListGeneratedProfiles() []seccompmanager.WorkloadProfiles {
+	s.generatedMutex.Lock()
+	defer s.generatedMutex.Unlock()
+	report := make([]seccompmanager.WorkloadProfiles, 0, len(s.generated))
+	for key, workload := range s.generated {
+		namespace, name, _ := strings.Cut(key, "/")
+		switchable, reason := workload.switchable()
+		pods := workload.pods.ToSlice()
+		sort.Strings(pods)
+		report = append(report, seccompmanager.WorkloadProfiles{
+			Namespace:  namespace,
+			Workload:   name,
+			Pods:       pods,
+			Containers: maps.Clone(workload.containers),
+			Switchable: switchable,
+			Reason:     reason,
+		})
+	}
+	slices.SortFunc(report, func(a, b seccompmanager.WorkloadProfiles) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Workload, b.Workload))
+	})
+	return report
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"sync"
 
 	securejoin "github.com/cyphar/filepath-securejoin"
 	mapset "github.com/deckarep/golang-set/v2"
@@ -22,8 +23,12 @@ import (
 
 type SeccompManager struct {
 	appFs              afero.Fs
+	generated          map[string]*workloadProfiles // key is namespace/workload
+	generatedMutex     sync.Mutex
 	profilesPaths      maps.SafeMap[types.UID, mapset.Set[string]]
 	seccompProfilesDir string
+	suggestions        map[string]mapset.Set[string] // key is the localhost profile
+	suggestionsMutex   sync.Mutex
 }
 
 func NewSeccompManager() (*SeccompManager, error) {
@@ -33,7 +38,9 @@ func NewSeccompManager() (*SeccompManager, error) {
 	}
 	return &SeccompManager{
 		appFs:              afero.NewOsFs(),
+		generated:          make(map[string]*workloadProfiles),
 		seccompProfilesDir: seccompProfilesDir,
+		suggestions:        make(map[string]mapset.Set[string]),
 	}, nil
 }
 
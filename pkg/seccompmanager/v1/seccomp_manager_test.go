@@ -1,13 +1,18 @@
 package seccompmanager
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"testing"
 
+	"github.com/containers/common/pkg/seccomp"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/kubescape/node-agent/pkg/seccompmanager"
 	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -99,6 +104,97 @@ func TestSeccompManager(t *testing.T) {
 	}
 }
 
+func TestGenerateSeccompProfile(t *testing.T) {
+	s := &SeccompManager{
+		appFs:              afero.NewMemMapFs(),
+		generated:          make(map[string]*workloadProfiles),
+		seccompProfilesDir: "/seccomp",
+	}
+	profile := seccompmanager.GeneratedProfile{
+		Namespace:     "default",
+		Workload:      "replicaset-nginx-77b4fdf86c",
+		PodName:       "nginx-77b4fdf86c-abcde",
+		Container:     "nginx",
+		PodContainers: []string{"init", "nginx"},
+		Syscalls:      []string{"read", "write", "accept4"},
+	}
+	paths, err := s.GenerateSeccompProfile(profile)
+	require.NoError(t, err)
+	assert.Equal(t, map[seccompmanager.Mode]string{
+		seccompmanager.ModeComplain: "kubescape/default/replicaset-nginx-77b4fdf86c-nginx-complain.json",
+		seccompmanager.ModeEnforce:  "kubescape/default/replicaset-nginx-77b4fdf86c-nginx-enforce.json",
+	}, paths)
+
+	for mode, defaultAction := range map[seccompmanager.Mode]seccomp.Action{seccompmanager.ModeComplain: seccomp.ActLog, seccompmanager.ModeEnforce: seccomp.ActErrno} {
+		profileBytes, err := afero.ReadFile(s.appFs, filepath.Join(s.seccompProfilesDir, paths[mode]))
+		require.NoError(t, err)
+		var spec v1beta1.SingleSeccompProfileSpec
+		require.NoError(t, json.Unmarshal(profileBytes, &spec))
+		assert.Equal(t, defaultAction, spec.DefaultAction)
+		assert.Equal(t, []v1beta1.Flag{"SECCOMP_FILTER_FLAG_LOG"}, spec.Flags)
+		require.Len(t, spec.Syscalls, 1)
+		assert.Equal(t, seccomp.ActAllow, spec.Syscalls[0].Action)
+		assert.Subset(t, spec.Syscalls[0].Names, []string{"accept4", "execve", "read", "write"})
+		assert.IsNonDecreasing(t, spec.Syscalls[0].Names)
+	}
+
+	// the pods need profiles for all their containers
+	report := s.ListGeneratedProfiles()
+	require.Len(t, report, 1)
+	assert.False(t, report[0].Switchable)
+	assert.Equal(t, "no profile generated for container init", report[0].Reason)
+
+	profile.Container = "init"
+	profile.Syscalls = []string{"openat"}
+	_, err = s.GenerateSeccompProfile(profile)
+	require.NoError(t, err)
+	report = s.ListGeneratedProfiles()
+	require.Len(t, report, 1)
+	assert.True(t, report[0].Switchable)
+	assert.Equal(t, []string{"nginx-77b4fdf86c-abcde"}, report[0].Pods)
+	assert.Len(t, report[0].Containers, 2)
+
+	// the pods already using a Localhost profile are not reported as switchable
+	localhost := "custom/nginx.json"
+	profile.Workload = "replicaset-redis-5d4f8b7c9"
+	profile.LocalhostProfile = &localhost
+	profile.PodContainers = []string{"init"}
+	_, err = s.GenerateSeccompProfile(profile)
+	require.NoError(t, err)
+	report = s.ListGeneratedProfiles()
+	require.Len(t, report, 2)
+	assert.Equal(t, "replicaset-redis-5d4f8b7c9", report[1].Workload)
+	assert.False(t, report[1].Switchable)
+}
+
+func TestSuggestSyscall(t *testing.T) {
+	s := &SeccompManager{
+		appFs:              afero.NewMemMapFs(),
+		generated:          make(map[string]*workloadProfiles),
+		seccompProfilesDir: "/seccomp",
+		suggestions:        make(map[string]mapset.Set[string]),
+	}
+	paths, err := s.GenerateSeccompProfile(seccompmanager.GeneratedProfile{
+		Namespace: "default",
+		Workload:  "replicaset-nginx-77b4fdf86c",
+		Container: "nginx",
+		Syscalls:  []string{"read", "write"},
+	})
+	require.NoError(t, err)
+
+	enforce := paths[seccompmanager.ModeEnforce]
+	s.SuggestSyscall(enforce, "ptrace")
+	s.SuggestSyscall(enforce, "accept4")
+	s.SuggestSyscall(enforce, "ptrace")
+	// the syscalls allowed by the profile are not suggested
+	s.SuggestSyscall(enforce, "read")
+	s.SuggestSyscall("custom/redis.json", "bpf")
+	assert.Equal(t, []seccompmanager.ProfileSuggestions{
+		{Path: "custom/redis.json", Syscalls: []string{"bpf"}},
+		{Path: enforce, Syscalls: []string{"accept4", "ptrace"}},
+	}, s.ListSuggestions())
+}
+
 func Test_getProfilesDir(t *testing.T) {
 	tests := []struct {
 		name        string
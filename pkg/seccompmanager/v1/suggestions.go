@@ -0,0 +1,57 @@
+package seccompmanager
+
+import (
+	"cmp"
+	"slices"
+	"sort"
+
+	"github.com/containers/common/pkg/seccomp"
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	"github.com/kubescape/node-agent/pkg/seccompmanager"
+)
+
+// SuggestSyscall records a syscall logged or denied by a Localhost profile, the syscalls already allowed by the
+// profile are ignored, e.g. denied on their arguments
+func (s *SeccompManager) SuggestSyscall(path, syscall string) {
+	s.suggestionsMutex.Lock()
+	defer s.suggestionsMutex.Unlock()
+	suggestions, ok := s.suggestions[path]
+	if ok && suggestions.ContainsOne(syscall) {
+		return
+	}
+	if profile, err := s.GetSeccompProfile("", &path); err == nil {
+		for _, rule := range profile.Spec.Syscalls {
+			if rule.Action == seccomp.ActAllow && len(rule.Args) == 0 && slices.Contains(rule.Names, syscall) {
+				return
+			}
+		}
+	}
+	if !ok {
+		suggestions = mapset.NewThreadUnsafeSet[string]()
+		s.suggestions[path] = suggestions
+	}
+	suggestions.Add(syscall)
+	logger.L().Info("SeccompManager - syscall not allowed by the seccomp profile, consider allowing it",
+		helpers.String("path", path), helpers.String("syscall", syscall))
+}
+
+// ListSuggestions reports the suggested syscalls by localhost profile
+func (s *SeccompManager) ListSuggestions() []seccompmanager.ProfileSuggestions {
+	s.suggestionsMutex.Lock()
+	defer s.suggestionsMutex.Unlock()
+	report := make([]seccompmanager.ProfileSuggestions, 0, len(s.suggestions))
+	for path, suggestions := range s.suggestions {
+		syscalls := suggestions.ToSlice()
+		sort.Strings(syscalls)
+		report = append(report, seccompmanager.ProfileSuggestions{
+			Path:     path,
+			Syscalls: syscalls,
+		})
+	}
+	slices.SortFunc(report, func(a, b seccompmanager.ProfileSuggestions) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+	return report
+}
@@ -13,7 +13,15 @@ type StorageClient interface {
 	CreateSBOM(SBOM *v1beta1.SBOMSyft) (*v1beta1.SBOMSyft, error)
 	GetSBOM(name string) (*v1beta1.SBOMSyft, error)
 	GetSBOMMeta(name string) (*v1beta1.SBOMSyft, error)
+	ListSBOMMetas() ([]v1beta1.SBOMSyft, error)
+	DeleteSBOM(name string) error
 	ReplaceSBOM(SBOM *v1beta1.SBOMSyft) (*v1beta1.SBOMSyft, error)
+	CreateFilteredSBOM(SBOM *v1beta1.SBOMSyftFiltered) (*v1beta1.SBOMSyftFiltered, error)
+	GetFilteredSBOMMeta(namespace, name string) (*v1beta1.SBOMSyftFiltered, error)
+	ReplaceFilteredSBOM(SBOM *v1beta1.SBOMSyftFiltered) (*v1beta1.SBOMSyftFiltered, error)
+	CreateVulnerabilityManifest(manifest *v1beta1.VulnerabilityManifest) (*v1beta1.VulnerabilityManifest, error)
+	GetVulnerabilityManifestMeta(name string) (*v1beta1.VulnerabilityManifest, error)
+	ReplaceVulnerabilityManifest(manifest *v1beta1.VulnerabilityManifest) (*v1beta1.VulnerabilityManifest, error)
 	IncrementImageUse(imageID string)
 	DecrementImageUse(imageID string)
 	GetNetworkNeighbors(namespace, name string) (*v1beta1.NetworkNeighbors, error)
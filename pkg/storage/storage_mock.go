@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"slices"
+
 	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
 	spdxv1beta1 "github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
 )
@@ -12,14 +14,16 @@ const (
 )
 
 type StorageHttpClientMock struct {
-	ApplicationActivities []*spdxv1beta1.ApplicationActivity
-	ApplicationProfiles   []*spdxv1beta1.ApplicationProfile
-	SyftSBOMs             []*spdxv1beta1.SBOMSyft
-	NetworkNeighborhoods  []*v1beta1.NetworkNeighborhood
-	NetworkNeighborses    []*v1beta1.NetworkNeighbors
-	ImageCounters         map[string]int
-	mockSBOM              *v1beta1.SBOMSyft
-	failedOnce            bool
+	ApplicationActivities  []*spdxv1beta1.ApplicationActivity
+	ApplicationProfiles    []*spdxv1beta1.ApplicationProfile
+	SyftSBOMs              []*spdxv1beta1.SBOMSyft
+	FilteredSyftSBOMs      []*spdxv1beta1.SBOMSyftFiltered
+	VulnerabilityManifests []*v1beta1.VulnerabilityManifest
+	NetworkNeighborhoods   []*v1beta1.NetworkNeighborhood
+	NetworkNeighborses     []*v1beta1.NetworkNeighbors
+	ImageCounters          map[string]int
+	mockSBOM               *v1beta1.SBOMSyft
+	failedOnce             bool
 }
 
 var _ StorageClient = (*StorageHttpClientMock)(nil)
@@ -47,11 +51,54 @@ func (sc *StorageHttpClientMock) GetSBOMMeta(_ string) (*v1beta1.SBOMSyft, error
 	return sc.mockSBOM, nil
 }
 
+func (sc *StorageHttpClientMock) ListSBOMMetas() ([]v1beta1.SBOMSyft, error) {
+	sboms := make([]v1beta1.SBOMSyft, 0, len(sc.SyftSBOMs))
+	for _, sbom := range sc.SyftSBOMs {
+		sboms = append(sboms, *sbom)
+	}
+	return sboms, nil
+}
+
+func (sc *StorageHttpClientMock) DeleteSBOM(name string) error {
+	sc.SyftSBOMs = slices.DeleteFunc(sc.SyftSBOMs, func(sbom *v1beta1.SBOMSyft) bool {
+		return sbom.Name == name
+	})
+	return nil
+}
+
 func (sc *StorageHttpClientMock) ReplaceSBOM(SBOM *v1beta1.SBOMSyft) (*v1beta1.SBOMSyft, error) {
 	sc.SyftSBOMs = append(sc.SyftSBOMs, SBOM)
 	return SBOM, nil
 }
 
+func (sc *StorageHttpClientMock) CreateFilteredSBOM(SBOM *v1beta1.SBOMSyftFiltered) (*v1beta1.SBOMSyftFiltered, error) {
+	sc.FilteredSyftSBOMs = append(sc.FilteredSyftSBOMs, SBOM)
+	return SBOM, nil
+}
+
+func (sc *StorageHttpClientMock) GetFilteredSBOMMeta(_, _ string) (*v1beta1.SBOMSyftFiltered, error) {
+	return nil, nil
+}
+
+func (sc *StorageHttpClientMock) ReplaceFilteredSBOM(SBOM *v1beta1.SBOMSyftFiltered) (*v1beta1.SBOMSyftFiltered, error) {
+	sc.FilteredSyftSBOMs = append(sc.FilteredSyftSBOMs, SBOM)
+	return SBOM, nil
+}
+
+func (sc *StorageHttpClientMock) CreateVulnerabilityManifest(manifest *v1beta1.VulnerabilityManifest) (*v1beta1.VulnerabilityManifest, error) {
+	sc.VulnerabilityManifests = append(sc.VulnerabilityManifests, manifest)
+	return manifest, nil
+}
+
+func (sc *StorageHttpClientMock) GetVulnerabilityManifestMeta(_ string) (*v1beta1.VulnerabilityManifest, error) {
+	return nil, nil
+}
+
+func (sc *StorageHttpClientMock) ReplaceVulnerabilityManifest(manifest *v1beta1.VulnerabilityManifest) (*v1beta1.VulnerabilityManifest, error) {
+	sc.VulnerabilityManifests = append(sc.VulnerabilityManifests, manifest)
+	return manifest, nil
+}
+
 func (sc *StorageHttpClientMock) IncrementImageUse(imageID string) {
 	if _, ok := sc.ImageCounters[imageID]; !ok {
 		sc.ImageCounters[imageID] = 0
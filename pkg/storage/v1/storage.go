@@ -158,10 +158,46 @@ func (sc Storage) GetSBOMMeta(name string) (*v1beta1.SBOMSyft, error) {
 	return sc.StorageClient.SBOMSyfts(sc.namespace).Get(context.Background(), name, metav1.GetOptions{ResourceVersion: "metadata"})
 }
 
+func (sc Storage) ListSBOMMetas() ([]v1beta1.SBOMSyft, error) {
+	list, err := sc.StorageClient.SBOMSyfts(sc.namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (sc Storage) DeleteSBOM(name string) error {
+	return sc.StorageClient.SBOMSyfts(sc.namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
+
 func (sc Storage) ReplaceSBOM(SBOM *v1beta1.SBOMSyft) (*v1beta1.SBOMSyft, error) {
 	return sc.StorageClient.SBOMSyfts(sc.namespace).Update(context.Background(), SBOM, metav1.UpdateOptions{})
 }
 
+func (sc Storage) CreateFilteredSBOM(SBOM *v1beta1.SBOMSyftFiltered) (*v1beta1.SBOMSyftFiltered, error) {
+	return sc.StorageClient.SBOMSyftFiltereds(SBOM.Namespace).Create(context.Background(), SBOM, metav1.CreateOptions{})
+}
+
+func (sc Storage) GetFilteredSBOMMeta(namespace, name string) (*v1beta1.SBOMSyftFiltered, error) {
+	return sc.StorageClient.SBOMSyftFiltereds(namespace).Get(context.Background(), name, metav1.GetOptions{ResourceVersion: "metadata"})
+}
+
+func (sc Storage) ReplaceFilteredSBOM(SBOM *v1beta1.SBOMSyftFiltered) (*v1beta1.SBOMSyftFiltered, error) {
+	return sc.StorageClient.SBOMSyftFiltereds(SBOM.Namespace).Update(context.Background(), SBOM, metav1.UpdateOptions{})
+}
+
+func (sc Storage) CreateVulnerabilityManifest(manifest *v1beta1.VulnerabilityManifest) (*v1beta1.VulnerabilityManifest, error) {
+	return sc.StorageClient.VulnerabilityManifests(sc.namespace).Create(context.Background(), manifest, metav1.CreateOptions{})
+}
+
+func (sc Storage) GetVulnerabilityManifestMeta(name string) (*v1beta1.VulnerabilityManifest, error) {
+	return sc.StorageClient.VulnerabilityManifests(sc.namespace).Get(context.Background(), name, metav1.GetOptions{ResourceVersion: "metadata"})
+}
+
+func (sc Storage) ReplaceVulnerabilityManifest(manifest *v1beta1.VulnerabilityManifest) (*v1beta1.VulnerabilityManifest, error) {
+	return sc.StorageClient.VulnerabilityManifests(sc.namespace).Update(context.Background(), manifest, metav1.UpdateOptions{})
+}
+
 func (sc Storage) IncrementImageUse(_ string) {
 	// noop
 }
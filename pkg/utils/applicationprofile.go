@@ -11,8 +11,18 @@ import (
 const (
 	ProcessAllowed   = "processAllowed"
 	ContainerAllowed = "containerAllowed"
+	// ProcessLineageRuleID is the rule policy under which parent->child exec edges are stored in the profile
+	ProcessLineageRuleID = "R0012"
+	// ProcessLineageLearned is the entry of the lineage rule policy marking the profiles recorded with lineage learning,
+	// the containers whose parents are outside of them have no edge
+	ProcessLineageLearned = "lineage-learned"
 )
 
+// CreateProcessLineageEdge returns the identifier of a parent->child exec edge.
+func CreateProcessLineageEdge(parentPath, path string) string {
+	return parentPath + "->" + path
+}
+
 func CreateCapabilitiesPatchOperations(capabilities, syscalls []string, execs map[string][]string, opens map[string]mapset.Set[string], endpoints map[string]*v1beta1.HTTPEndpoint, rulePolicies map[string]v1beta1.RulePolicy, containerType string, containerIndex int) []PatchOperation {
 	var profileOperations []PatchOperation
 	// add capabilities
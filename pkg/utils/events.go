@@ -17,17 +17,30 @@ type EnrichEvent interface {
 type EventType string
 
 const (
-	ExecveEventType       EventType = "exec"
-	OpenEventType         EventType = "open"
-	CapabilitiesEventType EventType = "capabilities"
-	DnsEventType          EventType = "dns"
-	NetworkEventType      EventType = "network"
-	SyscallEventType      EventType = "syscall"
-	RandomXEventType      EventType = "randomx"
-	SymlinkEventType      EventType = "symlink"
-	HardlinkEventType     EventType = "hardlink"
-	SSHEventType          EventType = "ssh"
-	HTTPEventType         EventType = "http"
-	PtraceEventType       EventType = "ptrace"
-	AllEventType          EventType = "all"
+	ExecveEventType            EventType = "exec"
+	OpenEventType              EventType = "open"
+	CapabilitiesEventType      EventType = "capabilities"
+	DnsEventType               EventType = "dns"
+	DnsNoAnswerEventType       EventType = "dnsnoanswer"
+	NetworkEventType           EventType = "network"
+	SyscallEventType           EventType = "syscall"
+	RandomXEventType           EventType = "randomx"
+	SymlinkEventType           EventType = "symlink"
+	HardlinkEventType          EventType = "hardlink"
+	SSHEventType               EventType = "ssh"
+	HTTPEventType              EventType = "http"
+	PtraceEventType            EventType = "ptrace"
+	FileSweepEventType         EventType = "filesweep"
+	ForkEventType              EventType = "fork"
+	ExitEventType              EventType = "exit"
+	SeccompEventType           EventType = "seccomp"
+	UnlinkEventType            EventType = "unlink"
+	RenameEventType            EventType = "rename"
+	ChmodEventType             EventType = "chmod"
+	ChownEventType             EventType = "chown"
+	MkdirEventType             EventType = "mkdir"
+	MmapEventType              EventType = "mmap"
+	ReverseShellEventType      EventType = "reverseshell"
+	ImageVerificationEventType EventType = "imageverification"
+	AllEventType               EventType = "all"
 )
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+const maxCachedFileHashes = 10000
+
+// FileHashes holds the hashes of a file content.
+type FileHashes struct {
+	MD5    string
+	SHA1   string
+	SHA256 string
+}
+
+// fileHashesCache caches the hashes by path, size and modification time, so a file
+// rewritten in place is hashed again.
+var fileHashesCache, _ = lru.New[string, FileHashes](maxCachedFileHashes)
+
+// GetFileHashes returns the MD5, SHA1 and SHA256 hashes of the given file, using a cache.
+func GetFileHashes(path string) (FileHashes, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FileHashes{}, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return FileHashes{}, err
+	}
+	key := fmt.Sprintf("%s:%d:%d", path, fileInfo.Size(), fileInfo.ModTime().UnixNano())
+	if hashes, ok := fileHashesCache.Get(key); ok {
+		return hashes, nil
+	}
+
+	md5Hash := md5.New()
+	sha1Hash := sha1.New()
+	sha256Hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, sha1Hash, sha256Hash), file); err != nil {
+		return FileHashes{}, err
+	}
+
+	hashes := FileHashes{
+		MD5:    hashToString(md5Hash),
+		SHA1:   hashToString(sha1Hash),
+		SHA256: hashToString(sha256Hash),
+	}
+	fileHashesCache.Add(key, hashes)
+	return hashes, nil
+}
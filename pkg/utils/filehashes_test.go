@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFileHashes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(path, []byte("hello"), 0644))
+
+	hashes, err := GetFileHashes(path)
+	assert.NoError(t, err)
+	assert.Equal(t, FileHashes{
+		MD5:    "5d41402abc4b2a76b9719d911017c592",
+		SHA1:   "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d",
+		SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}, hashes)
+
+	sha1Hash, md5Hash, err := CalculateFileHashes(path)
+	assert.NoError(t, err)
+	assert.Equal(t, hashes.SHA1, sha1Hash)
+	assert.Equal(t, hashes.MD5, md5Hash)
+
+	// a rewritten file is hashed again
+	assert.NoError(t, os.WriteFile(path, []byte("hello world"), 0644))
+	hashes, err = GetFileHashes(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", hashes.SHA256)
+
+	_, err = GetFileHashes(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"hash"
 	"io"
+	"io/fs"
 	"iter"
 	"math/rand"
 	"os"
@@ -460,6 +461,12 @@ func GetExecPathFromEvent(event *tracerexectype.Event) string {
 	return event.Comm
 }
 
+// Get the path of the executable from the given command line.
+func GetExecPathFromCmdline(cmdline string) string {
+	path, _, _ := strings.Cut(cmdline, " ")
+	return path
+}
+
 // Get exec args from the given event.
 func GetExecArgsFromEvent(event *tracerexectype.Event) []string {
 	if len(event.Args) > 1 {
@@ -484,6 +491,26 @@ func GetFileSize(path string) (int64, error) {
 	return fileInfo.Size(), nil
 }
 
+// WalkFiles calls fn for the given file or for each file under the given directory. The hidden files and directories
+// are skipped, including the ..data links of mounted ConfigMaps, so their files are visited once.
+func WalkFiles(root string, fn func(path string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		return fn(path, d)
+	})
+}
+
 func CalculateSHA256FileExecHash(path string, args []string) string {
 	hsh := sha256.New()
 	hsh.Write([]byte(fmt.Sprintf("%s;%v", path, args)))
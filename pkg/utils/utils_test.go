@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"io/fs"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -380,3 +383,33 @@ func TestNormalizeImageName(t *testing.T) {
 		})
 	}
 }
+
+func TestWalkFiles(t *testing.T) {
+	// lay out the directory like a mounted ConfigMap
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "..2025_01_01_00_00_00.000000000")
+	assert.NoError(t, os.Mkdir(dataDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dataDir, "feed.txt"), []byte("data"), 0644))
+	assert.NoError(t, os.Symlink(filepath.Base(dataDir), filepath.Join(dir, "..data")))
+	assert.NoError(t, os.Symlink(filepath.Join("..data", "feed.txt"), filepath.Join(dir, "feed.txt")))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "rules.yar"), []byte("data"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), []byte("data"), 0644))
+
+	var paths []string
+	assert.NoError(t, WalkFiles(dir, func(path string, _ fs.DirEntry) error {
+		paths = append(paths, path)
+		return nil
+	}))
+	assert.Equal(t, []string{filepath.Join(dir, "feed.txt"), filepath.Join(dir, "nested", "rules.yar")}, paths)
+
+	// a file is visited by itself, even when hidden
+	paths = nil
+	assert.NoError(t, WalkFiles(filepath.Join(dir, ".hidden"), func(path string, _ fs.DirEntry) error {
+		paths = append(paths, path)
+		return nil
+	}))
+	assert.Equal(t, []string{filepath.Join(dir, ".hidden")}, paths)
+
+	assert.Error(t, WalkFiles(filepath.Join(dir, "missing"), func(string, fs.DirEntry) error { return nil }))
+}
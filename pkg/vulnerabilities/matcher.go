@@ -0,0 +1,295 @@
+//go:build grype
+
+package vulnerabilities
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anchore/clio"
+	"github.com/anchore/grype/grype"
+	v5 "github.com/anchore/grype/grype/db/v5"
+	"github.com/anchore/grype/grype/db/v5/matcher"
+	"github.com/anchore/grype/grype/db/v5/matcher/dotnet"
+	"github.com/anchore/grype/grype/db/v5/matcher/golang"
+	"github.com/anchore/grype/grype/db/v5/matcher/java"
+	"github.com/anchore/grype/grype/db/v5/matcher/javascript"
+	"github.com/anchore/grype/grype/db/v5/matcher/python"
+	"github.com/anchore/grype/grype/db/v5/matcher/ruby"
+	"github.com/anchore/grype/grype/db/v5/matcher/rust"
+	"github.com/anchore/grype/grype/db/v5/matcher/stock"
+	"github.com/anchore/grype/grype/db/v5/store"
+	"github.com/anchore/grype/grype/match"
+	grypepkg "github.com/anchore/grype/grype/pkg"
+	"github.com/anchore/grype/grype/presenter/models"
+	"github.com/anchore/grype/grype/vulnerability"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/goradd/maps"
+	"github.com/kubescape/go-logger"
+	"github.com/kubescape/go-logger/helpers"
+	helpersv1 "github.com/kubescape/k8s-interface/instanceidhandler/v1/helpers"
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/storage"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	databaseFileName = "vulnerability.db"
+	// manifestNamePrefix keeps the manifests of the agent apart from the ones of the image scanner named after the SBOM
+	manifestNamePrefix = "node-agent-"
+)
+
+// Matcher matches the SBOMs against an offline Grype vulnerability database, it stores the vulnerability
+// manifest of each image and keeps its top CVEs to enrich the alerts of the image.
+type Matcher struct {
+	cfg           config.VulnerabilityMatchConfig
+	provider      *v5.ProviderStore
+	matcher       *grype.VulnerabilityMatcher
+	storageClient storage.StorageClient
+	dbBuilt       time.Time
+	topCVEs       maps.SafeMap[string, []CVE] // image digest -> top CVEs
+	version       string
+}
+
+// NewMatcher opens the vulnerability database read-only, it is never updated by the agent
+func NewMatcher(cfg config.VulnerabilityMatchConfig, storageClient storage.StorageClient) (*Matcher, error) {
+	path := cfg.DatabasePath
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("vulnerability database: %w", err)
+	}
+	if info.IsDir() {
+		path = filepath.Join(path, databaseFileName)
+	}
+	reader, err := store.New(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("opening vulnerability database: %w", err)
+	}
+	id, err := reader.GetID()
+	if err != nil {
+		_ = reader.Close()
+		return nil, fmt.Errorf("reading vulnerability database ID: %w", err)
+	}
+	if id == nil || id.SchemaVersion != v5.SchemaVersion {
+		_ = reader.Close()
+		return nil, fmt.Errorf("unsupported vulnerability database schema, expected version %d", v5.SchemaVersion)
+	}
+	vulnerabilityProvider, err := v5.NewVulnerabilityProvider(reader)
+	if err != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+	provider := &v5.ProviderStore{
+		VulnerabilityProvider:         vulnerabilityProvider,
+		VulnerabilityMetadataProvider: v5.NewVulnerabilityMetadataProvider(reader),
+		ExclusionProvider:             v5.NewMatchExclusionProvider(reader),
+		Closer:                        reader,
+	}
+	vulnerabilityMatcher := grype.DefaultVulnerabilityMatcher(*provider)
+	vulnerabilityMatcher.Matchers = matcher.NewDefaultMatchers(matcher.Config{
+		Java:       java.MatcherConfig{UseCPEs: cfg.UseCPEs},
+		Ruby:       ruby.MatcherConfig{UseCPEs: cfg.UseCPEs},
+		Python:     python.MatcherConfig{UseCPEs: cfg.UseCPEs},
+		Dotnet:     dotnet.MatcherConfig{UseCPEs: cfg.UseCPEs},
+		Javascript: javascript.MatcherConfig{UseCPEs: cfg.UseCPEs},
+		Golang:     golang.MatcherConfig{UseCPEs: cfg.UseCPEs},
+		Rust:       rust.MatcherConfig{UseCPEs: cfg.UseCPEs},
+		// the packages without a dedicated matcher are only found by CPE
+		Stock: stock.MatcherConfig{UseCPEs: true},
+	})
+	return &Matcher{
+		cfg:           cfg,
+		provider:      provider,
+		matcher:       vulnerabilityMatcher,
+		storageClient: storageClient,
+		dbBuilt:       id.BuildTimestamp,
+		version:       packageVersion("github.com/anchore/grype"),
+	}, nil
+}
+
+// Close closes the vulnerability database
+func (m *Matcher) Close() error {
+	return m.provider.Close()
+}
+
+// Match matches the packages of the SBOM and saves the vulnerability manifest of the image, see manifestName
+func (m *Matcher) Match(sbom *v1beta1.SBOMSyft) error {
+	document, err := json.Marshal(sbom.Spec.Syft)
+	if err != nil {
+		return fmt.Errorf("encoding SBOM: %w", err)
+	}
+	syftSBOM, _, _, err := syftjson.NewFormatDecoder().Decode(bytes.NewReader(document))
+	if err != nil {
+		return fmt.Errorf("decoding SBOM: %w", err)
+	}
+	packages := grypepkg.FromCollection(syftSBOM.Artifacts.Packages, grypepkg.SynthesisConfig{})
+	context := grypepkg.Context{
+		Source: &syftSBOM.Source,
+		Distro: syftSBOM.Artifacts.LinuxDistribution,
+	}
+	matches, ignoredMatches, err := m.matcher.FindMatches(packages, context)
+	if err != nil {
+		return fmt.Errorf("matching vulnerabilities: %w", err)
+	}
+
+	imageID := sbom.Annotations[helpersv1.ImageIDMetadataKey]
+	m.topCVEs.Set(imageDigest(imageID), m.rankCVEs(matches.Sorted()))
+
+	grypeDocument, err := models.NewDocument(clio.Identification{Name: "grype", Version: m.version}, packages, context, *matches, ignoredMatches, m.provider, nil, nil)
+	if err != nil {
+		return fmt.Errorf("creating vulnerability document: %w", err)
+	}
+	manifest := &v1beta1.VulnerabilityManifest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: manifestName(sbom.Name),
+			Annotations: map[string]string{
+				helpersv1.ContextMetadataKey:  helpersv1.ContextMetadataKeyNonFiltered,
+				helpersv1.ImageIDMetadataKey:  imageID,
+				helpersv1.ImageTagMetadataKey: sbom.Annotations[helpersv1.ImageTagMetadataKey],
+				helpersv1.StatusMetadataKey:   helpersv1.Ready,
+			},
+			Labels: sbom.Labels,
+		},
+		Spec: v1beta1.VulnerabilityManifestSpec{
+			Metadata: v1beta1.VulnerabilityManifestMeta{
+				Tool: v1beta1.VulnerabilityManifestToolMeta{
+					Name:            "grype",
+					Version:         m.version,
+					DatabaseVersion: m.dbBuilt.UTC().Format(time.RFC3339),
+				},
+				Report: v1beta1.VulnerabilityManifestReportMeta{
+					CreatedAt: metav1.Now(),
+				},
+			},
+		},
+	}
+	// the storage payload is the Grype JSON document
+	data, err := json.Marshal(grypeDocument)
+	if err != nil {
+		return fmt.Errorf("encoding vulnerability document: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest.Spec.Payload); err != nil {
+		return fmt.Errorf("decoding vulnerability document: %w", err)
+	}
+	return m.saveManifest(manifest)
+}
+
+// manifestName returns the name of the manifest of the SBOM, the end of the SBOM name holding its hash is kept
+func manifestName(sbomName string) string {
+	if overflow := len(manifestNamePrefix) + len(sbomName) - validation.DNS1123SubdomainMaxLength; overflow > 0 {
+		sbomName = strings.TrimLeft(sbomName[overflow:], "-.")
+	}
+	return manifestNamePrefix + sbomName
+}
+
+func (m *Matcher) saveManifest(manifest *v1beta1.VulnerabilityManifest) error {
+	_, err := m.storageClient.CreateVulnerabilityManifest(manifest)
+	if !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	existing, err := m.storageClient.GetVulnerabilityManifestMeta(manifest.Name)
+	if err != nil {
+		return err
+	}
+	manifest.ResourceVersion = existing.ResourceVersion
+	_, err = m.storageClient.ReplaceVulnerabilityManifest(manifest)
+	return err
+}
+
+// rankCVEs returns the most severe vulnerabilities, by severity then CVSS score
+func (m *Matcher) rankCVEs(matches []match.Match) []CVE {
+	type rankedCVE struct {
+		CVE
+		severity vulnerability.Severity
+	}
+	var ranked []rankedCVE
+	seen := make(map[string]bool)
+	for _, found := range matches {
+		key := found.Vulnerability.ID + "/" + found.Package.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		cve := rankedCVE{
+			CVE: CVE{
+				ID:       found.Vulnerability.ID,
+				Severity: vulnerability.UnknownSeverity.String(),
+				Package:  found.Package.Name,
+				Version:  found.Package.Version,
+				FixedIn:  found.Vulnerability.Fix.Versions,
+			},
+		}
+		if metadata, err := m.provider.VulnerabilityMetadata(found.Vulnerability.Reference); err == nil && metadata != nil {
+			cve.severity = vulnerability.ParseSeverity(metadata.Severity)
+			cve.Severity = cve.severity.String()
+			for _, cvss := range metadata.Cvss {
+				cve.Score = max(cve.Score, cvss.Metrics.BaseScore)
+			}
+		}
+		ranked = append(ranked, cve)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].severity != ranked[j].severity {
+			return ranked[i].severity > ranked[j].severity
+		}
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	if len(ranked) > m.cfg.TopCVEs {
+		ranked = ranked[:m.cfg.TopCVEs]
+	}
+	cves := make([]CVE, len(ranked))
+	for i := range ranked {
+		cves[i] = ranked[i].CVE
+	}
+	return cves
+}
+
+// TopCVEs returns the top CVEs of the image, nil if it was not matched
+func (m *Matcher) TopCVEs(digest string) []CVE {
+	return m.topCVEs.Get(imageDigest(digest))
+}
+
+// Matched returns true if the image was already matched since the agent started
+func (m *Matcher) Matched(imageID string) bool {
+	return m.topCVEs.Has(imageDigest(imageID))
+}
+
+// EnrichRuleFailure attaches the top CVEs of the image to the alert
+func (m *Matcher) EnrichRuleFailure(ruleFailure ruleengine.RuleFailure) {
+	cves := m.TopCVEs(ruleFailure.GetRuntimeAlertK8sDetails().ImageDigest)
+	if len(cves) == 0 {
+		return
+	}
+	baseRuntimeAlert := ruleFailure.GetBaseRuntimeAlert()
+	if baseRuntimeAlert.Arguments == nil {
+		baseRuntimeAlert.Arguments = make(map[string]interface{})
+	}
+	baseRuntimeAlert.Arguments[TopCVEsArgument] = cves
+	ruleFailure.SetBaseRuntimeAlert(baseRuntimeAlert)
+	logger.L().Debug("VulnerabilityMatcher - attached top CVEs to alert", helpers.String("rule ID", ruleFailure.GetRuleId()), helpers.Int("CVEs", len(cves)))
+}
+
+func packageVersion(name string) string {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range bi.Deps {
+			if dep.Path == name {
+				return dep.Version
+			}
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,40 @@
+//go:build !grype
+
+package vulnerabilities
+
+import (
+	"errors"
+
+	"github.com/kubescape/node-agent/pkg/config"
+	"github.com/kubescape/node-agent/pkg/ruleengine"
+	"github.com/kubescape/node-agent/pkg/storage"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+)
+
+var ErrNotBuilt = errors.New("vulnerability matching requires an agent built with the grype build tag")
+
+// Matcher is not available without the grype build tag
+type Matcher struct{}
+
+func NewMatcher(_ config.VulnerabilityMatchConfig, _ storage.StorageClient) (*Matcher, error) {
+	return nil, ErrNotBuilt
+}
+
+func (m *Matcher) Close() error {
+	return nil
+}
+
+func (m *Matcher) Match(_ *v1beta1.SBOMSyft) error {
+	return ErrNotBuilt
+}
+
+func (m *Matcher) TopCVEs(_ string) []CVE {
+	return nil
+}
+
+func (m *Matcher) Matched(_ string) bool {
+	return false
+}
+
+func (m *Matcher) EnrichRuleFailure(_ ruleengine.RuleFailure) {
+}
@@ -0,0 +1,161 @@
+//go:build grype
+
+package vulnerabilities
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v5 "github.com/anchore/grype/grype/db/v5"
+	"github.com/anchore/grype/grype/db/v5/store"
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/format/syftjson"
+	"github.com/anchore/syft/syft/linux"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	apitypes "github.com/armosec/armoapi-go/armotypes"
+	helpersv1 "github.com/kubescape/k8s-interface/instanceidhandler/v1/helpers"
+	"github.com/kubescape/node-agent/pkg/config"
+	ruleenginev1 "github.com/kubescape/node-agent/pkg/ruleengine/v1"
+	"github.com/kubescape/node-agent/pkg/storage"
+	"github.com/kubescape/storage/pkg/apis/softwarecomposition/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const testImageID = "docker.io/library/debian@sha256:0f9fe2a5c5d8b1e4dd3fd1f02ba3d5a6ed0cbd0e1f1d4bf47d6e7a93ef9a1c6b"
+
+// createTestDatabase writes a Grype v5 database with vulnerabilities of the Debian 12 openssl and curl packages
+func createTestDatabase(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), databaseFileName)
+	writer, err := store.New(path, true)
+	require.NoError(t, err)
+	require.NoError(t, writer.SetID(v5.NewID(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))))
+	require.NoError(t, writer.AddVulnerability(
+		v5.Vulnerability{
+			ID:                "CVE-2024-0001",
+			PackageName:       "openssl",
+			Namespace:         "debian:distro:debian:12",
+			VersionConstraint: "< 3.0.15-1~deb12u1",
+			VersionFormat:     "dpkg",
+			Fix:               v5.Fix{Versions: []string{"3.0.15-1~deb12u1"}, State: v5.FixedState},
+		},
+		v5.Vulnerability{
+			ID:                "CVE-2024-0002",
+			PackageName:       "openssl",
+			Namespace:         "debian:distro:debian:12",
+			VersionConstraint: "< 3.0.14-1~deb12u1",
+			VersionFormat:     "dpkg",
+			Fix:               v5.Fix{Versions: []string{"3.0.14-1~deb12u1"}, State: v5.FixedState},
+		},
+		v5.Vulnerability{
+			ID:                "CVE-2024-0003",
+			PackageName:       "curl",
+			Namespace:         "debian:distro:debian:12",
+			VersionConstraint: "< 7.88.1-10+deb12u8",
+			VersionFormat:     "dpkg",
+			Fix:               v5.Fix{State: v5.NotFixedState},
+		},
+		v5.Vulnerability{
+			ID:                "CVE-2024-0004",
+			PackageName:       "curl",
+			Namespace:         "debian:distro:debian:12",
+			VersionConstraint: "< 7.0.0",
+			VersionFormat:     "dpkg",
+		},
+	))
+	require.NoError(t, writer.AddVulnerabilityMetadata(
+		v5.VulnerabilityMetadata{ID: "CVE-2024-0001", Namespace: "debian:distro:debian:12", Severity: "High", Cvss: []v5.Cvss{{Version: "3.1", Metrics: v5.NewCvssMetrics(7.5, 3.9, 3.6)}}},
+		v5.VulnerabilityMetadata{ID: "CVE-2024-0002", Namespace: "debian:distro:debian:12", Severity: "High", Cvss: []v5.Cvss{{Version: "3.1", Metrics: v5.NewCvssMetrics(8.1, 3.9, 3.6)}}},
+		v5.VulnerabilityMetadata{ID: "CVE-2024-0003", Namespace: "debian:distro:debian:12", Severity: "Critical"},
+	))
+	require.NoError(t, writer.Close())
+	return path
+}
+
+func createTestSBOM(t *testing.T) *v1beta1.SBOMSyft {
+	s := sbom.SBOM{
+		Artifacts: sbom.Artifacts{
+			Packages: pkg.NewCollection(
+				pkg.Package{Name: "openssl", Version: "3.0.11-1~deb12u2", Type: pkg.DebPkg},
+				pkg.Package{Name: "curl", Version: "7.88.1-10+deb12u5", Type: pkg.DebPkg},
+				pkg.Package{Name: "bash", Version: "5.2.15-2+b7", Type: pkg.DebPkg},
+			),
+			LinuxDistribution: &linux.Release{ID: "debian", VersionID: "12"},
+		},
+		Source: source.Description{Name: "debian", Version: "12", Metadata: source.ImageMetadata{UserInput: "debian:12", ID: "sha256:7b34f2fc561c06e26d69d7a5a58a2d6a1c4cf8b7f5b6a2a5b8d2c0c3d4e5f6a7"}},
+	}
+	data, err := format.Encode(s, syftjson.NewFormatEncoder())
+	require.NoError(t, err)
+	syftSBOM := &v1beta1.SBOMSyft{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "docker.io-library-debian-12-9a1c6b",
+			Annotations: map[string]string{helpersv1.ImageIDMetadataKey: testImageID},
+		},
+	}
+	require.NoError(t, json.NewDecoder(bytes.NewReader(data)).Decode(&syftSBOM.Spec.Syft))
+	return syftSBOM
+}
+
+func TestMatch(t *testing.T) {
+	storageClient := &storage.StorageHttpClientMock{}
+	m, err := NewMatcher(config.VulnerabilityMatchConfig{DatabasePath: createTestDatabase(t), TopCVEs: 2}, storageClient)
+	require.NoError(t, err)
+	defer func() {
+		_ = m.Close()
+	}()
+
+	require.NoError(t, m.Match(createTestSBOM(t)))
+
+	// the manifest is named after the SBOM, apart from the ones of the image scanner, and has all the matches
+	require.Len(t, storageClient.VulnerabilityManifests, 1)
+	manifest := storageClient.VulnerabilityManifests[0]
+	assert.Equal(t, "node-agent-docker.io-library-debian-12-9a1c6b", manifest.Name)
+	assert.Equal(t, testImageID, manifest.Annotations[helpersv1.ImageIDMetadataKey])
+	assert.Equal(t, "2024-12-01T00:00:00Z", manifest.Spec.Metadata.Tool.DatabaseVersion)
+	var ids []string
+	for _, found := range manifest.Spec.Payload.Matches {
+		ids = append(ids, found.Vulnerability.ID)
+	}
+	assert.ElementsMatch(t, []string{"CVE-2024-0001", "CVE-2024-0002", "CVE-2024-0003"}, ids)
+
+	// the top CVEs are ranked by severity, then score
+	cves := m.TopCVEs("sha256:0f9fe2a5c5d8b1e4dd3fd1f02ba3d5a6ed0cbd0e1f1d4bf47d6e7a93ef9a1c6b")
+	require.Len(t, cves, 2)
+	assert.Equal(t, CVE{ID: "CVE-2024-0003", Severity: "critical", Package: "curl", Version: "7.88.1-10+deb12u5"}, cves[0])
+	assert.Equal(t, CVE{ID: "CVE-2024-0002", Severity: "high", Score: 8.1, Package: "openssl", Version: "3.0.11-1~deb12u2", FixedIn: []string{"3.0.14-1~deb12u1"}}, cves[1])
+
+	// the alerts of the image get the top CVEs
+	ruleFailure := &ruleenginev1.GenericRuleFailure{
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{ImageDigest: "sha256:0f9fe2a5c5d8b1e4dd3fd1f02ba3d5a6ed0cbd0e1f1d4bf47d6e7a93ef9a1c6b"},
+	}
+	m.EnrichRuleFailure(ruleFailure)
+	assert.Equal(t, cves, ruleFailure.GetBaseRuntimeAlert().Arguments[TopCVEsArgument])
+
+	// the alerts of other images are not modified
+	ruleFailure = &ruleenginev1.GenericRuleFailure{
+		RuntimeAlertK8sDetails: apitypes.RuntimeAlertK8sDetails{ImageDigest: "sha256:1234"},
+	}
+	m.EnrichRuleFailure(ruleFailure)
+	assert.Nil(t, ruleFailure.GetBaseRuntimeAlert().Arguments)
+}
+
+func TestNewMatcherMissingDatabase(t *testing.T) {
+	_, err := NewMatcher(config.VulnerabilityMatchConfig{DatabasePath: filepath.Join(t.TempDir(), "missing.db")}, &storage.StorageHttpClientMock{})
+	assert.Error(t, err)
+}
+
+func TestManifestName(t *testing.T) {
+	assert.Equal(t, "node-agent-nginx-28402d", manifestName("nginx-28402d"))
+	// the name is cut at the start of the SBOM name, the separators left at the cut are dropped
+	long := manifestName(strings.Repeat("a", 8) + "-.-" + strings.Repeat("b", 232) + "-28402d")
+	assert.Equal(t, "node-agent-"+strings.Repeat("b", 232)+"-28402d", long)
+	assert.Empty(t, validation.IsDNS1123Subdomain(long))
+}
@@ -0,0 +1,27 @@
+package vulnerabilities
+
+import "strings"
+
+// The matching against the Grype database is built with the grype build tag, the Grype dependency tree is
+// only linked into the agents enabling vulnerabilityMatching.
+
+// TopCVEsArgument is the alert argument listing the top CVEs of the image
+const TopCVEsArgument = "topCVEs"
+
+// CVE is a vulnerability matched in an image, as attached to the alerts
+type CVE struct {
+	ID       string   `json:"id"`
+	Severity string   `json:"severity"`
+	Score    float64  `json:"score,omitempty"`
+	Package  string   `json:"package"`
+	Version  string   `json:"version"`
+	FixedIn  []string `json:"fixedIn,omitempty"`
+}
+
+// imageDigest returns the digest of an image ID, e.g. "sha256:..." of "docker.io/library/nginx@sha256:..."
+func imageDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	return imageID
+}